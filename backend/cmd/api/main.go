@@ -9,13 +9,19 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/google/uuid"
+
 	"github.com/jagadeesh/grainlify/backend/internal/api"
+	"github.com/jagadeesh/grainlify/backend/internal/auth"
 	"github.com/jagadeesh/grainlify/backend/internal/bus"
 	"github.com/jagadeesh/grainlify/backend/internal/bus/natsbus"
 	"github.com/jagadeesh/grainlify/backend/internal/config"
 	"github.com/jagadeesh/grainlify/backend/internal/db"
+	"github.com/jagadeesh/grainlify/backend/internal/handlers"
 	"github.com/jagadeesh/grainlify/backend/internal/migrate"
+	"github.com/jagadeesh/grainlify/backend/internal/retention"
 	"github.com/jagadeesh/grainlify/backend/internal/syncjobs"
+	"github.com/jagadeesh/grainlify/backend/internal/tokenvault"
 )
 
 func main() {
@@ -45,6 +51,31 @@ func main() {
 		"public_base_url", cfg.PublicBaseURL,
 	)
 
+	if err := cfg.Validate(); err != nil {
+		slog.Error("invalid configuration", "error", err)
+		os.Exit(1)
+	}
+	// config can't see which claim names the auth package owns.
+	if err := auth.CheckExtraClaims(cfg.JWTExtraClaims()); err != nil {
+		slog.Error("invalid configuration", "error", fmt.Errorf("JWT_EXTRA_CLAIMS: %w", err))
+		os.Exit(1)
+	}
+
+	// GitHub rejects logins with redirect_uri_mismatch unless this matches the OAuth app settings.
+	githubRedirect, githubRedirectSource := handlers.ResolveGitHubRedirect(cfg)
+	slog.Info("GitHub OAuth redirect resolved",
+		"redirect_url", githubRedirect,
+		"source", githubRedirectSource,
+	)
+
+	vault, err := tokenvault.New(cfg.TokenVault, cfg.VaultAddr, cfg.VaultToken, cfg.VaultKVMount, cfg.VaultTokenPath)
+	if err != nil {
+		slog.Error("token vault configuration invalid", "error", err)
+		os.Exit(1)
+	}
+	tokenvault.SetDefault(vault)
+	slog.Info("token vault configured", "token_vault", cfg.TokenVault)
+
 	slog.Info("connecting to database", "step", "4", "action", "connecting_to_database")
 	var database *db.DB
 	if cfg.DBURL == "" {
@@ -74,6 +105,7 @@ func main() {
 		slog.Info("db connection successful", "step", "4.3", "action", "db_connection_successful",
 			"max_conns", 10,
 		)
+		d.MaxRetries = cfg.DBMaxRetries
 		database = d
 		defer func() {
 			slog.Info("closing database connection")
@@ -134,6 +166,13 @@ func main() {
 		slog.Info("nats skipped", "step", "6", "action", "nats_skipped", "reason", "NATS_URL not set")
 	}
 
+	if database != nil && database.Pool != nil {
+		// Per-user feature flags ride in the access token's features claim.
+		auth.SetFeatureLoader(func(ctx context.Context, userID uuid.UUID) ([]string, error) {
+			return auth.UserFeatures(ctx, database.Pool, userID)
+		})
+	}
+
 	slog.Info("initializing api", "step", "7", "action", "initializing_api")
 	app := api.New(cfg, api.Deps{DB: database, Bus: eventBus})
 	slog.Info("api initialized", "step", "7", "action", "api_initialized")
@@ -164,6 +203,24 @@ func main() {
 		)
 	}
 
+	if database != nil && database.Pool != nil {
+		// Singleton workers: with WORKER_LEADER_ELECTION only the instance holding the
+		// worker's advisory lock runs it.
+		startWorker := func(key string, run func(context.Context)) {
+			if cfg.WorkerLeaderElection {
+				go database.RunAsLeader(context.Background(), key, run)
+				return
+			}
+			go run(context.Background())
+		}
+		startWorker("github_identity_reconciler", handlers.NewGitHubIdentityReconciler(cfg, database.Pool).RunPeriodic)
+		startWorker("github_dead_token_unlinker", handlers.NewGitHubDeadTokenUnlinker(cfg, database).RunPeriodic)
+		startWorker("oauth_state_janitor", func(ctx context.Context) {
+			handlers.StartOAuthStateJanitor(ctx, database, cfg.OAuthStateCleanupInterval)
+		})
+		startWorker("retention_reaper", retention.New(cfg, database.Pool).Run)
+	}
+
 	errCh := make(chan error, 1)
 	go func() {
 		slog.Info("starting http server", "step", "9", "action", "starting_http_server",
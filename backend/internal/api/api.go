@@ -2,19 +2,23 @@ package api
 
 import (
 	"log/slog"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
+	"github.com/gofiber/fiber/v2/middleware/limiter"
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
 	"github.com/gofiber/fiber/v2/middleware/requestid"
 
 	"github.com/jagadeesh/grainlify/backend/internal/auth"
 	"github.com/jagadeesh/grainlify/backend/internal/bus"
+	"github.com/jagadeesh/grainlify/backend/internal/clientip"
 	"github.com/jagadeesh/grainlify/backend/internal/config"
 	"github.com/jagadeesh/grainlify/backend/internal/db"
+	"github.com/jagadeesh/grainlify/backend/internal/github"
 	"github.com/jagadeesh/grainlify/backend/internal/handlers"
 )
 
@@ -56,61 +60,66 @@ func New(cfg config.Config, deps Deps) *fiber.App {
 	})
 
 	app.Use(recover.New())
+	if cfg.PublicHostCheck {
+		app.Use(checkPublicHost(cfg.PublicBaseURL, cfg.ExpectedHost))
+	}
+	app.Use(retryAfter503(cfg.RetryAfterUnavailable, cfg.RetryAfterMisconfigured))
 
 	// Configure CORS from environment variables
 	corsConfig := cors.Config{
 		AllowHeaders:     "Origin, Content-Type, Accept, Authorization, X-Admin-Bootstrap-Token",
 		AllowMethods:     "GET,POST,PUT,PATCH,DELETE,OPTIONS",
 		AllowCredentials: true,
+		// Let browser clients read both budgets to self-throttle.
+		ExposeHeaders: "X-RateLimit-Limit, X-RateLimit-Remaining, X-RateLimit-Reset, Retry-After, " +
+			"X-GitHub-RateLimit-Limit, X-GitHub-RateLimit-Remaining, X-GitHub-RateLimit-Reset, " +
+			auth.RefreshedTokenHeader,
 	}
 
 	// Always use AllowOriginsFunc so we can:
 	// - allow localhost for dev
 	// - allow explicit CORS_ORIGINS (comma-separated)
 	// - allow FrontendBaseURL
-	explicitOrigins := map[string]struct{}{}
-	if strings.TrimSpace(cfg.CORSOrigins) != "" {
-		for _, o := range strings.Split(cfg.CORSOrigins, ",") {
-			o = strings.TrimSpace(o)
-			if o == "" {
-				continue
-			}
-			explicitOrigins[o] = struct{}{}
-		}
-	}
-
-	corsConfig.AllowOriginsFunc = func(origin string) bool {
-		// Always allow localhost origins for development / local frontend testing.
-		if strings.HasPrefix(origin, "http://localhost:") ||
-			strings.HasPrefix(origin, "http://127.0.0.1:") ||
-			strings.HasPrefix(origin, "https://localhost:") ||
-			strings.HasPrefix(origin, "https://127.0.0.1:") {
-			return true
-		}
+	// (wildcards like https://*.example.com are supported; see config.OriginAllowlist)
+	corsConfig.AllowOriginsFunc = cfg.OriginAllowlist().Allows
 
-		// Allow all Vercel preview deployments (*.vercel.app)
-		if strings.HasSuffix(origin, ".vercel.app") {
-			return true
-		}
+	app.Use(rejectDisallowedOrigins(corsConfig.AllowOriginsFunc, cfg.PublicBaseURL))
+	app.Use(cors.New(corsConfig))
+	app.Use(logger.New())
 
-		// Check explicit CORS origins from config
-		if _, ok := explicitOrigins[origin]; ok {
-			return true
-		}
+	// Per-IP limiter; fiber's limiter sets X-RateLimit-Limit/Remaining/Reset (seconds until reset).
+	// Webhooks are exempt: GitHub delivers from a small set of shared IPs.
+	if cfg.RateLimitMax > 0 {
+		app.Use(limiter.New(limiter.Config{
+			Max:        cfg.RateLimitMax,
+			Expiration: cfg.RateLimitWindow,
+			KeyGenerator: func(c *fiber.Ctx) string {
+				return clientip.FromRequest(c, cfg)
+			},
+			Next: func(c *fiber.Ctx) bool {
+				return strings.HasPrefix(c.Path(), "/webhooks/")
+			},
+			LimitReached: func(c *fiber.Ctx) error {
+				return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": "rate_limited"})
+			},
+		}))
+	}
 
-		// If FrontendBaseURL is set, allow it (exact match or with path)
-		if cfg.FrontendBaseURL != "" {
-			frontendBase := strings.TrimSuffix(cfg.FrontendBaseURL, "/")
-			if origin == frontendBase || strings.HasPrefix(origin, frontendBase+"/") {
-				return true
+	// Pass through the GitHub budget consumed by this request, for endpoints that call GitHub.
+	// X-GitHub-RateLimit-Reset is a unix timestamp, as GitHub sends it.
+	app.Use(func(c *fiber.Ctx) error {
+		rl := &github.RateLimit{}
+		c.Locals(github.RateLimitContextKey, rl)
+		err := c.Next()
+		if limit, remaining, reset, ok := rl.Snapshot(); ok {
+			if limit > 0 {
+				c.Set("X-GitHub-RateLimit-Limit", strconv.Itoa(limit))
 			}
+			c.Set("X-GitHub-RateLimit-Remaining", strconv.Itoa(remaining))
+			c.Set("X-GitHub-RateLimit-Reset", strconv.FormatInt(reset, 10))
 		}
-
-		return false
-	}
-
-	app.Use(cors.New(corsConfig))
-	app.Use(logger.New())
+		return err
+	})
 
 	// Routes.
 	// Root handler - also handle POST requests to catch misconfigured webhooks
@@ -137,42 +146,86 @@ func New(cfg config.Config, deps Deps) *fiber.App {
 	})
 	app.Get("/health", handlers.Health())
 	app.Get("/ready", handlers.Ready(deps.DB))
+	app.Get("/metrics", handlers.Metrics(cfg.MetricsToken))
+
+	// Every authenticated route shares one middleware so session checks apply uniformly.
+	authOpts := auth.AuthOptions{JWTSecret: cfg.JWTSecret, Issuer: cfg.JWTIssuer, RoleScopes: cfg.RoleScopes, SessionCookie: cfg.SessionCookies()}
+	if deps.DB != nil {
+		authOpts.Pool = deps.DB.Pool
+	}
+	if cfg.SlidingSession {
+		authOpts.Sliding = &auth.SlidingSession{
+			Threshold:   cfg.SlidingSessionThreshold,
+			TTL:         cfg.SlidingSessionTTL,
+			MaxLifetime: cfg.SlidingSessionMaxLifetime,
+		}
+	}
+	requireAuth := auth.RequireAuthWith(authOpts)
 
 	authHandler := handlers.NewAuthHandler(cfg, deps.DB)
-	authGroup := app.Group("/auth")
-	app.Get("/me", auth.RequireAuth(cfg.JWTSecret), authHandler.Me())
-	app.Post("/me/github/resync", auth.RequireAuth(cfg.JWTSecret), authHandler.ResyncGitHubProfile())
+	authGroup := app.Group(cfg.AuthPath("/auth"))
+	app.Get("/me", requireAuth, authHandler.Me())
+	app.Get("/me/capabilities", requireAuth, authHandler.Capabilities())
+	app.Post("/me/github/resync", requireAuth, authHandler.ResyncGitHubProfile())
+	app.Get("/me/activity", requireAuth, authHandler.MyActivity())
 
 	// User profile endpoints
 	userProfile := handlers.NewUserProfileHandler(cfg, deps.DB)
-	app.Get("/profile", auth.RequireAuth(cfg.JWTSecret), userProfile.Profile())
+	app.Get("/profile", requireAuth, userProfile.Profile())
 	app.Get("/profile/public", userProfile.PublicProfile()) // Public profile endpoint (no auth required)
-	app.Get("/profile/calendar", auth.RequireAuth(cfg.JWTSecret), userProfile.ContributionCalendar())
-	app.Get("/profile/activity", auth.RequireAuth(cfg.JWTSecret), userProfile.ContributionActivity())
-	app.Get("/profile/projects", auth.RequireAuth(cfg.JWTSecret), userProfile.ProjectsContributed())
-	app.Put("/profile/update", auth.RequireAuth(cfg.JWTSecret), userProfile.UpdateProfile())
-	app.Put("/profile/avatar", auth.RequireAuth(cfg.JWTSecret), userProfile.UpdateAvatar())
+	app.Get("/profile/calendar", requireAuth, userProfile.ContributionCalendar())
+	app.Get("/profile/activity", requireAuth, userProfile.ContributionActivity())
+	app.Get("/profile/projects", requireAuth, userProfile.ProjectsContributed())
+	app.Put("/profile/update", requireAuth, userProfile.UpdateProfile())
+	app.Put("/profile/avatar", requireAuth, userProfile.UpdateAvatar())
 
 	ghOAuth := handlers.NewGitHubOAuthHandler(cfg, deps.DB)
+	// Every OAuth start stores a state row; one budget per client IP covers all of them.
+	oauthStartLimit := limitOAuthStart(cfg)
 	// GitHub-only login/signup:
-	authGroup.Get("/github/login/start", ghOAuth.LoginStart())
+	authGroup.Get("/github/login/start", oauthStartLimit, ghOAuth.LoginStart())
 	// Alias to unified callback (for backwards compatibility with older callback URLs).
 	authGroup.Get("/github/login/callback", ghOAuth.CallbackUnified())
 
 	// Legacy "link GitHub to existing account" endpoints (still available).
-	authGroup.Post("/github/start", auth.RequireAuth(cfg.JWTSecret), ghOAuth.Start())
+	authGroup.Post("/github/start", oauthStartLimit, requireAuth, ghOAuth.Start())
 	authGroup.Get("/github/callback", ghOAuth.CallbackUnified())
-	authGroup.Get("/github/status", auth.RequireAuth(cfg.JWTSecret), ghOAuth.Status())
+	authGroup.Post("/finalize", ghOAuth.Finalize())
+	authGroup.Get("/github/status", requireAuth, ghOAuth.Status())
+	authGroup.Delete("/github", requireAuth, ghOAuth.Unlink())
+	// Pending link confirmation (REQUIRE_LINK_CONFIRMATION).
+	authGroup.Get("/github/link/pending", requireAuth, ghOAuth.LinkPreview())
+	authGroup.Post("/github/link/confirm", requireAuth, ghOAuth.ConfirmLink())
+	authGroup.Delete("/github/link/pending", requireAuth, ghOAuth.CancelPendingLink())
+	authGroup.Post("/github/link/revoke", ghOAuth.RevokeLink())
+	authGroup.Get("/github/orgs", requireAuth, ghOAuth.ListOrgs())
+	authGroup.Get("/github/orgs/repos", requireAuth, ghOAuth.ListOrgRepos())
+	authGroup.Get("/github/repos/search", requireAuth, ghOAuth.SearchRepos())
+	authGroup.Get("/github/repos/summary", requireAuth, ghOAuth.RepoSummary())
+	authGroup.Get("/github/scopes/upgrade", requireAuth, ghOAuth.ScopeUpgrade())
+
+	// Login through any other registered OAuth provider (the GitHub routes above win for github).
+	oauthLogin := handlers.NewOAuthLoginHandler(cfg, deps.DB, handlers.NewOAuthProviders(cfg))
+	authGroup.Get("/:provider/login/start", oauthStartLimit, oauthLogin.Start())
+	authGroup.Get("/:provider/login/callback", oauthLogin.Callback())
+
+	// Sign out this token only, or everywhere (suspected compromise).
+	authGroup.Post("/logout", requireAuth, authHandler.Logout())
+	authGroup.Post("/sessions/revoke", requireAuth, authHandler.RevokeAllSessions())
+
+	// Rotating refresh tokens.
+	authGroup.Post("/refresh-token", requireAuth, authHandler.IssueRefreshToken())
+	authGroup.Post("/refresh", authHandler.Refresh())
 
 	// GitHub App installation endpoints
 	ghApp := handlers.NewGitHubAppHandler(cfg, deps.DB)
-	authGroup.Post("/github/app/install/start", auth.RequireAuth(cfg.JWTSecret), ghApp.StartInstallation())
-	app.Get("/auth/github/app/install/callback", ghApp.HandleInstallationCallback())
+	authGroup.Post("/github/app/install/start", requireAuth, ghApp.StartInstallation())
+	authGroup.Get("/github/app/install/callback", ghApp.HandleInstallationCallback())
 
 	// KYC verification endpoints
 	kyc := handlers.NewKYCHandler(cfg, deps.DB)
-	authGroup.Post("/kyc/start", auth.RequireAuth(cfg.JWTSecret), kyc.Start())
-	authGroup.Get("/kyc/status", auth.RequireAuth(cfg.JWTSecret), kyc.Status())
+	authGroup.Post("/kyc/start", requireAuth, kyc.Start())
+	authGroup.Get("/kyc/status", requireAuth, kyc.Status())
 
 	// Public ecosystems list (includes computed project_count and user_count).
 	ecosystems := handlers.NewEcosystemsPublicHandler(deps.DB)
@@ -198,33 +251,44 @@ func New(cfg config.Config, deps Deps) *fiber.App {
 	app.Get("/projects/filters", projectsPublic.FilterOptions())
 
 	projects := handlers.NewProjectsHandler(cfg, deps.DB)
-	app.Post("/projects", auth.RequireAuth(cfg.JWTSecret), projects.Create())
+	app.Post("/projects", requireAuth, projects.Create())
 	// IMPORTANT: /projects/mine must come BEFORE /projects/:id to avoid route conflict
-	app.Get("/projects/mine", auth.RequireAuth(cfg.JWTSecret), projects.Mine())
+	app.Get("/projects/mine", requireAuth, projects.Mine())
 
 	// These routes with :id must come AFTER specific routes like /projects/mine
 	app.Get("/projects/:id", projectsPublic.Get())
 	app.Get("/projects/:id/issues/public", projectsPublic.IssuesPublic())
 	app.Get("/projects/:id/prs/public", projectsPublic.PRsPublic())
-	app.Post("/projects/:id/verify", auth.RequireAuth(cfg.JWTSecret), projects.Verify())
+	app.Post("/projects/:id/verify", requireAuth, projects.Verify())
 
 	sync := handlers.NewSyncHandler(deps.DB)
-	app.Post("/projects/:id/sync", auth.RequireAuth(cfg.JWTSecret), sync.EnqueueFullSync())
-	app.Get("/projects/:id/sync/jobs", auth.RequireAuth(cfg.JWTSecret), sync.JobsForProject())
+	app.Post("/projects/:id/sync", requireAuth, sync.EnqueueFullSync())
+	app.Get("/projects/:id/sync/jobs", requireAuth, sync.JobsForProject())
 
 	data := handlers.NewProjectDataHandler(deps.DB)
-	app.Get("/projects/:id/issues", auth.RequireAuth(cfg.JWTSecret), data.Issues())
-	app.Get("/projects/:id/prs", auth.RequireAuth(cfg.JWTSecret), data.PRs())
-	app.Get("/projects/:id/events", auth.RequireAuth(cfg.JWTSecret), data.Events())
+	app.Get("/projects/:id/issues", requireAuth, data.Issues())
+	app.Get("/projects/:id/prs", requireAuth, data.PRs())
+	app.Get("/projects/:id/events", requireAuth, data.Events())
 
 	issueApps := handlers.NewIssueApplicationsHandler(cfg, deps.DB)
-	app.Post("/projects/:id/issues/:number/apply", auth.RequireAuth(cfg.JWTSecret), issueApps.Apply())
+	app.Post("/projects/:id/issues/:number/apply", requireAuth, issueApps.Apply())
 
 	admin := handlers.NewAdminHandler(cfg, deps.DB)
-	adminGroup := app.Group("/admin", auth.RequireAuth(cfg.JWTSecret))
+	adminGroup := app.Group("/admin", requireAuth)
 	adminGroup.Post("/bootstrap", admin.BootstrapAdmin())
 	adminGroup.Get("/users", auth.RequireRole("admin"), admin.ListUsers())
 	adminGroup.Put("/users/:id/role", auth.RequireRole("admin"), admin.SetUserRole())
+	adminGroup.Post("/users/:id/sessions/revoke", auth.RequireRole("admin"), admin.RevokeUserSessions())
+	adminGroup.Get("/users/:id/features", auth.RequireRole("admin"), admin.ListUserFeatures())
+	adminGroup.Put("/users/:id/features/:feature", auth.RequireRole("admin"), admin.EnableUserFeature())
+	adminGroup.Delete("/users/:id/features/:feature", auth.RequireRole("admin"), admin.DisableUserFeature())
+	adminGroup.Get("/cache/stats", auth.RequireRole("admin"), admin.CacheStats())
+	adminGroup.Get("/github-identities/stats", auth.RequireRole("admin"), admin.GitHubIdentityStats())
+	adminGroup.Get("/login-event-webhooks/stats", auth.RequireRole("admin"), admin.LoginEventWebhookStats())
+	adminGroup.Get("/github-oauth/redirect", auth.RequireRole("admin"), admin.GitHubRedirectDebug())
+	adminGroup.Post("/github-accounts/import", auth.RequireRole("admin"), admin.ImportGitHubAccount())
+	adminGroup.Post("/github-accounts/revoke-scope", auth.RequireRole("admin"), admin.RevokeByScope())
+	adminGroup.Get("/audit", auth.RequireRole("admin"), admin.ListAuditEvents())
 
 	ecosystemsAdmin := handlers.NewEcosystemsAdminHandler(deps.DB)
 	adminGroup.Get("/ecosystems", auth.RequireRole("admin"), ecosystemsAdmin.List())
@@ -0,0 +1,45 @@
+package api
+
+import (
+	"log/slog"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// rejectDisallowedOrigins makes CORS rejections visible. The cors middleware just leaves out the
+// Access-Control-* headers for an origin it doesn't allow, which browsers report as an opaque
+// CORS error. Every rejection is logged with the request id; preflights still go on to the cors
+// middleware, while actual requests are stopped with a structured origin_not_allowed error.
+//
+// Requests from the API's own origin (same-origin POSTs carry an Origin header too) and requests
+// without an Origin (server-to-server, webhooks) are never affected.
+func rejectDisallowedOrigins(allows func(string) bool, publicBaseURL string) fiber.Handler {
+	public := strings.ToLower(strings.TrimSuffix(strings.TrimSpace(publicBaseURL), "/"))
+	return func(c *fiber.Ctx) error {
+		origin := c.Get(fiber.HeaderOrigin)
+		if origin == "" || allows(origin) {
+			return c.Next()
+		}
+		o := strings.ToLower(strings.TrimSuffix(origin, "/"))
+		if o == strings.ToLower(c.BaseURL()) || (public != "" && o == public) {
+			return c.Next()
+		}
+
+		preflight := c.Method() == fiber.MethodOptions && c.Get(fiber.HeaderAccessControlRequestMethod) != ""
+		slog.Warn("CORS origin not allowed",
+			"origin", origin,
+			"method", c.Method(),
+			"path", c.Path(),
+			"preflight", preflight,
+			"request_id", c.Locals("requestid"),
+		)
+		if preflight {
+			return c.Next()
+		}
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error":  "origin_not_allowed",
+			"origin": origin,
+		})
+	}
+}
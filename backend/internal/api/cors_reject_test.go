@@ -0,0 +1,42 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestRejectDisallowedOrigins(t *testing.T) {
+	app := fiber.New()
+	app.Use(rejectDisallowedOrigins(func(o string) bool { return o == "https://app.example.com" }, "https://api.example.com"))
+	app.All("/x", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusNoContent) })
+
+	cases := []struct {
+		method, origin, preflight string
+		want                      int
+	}{
+		{"GET", "", "", fiber.StatusNoContent},
+		{"GET", "https://app.example.com", "", fiber.StatusNoContent},
+		{"POST", "https://api.example.com", "", fiber.StatusNoContent},
+		{"POST", "http://example.com", "", fiber.StatusNoContent}, // the request's own origin
+		{"GET", "https://evil.example.net", "", fiber.StatusForbidden},
+		{"OPTIONS", "https://evil.example.net", "POST", fiber.StatusNoContent},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(tc.method, "http://example.com/x", nil)
+		if tc.origin != "" {
+			req.Header.Set(fiber.HeaderOrigin, tc.origin)
+		}
+		if tc.preflight != "" {
+			req.Header.Set(fiber.HeaderAccessControlRequestMethod, tc.preflight)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != tc.want {
+			t.Errorf("%s from %q: status %d, want %d", tc.method, tc.origin, resp.StatusCode, tc.want)
+		}
+	}
+}
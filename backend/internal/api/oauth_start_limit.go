@@ -0,0 +1,98 @@
+package api
+
+import (
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/jagadeesh/grainlify/backend/internal/clientip"
+	"github.com/jagadeesh/grainlify/backend/internal/config"
+)
+
+// tokenBucketLimiter allows each key up to perMinute requests per minute, refilled
+// continuously, with bursts of up to perMinute. Every OAuth start writes an oauth_states row,
+// so these endpoints get their own, tighter budget than the global per-IP limiter.
+type tokenBucketLimiter struct {
+	capacity float64
+	perSec   float64
+	now      func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	calls   int
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucketLimiter(perMinute int) *tokenBucketLimiter {
+	return &tokenBucketLimiter{
+		capacity: float64(perMinute),
+		perSec:   float64(perMinute) / 60,
+		now:      time.Now,
+		buckets:  map[string]*tokenBucket{},
+	}
+}
+
+// allow takes a token for key. When none is left it reports how long until one is.
+func (l *tokenBucketLimiter) allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.calls++
+	if l.calls%1024 == 0 {
+		l.sweep(now)
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: l.capacity, last: now}
+		l.buckets[key] = b
+	}
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(l.capacity, b.tokens+elapsed*l.perSec)
+	}
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - b.tokens) / l.perSec * float64(time.Second))
+	return false, wait
+}
+
+// sweep drops buckets that have refilled completely; they're indistinguishable from new ones.
+func (l *tokenBucketLimiter) sweep(now time.Time) {
+	full := time.Duration(l.capacity / l.perSec * float64(time.Second))
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= full {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// limitOAuthStart rate-limits the OAuth start endpoints per client IP (see clientIP).
+func limitOAuthStart(cfg config.Config) fiber.Handler {
+	if cfg.OAuthStartRateLimit <= 0 {
+		return func(c *fiber.Ctx) error { return c.Next() }
+	}
+	l := newTokenBucketLimiter(cfg.OAuthStartRateLimit)
+	return func(c *fiber.Ctx) error {
+		ok, wait := l.allow(clientip.FromRequest(c, cfg))
+		if ok {
+			return c.Next()
+		}
+		secs := int(math.Ceil(wait.Seconds()))
+		if secs < 1 {
+			secs = 1
+		}
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(secs))
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": "rate_limited"})
+	}
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/jagadeesh/grainlify/backend/internal/config"
+)
+
+func oauthStartApp(cfg config.Config) *fiber.App {
+	app := fiber.New()
+	app.Get("/auth/github/login/start", limitOAuthStart(cfg), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusFound)
+	})
+	return app
+}
+
+func startStatus(t *testing.T, app *fiber.App, xff string) (int, string) {
+	t.Helper()
+	req := httptest.NewRequest("GET", "/auth/github/login/start", nil)
+	if xff != "" {
+		req.Header.Set("X-Forwarded-For", xff)
+	}
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, resp.Header.Get("Retry-After")
+}
+
+func TestLimitOAuthStartReturns429WithRetryAfter(t *testing.T) {
+	app := oauthStartApp(config.Config{OAuthStartRateLimit: 3})
+	for i := 0; i < 3; i++ {
+		if code, _ := startStatus(t, app, ""); code != fiber.StatusFound {
+			t.Fatalf("request %d: status = %d, want 302", i+1, code)
+		}
+	}
+	code, retry := startStatus(t, app, "")
+	if code != fiber.StatusTooManyRequests {
+		t.Fatalf("status over limit = %d, want 429", code)
+	}
+	// 3/minute refills a token every 20s.
+	if retry != "20" {
+		t.Errorf("Retry-After = %q, want 20", retry)
+	}
+}
+
+func TestLimitOAuthStartDisabled(t *testing.T) {
+	app := oauthStartApp(config.Config{})
+	for i := 0; i < 50; i++ {
+		if code, _ := startStatus(t, app, ""); code != fiber.StatusFound {
+			t.Fatalf("request %d: status = %d, want 302", i+1, code)
+		}
+	}
+}
+
+func TestLimitOAuthStartIgnoresForwardedForUnlessTrusted(t *testing.T) {
+	app := oauthStartApp(config.Config{OAuthStartRateLimit: 1})
+	if code, _ := startStatus(t, app, "203.0.113.1"); code != fiber.StatusFound {
+		t.Fatalf("first request: status = %d", code)
+	}
+	// A spoofed X-Forwarded-For must not buy a fresh bucket.
+	if code, _ := startStatus(t, app, "203.0.113.2"); code != fiber.StatusTooManyRequests {
+		t.Fatalf("spoofed X-Forwarded-For: status = %d, want 429", code)
+	}
+}
+
+func TestLimitOAuthStartKeysOnForwardedForWhenTrusted(t *testing.T) {
+	app := oauthStartApp(config.Config{
+		OAuthStartRateLimit:      1,
+		TrustProxyHeaders:        true,
+		ExternalIDTrustedProxies: []string{"10.0.0.0/8"},
+	})
+	if code, _ := startStatus(t, app, "203.0.113.1, 10.0.0.5"); code != fiber.StatusFound {
+		t.Fatalf("client 1: status = %d", code)
+	}
+	if code, _ := startStatus(t, app, "203.0.113.2, 10.0.0.5"); code != fiber.StatusFound {
+		t.Fatalf("client 2: status = %d, want its own bucket", code)
+	}
+	// The client can prepend anything; only the hop our proxy appended counts.
+	if code, _ := startStatus(t, app, "198.51.100.9, 203.0.113.1, 10.0.0.5"); code != fiber.StatusTooManyRequests {
+		t.Fatalf("client 1 with prepended hop: status = %d, want 429", code)
+	}
+}
+
+func TestTokenBucketRefills(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	l := newTokenBucketLimiter(60)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 60; i++ {
+		if ok, _ := l.allow("ip"); !ok {
+			t.Fatalf("burst request %d refused", i+1)
+		}
+	}
+	ok, wait := l.allow("ip")
+	if ok || wait != time.Second {
+		t.Fatalf("allow over burst = %v, %s; want false, 1s", ok, wait)
+	}
+	now = now.Add(time.Second)
+	if ok, _ := l.allow("ip"); !ok {
+		t.Fatal("token not refilled after 1s")
+	}
+	if ok, _ := l.allow("other"); !ok {
+		t.Fatal("other key shares the bucket")
+	}
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"log/slog"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// checkPublicHost warns when PUBLIC_BASE_URL names a different host than the one the API is
+// actually served on, the usual copy-paste-wrong-domain mistake that otherwise only shows up
+// as GitHub redirect_uri_mismatch or webhooks going elsewhere. With expectedHost set, the check
+// runs once at startup; otherwise the first request (probes excluded) supplies the host.
+// It only logs; requests are never affected.
+func checkPublicHost(publicBaseURL, expectedHost string) fiber.Handler {
+	configured := publicBaseHost(publicBaseURL)
+	if configured == "" {
+		return func(c *fiber.Ctx) error { return c.Next() }
+	}
+	if expectedHost != "" {
+		warnPublicHostMismatch(configured, expectedHost, "EXPECTED_HOST")
+		return func(c *fiber.Ctx) error { return c.Next() }
+	}
+
+	var once sync.Once
+	return func(c *fiber.Ctx) error {
+		if p := c.Path(); p != "/health" && p != "/ready" && p != "/metrics" {
+			once.Do(func() {
+				warnPublicHostMismatch(configured, c.Hostname(), "first request")
+			})
+		}
+		return c.Next()
+	}
+}
+
+func warnPublicHostMismatch(configured, serving, source string) {
+	if sameHost(configured, serving) {
+		slog.Info("PUBLIC_BASE_URL host matches serving host", "host", configured, "source", source)
+		return
+	}
+	slog.Warn("PUBLIC_BASE_URL host does not match the host this API is served on; "+
+		"OAuth callbacks and webhook URLs built from it will point elsewhere",
+		"public_base_url_host", configured,
+		"serving_host", serving,
+		"source", source,
+	)
+}
+
+// publicBaseHost returns the host[:port] of PUBLIC_BASE_URL, or "" when unset or unparsable.
+func publicBaseHost(publicBaseURL string) string {
+	u, err := url.Parse(strings.TrimSpace(publicBaseURL))
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+// sameHost compares two host[:port] values case-insensitively. A port on only one side is
+// ignored, since proxies commonly drop or add the default port.
+func sameHost(a, b string) bool {
+	ah, ap := splitHostPort(a)
+	bh, bp := splitHostPort(b)
+	if !strings.EqualFold(ah, bh) {
+		return false
+	}
+	return ap == "" || bp == "" || ap == bp
+}
+
+func splitHostPort(hostport string) (host, port string) {
+	hostport = strings.TrimSpace(hostport)
+	if h, p, err := net.SplitHostPort(hostport); err == nil {
+		return h, p
+	}
+	return strings.Trim(hostport, "[]"), ""
+}
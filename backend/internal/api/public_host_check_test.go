@@ -0,0 +1,32 @@
+package api
+
+import "testing"
+
+func TestSameHost(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want bool
+	}{
+		{"api.example.com", "api.example.com", true},
+		{"API.example.com", "api.example.com", true},
+		{"api.example.com:443", "api.example.com", true},
+		{"localhost:8080", "localhost:8080", true},
+		{"localhost:8080", "localhost:3000", false},
+		{"api.example.com", "api.example.org", false},
+		{"[::1]:8080", "::1", true},
+	}
+	for _, tc := range cases {
+		if got := sameHost(tc.a, tc.b); got != tc.want {
+			t.Errorf("sameHost(%q, %q) = %v, want %v", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+func TestPublicBaseHost(t *testing.T) {
+	if got := publicBaseHost("https://api.example.com/"); got != "api.example.com" {
+		t.Errorf("publicBaseHost = %q", got)
+	}
+	if got := publicBaseHost(""); got != "" {
+		t.Errorf("expected empty host for unset PUBLIC_BASE_URL, got %q", got)
+	}
+}
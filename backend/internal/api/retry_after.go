@@ -0,0 +1,46 @@
+package api
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// retryAfter503 adds a Retry-After header to 503 responses that don't set one. Handlers report
+// why they're unavailable in the "error" (or, for /ready, "reason") field; missing
+// configuration won't fix itself soon, so it gets the long hint. A missing DB pool is treated as
+// transient: it's usually the database still coming up.
+func retryAfter503(unavailable, misconfigured time.Duration) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		err := c.Next()
+		if c.Response().StatusCode() != fiber.StatusServiceUnavailable || c.GetRespHeader(fiber.HeaderRetryAfter) != "" {
+			return err
+		}
+		d := unavailable
+		if isMisconfiguration(c.Response().Body()) {
+			d = misconfigured
+		}
+		if secs := int(d.Round(time.Second).Seconds()); secs > 0 {
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(secs))
+		}
+		return err
+	}
+}
+
+func isMisconfiguration(body []byte) bool {
+	var resp struct {
+		Error  string `json:"error"`
+		Reason string `json:"reason"`
+	}
+	if json.Unmarshal(body, &resp) != nil {
+		return false
+	}
+	code := resp.Error
+	if code == "" {
+		code = resp.Reason
+	}
+	return strings.HasSuffix(code, "_not_configured") && code != "db_not_configured"
+}
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestRetryAfter503(t *testing.T) {
+	app := fiber.New()
+	app.Use(retryAfter503(10*time.Second, 5*time.Minute))
+	app.Get("/db", func(c *fiber.Ctx) error {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "db_not_configured"})
+	})
+	app.Get("/secret", func(c *fiber.Ctx) error {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "github_oauth_not_configured"})
+	})
+	app.Get("/ready", func(c *fiber.Ctx) error {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"ok": false, "reason": "db_unreachable"})
+	})
+	app.Get("/own", func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderRetryAfter, "1")
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "busy"})
+	})
+	app.Get("/ok", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	cases := map[string]string{
+		"/db":     "10",
+		"/secret": "300",
+		"/ready":  "10",
+		"/own":    "1",
+		"/ok":     "",
+	}
+	for path, want := range cases {
+		resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := resp.Header.Get(fiber.HeaderRetryAfter); got != want {
+			t.Errorf("%s: Retry-After = %q, want %q", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+// Package apierr is the error shape API handlers answer with: a stable snake_case code that
+// clients switch on, an optional human-readable message, and the HTTP status.
+package apierr
+
+import "github.com/gofiber/fiber/v2"
+
+// Error is an API error. Code is the contract with clients and must not change once
+// published; Message is for humans and may.
+type Error struct {
+	Code       string
+	Message    string
+	HTTPStatus int
+}
+
+// LocalCode is the fiber.Ctx local Respond stores the error code under, so middleware that
+// runs after the handler (e.g. metrics) can tell which error was answered.
+const LocalCode = "apierr_code"
+
+// New returns the error with code, answered with status.
+func New(status int, code string) Error {
+	return Error{Code: code, HTTPStatus: status}
+}
+
+// WithMessage returns a copy of e carrying msg.
+func (e Error) WithMessage(msg string) Error {
+	e.Message = msg
+	return e
+}
+
+func (e Error) Error() string {
+	if e.Message == "" {
+		return e.Code
+	}
+	return e.Code + ": " + e.Message
+}
+
+// Body is the JSON envelope: {"error": code} plus "message" when there is one.
+func (e Error) Body() fiber.Map {
+	body := fiber.Map{"error": e.Code}
+	if e.Message != "" {
+		body["message"] = e.Message
+	}
+	return body
+}
+
+// Respond writes e as the response. A zero HTTPStatus answers 500.
+func Respond(c *fiber.Ctx, e Error) error {
+	status := e.HTTPStatus
+	if status == 0 {
+		status = fiber.StatusInternalServerError
+	}
+	c.Locals(LocalCode, e.Code)
+	return c.Status(status).JSON(e.Body())
+}
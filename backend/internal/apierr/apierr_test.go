@@ -0,0 +1,60 @@
+package apierr
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestRespond(t *testing.T) {
+	cases := []struct {
+		name       string
+		err        Error
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "code only",
+			err:        New(fiber.StatusServiceUnavailable, "db_not_configured"),
+			wantStatus: fiber.StatusServiceUnavailable,
+			wantBody:   `{"error":"db_not_configured"}`,
+		},
+		{
+			name:       "with message",
+			err:        New(fiber.StatusForbidden, "redirect_uri_not_allowed").WithMessage("Redirect URI is not from an allowed origin"),
+			wantStatus: fiber.StatusForbidden,
+			wantBody:   `{"error":"redirect_uri_not_allowed","message":"Redirect URI is not from an allowed origin"}`,
+		},
+		{
+			name:       "missing status",
+			err:        Error{Code: "token_issue_failed"},
+			wantStatus: fiber.StatusInternalServerError,
+			wantBody:   `{"error":"token_issue_failed"}`,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Get("/", func(c *fiber.Ctx) error { return Respond(c, tc.err) })
+			resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != tc.wantStatus || string(body) != tc.wantBody {
+				t.Errorf("got %d %s, want %d %s", resp.StatusCode, body, tc.wantStatus, tc.wantBody)
+			}
+		})
+	}
+}
+
+func TestErrorString(t *testing.T) {
+	if got := New(400, "invalid_state").Error(); got != "invalid_state" {
+		t.Errorf("Error() = %q", got)
+	}
+	if got := New(400, "invalid_state").WithMessage("expired").Error(); got != "invalid_state: expired" {
+		t.Errorf("Error() = %q", got)
+	}
+}
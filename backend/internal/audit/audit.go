@@ -0,0 +1,87 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/jagadeesh/grainlify/backend/internal/db"
+)
+
+// Actions recorded in audit_events.
+const (
+	ActionSessionsRevoked       = "sessions_revoked"
+	ActionGitHubAccountImported = "github_account_imported"
+	ActionGitHubLinkRevoked     = "github_link_revoked"
+	// One row per successful login; these rows are the login history. Logins through other
+	// providers carry the provider name in metadata.
+	ActionLogin = "github_login"
+	// A GitHub account linked (or re-authorized) through the link flow's OAuth callback.
+	ActionGitHubLink = "github_link"
+	// The user re-confirmed GitHub consent after FORCE_REAUTH_INTERVAL.
+	ActionGitHubReconsent = "github_reconsent"
+	// A github_accounts row removed after its token stayed dead past AUTO_UNLINK_DEAD_TOKEN_AFTER.
+	ActionGitHubAutoUnlinked = "github_account_auto_unlinked"
+	// A rotated refresh token was presented again; its whole family was revoked.
+	ActionRefreshTokenReuse = "refresh_token_reuse_detected"
+	// An admin flagged every GitHub account holding a scope for re-link (one event per run).
+	ActionGitHubScopeRevoked = "github_scope_revoked"
+	// The user disconnected their GitHub account (DELETE /auth/github).
+	ActionGitHubUnlinked = "github_unlinked"
+	// An admin turned a per-user feature flag on or off.
+	ActionUserFeatureSet = "user_feature_set"
+	// A first GitHub login was attached to the existing account holding its verified email.
+	ActionGitHubMergedByEmail = "github_login_merged_by_email"
+)
+
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+// Event is one row in audit_events.
+// ActorUserID is who triggered the action; UserID is the account it applies to.
+type Event struct {
+	ActorUserID *uuid.UUID
+	UserID      *uuid.UUID
+	Action      string
+	Outcome     string
+	IP          string
+	UserAgent   string
+	Metadata    map[string]any
+}
+
+func Record(ctx context.Context, d *db.DB, e Event) error {
+	if d == nil || d.Pool == nil {
+		return fmt.Errorf("db not configured")
+	}
+	if e.Action == "" {
+		return fmt.Errorf("audit action is required")
+	}
+	if e.Outcome == "" {
+		e.Outcome = OutcomeSuccess
+	}
+	meta := e.Metadata
+	if meta == nil {
+		meta = map[string]any{}
+	}
+	metaJSON, err := json.Marshal(meta)
+	if err != nil {
+		return fmt.Errorf("marshal audit metadata: %w", err)
+	}
+
+	_, err = d.Pool.Exec(ctx, `
+INSERT INTO audit_events (actor_user_id, user_id, action, outcome, ip, user_agent, metadata)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+`, e.ActorUserID, e.UserID, e.Action, e.Outcome, nullIfEmpty(e.IP), nullIfEmpty(e.UserAgent), metaJSON)
+	return err
+}
+
+func nullIfEmpty(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
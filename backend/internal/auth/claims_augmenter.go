@@ -0,0 +1,139 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+// ClaimsAugmenter returns deployment-specific claims (tenant id, plan, ...) to embed in the
+// access tokens minted for userID. Returning an error fails the token issue.
+type ClaimsAugmenter func(ctx context.Context, userID uuid.UUID) (map[string]any, error)
+
+// NoClaims is the default augmenter: it adds nothing.
+func NoClaims(context.Context, uuid.UUID) (map[string]any, error) {
+	return nil, nil
+}
+
+var claimsAugmenter ClaimsAugmenter = NoClaims
+
+// SetClaimsAugmenter installs the augmenter IssueJWT consults. Call it once at startup, before
+// any token is issued; nil restores NoClaims.
+func SetClaimsAugmenter(fn ClaimsAugmenter) {
+	if fn == nil {
+		fn = NoClaims
+	}
+	claimsAugmenter = fn
+}
+
+// reservedClaims are the claims Claims itself owns. An augmenter may not set them: sub, exp,
+// jti and role are what authorization rests on, and the rest would silently shadow fields.
+var reservedClaims = map[string]bool{
+	"iss": true, "sub": true, "aud": true, "exp": true, "nbf": true, "iat": true, "jti": true,
+	"token_use": true, "role": true, "scope": true, "wallet_type": true, "address": true, "auth_time": true,
+	"features": true,
+}
+
+// WithContext passes the request context on to the ClaimsAugmenter.
+func WithContext(ctx context.Context) IssueOption {
+	return func(c *Claims) {
+		c.issueCtx = ctx
+	}
+}
+
+// WithExtraClaims adds claims to the token, e.g. a deployment's static tenant or plan. Claims
+// from the ClaimsAugmenter win over these; reserved names fail the issue (CheckExtraClaims).
+func WithExtraClaims(extra map[string]any) IssueOption {
+	return func(c *Claims) {
+		if len(extra) == 0 {
+			return
+		}
+		if c.Extra == nil {
+			c.Extra = make(map[string]any, len(extra))
+		}
+		for k, v := range extra {
+			c.Extra[k] = v
+		}
+	}
+}
+
+// CheckExtraClaims rejects claims that would override the ones Claims owns.
+func CheckExtraClaims(extra map[string]any) error {
+	var reserved []string
+	for k := range extra {
+		if reservedClaims[k] {
+			reserved = append(reserved, k)
+		}
+	}
+	if len(reserved) > 0 {
+		sort.Strings(reserved)
+		return fmt.Errorf("reserved claims may not be set: %v", reserved)
+	}
+	return nil
+}
+
+// augmentClaims runs the configured augmenter and rejects any reserved claim it returns.
+func augmentClaims(ctx context.Context, fn ClaimsAugmenter, userID uuid.UUID) (map[string]any, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	extra, err := fn(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("claims augmenter: %w", err)
+	}
+	if err := CheckExtraClaims(extra); err != nil {
+		return nil, fmt.Errorf("claims augmenter: %w", err)
+	}
+	if len(extra) == 0 {
+		return nil, nil
+	}
+	return extra, nil
+}
+
+// claimsFields has Claims' fields without its JSON methods.
+type claimsFields Claims
+
+// MarshalJSON writes the standard claims followed by Extra.
+func (c Claims) MarshalJSON() ([]byte, error) {
+	base, err := json.Marshal(claimsFields(c))
+	if err != nil || len(c.Extra) == 0 {
+		return base, err
+	}
+	merged := map[string]any{}
+	if err := json.Unmarshal(base, &merged); err != nil {
+		return nil, err
+	}
+	for k, v := range c.Extra {
+		if !reservedClaims[k] {
+			merged[k] = v
+		}
+	}
+	return json.Marshal(merged)
+}
+
+// UnmarshalJSON reads the standard claims and keeps any others in Extra, so a renewed
+// token (SlidingSession) carries them over.
+func (c *Claims) UnmarshalJSON(data []byte) error {
+	var fields claimsFields
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	var all map[string]any
+	if err := json.Unmarshal(data, &all); err != nil {
+		return err
+	}
+	*c = Claims(fields)
+	for k, v := range all {
+		if reservedClaims[k] {
+			continue
+		}
+		if c.Extra == nil {
+			c.Extra = map[string]any{}
+		}
+		c.Extra[k] = v
+	}
+	return nil
+}
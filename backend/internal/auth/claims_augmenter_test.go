@@ -0,0 +1,115 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestIssueJWT_ClaimsAugmenter(t *testing.T) {
+	t.Cleanup(func() { SetClaimsAugmenter(nil) })
+
+	userID := uuid.New()
+	SetClaimsAugmenter(func(_ context.Context, id uuid.UUID) (map[string]any, error) {
+		if id != userID {
+			t.Errorf("augmenter got user %s, want %s", id, userID)
+		}
+		return map[string]any{"tenant_id": "acme", "plan": "pro"}, nil
+	})
+
+	token, err := IssueJWT("secret", userID, "contributor", "", "", time.Hour)
+	if err != nil {
+		t.Fatalf("IssueJWT failed: %v", err)
+	}
+	claims, err := ParseJWT("secret", token)
+	if err != nil {
+		t.Fatalf("ParseJWT failed: %v", err)
+	}
+	if claims.Subject != userID.String() || claims.Role != "contributor" || claims.ID == "" {
+		t.Errorf("standard claims changed: sub=%q role=%q jti=%q", claims.Subject, claims.Role, claims.ID)
+	}
+	if claims.Extra["tenant_id"] != "acme" || claims.Extra["plan"] != "pro" {
+		t.Errorf("expected custom claims, got %v", claims.Extra)
+	}
+
+	// Sliding renewals keep the custom claims.
+	renewed, ok, err := SlidingSession{Threshold: 2 * time.Hour, TTL: 3 * time.Hour}.Renew("secret", claims, time.Now())
+	if err != nil || !ok {
+		t.Fatalf("Renew: ok=%v err=%v", ok, err)
+	}
+	renewedClaims, err := ParseJWT("secret", renewed)
+	if err != nil {
+		t.Fatalf("ParseJWT(renewed) failed: %v", err)
+	}
+	if renewedClaims.Extra["tenant_id"] != "acme" {
+		t.Errorf("expected tenant_id to survive renewal, got %v", renewedClaims.Extra)
+	}
+}
+
+func TestIssueJWT_ClaimsAugmenterCannotOverwriteReserved(t *testing.T) {
+	t.Cleanup(func() { SetClaimsAugmenter(nil) })
+
+	for _, claim := range []string{"sub", "exp", "jti", "role"} {
+		SetClaimsAugmenter(func(context.Context, uuid.UUID) (map[string]any, error) {
+			return map[string]any{claim: "admin", "plan": "pro"}, nil
+		})
+		_, err := IssueJWT("secret", uuid.New(), "contributor", "", "", time.Hour)
+		if err == nil || !strings.Contains(err.Error(), claim) {
+			t.Errorf("expected %s to be rejected, got %v", claim, err)
+		}
+	}
+}
+
+func TestIssueJWT_DefaultAugmenterAddsNothing(t *testing.T) {
+	token, err := IssueJWT("secret", uuid.New(), "contributor", "", "", time.Hour, WithContext(context.Background()))
+	if err != nil {
+		t.Fatalf("IssueJWT failed: %v", err)
+	}
+	claims, err := ParseJWT("secret", token)
+	if err != nil {
+		t.Fatalf("ParseJWT failed: %v", err)
+	}
+	if len(claims.Extra) != 0 {
+		t.Errorf("expected no custom claims, got %v", claims.Extra)
+	}
+}
+
+func TestIssueJWT_WithExtraClaims(t *testing.T) {
+	t.Cleanup(func() { SetClaimsAugmenter(nil) })
+	SetClaimsAugmenter(func(context.Context, uuid.UUID) (map[string]any, error) {
+		return map[string]any{"plan": "pro"}, nil
+	})
+
+	static := map[string]any{"tenant": "acme", "plan": "free"}
+	token, err := IssueJWT("secret", uuid.New(), "contributor", "", "", time.Hour, WithExtraClaims(static))
+	if err != nil {
+		t.Fatalf("IssueJWT failed: %v", err)
+	}
+	claims, err := ParseJWT("secret", token)
+	if err != nil {
+		t.Fatalf("ParseJWT failed: %v", err)
+	}
+	// The per-user augmenter wins over the static claims.
+	if claims.Extra["tenant"] != "acme" || claims.Extra["plan"] != "pro" {
+		t.Errorf("extra claims = %v, want tenant=acme plan=pro", claims.Extra)
+	}
+	if static["plan"] != "free" {
+		t.Error("WithExtraClaims modified the caller's map")
+	}
+}
+
+func TestIssueJWT_WithExtraClaimsRejectsReserved(t *testing.T) {
+	for _, claim := range []string{"exp", "sub", "jti", "features"} {
+		_, err := IssueJWT("secret", uuid.New(), "contributor", "", "", time.Hour,
+			WithExtraClaims(map[string]any{claim: "x", "tenant": "acme"}))
+		if err == nil || !strings.Contains(err.Error(), claim) {
+			t.Errorf("extra claim %q: err = %v, want it rejected", claim, err)
+		}
+	}
+	if err := CheckExtraClaims(map[string]any{"tenant": "acme"}); err != nil {
+		t.Errorf("CheckExtraClaims(tenant) = %v", err)
+	}
+}
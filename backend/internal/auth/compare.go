@@ -0,0 +1,9 @@
+package auth
+
+import "crypto/subtle"
+
+// SecureCompare reports whether a and b are equal in time that depends only on their lengths,
+// for comparing secrets such as CSRF tokens.
+func SecureCompare(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
@@ -0,0 +1,21 @@
+package auth
+
+import "testing"
+
+func TestSecureCompare(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want bool
+	}{
+		{"state-token", "state-token", true},
+		{"state-token", "state-tokeN", false},
+		{"state-token", "state-toke", false},
+		{"", "", true},
+		{"", "x", false},
+	}
+	for _, tc := range cases {
+		if got := SecureCompare(tc.a, tc.b); got != tc.want {
+			t.Errorf("SecureCompare(%q, %q) = %v, want %v", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
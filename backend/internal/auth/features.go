@@ -0,0 +1,119 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// featureNamePattern is what a feature flag name may look like, e.g. "repo_insights" or
+// "billing.v2".
+var featureNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]{0,63}$`)
+
+// ValidFeatureName reports whether name can be used as a feature flag.
+func ValidFeatureName(name string) bool {
+	return featureNamePattern.MatchString(name)
+}
+
+// FeatureLoader returns the features enabled for userID, embedded by IssueJWT in the
+// features claim. Returning an error fails the token issue.
+type FeatureLoader func(ctx context.Context, userID uuid.UUID) ([]string, error)
+
+// NoFeatures is the default loader: no user has any feature.
+func NoFeatures(context.Context, uuid.UUID) ([]string, error) {
+	return nil, nil
+}
+
+var featureLoader FeatureLoader = NoFeatures
+
+// SetFeatureLoader installs the loader IssueJWT consults. Call it once at startup, before any
+// token is issued; nil restores NoFeatures.
+func SetFeatureLoader(fn FeatureLoader) {
+	if fn == nil {
+		fn = NoFeatures
+	}
+	featureLoader = fn
+}
+
+func loadFeatures(ctx context.Context, fn FeatureLoader, userID uuid.UUID) ([]string, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	features, err := fn(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("feature loader: %w", err)
+	}
+	return features, nil
+}
+
+// WithFeatures embeds features instead of asking the FeatureLoader.
+func WithFeatures(features []string) IssueOption {
+	return func(c *Claims) {
+		c.Features = append([]string{}, features...)
+	}
+}
+
+// HasFeature reports whether the token carries feature. Features toggled after the token was
+// issued only show up in the next token.
+func HasFeature(claims *Claims, feature string) bool {
+	return claims != nil && slices.Contains(claims.Features, feature)
+}
+
+// RequireFeature gates an endpoint on a per-user feature flag from the token's features claim.
+// Must run after RequireAuth/RequireAuthWith.
+func RequireFeature(feature string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, _ := c.Locals(LocalClaims).(*Claims)
+		if !HasFeature(claims, feature) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error":   "feature_not_enabled",
+				"feature": feature,
+			})
+		}
+		return c.Next()
+	}
+}
+
+// UserFeatures lists the features enabled for userID, sorted by name.
+func UserFeatures(ctx context.Context, pool *pgxpool.Pool, userID uuid.UUID) ([]string, error) {
+	if pool == nil {
+		return nil, fmt.Errorf("db not configured")
+	}
+	rows, err := pool.Query(ctx, `SELECT feature FROM user_features WHERE user_id = $1 ORDER BY feature`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var features []string
+	for rows.Next() {
+		var f string
+		if err := rows.Scan(&f); err != nil {
+			return nil, err
+		}
+		features = append(features, f)
+	}
+	return features, rows.Err()
+}
+
+// SetUserFeature turns feature on or off for userID. enabledBy (the admin, if any) is kept
+// with the row. Enabling an enabled feature or disabling a disabled one is not an error.
+func SetUserFeature(ctx context.Context, pool *pgxpool.Pool, userID uuid.UUID, feature string, enabled bool, enabledBy *uuid.UUID) error {
+	if pool == nil {
+		return fmt.Errorf("db not configured")
+	}
+	if !enabled {
+		_, err := pool.Exec(ctx, `DELETE FROM user_features WHERE user_id = $1 AND feature = $2`, userID, feature)
+		return err
+	}
+	_, err := pool.Exec(ctx, `
+INSERT INTO user_features (user_id, feature, enabled_by)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, feature) DO NOTHING
+`, userID, feature, enabledBy)
+	return err
+}
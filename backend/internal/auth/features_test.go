@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+func TestIssueJWT_FeatureLoader(t *testing.T) {
+	t.Cleanup(func() { SetFeatureLoader(nil) })
+
+	userID := uuid.New()
+	SetFeatureLoader(func(_ context.Context, id uuid.UUID) ([]string, error) {
+		if id != userID {
+			t.Errorf("loader got user %s, want %s", id, userID)
+		}
+		return []string{"repo_insights"}, nil
+	})
+
+	token, err := IssueJWT("secret", userID, "contributor", "", "", time.Hour)
+	if err != nil {
+		t.Fatalf("IssueJWT failed: %v", err)
+	}
+	claims, err := ParseJWT("secret", token)
+	if err != nil {
+		t.Fatalf("ParseJWT failed: %v", err)
+	}
+	if !HasFeature(claims, "repo_insights") || HasFeature(claims, "billing") {
+		t.Errorf("unexpected features %v", claims.Features)
+	}
+
+	// An explicit WithFeatures skips the loader.
+	token, _ = IssueJWT("secret", userID, "contributor", "", "", time.Hour, WithFeatures([]string{"billing"}))
+	claims, _ = ParseJWT("secret", token)
+	if !HasFeature(claims, "billing") || HasFeature(claims, "repo_insights") {
+		t.Errorf("WithFeatures: unexpected features %v", claims.Features)
+	}
+
+	SetFeatureLoader(func(context.Context, uuid.UUID) ([]string, error) {
+		return nil, errors.New("db down")
+	})
+	if _, err := IssueJWT("secret", userID, "contributor", "", "", time.Hour); err == nil {
+		t.Error("expected a loader error to fail the issue")
+	}
+}
+
+func TestRequireFeature(t *testing.T) {
+	app := fiber.New()
+	app.Get("/", RequireAuthWith(AuthOptions{JWTSecret: "secret"}), RequireFeature("repo_insights"), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	enabled, _ := IssueJWT("secret", uuid.New(), "contributor", "", "", time.Minute, WithFeatures([]string{"repo_insights"}))
+	if code := doGet(t, app, enabled); code != fiber.StatusOK {
+		t.Errorf("expected 200 with the feature, got %d", code)
+	}
+	other, _ := IssueJWT("secret", uuid.New(), "contributor", "", "", time.Minute, WithFeatures([]string{"billing"}))
+	if code := doGet(t, app, other); code != fiber.StatusForbidden {
+		t.Errorf("expected 403 without the feature, got %d", code)
+	}
+	none, _ := IssueJWT("secret", uuid.New(), "admin", "", "", time.Minute)
+	if code := doGet(t, app, none); code != fiber.StatusForbidden {
+		t.Errorf("expected 403 for a token without features, got %d", code)
+	}
+}
+
+func TestValidFeatureName(t *testing.T) {
+	for name, want := range map[string]bool{
+		"repo_insights": true,
+		"billing.v2":    true,
+		"beta-ui":       true,
+		"":              false,
+		"Repo":          false,
+		"_hidden":       false,
+		"a b":           false,
+	} {
+		if got := ValidFeatureName(name); got != want {
+			t.Errorf("ValidFeatureName(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
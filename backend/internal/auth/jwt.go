@@ -1,21 +1,58 @@
 package auth
 
 import (
+	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 )
 
+// tokenUseID marks id_tokens so they can never pass as access tokens (same key and alg).
+const tokenUseID = "id"
+
 type Claims struct {
 	jwt.RegisteredClaims
+	TokenUse   string `json:"token_use,omitempty"`
 	Role       string `json:"role"`
+	Scope      string `json:"scope,omitempty"` // space-separated capabilities, e.g. "repos:read webhooks:write"
 	WalletType string `json:"wallet_type,omitempty"`
 	Address    string `json:"address,omitempty"`
+	// AuthTime is the original login (unix seconds) on tokens renewed by a SlidingSession.
+	AuthTime int64 `json:"auth_time,omitempty"`
+	// Features are the per-user feature flags enabled when the token was issued (HasFeature).
+	Features []string `json:"features,omitempty"`
+	// Extra holds deployment-specific claims added by WithExtraClaims and the ClaimsAugmenter.
+	Extra map[string]any `json:"-"`
+
+	issueCtx context.Context
+}
+
+// IssueOption customizes a token minted by IssueJWT.
+type IssueOption func(*Claims)
+
+// WithIssuer stamps the iss claim. An empty issuer leaves the claim unset.
+func WithIssuer(issuer string) IssueOption {
+	return func(c *Claims) {
+		c.Issuer = issuer
+	}
 }
 
-func IssueJWT(secret string, userID uuid.UUID, role string, walletType WalletType, address string, ttl time.Duration) (string, error) {
+// WithScopes embeds the given capabilities as the space-separated scope claim.
+func WithScopes(scopes []string) IssueOption {
+	return func(c *Claims) {
+		c.Scope = strings.Join(scopes, " ")
+	}
+}
+
+// Scopes returns the capabilities carried in the scope claim.
+func (c *Claims) Scopes() []string {
+	return strings.Fields(c.Scope)
+}
+
+func IssueJWT(secret string, userID uuid.UUID, role string, walletType WalletType, address string, ttl time.Duration, opts ...IssueOption) (string, error) {
 	if secret == "" {
 		return "", fmt.Errorf("JWT_SECRET is required")
 	}
@@ -26,6 +63,8 @@ func IssueJWT(secret string, userID uuid.UUID, role string, walletType WalletTyp
 	now := time.Now()
 	claims := Claims{
 		RegisteredClaims: jwt.RegisteredClaims{
+			// jti lets a single token be revoked (POST /auth/logout).
+			ID:        uuid.NewString(),
 			Subject:   userID.String(),
 			IssuedAt:  jwt.NewNumericDate(now),
 			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
@@ -34,21 +73,89 @@ func IssueJWT(secret string, userID uuid.UUID, role string, walletType WalletTyp
 		WalletType: string(walletType),
 		Address:    address,
 	}
+	for _, opt := range opts {
+		opt(&claims)
+	}
+	if claims.Features == nil {
+		features, err := loadFeatures(claims.issueCtx, featureLoader, userID)
+		if err != nil {
+			return "", err
+		}
+		claims.Features = features
+	}
+	if err := CheckExtraClaims(claims.Extra); err != nil {
+		return "", err
+	}
+	extra, err := augmentClaims(claims.issueCtx, claimsAugmenter, userID)
+	if err != nil {
+		return "", err
+	}
+	for k, v := range extra {
+		if claims.Extra == nil {
+			claims.Extra = make(map[string]any, len(extra))
+		}
+		claims.Extra[k] = v
+	}
+
+	t := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return t.SignedString([]byte(secret))
+}
+
+// IDTokenClaims is an OIDC-style identity assertion issued alongside the access token.
+// It only describes who the user is; it must never be accepted as an access token.
+type IDTokenClaims struct {
+	jwt.RegisteredClaims
+	TokenUse  string `json:"token_use"`
+	Login     string `json:"login,omitempty"`
+	Email     string `json:"email,omitempty"`
+	AvatarURL string `json:"avatar_url,omitempty"`
+}
+
+func IssueIDToken(secret string, userID uuid.UUID, login, email, avatarURL string, ttl time.Duration) (string, error) {
+	if secret == "" {
+		return "", fmt.Errorf("JWT_SECRET is required")
+	}
+	if ttl <= 0 {
+		ttl = 5 * time.Minute
+	}
+
+	now := time.Now()
+	claims := IDTokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   userID.String(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+		TokenUse:  tokenUseID,
+		Login:     login,
+		Email:     email,
+		AvatarURL: avatarURL,
+	}
 
 	t := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return t.SignedString([]byte(secret))
 }
 
 func ParseJWT(secret string, tokenString string) (*Claims, error) {
+	return ParseJWTForIssuer(secret, tokenString, "")
+}
+
+// ParseJWTForIssuer is ParseJWT that additionally requires the iss claim to equal issuer.
+// An empty issuer skips the check so deployments without JWT_ISSUER keep working.
+func ParseJWTForIssuer(secret string, tokenString string, issuer string) (*Claims, error) {
 	if secret == "" {
 		return nil, fmt.Errorf("JWT_SECRET is required")
 	}
+	var parserOpts []jwt.ParserOption
+	if issuer != "" {
+		parserOpts = append(parserOpts, jwt.WithIssuer(issuer))
+	}
 	parsed, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (any, error) {
 		if token.Method != jwt.SigningMethodHS256 {
 			return nil, fmt.Errorf("unexpected signing method")
 		}
 		return []byte(secret), nil
-	})
+	}, parserOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -56,6 +163,12 @@ func ParseJWT(secret string, tokenString string) (*Claims, error) {
 	if !ok || !parsed.Valid {
 		return nil, fmt.Errorf("invalid token")
 	}
+	if claims.TokenUse == tokenUseID {
+		return nil, fmt.Errorf("id_token cannot be used as an access token")
+	}
+	if claims.TokenUse == tokenUseRefresh {
+		return nil, fmt.Errorf("refresh token cannot be used as an access token")
+	}
 	return claims, nil
 }
 
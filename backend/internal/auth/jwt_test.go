@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestParseJWT_RejectsIDToken(t *testing.T) {
+	idToken, err := IssueIDToken("secret", uuid.New(), "octocat", "octocat@example.com", "", time.Minute)
+	if err != nil {
+		t.Fatalf("IssueIDToken failed: %v", err)
+	}
+	if _, err := ParseJWT("secret", idToken); err == nil {
+		t.Error("expected id_token to be rejected as an access token")
+	}
+
+	access, err := IssueJWT("secret", uuid.New(), "contributor", "", "", time.Minute)
+	if err != nil {
+		t.Fatalf("IssueJWT failed: %v", err)
+	}
+	if _, err := ParseJWT("secret", access); err != nil {
+		t.Errorf("expected access token to parse, got %v", err)
+	}
+}
+
+func TestParseJWTForIssuer(t *testing.T) {
+	token, err := IssueJWT("secret", uuid.New(), "contributor", "", "", time.Minute, WithIssuer("grainlify-prod"))
+	if err != nil {
+		t.Fatalf("IssueJWT failed: %v", err)
+	}
+
+	claims, err := ParseJWTForIssuer("secret", token, "grainlify-prod")
+	if err != nil {
+		t.Fatalf("expected matching issuer to parse, got %v", err)
+	}
+	if claims.Issuer != "grainlify-prod" {
+		t.Errorf("expected iss grainlify-prod, got %q", claims.Issuer)
+	}
+
+	if _, err := ParseJWTForIssuer("secret", token, "grainlify-staging"); err == nil {
+		t.Error("expected mismatching issuer to be rejected")
+	}
+
+	// Without a configured issuer the claim is not enforced (backward compatible).
+	if _, err := ParseJWTForIssuer("secret", token, ""); err != nil {
+		t.Errorf("expected token to parse without issuer check, got %v", err)
+	}
+
+	unstamped, err := IssueJWT("secret", uuid.New(), "contributor", "", "", time.Minute)
+	if err != nil {
+		t.Fatalf("IssueJWT failed: %v", err)
+	}
+	if _, err := ParseJWTForIssuer("secret", unstamped, "grainlify-prod"); err == nil {
+		t.Error("expected token without iss to be rejected when an issuer is required")
+	}
+}
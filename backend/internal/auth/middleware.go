@@ -3,18 +3,69 @@ package auth
 import (
 	"log/slog"
 	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 const (
 	LocalUserID = "user_id"
 	LocalRole   = "role"
+	LocalScopes = "scopes"
+	// LocalClaims holds the verified *Claims of the request's access token.
+	LocalClaims = "claims"
 )
 
+// ScopeAll grants every scope checked by RequireScope.
+const ScopeAll = "*"
+
+// AuthOptions configures RequireAuthWith.
+type AuthOptions struct {
+	JWTSecret string
+
+	// RoleScopes supplies default scopes for tokens issued without a scope claim
+	// (e.g. tokens minted before scopes existed).
+	RoleScopes map[string][]string
+
+	// Issuer, when set, rejects tokens whose iss claim differs (e.g. staging tokens in prod).
+	Issuer string
+
+	// Pool enables server-side session checks (revoke-all, logout). Without it only the
+	// signature and expiry of the token are verified.
+	Pool *pgxpool.Pool
+
+	// RevokedTokens is the per-token deny-list checked by jti. Defaults to the revoked_tokens
+	// table when Pool is set.
+	RevokedTokens RevokedTokenStore
+
+	// Sliding, when set, renews tokens near expiry and returns the new one in
+	// RefreshedTokenHeader.
+	Sliding *SlidingSession
+
+	// SessionCookie accepts the token from the SessionCookieName cookie when the request has
+	// no Authorization header (SESSION_MODE=cookie). Sliding renewals then replace the cookie.
+	SessionCookie bool
+}
+
 func RequireAuth(jwtSecret string) fiber.Handler {
+	return RequireAuthWith(AuthOptions{JWTSecret: jwtSecret})
+}
+
+func RequireAuthWith(opts AuthOptions) fiber.Handler {
+	jwtSecret := opts.JWTSecret
+	revokedTokens := opts.RevokedTokens
+	if revokedTokens == nil && opts.Pool != nil {
+		revokedTokens = NewDBRevokedTokens(opts.Pool)
+	}
 	return func(c *fiber.Ctx) error {
 		h := strings.TrimSpace(c.Get("Authorization"))
+		fromCookie := false
+		if h == "" && opts.SessionCookie {
+			if v := c.Cookies(SessionCookieName); v != "" {
+				h, fromCookie = "Bearer "+v, true
+			}
+		}
 		if h == "" || !strings.HasPrefix(strings.ToLower(h), "bearer ") {
 			slog.Warn("auth middleware: missing or invalid Authorization header",
 				"path", c.Path(),
@@ -38,7 +89,7 @@ func RequireAuth(jwtSecret string) fiber.Handler {
 				"error": "missing_bearer_token",
 			})
 		}
-		claims, err := ParseJWT(jwtSecret, token)
+		claims, err := ParseJWTForIssuer(jwtSecret, token, opts.Issuer)
 		if err != nil {
 			slog.Warn("auth middleware: JWT parse failed",
 				"path", c.Path(),
@@ -52,12 +103,75 @@ func RequireAuth(jwtSecret string) fiber.Handler {
 			})
 		}
 
+		if opts.Pool != nil {
+			var issuedAt time.Time
+			if claims.IssuedAt != nil {
+				issuedAt = claims.IssuedAt.Time
+			}
+			revoked, err := SessionRevoked(c.Context(), opts.Pool, claims.Subject, issuedAt)
+			if err != nil {
+				slog.Error("auth middleware: session check failed",
+					"path", c.Path(),
+					"error", err,
+					"request_id", c.Locals("requestid"),
+				)
+				return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+					"error": "session_check_failed",
+				})
+			}
+			if revoked {
+				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+					"error": "session_revoked",
+				})
+			}
+		}
+
+		// Tokens minted before jti existed cannot be revoked individually.
+		if revokedTokens != nil && claims.ID != "" {
+			revoked, err := revokedTokens.IsTokenRevoked(c.Context(), claims.ID)
+			if err != nil {
+				slog.Error("auth middleware: revoked token check failed",
+					"path", c.Path(),
+					"error", err,
+					"request_id", c.Locals("requestid"),
+				)
+				return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+					"error": "session_check_failed",
+				})
+			}
+			if revoked {
+				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+					"error": "token_revoked",
+				})
+			}
+		}
+
+		scopes := claims.Scopes()
+		if len(scopes) == 0 {
+			scopes = opts.RoleScopes[claims.Role]
+		}
+
+		if opts.Sliding != nil {
+			if renewed, ok, err := opts.Sliding.Renew(jwtSecret, claims, time.Now()); err != nil {
+				slog.Error("auth middleware: sliding session renewal failed", "error", err, "request_id", c.Locals("requestid"))
+			} else if ok {
+				c.Set(RefreshedTokenHeader, renewed)
+				if fromCookie {
+					SetSessionCookie(c, renewed, opts.Sliding.TTL)
+				}
+			}
+		}
+
 		c.Locals(LocalUserID, claims.Subject)
 		c.Locals(LocalRole, claims.Role)
+		c.Locals(LocalScopes, scopes)
+		c.Locals(LocalClaims, claims)
 		return c.Next()
 	}
 }
 
+// RequireRole gates an endpoint on the token's role claim: 403 missing_role without one,
+// insufficient_role when it isn't one of roles. Must run after RequireAuth/RequireAuthWith.
 func RequireRole(roles ...string) fiber.Handler {
 	allowed := map[string]struct{}{}
 	for _, r := range roles {
@@ -79,11 +193,24 @@ func RequireRole(roles ...string) fiber.Handler {
 	}
 }
 
-
-
-
-
-
-
-
-
+// RequireScope gates an endpoint on a capability from the token's scope claim.
+// Must run after RequireAuth/RequireAuthWith.
+func RequireScope(scope string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		scopes, _ := c.Locals(LocalScopes).([]string)
+		if len(scopes) == 0 {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "missing_scope",
+			})
+		}
+		for _, s := range scopes {
+			if s == scope || s == ScopeAll {
+				return c.Next()
+			}
+		}
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error":          "insufficient_scope",
+			"required_scope": scope,
+		})
+	}
+}
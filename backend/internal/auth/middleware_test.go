@@ -0,0 +1,167 @@
+package auth
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+func scopedApp(t *testing.T, opts AuthOptions, scope string) *fiber.App {
+	t.Helper()
+	app := fiber.New()
+	app.Get("/", RequireAuthWith(opts), RequireScope(scope), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+	return app
+}
+
+func doGet(t *testing.T, app *fiber.App, token string) int {
+	t.Helper()
+	req := httptest.NewRequest("GET", "/", nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	return resp.StatusCode
+}
+
+func TestRequireScope(t *testing.T) {
+	opts := AuthOptions{JWTSecret: "secret"}
+	app := scopedApp(t, opts, "webhooks:write")
+
+	granted, _ := IssueJWT("secret", uuid.New(), "maintainer", "", "", time.Minute, WithScopes([]string{"repos:read", "webhooks:write"}))
+	if code := doGet(t, app, granted); code != fiber.StatusOK {
+		t.Errorf("expected 200 with scope, got %d", code)
+	}
+
+	denied, _ := IssueJWT("secret", uuid.New(), "contributor", "", "", time.Minute, WithScopes([]string{"repos:read"}))
+	if code := doGet(t, app, denied); code != fiber.StatusForbidden {
+		t.Errorf("expected 403 without scope, got %d", code)
+	}
+
+	wildcard, _ := IssueJWT("secret", uuid.New(), "admin", "", "", time.Minute, WithScopes([]string{ScopeAll}))
+	if code := doGet(t, app, wildcard); code != fiber.StatusOK {
+		t.Errorf("expected 200 with wildcard scope, got %d", code)
+	}
+}
+
+func TestRequireScope_RoleDefaults(t *testing.T) {
+	opts := AuthOptions{
+		JWTSecret:  "secret",
+		RoleScopes: map[string][]string{"maintainer": {"webhooks:write"}},
+	}
+	app := scopedApp(t, opts, "webhooks:write")
+
+	// Tokens without a scope claim fall back to the role's configured scopes.
+	legacy, _ := IssueJWT("secret", uuid.New(), "maintainer", "", "", time.Minute)
+	if code := doGet(t, app, legacy); code != fiber.StatusOK {
+		t.Errorf("expected 200 from role default scopes, got %d", code)
+	}
+
+	contributor, _ := IssueJWT("secret", uuid.New(), "contributor", "", "", time.Minute)
+	if code := doGet(t, app, contributor); code != fiber.StatusForbidden {
+		t.Errorf("expected 403 for role without scopes, got %d", code)
+	}
+}
+
+type memRevokedTokens map[string]time.Time
+
+func (m memRevokedTokens) RevokeToken(_ context.Context, jti string, _ uuid.UUID, expiresAt time.Time) error {
+	m[jti] = expiresAt
+	return nil
+}
+
+func (m memRevokedTokens) IsTokenRevoked(_ context.Context, jti string) (bool, error) {
+	_, ok := m[jti]
+	return ok, nil
+}
+
+func TestRequireAuth_RevokedToken(t *testing.T) {
+	revoked := memRevokedTokens{}
+	app := fiber.New()
+	app.Get("/", RequireAuthWith(AuthOptions{JWTSecret: "secret", RevokedTokens: revoked}), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	token, _ := IssueJWT("secret", uuid.New(), "contributor", "", "", time.Minute)
+	other, _ := IssueJWT("secret", uuid.New(), "contributor", "", "", time.Minute)
+	if code := doGet(t, app, token); code != fiber.StatusOK {
+		t.Fatalf("expected 200 before logout, got %d", code)
+	}
+
+	claims, err := ParseJWT("secret", token)
+	if err != nil || claims.ID == "" {
+		t.Fatalf("token must carry a jti: %v", err)
+	}
+	_ = revoked.RevokeToken(context.Background(), claims.ID, uuid.Nil, claims.ExpiresAt.Time)
+
+	if code := doGet(t, app, token); code != fiber.StatusUnauthorized {
+		t.Errorf("expected 401 after logout, got %d", code)
+	}
+	if code := doGet(t, app, other); code != fiber.StatusOK {
+		t.Errorf("other tokens must keep working, got %d", code)
+	}
+}
+
+func TestRequireAuth_SessionCookie(t *testing.T) {
+	token, _ := IssueJWT("secret", uuid.New(), "contributor", "", "", time.Minute)
+	cookieGet := func(opts AuthOptions, value string) int {
+		app := fiber.New()
+		app.Get("/", RequireAuthWith(opts), func(c *fiber.Ctx) error {
+			return c.SendStatus(fiber.StatusOK)
+		})
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Cookie", SessionCookieName+"="+value)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		return resp.StatusCode
+	}
+
+	if code := cookieGet(AuthOptions{JWTSecret: "secret", SessionCookie: true}, token); code != fiber.StatusOK {
+		t.Errorf("session cookie: got %d, want 200", code)
+	}
+	if code := cookieGet(AuthOptions{JWTSecret: "secret"}, token); code != fiber.StatusUnauthorized {
+		t.Errorf("cookie must be ignored outside cookie mode, got %d", code)
+	}
+	if code := cookieGet(AuthOptions{JWTSecret: "secret", SessionCookie: true}, "garbage"); code != fiber.StatusUnauthorized {
+		t.Errorf("invalid cookie token: got %d, want 401", code)
+	}
+}
+
+func TestRequireRole(t *testing.T) {
+	app := fiber.New()
+	app.Get("/", RequireAuthWith(AuthOptions{JWTSecret: "secret"}), RequireRole("admin", "maintainer"), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	cases := []struct {
+		role string
+		want int
+	}{
+		{"admin", fiber.StatusOK},
+		{"maintainer", fiber.StatusOK},
+		{"contributor", fiber.StatusForbidden},
+		{"", fiber.StatusForbidden},
+	}
+	for _, tc := range cases {
+		token, err := IssueJWT("secret", uuid.New(), tc.role, "", "", time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code := doGet(t, app, token); code != tc.want {
+			t.Errorf("role %q: got %d, want %d", tc.role, code, tc.want)
+		}
+	}
+	if code := doGet(t, app, ""); code != fiber.StatusUnauthorized {
+		t.Errorf("without a token the auth middleware must answer first, got %d", code)
+	}
+}
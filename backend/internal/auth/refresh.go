@@ -0,0 +1,267 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// tokenUseRefresh marks refresh tokens so they can never pass as access tokens (same key and alg).
+const tokenUseRefresh = "refresh"
+
+var (
+	// ErrRefreshTokenInvalid covers malformed, tampered, unknown, expired and revoked refresh tokens.
+	ErrRefreshTokenInvalid = errors.New("invalid_refresh_token")
+	// ErrRefreshTokenReused means an already-rotated token was presented again; its family
+	// has been revoked.
+	ErrRefreshTokenReused = errors.New("refresh_token_reused")
+)
+
+// RefreshClaims identify one refresh token (jti) and the login it descends from (fid).
+// Every rotation keeps the family id.
+type RefreshClaims struct {
+	jwt.RegisteredClaims
+	TokenUse string `json:"token_use"`
+	FamilyID string `json:"fid"`
+}
+
+// RefreshToken is the outcome of saving or rotating a refresh token. Token is the raw value
+// handed to the client; only its salted hash is stored.
+type RefreshToken struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	FamilyID  uuid.UUID
+	Token     string
+	ExpiresAt time.Time
+}
+
+// IssueRefreshToken mints a signed refresh token that starts a new family. It must be stored
+// with SaveRefreshToken before it can be redeemed.
+func IssueRefreshToken(secret string, userID uuid.UUID, ttl time.Duration) (string, error) {
+	return issueRefreshToken(secret, userID, uuid.New(), ttl)
+}
+
+func issueRefreshToken(secret string, userID, familyID uuid.UUID, ttl time.Duration) (string, error) {
+	if secret == "" {
+		return "", fmt.Errorf("JWT_SECRET is required")
+	}
+	if ttl <= 0 {
+		ttl = 30 * 24 * time.Hour
+	}
+
+	now := time.Now()
+	claims := RefreshClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			Subject:   userID.String(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+		TokenUse: tokenUseRefresh,
+		FamilyID: familyID.String(),
+	}
+	t := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return t.SignedString([]byte(secret))
+}
+
+// ParseRefreshToken checks the signature, expiry and token_use of a refresh token.
+// It does not consult the database; RotateRefreshToken does.
+func ParseRefreshToken(secret, tokenString string) (*RefreshClaims, error) {
+	if secret == "" {
+		return nil, fmt.Errorf("JWT_SECRET is required")
+	}
+	parsed, err := jwt.ParseWithClaims(tokenString, &RefreshClaims{}, func(token *jwt.Token) (any, error) {
+		if token.Method != jwt.SigningMethodHS256 {
+			return nil, fmt.Errorf("unexpected signing method")
+		}
+		return []byte(secret), nil
+	})
+	if err != nil {
+		return nil, ErrRefreshTokenInvalid
+	}
+	claims, ok := parsed.Claims.(*RefreshClaims)
+	if !ok || !parsed.Valid || claims.TokenUse != tokenUseRefresh {
+		return nil, ErrRefreshTokenInvalid
+	}
+	return claims, nil
+}
+
+// SaveRefreshToken records a token minted by IssueRefreshToken so it can be redeemed once.
+func SaveRefreshToken(ctx context.Context, pool *pgxpool.Pool, secret, token string) (RefreshToken, error) {
+	if pool == nil {
+		return RefreshToken{}, fmt.Errorf("db not configured")
+	}
+	claims, err := ParseRefreshToken(secret, token)
+	if err != nil {
+		return RefreshToken{}, err
+	}
+	rt, err := refreshTokenFromClaims(claims, token)
+	if err != nil {
+		return RefreshToken{}, err
+	}
+	if err := insertRefreshToken(ctx, pool, rt); err != nil {
+		return RefreshToken{}, err
+	}
+	return rt, nil
+}
+
+// refreshRow is the stored state of a refresh token, as far as redeeming it is concerned.
+type refreshRow struct {
+	salt, hash        []byte
+	createdAt         time.Time
+	expiresAt         time.Time
+	rotatedAt         *time.Time
+	revokedAt         *time.Time
+	sessionsRevokedAt *time.Time
+}
+
+// checkRefreshRow decides whether presenting token against row is a valid redemption.
+// A token that was already rotated is reuse (the caller revokes the family); anything else
+// that does not match is simply invalid.
+func checkRefreshRow(row refreshRow, token string, now time.Time) error {
+	if subtle.ConstantTimeCompare(hashRefreshToken(row.salt, token), row.hash) != 1 {
+		return ErrRefreshTokenInvalid
+	}
+	if row.rotatedAt != nil {
+		return ErrRefreshTokenReused
+	}
+	if row.revokedAt != nil || !now.Before(row.expiresAt) {
+		return ErrRefreshTokenInvalid
+	}
+	// Tokens issued before the user's last revoke-all die with the sessions.
+	if row.sessionsRevokedAt != nil && !row.createdAt.After(*row.sessionsRevokedAt) {
+		return ErrRefreshTokenInvalid
+	}
+	return nil
+}
+
+// RotateRefreshToken redeems a refresh token: it is marked rotated and its successor in the same
+// family is stored and returned. Presenting a token that was already rotated revokes the whole
+// family and returns ErrRefreshTokenReused together with the affected user and family, for auditing.
+func RotateRefreshToken(ctx context.Context, pool *pgxpool.Pool, secret, token string, ttl time.Duration) (RefreshToken, error) {
+	if pool == nil {
+		return RefreshToken{}, fmt.Errorf("db not configured")
+	}
+	claims, err := ParseRefreshToken(secret, token)
+	if err != nil {
+		return RefreshToken{}, err
+	}
+	current, err := refreshTokenFromClaims(claims, token)
+	if err != nil {
+		return RefreshToken{}, err
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return RefreshToken{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	var row refreshRow
+	err = tx.QueryRow(ctx, `
+SELECT rt.token_salt, rt.token_hash, rt.created_at, rt.expires_at, rt.rotated_at, rt.revoked_at,
+       u.sessions_revoked_at
+FROM refresh_tokens rt
+JOIN users u ON u.id = rt.user_id
+WHERE rt.id = $1 AND rt.user_id = $2 AND rt.family_id = $3
+FOR UPDATE OF rt
+`, current.ID, current.UserID, current.FamilyID).Scan(&row.salt, &row.hash, &row.createdAt, &row.expiresAt, &row.rotatedAt, &row.revokedAt, &row.sessionsRevokedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return RefreshToken{}, ErrRefreshTokenInvalid
+	}
+	if err != nil {
+		return RefreshToken{}, err
+	}
+
+	switch err := checkRefreshRow(row, token, time.Now()); {
+	case errors.Is(err, ErrRefreshTokenReused):
+		if _, err := tx.Exec(ctx, `
+UPDATE refresh_tokens SET revoked_at = now()
+WHERE family_id = $1 AND revoked_at IS NULL
+`, current.FamilyID); err != nil {
+			return RefreshToken{}, err
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return RefreshToken{}, err
+		}
+		current.Token = ""
+		return current, ErrRefreshTokenReused
+	case err != nil:
+		return RefreshToken{}, err
+	}
+
+	nextToken, err := issueRefreshToken(secret, current.UserID, current.FamilyID, ttl)
+	if err != nil {
+		return RefreshToken{}, err
+	}
+	nextClaims, err := ParseRefreshToken(secret, nextToken)
+	if err != nil {
+		return RefreshToken{}, err
+	}
+	next, err := refreshTokenFromClaims(nextClaims, nextToken)
+	if err != nil {
+		return RefreshToken{}, err
+	}
+	if err := insertRefreshToken(ctx, tx, next); err != nil {
+		return RefreshToken{}, err
+	}
+	if _, err := tx.Exec(ctx, `
+UPDATE refresh_tokens SET rotated_at = now(), replaced_by = $2 WHERE id = $1
+`, current.ID, next.ID); err != nil {
+		return RefreshToken{}, err
+	}
+	return next, tx.Commit(ctx)
+}
+
+func refreshTokenFromClaims(claims *RefreshClaims, token string) (RefreshToken, error) {
+	id, err := uuid.Parse(claims.ID)
+	if err != nil {
+		return RefreshToken{}, ErrRefreshTokenInvalid
+	}
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return RefreshToken{}, ErrRefreshTokenInvalid
+	}
+	familyID, err := uuid.Parse(claims.FamilyID)
+	if err != nil {
+		return RefreshToken{}, ErrRefreshTokenInvalid
+	}
+	rt := RefreshToken{ID: id, UserID: userID, FamilyID: familyID, Token: token}
+	if claims.ExpiresAt != nil {
+		rt.ExpiresAt = claims.ExpiresAt.Time
+	}
+	return rt, nil
+}
+
+type refreshExecer interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+func insertRefreshToken(ctx context.Context, ex refreshExecer, rt RefreshToken) error {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	_, err := ex.Exec(ctx, `
+INSERT INTO refresh_tokens (id, user_id, family_id, token_salt, token_hash, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+`, rt.ID, rt.UserID, rt.FamilyID, salt, hashRefreshToken(salt, rt.Token), rt.ExpiresAt)
+	return err
+}
+
+func hashRefreshToken(salt []byte, token string) []byte {
+	h := sha256.New()
+	h.Write(salt)
+	h.Write([]byte(token))
+	return h.Sum(nil)
+}
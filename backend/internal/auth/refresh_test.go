@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func TestRefreshTokenParse(t *testing.T) {
+	userID := uuid.New()
+	token, err := IssueRefreshToken("secret", userID, time.Hour)
+	if err != nil {
+		t.Fatalf("IssueRefreshToken: %v", err)
+	}
+	claims, err := ParseRefreshToken("secret", token)
+	if err != nil {
+		t.Fatalf("ParseRefreshToken: %v", err)
+	}
+	if claims.Subject != userID.String() || claims.ID == "" || claims.FamilyID == "" {
+		t.Fatalf("unexpected claims: %+v", claims)
+	}
+
+	if _, err := ParseJWT("secret", token); err == nil {
+		t.Fatal("refresh token must not pass as an access token")
+	}
+	access, err := IssueJWT("secret", userID, "contributor", "", "", time.Hour)
+	if err != nil {
+		t.Fatalf("IssueJWT: %v", err)
+	}
+	if _, err := ParseRefreshToken("secret", access); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Fatalf("access token must not pass as a refresh token, got %v", err)
+	}
+}
+
+func TestRefreshTokenExpired(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	claims := RefreshClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			Subject:   uuid.NewString(),
+			IssuedAt:  jwt.NewNumericDate(past),
+			ExpiresAt: jwt.NewNumericDate(past.Add(time.Minute)),
+		},
+		TokenUse: tokenUseRefresh,
+		FamilyID: uuid.NewString(),
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	if _, err := ParseRefreshToken("secret", token); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Fatalf("expired token: got %v", err)
+	}
+
+	// The stored expiry is enforced as well.
+	salt := []byte("salt")
+	row := refreshRow{salt: salt, hash: hashRefreshToken(salt, token), expiresAt: time.Now().Add(-time.Second)}
+	if err := checkRefreshRow(row, token, time.Now()); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Fatalf("expired row: got %v", err)
+	}
+}
+
+func TestRefreshTokenTamperedSignature(t *testing.T) {
+	token, err := IssueRefreshToken("secret", uuid.New(), time.Hour)
+	if err != nil {
+		t.Fatalf("IssueRefreshToken: %v", err)
+	}
+	if _, err := ParseRefreshToken("other-secret", token); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Fatalf("wrong key: got %v", err)
+	}
+
+	i := strings.LastIndex(token, ".") + 1
+	flipped := byte('A')
+	if token[i] == 'A' {
+		flipped = 'B'
+	}
+	tampered := token[:i] + string(flipped) + token[i+1:]
+	if _, err := ParseRefreshToken("secret", tampered); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Fatalf("tampered signature: got %v", err)
+	}
+}
+
+func TestCheckRefreshRowReuse(t *testing.T) {
+	token := "rt"
+	salt := []byte("salt")
+	now := time.Now()
+	row := refreshRow{salt: salt, hash: hashRefreshToken(salt, token), createdAt: now.Add(-time.Hour), expiresAt: now.Add(time.Hour)}
+
+	if err := checkRefreshRow(row, token, now); err != nil {
+		t.Fatalf("fresh token: got %v", err)
+	}
+	if err := checkRefreshRow(row, "other", now); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Fatalf("hash mismatch: got %v", err)
+	}
+
+	rotated := now.Add(-time.Minute)
+	row.rotatedAt = &rotated
+	if err := checkRefreshRow(row, token, now); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("rotated token must be reported as reuse, got %v", err)
+	}
+	// Reuse is reported even after the family was revoked, so every replay is audited.
+	row.revokedAt = &now
+	if err := checkRefreshRow(row, token, now); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("replay after revocation: got %v", err)
+	}
+
+	row.rotatedAt = nil
+	if err := checkRefreshRow(row, token, now); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Fatalf("revoked token: got %v", err)
+	}
+
+	row.revokedAt = nil
+	revokeAll := now.Add(-time.Minute)
+	row.sessionsRevokedAt = &revokeAll
+	if err := checkRefreshRow(row, token, now); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Fatalf("token older than revoke-all: got %v", err)
+	}
+}
@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// RevokedTokenStore is the deny-list of access tokens (by jti) ended before their expiry.
+type RevokedTokenStore interface {
+	// RevokeToken denies jti until expiresAt, after which the entry may be pruned.
+	RevokeToken(ctx context.Context, jti string, userID uuid.UUID, expiresAt time.Time) error
+	IsTokenRevoked(ctx context.Context, jti string) (bool, error)
+}
+
+type dbRevokedTokens struct {
+	pool *pgxpool.Pool
+}
+
+// NewDBRevokedTokens keeps the deny-list in the revoked_tokens table.
+func NewDBRevokedTokens(pool *pgxpool.Pool) RevokedTokenStore {
+	return dbRevokedTokens{pool: pool}
+}
+
+func (s dbRevokedTokens) RevokeToken(ctx context.Context, jti string, userID uuid.UUID, expiresAt time.Time) error {
+	if s.pool == nil {
+		return fmt.Errorf("db not configured")
+	}
+	_, err := s.pool.Exec(ctx, `
+INSERT INTO revoked_tokens (jti, user_id, expires_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (jti) DO UPDATE SET expires_at = GREATEST(revoked_tokens.expires_at, EXCLUDED.expires_at)
+`, jti, userID, expiresAt)
+	return err
+}
+
+func (s dbRevokedTokens) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	var one int
+	err := s.pool.QueryRow(ctx, `SELECT 1 FROM revoked_tokens WHERE jti = $1`, jti).Scan(&one)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,37 @@
+package auth
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// SessionCookieName holds the access token with SESSION_MODE=cookie.
+const SessionCookieName = "grainlify_session"
+
+// SetSessionCookie stores an access token in the session cookie: Secure, HttpOnly and
+// SameSite=Lax, so scripts can't read it and cross-site subrequests don't carry it.
+func SetSessionCookie(c *fiber.Ctx, token string, ttl time.Duration) {
+	c.Cookie(&fiber.Cookie{
+		Name:     SessionCookieName,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   int(ttl.Seconds()),
+		Secure:   true,
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+}
+
+// ClearSessionCookie expires the session cookie (logout).
+func ClearSessionCookie(c *fiber.Ctx) {
+	c.Cookie(&fiber.Cookie{
+		Name:     SessionCookieName,
+		Value:    "",
+		Path:     "/",
+		Expires:  time.Unix(0, 0),
+		Secure:   true,
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+}
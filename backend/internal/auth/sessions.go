@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrUserNotFound means the user whose sessions were to be revoked doesn't exist.
+var ErrUserNotFound = errors.New("user_not_found")
+
+// RevokeAllSessions invalidates every access token issued to the user up to now.
+// RequireAuthWith rejects tokens whose iat is at or before users.sessions_revoked_at,
+// so the user has to authenticate again everywhere.
+func RevokeAllSessions(ctx context.Context, pool *pgxpool.Pool, userID uuid.UUID) (time.Time, error) {
+	if pool == nil {
+		return time.Time{}, fmt.Errorf("db not configured")
+	}
+
+	var revokedAt time.Time
+	err := pool.QueryRow(ctx, `
+UPDATE users
+SET sessions_revoked_at = now(), updated_at = now()
+WHERE id = $1
+RETURNING sessions_revoked_at
+`, userID).Scan(&revokedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return time.Time{}, ErrUserNotFound
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return revokedAt, nil
+}
+
+// RevokeSessionsIssuedBefore invalidates every token with an iat before the second of cutoff,
+// leaving a token minted at cutoff valid. Used to end all prior sessions right before issuing
+// a new one (RevokeAllSessions would also reject a token minted in the same second).
+// An existing later revocation is kept.
+func RevokeSessionsIssuedBefore(ctx context.Context, pool *pgxpool.Pool, userID uuid.UUID, cutoff time.Time) error {
+	if pool == nil {
+		return fmt.Errorf("db not configured")
+	}
+	revokedAt := cutoff.Truncate(time.Second).Add(-time.Second)
+	_, err := pool.Exec(ctx, `
+UPDATE users
+SET sessions_revoked_at = GREATEST(COALESCE(sessions_revoked_at, $2), $2), updated_at = now()
+WHERE id = $1
+`, userID, revokedAt)
+	return err
+}
+
+// SessionRevoked reports whether a token issued at issuedAt predates the user's last revoke-all.
+func SessionRevoked(ctx context.Context, pool *pgxpool.Pool, userID string, issuedAt time.Time) (bool, error) {
+	var revokedAt *time.Time
+	err := pool.QueryRow(ctx, `SELECT sessions_revoked_at FROM users WHERE id = $1`, userID).Scan(&revokedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Missing users are handled by the endpoints themselves.
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if revokedAt == nil {
+		return false, nil
+	}
+	// iat only has second precision, so a token minted in the same second as the revocation is
+	// treated as revoked too.
+	return issuedAt.Unix() <= revokedAt.Unix(), nil
+}
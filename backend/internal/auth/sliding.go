@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// RefreshedTokenHeader carries the replacement token minted by a sliding session.
+const RefreshedTokenHeader = "X-Refreshed-Token"
+
+// SlidingSession renews tokens that are close to expiry while the user stays active.
+type SlidingSession struct {
+	// Threshold is how close to exp a token must be before it is renewed.
+	Threshold time.Duration
+	// TTL is the lifetime of a renewed token.
+	TTL time.Duration
+	// MaxLifetime caps the whole session, counted from the original login (auth_time).
+	MaxLifetime time.Duration
+}
+
+// Renew returns a fresh token for claims when it is within Threshold of expiry, keeping every
+// claim except iat/exp. ok is false when no renewal is due or the session has reached
+// MaxLifetime.
+func (s SlidingSession) Renew(secret string, claims *Claims, now time.Time) (token string, ok bool, err error) {
+	if secret == "" {
+		return "", false, fmt.Errorf("JWT_SECRET is required")
+	}
+	if claims.ExpiresAt == nil || claims.ExpiresAt.Sub(now) > s.Threshold {
+		return "", false, nil
+	}
+
+	authTime := claims.sessionStart()
+	exp := now.Add(s.TTL)
+	if s.MaxLifetime > 0 {
+		if limit := authTime.Add(s.MaxLifetime); exp.After(limit) {
+			exp = limit
+		}
+	}
+	if !exp.After(claims.ExpiresAt.Time) {
+		return "", false, nil
+	}
+
+	renewed := *claims
+	renewed.IssuedAt = jwt.NewNumericDate(now)
+	renewed.ExpiresAt = jwt.NewNumericDate(exp)
+	renewed.AuthTime = authTime.Unix()
+
+	t := jwt.NewWithClaims(jwt.SigningMethodHS256, renewed)
+	token, err = t.SignedString([]byte(secret))
+	if err != nil {
+		return "", false, err
+	}
+	return token, true, nil
+}
+
+// sessionStart is when the user originally authenticated: auth_time on renewed tokens, iat
+// on the token issued at login.
+func (c *Claims) sessionStart() time.Time {
+	if c.AuthTime > 0 {
+		return time.Unix(c.AuthTime, 0)
+	}
+	if c.IssuedAt != nil {
+		return c.IssuedAt.Time
+	}
+	return time.Time{}
+}
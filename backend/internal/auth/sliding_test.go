@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestSlidingSessionRenew(t *testing.T) {
+	s := SlidingSession{Threshold: 10 * time.Minute, TTL: time.Hour, MaxLifetime: 2 * time.Hour}
+
+	token, err := IssueJWT("secret", uuid.New(), "maintainer", "", "", time.Hour, WithIssuer("grainlify"), WithScopes([]string{"repos:read"}))
+	if err != nil {
+		t.Fatalf("IssueJWT failed: %v", err)
+	}
+	claims, err := ParseJWT("secret", token)
+	if err != nil {
+		t.Fatalf("ParseJWT failed: %v", err)
+	}
+	login := claims.IssuedAt.Time
+
+	if _, ok, _ := s.Renew("secret", claims, login.Add(30*time.Minute)); ok {
+		t.Fatal("token with 30m left must not be renewed")
+	}
+
+	renewedToken, ok, err := s.Renew("secret", claims, login.Add(55*time.Minute))
+	if err != nil || !ok {
+		t.Fatalf("expected renewal near expiry, ok=%v err=%v", ok, err)
+	}
+	renewed, err := ParseJWTForIssuer("secret", renewedToken, "grainlify")
+	if err != nil {
+		t.Fatalf("renewed token does not parse: %v", err)
+	}
+	if renewed.Subject != claims.Subject || renewed.Role != "maintainer" || renewed.Scope != "repos:read" {
+		t.Errorf("renewed token lost claims: %+v", renewed)
+	}
+	if renewed.AuthTime != login.Unix() {
+		t.Errorf("auth_time = %d, want original login %d", renewed.AuthTime, login.Unix())
+	}
+	if want := login.Add(115 * time.Minute).Unix(); renewed.ExpiresAt.Unix() != want {
+		t.Errorf("exp = %d, want %d", renewed.ExpiresAt.Unix(), want)
+	}
+
+	// Near the absolute limit the session can't be extended any further.
+	renewed.ExpiresAt.Time = login.Add(2 * time.Hour)
+	if _, ok, _ := s.Renew("secret", renewed, login.Add(115*time.Minute)); ok {
+		t.Error("session past MaxLifetime must not be renewed")
+	}
+}
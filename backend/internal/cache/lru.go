@@ -0,0 +1,137 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LRU is a size-bounded, TTL-aware in-memory cache safe for concurrent use.
+// When full, the least recently used entry is evicted; expired entries are dropped lazily on read.
+type LRU[K comparable, V any] struct {
+	mu         sync.Mutex
+	maxEntries int
+	ttl        time.Duration
+	ll         *list.List
+	items      map[K]*list.Element
+
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	evictions atomic.Uint64
+
+	now func() time.Time
+}
+
+type entry[K comparable, V any] struct {
+	key       K
+	value     V
+	expiresAt time.Time
+}
+
+// Stats is a point-in-time snapshot of cache counters.
+type Stats struct {
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+	Entries   int    `json:"entries"`
+}
+
+// New returns an LRU holding at most maxEntries items, each living for ttl unless overridden
+// with SetWithTTL. maxEntries <= 0 defaults to 1000; ttl <= 0 means entries never expire.
+func New[K comparable, V any](maxEntries int, ttl time.Duration) *LRU[K, V] {
+	if maxEntries <= 0 {
+		maxEntries = 1000
+	}
+	return &LRU[K, V]{
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		ll:         list.New(),
+		items:      make(map[K]*list.Element),
+		now:        time.Now,
+	}
+}
+
+func (c *LRU[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		c.misses.Add(1)
+		var zero V
+		return zero, false
+	}
+	e := el.Value.(*entry[K, V])
+	if !e.expiresAt.IsZero() && !c.now().Before(e.expiresAt) {
+		c.removeElement(el)
+		c.misses.Add(1)
+		var zero V
+		return zero, false
+	}
+	c.ll.MoveToFront(el)
+	c.hits.Add(1)
+	return e.value, true
+}
+
+func (c *LRU[K, V]) Set(key K, value V) {
+	c.SetWithTTL(key, value, c.ttl)
+}
+
+// SetWithTTL stores value with a per-entry lifetime; ttl <= 0 means no expiry.
+func (c *LRU[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = c.now().Add(ttl)
+	}
+
+	if el, ok := c.items[key]; ok {
+		e := el.Value.(*entry[K, V])
+		e.value = value
+		e.expiresAt = expiresAt
+		c.ll.MoveToFront(el)
+		return
+	}
+
+	el := c.ll.PushFront(&entry[K, V]{key: key, value: value, expiresAt: expiresAt})
+	c.items[key] = el
+	for c.ll.Len() > c.maxEntries {
+		oldest := c.ll.Back()
+		if oldest == nil {
+			break
+		}
+		c.removeElement(oldest)
+		c.evictions.Add(1)
+	}
+}
+
+func (c *LRU[K, V]) Delete(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		c.removeElement(el)
+	}
+}
+
+func (c *LRU[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+func (c *LRU[K, V]) Stats() Stats {
+	return Stats{
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Evictions: c.evictions.Load(),
+		Entries:   c.Len(),
+	}
+}
+
+func (c *LRU[K, V]) removeElement(el *list.Element) {
+	c.ll.Remove(el)
+	delete(c.items, el.Value.(*entry[K, V]).key)
+}
@@ -0,0 +1,45 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLRU_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := New[string, int](2, 0)
+	c.Set("a", 1)
+	c.Set("b", 2)
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("expected a to be cached")
+	}
+	c.Set("c", 3) // evicts b, the least recently used
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("expected b to be evicted")
+	}
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Errorf("expected a=1, got %v %v", v, ok)
+	}
+	if st := c.Stats(); st.Evictions != 1 || st.Entries != 2 {
+		t.Errorf("unexpected stats: %+v", st)
+	}
+}
+
+func TestLRU_TTLExpiry(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	c := New[string, string](10, time.Minute)
+	c.now = func() time.Time { return now }
+
+	c.Set("k", "v")
+	if _, ok := c.Get("k"); !ok {
+		t.Fatal("expected k to be cached")
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok := c.Get("k"); ok {
+		t.Error("expected k to be expired")
+	}
+	if st := c.Stats(); st.Hits != 1 || st.Misses != 1 || st.Entries != 0 {
+		t.Errorf("unexpected stats: %+v", st)
+	}
+}
@@ -0,0 +1,32 @@
+package cache
+
+import "sync"
+
+// Statser is implemented by every cache that reports hit/miss counters.
+type Statser interface {
+	Stats() Stats
+}
+
+var (
+	registryMu sync.Mutex
+	registry   = map[string]Statser{}
+)
+
+// Register makes a cache's counters visible through AllStats under name.
+// Registering the same name again replaces the previous cache.
+func Register(name string, c Statser) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[name] = c
+}
+
+// AllStats snapshots the counters of every registered cache.
+func AllStats() map[string]Stats {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	out := make(map[string]Stats, len(registry))
+	for name, c := range registry {
+		out[name] = c.Stats()
+	}
+	return out
+}
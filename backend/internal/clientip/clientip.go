@@ -0,0 +1,38 @@
+// Package clientip resolves the address a request came from for per-client limits and audit records.
+package clientip
+
+import (
+	"net"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/jagadeesh/grainlify/backend/internal/config"
+)
+
+// FromRequest is the client address to key per-IP limits on and to audit. Without TrustProxyHeaders it
+// is the direct peer. With it, X-Forwarded-For is read right to left, skipping
+// EXTERNAL_ID_TRUSTED_PROXIES hops: entries further left are whatever the client chose to send.
+func FromRequest(c *fiber.Ctx, cfg config.Config) string {
+	peer := c.Context().RemoteIP().String()
+	if !cfg.TrustProxyHeaders {
+		return peer
+	}
+	hops := strings.Split(c.Get(fiber.HeaderXForwardedFor), ",")
+	ip := ""
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if net.ParseIP(hop) == nil {
+			break
+		}
+		ip = hop
+		if !cfg.IsTrustedProxy(hop) {
+			break
+		}
+	}
+	if ip == "" {
+		return peer
+	}
+	// c.Get's result is only valid for this request, and callers may keep the value.
+	return strings.Clone(ip)
+}
@@ -0,0 +1,42 @@
+package clientip
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/jagadeesh/grainlify/backend/internal/config"
+)
+
+func TestFromRequest(t *testing.T) {
+	cases := []struct {
+		name string
+		cfg  config.Config
+		xff  string
+		want string
+	}{
+		{"peer without proxy trust", config.Config{}, "203.0.113.7", "0.0.0.0"},
+		{"rightmost hop", config.Config{TrustProxyHeaders: true}, "198.51.100.1, 203.0.113.7", "203.0.113.7"},
+		{"trusted hops skipped", config.Config{TrustProxyHeaders: true, ExternalIDTrustedProxies: []string{"10.0.0.0/8"}}, "198.51.100.1, 203.0.113.7, 10.0.0.5", "203.0.113.7"},
+		{"no header", config.Config{TrustProxyHeaders: true}, "", "0.0.0.0"},
+	}
+	for _, tc := range cases {
+		var got string
+		app := fiber.New()
+		app.Get("/", func(c *fiber.Ctx) error {
+			got = FromRequest(c, tc.cfg)
+			return nil
+		})
+		req := httptest.NewRequest("GET", "/", nil)
+		if tc.xff != "" {
+			req.Header.Set("X-Forwarded-For", tc.xff)
+		}
+		if _, err := app.Test(req); err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
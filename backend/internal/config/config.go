@@ -1,10 +1,15 @@
 package config
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
 	"log/slog"
 	"os"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type Config struct {
@@ -14,8 +19,25 @@ type Config struct {
 
 	DBURL       string
 	AutoMigrate bool
+	// Retries for queries wrapped in db.WithRetry after a transient failure (lost connection,
+	// failover, serialization failure). Zero disables retrying.
+	DBMaxRetries int
 
 	JWTSecret string
+	// Optional iss claim stamped on and required of every access token.
+	JWTIssuer string
+	// Lifetime of access tokens minted at login and refresh (JWT_ACCESS_TTL, 1m-24h).
+	JWTAccessTTL time.Duration
+	// Static claims (a JSON object, e.g. {"tenant":"acme"}) added to every access token.
+	// See JWTExtraClaims.
+	JWTExtraClaimsJSON string
+	// Default access-token scopes per role, from ROLE_SCOPES
+	// ("contributor=repos:read;maintainer=repos:read,webhooks:write;admin=*").
+	RoleScopes map[string][]string
+
+	// When true, GitHub login also returns a short-lived signed id_token (sub, login, email, avatar_url).
+	IssueIDToken bool
+	IDTokenTTL   time.Duration
 
 	NATSURL string
 
@@ -25,17 +47,72 @@ type Config struct {
 	GitHubOAuthSuccessRedirectURL string
 	GitHubLoginRedirectURL        string // Alternative callback URL (deprecated, use GitHubOAuthRedirectURL)
 	GitHubLoginSuccessRedirectURL string
+	// Scopes requested by the GitHub login and link flows. Validate refuses a list that lacks
+	// RequiredGitHubScopes.
+	GitHubOAuthScopes []string
+
+	// When true and LoginStart gets no redirect parameter, the post-login redirect defaults to the
+	// (allowlisted) host of the incoming request instead of FrontendBaseURL.
+	RedirectDefaultFromHost bool
+
+	// When true, the OAuth callback only accepts a redirect that was recorded in the state row at
+	// LoginStart and that the state parameter repeats exactly (OAuth 2.1 exact matching).
+	StrictRedirectMatch bool
+
+	// When true, LoginStart stores a hash of the browser family and major version from
+	// User-Agent with the state, and the callback rejects a different browser.
+	StateBindUserAgent bool
+
+	// When true, GitHub login redirects to the frontend with no query parameters and delivers the
+	// token via a one-time HttpOnly cookie redeemed at POST /auth/finalize.
+	LoginCookieHandoff bool
+
+	// How GitHub login hands the access token to the browser: SessionModeQuery puts it in the
+	// redirect query, SessionModeCookie sets an HttpOnly session cookie that RequireAuth reads.
+	SessionMode string
+
+	// When true, the github_link flow parks the authorization in pending_links and the user
+	// must confirm it (LinkPreview/ConfirmLink) before the account is linked.
+	RequireLinkConfirmation bool
 
 	// GitHub App configuration (for organization installations)
 	GitHubAppID         string // GitHub App ID (numeric)
 	GitHubAppSlug       string // GitHub App slug (e.g., "grainlify")
 	GitHubAppPrivateKey string // GitHub App private key (PEM format, base64 encoded)
+	// When true, repo webhooks are created with the project's GitHub App installation token
+	// instead of the owner's OAuth token (falling back to OAuth when there is no installation).
+	GitHubWebhooksViaApp bool
+
+	// Rate-limited GitHub GETs made right after login are retried this many times, waiting
+	// at most GitHubRateLimitMaxWait each time. 0 retries disables it.
+	GitHubRateLimitRetries int
+	GitHubRateLimitMaxWait time.Duration
 
 	// Used to validate GitHub webhook signatures (X-Hub-Signature-256).
 	GitHubWebhookSecret string
 
+	// GitHub webhook event types the receiver stores and processes. Others are acked
+	// with 200 and dropped. Empty means accept everything; "ping" is always accepted.
+	WebhookAcceptedEvents []string
+
+	// How long project verification waits for GitHub's ping after creating a webhook.
+	// Zero skips the wait; the ping still marks the webhook verified when it arrives.
+	WebhookPingWait time.Duration
+
 	// Public base URL of this backend, used when registering GitHub webhooks.
 	PublicBaseURL string
+	// PublicHostCheck logs a warning when PUBLIC_BASE_URL's host differs from ExpectedHost
+	// (checked at startup) or, without it, from the host of the first request served.
+	PublicHostCheck bool
+	ExpectedHost    string
+
+	// Bearer token required by GET /metrics. Empty leaves the endpoint open, which is only
+	// fine when it isn't reachable from outside.
+	MetricsToken string
+
+	// Path prefix the auth routes are mounted under (e.g. "/api/v1"). Empty means root.
+	// Normalized to a leading slash and no trailing slash.
+	AuthRoutePrefix string
 
 	// Frontend base URL (e.g., http://localhost:5173 or https://yourdomain.com)
 	// Used for OAuth redirects and CORS configuration
@@ -45,12 +122,157 @@ type Config struct {
 	// Example: "http://localhost:5173,https://grainlify.figma.site"
 	CORSOrigins string
 
+	// Hostnames that are never OAuth redirect targets, even when an allowlist rule matches.
+	// "*.example.com" covers subdomains of example.com.
+	RedirectURIDenylist []string
+
+	// Extra OAuth redirect origins as glob or "re:" regexp patterns, e.g. "https://*.pr.example.com".
+	// See CompileRedirectPatterns.
+	RedirectURIAllowedPatterns string
+	redirectPatterns           []*regexp.Regexp
+
+	// Frontend paths LoginStart's ?continue= may point into (e.g. "/onboarding"). Empty accepts
+	// any same-origin path.
+	ContinuePathPrefixes []string
+
+	// When ExternalIDHeader is set, github_login takes the caller's id in an external identity
+	// system from that header and links the GitHub account to the user holding it. The header
+	// is only honoured on requests whose direct peer is in ExternalIDTrustedProxies (IPs/CIDRs).
+	ExternalIDHeader         string
+	ExternalIDTrustedProxies []string
+
+	// Built-in allowlist rules for http(s)://localhost:* and *.vercel.app (CORS and OAuth
+	// redirects). Default on, except when APP_ENV/GO_ENV is production.
+	AllowLocalhostOrigins bool
+	AllowVercelOrigins    bool
+
+	// CORSOrigins and FrontendBaseURL pre-parsed by Load; see OriginAllowlist.
+	originAllowlist *OriginAllowlist
+
+	// Browser-facing OAuth errors (Accept: text/html) render an HTML page instead of JSON.
+	// OAuthErrorTemplate is an optional html/template file (fields: Lang, Code, Message, RetryURL);
+	// OAuthErrorMessagesFile is optional JSON {"<lang>": {"<error code>": "<message>"}}.
+	OAuthErrorTemplate     string
+	OAuthErrorMessagesFile string
+	OAuthErrorDefaultLang  string
+
+	// Most unexpired, unconsumed GitHub login flows (oauth_states) one IP may hold; further
+	// LoginStart requests are refused until some complete or expire. Keyed on the client IP
+	// resolved like the OAuth start rate limit (see TrustProxyHeaders). Zero (default) disables it.
+	MaxPendingStatesPerIP int
+
+	// Per-IP request limit per RateLimitWindow. Zero disables the limiter.
+	RateLimitMax    int
+	RateLimitWindow time.Duration
+
+	// Token-bucket limit on the OAuth start endpoints, in requests per minute per client IP
+	// (bursts up to the same number). Zero (default) disables it; behind a proxy it needs
+	// TrustProxyHeaders, or every user shares the proxy's bucket.
+	OAuthStartRateLimit int
+	// TrustProxyHeaders makes rate limiting key on the client address in X-Forwarded-For
+	// instead of the direct peer. Only set it behind a proxy that appends to the header.
+	TrustProxyHeaders bool
+
+	// Retry-After sent with 503s: RetryAfterUnavailable for transient outages (DB down or
+	// unreachable), RetryAfterMisconfigured for missing configuration. Zero omits the header.
+	RetryAfterUnavailable   time.Duration
+	RetryAfterMisconfigured time.Duration
+
+	// Defaults for the in-memory LRU caches (internal/cache).
+	CacheMaxEntries int
+	CacheDefaultTTL time.Duration
+
 	// Used to encrypt stored OAuth access tokens at rest. Must be 32 bytes base64 (AES-256-GCM key).
 	TokenEncKeyB64 string
+	// Named token keys for rotation, "id:base64key,id:base64key"; the first encrypts. Keys
+	// dropped from the list can no longer decrypt what they encrypted. See TokenEncKeys.
+	TokenEncKeysB64 string
+
+	// When false, the GitHub access token is used only within the OAuth callback (to fetch
+	// the user and email) and never persisted; github_accounts rows carry identity only.
+	StoreGitHubToken bool
+
+	// Where encrypted tokens live: "db" (the token column, default) or "hashicorp" (a Vault
+	// KV v2 engine; the column then holds a reference). Encryption applies either way.
+	TokenVault     string
+	VaultAddr      string
+	VaultToken     string
+	VaultKVMount   string
+	VaultTokenPath string
 
 	// Dev/admin convenience: allow promoting a logged-in user to admin via a shared token.
 	AdminBootstrapToken string
 
+	// SMTP relay for account-security notices. Email is disabled when SMTPHost is empty.
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	SMTPFrom     string
+
+	// When true, linking a new (or different) GitHub account emails the user's stored address
+	// with a "this wasn't me" link that unlinks the account and revokes all sessions.
+	NotifyOnLink bool
+
+	// Sliding sessions: a token within SlidingSessionThreshold of expiry is renewed on use
+	// (X-Refreshed-Token), each renewal lasting SlidingSessionTTL, never past
+	// SlidingSessionMaxLifetime after the original login.
+	SlidingSession            bool
+	SlidingSessionThreshold   time.Duration
+	SlidingSessionTTL         time.Duration
+	SlidingSessionMaxLifetime time.Duration
+
+	// Lifetime of each refresh token; rotation issues a new one with a fresh lifetime.
+	RefreshTokenTTL time.Duration
+
+	// When true, a successful GitHub login ends every other session of that user.
+	SingleSessionPerUser bool
+
+	// Adds PKCE (RFC 7636, S256) to GitHub OAuth flows on top of the state check.
+	GitHubOAuthUsePKCE bool
+
+	// When true, Start signs the github_link state (user, token jti, expiry) with JWT_SECRET
+	// instead of writing an oauth_states row.
+	StatelessLinkState bool
+
+	// When set, a GitHub login whose last consent (github_accounts.token_granted_at) is older
+	// than this revokes the app grant and sends the user through GitHub's consent screen again.
+	ForceReauthInterval time.Duration
+
+	// Signups through github_login with a GitHub account younger than this are refused
+	// (github_account_too_new). Existing users are not affected. Zero disables.
+	MinGitHubAccountAge time.Duration
+
+	// How often users.github_user_id is checked against github_accounts (0 disables), and
+	// whether mismatches are corrected from github_accounts or only logged.
+	GitHubIdentityReconcileInterval time.Duration
+	GitHubIdentityReconcileFix      bool
+
+	// Linked GitHub accounts whose stored token has been dead (reauth_required) longer than this
+	// are unlinked by a background job; the user is kept. Zero disables.
+	AutoUnlinkDeadTokenAfter time.Duration
+
+	// How often expired oauth_states rows (abandoned logins) are deleted. Zero disables.
+	OAuthStateCleanupInterval time.Duration
+
+	// When true, each background worker (reconciler, dead-token unlinker, state janitor,
+	// retention reaper) runs on one instance only, elected via a Postgres advisory lock.
+	WorkerLeaderElection bool
+
+	// Retention windows in days for audit_events (login rows excluded), login history (the
+	// github_login audit rows) and github_webhook_deliveries. 0 keeps rows forever.
+	AuditRetentionDays           int
+	LoginHistoryRetentionDays    int
+	WebhookDeliveryRetentionDays int
+
+	// Optional server-to-server login event (analytics). Each github_login POSTs a JSON
+	// payload signed with HMAC-SHA256 of LoginEventWebhookSecret.
+	LoginEventWebhookURL    string
+	LoginEventWebhookSecret string
+	// Further destinations from LOGIN_EVENT_WEBHOOKS ("url|secret;url|secret"). Each is
+	// delivered to independently; see LoginEventDestinations.
+	LoginEventWebhooks []LoginEventWebhook
+
 	// Didit KYC verification
 	DiditAPIKey        string
 	DiditWorkflowID    string
@@ -67,7 +289,7 @@ type Config struct {
 }
 
 func Load() Config {
-	env := getEnv("APP_ENV", "dev")
+	env := getEnv("APP_ENV", getEnv("GO_ENV", "dev"))
 	logLevel := getEnv("LOG_LEVEL", "info")
 
 	// Prefer HTTP_ADDR if provided, otherwise build it from PORT.
@@ -77,15 +299,28 @@ func Load() Config {
 		httpAddr = ":" + port
 	}
 
-	return Config{
+	cfg := Config{
 		Env:      env,
 		HTTPAddr: httpAddr,
 		Log:      logLevel,
 
-		DBURL:       getEnv("DB_URL", ""),
-		AutoMigrate: getEnvBool("AUTO_MIGRATE", false),
+		DBURL:        getEnv("DB_URL", ""),
+		DBMaxRetries: getEnvInt("DB_MAX_RETRIES", 3),
+		AutoMigrate:  getEnvBool("AUTO_MIGRATE", false),
+
+		JWTSecret:          getEnv("JWT_SECRET", ""),
+		JWTIssuer:          strings.TrimSpace(getEnv("JWT_ISSUER", "")),
+		JWTExtraClaimsJSON: strings.TrimSpace(getEnv("JWT_EXTRA_CLAIMS", "")),
+		RoleScopes: parseRoleScopes(getEnv("ROLE_SCOPES",
+			"contributor=repos:read;maintainer=repos:read,repos:write,webhooks:write;admin=*")),
 
-		JWTSecret: getEnv("JWT_SECRET", ""),
+		LoginCookieHandoff: getEnvBool("LOGIN_COOKIE_HANDOFF", false),
+		SessionMode:        strings.ToLower(getEnv("SESSION_MODE", SessionModeQuery)),
+
+		JWTAccessTTL: getEnvDuration("JWT_ACCESS_TTL", 60*time.Minute),
+
+		IssueIDToken: getEnvBool("ISSUE_ID_TOKEN", false),
+		IDTokenTTL:   getEnvDuration("ID_TOKEN_TTL", 5*time.Minute),
 
 		NATSURL: getEnv("NATS_URL", ""),
 
@@ -95,22 +330,113 @@ func Load() Config {
 		GitHubOAuthSuccessRedirectURL: getEnv("GITHUB_OAUTH_SUCCESS_REDIRECT_URL", ""),
 		GitHubLoginRedirectURL:        getEnv("GITHUB_LOGIN_REDIRECT_URL", ""),
 		GitHubLoginSuccessRedirectURL: getEnv("GITHUB_LOGIN_SUCCESS_REDIRECT_URL", ""),
+		GitHubOAuthScopes:             parseList(getEnv("GITHUB_OAUTH_SCOPES", DefaultGitHubOAuthScopes)),
+
+		RedirectDefaultFromHost: getEnvBool("REDIRECT_DEFAULT_FROM_HOST", false),
+		StrictRedirectMatch:     getEnvBool("STRICT_REDIRECT_MATCH", false),
+		StateBindUserAgent:      getEnvBool("STATE_BIND_USER_AGENT", false),
+		RequireLinkConfirmation: getEnvBool("REQUIRE_LINK_CONFIRMATION", false),
 
 		GitHubAppID:         getEnv("GITHUB_APP_ID", ""),
 		GitHubAppSlug:       getEnv("GITHUB_APP_SLUG", ""),
 		GitHubAppPrivateKey: getEnv("GITHUB_APP_PRIVATE_KEY", ""),
 
-		GitHubWebhookSecret: getEnv("GITHUB_WEBHOOK_SECRET", ""),
+		GitHubWebhooksViaApp: getEnvBool("GITHUB_WEBHOOKS_VIA_APP", false),
+
+		GitHubRateLimitRetries: getEnvInt("GITHUB_RATE_LIMIT_RETRIES", 2),
+		GitHubRateLimitMaxWait: getEnvDuration("GITHUB_RATE_LIMIT_MAX_WAIT", 10*time.Second),
 
-		PublicBaseURL: getEnv("PUBLIC_BASE_URL", ""),
+		GitHubWebhookSecret:   getEnv("GITHUB_WEBHOOK_SECRET", ""),
+		WebhookAcceptedEvents: parseList(getEnv("WEBHOOK_ACCEPTED_EVENTS", "")),
+		WebhookPingWait:       getEnvDuration("WEBHOOK_PING_WAIT", 0),
+
+		PublicBaseURL:   getEnv("PUBLIC_BASE_URL", ""),
+		PublicHostCheck: getEnvBool("PUBLIC_HOST_CHECK", false),
+		ExpectedHost:    getEnv("EXPECTED_HOST", ""),
+
+		MetricsToken: getEnv("METRICS_TOKEN", ""),
+
+		AuthRoutePrefix: normalizeRoutePrefix(getEnv("AUTH_ROUTE_PREFIX", "")),
 
 		FrontendBaseURL: getEnv("FRONTEND_BASE_URL", ""),
 		CORSOrigins:     getEnv("CORS_ORIGINS", ""),
 
-		TokenEncKeyB64: getEnv("TOKEN_ENC_KEY_B64", ""),
+		RedirectURIDenylist: parseList(strings.ToLower(getEnv("REDIRECT_URI_DENYLIST", ""))),
+
+		RedirectURIAllowedPatterns: getEnv("REDIRECT_URI_ALLOWED_PATTERNS", ""),
+
+		ContinuePathPrefixes: parseList(getEnv("CONTINUE_PATH_PREFIXES", "")),
+
+		ExternalIDHeader:         strings.TrimSpace(getEnv("EXTERNAL_ID_HEADER", "")),
+		ExternalIDTrustedProxies: parseList(getEnv("EXTERNAL_ID_TRUSTED_PROXIES", "")),
+
+		OAuthErrorTemplate:     getEnv("OAUTH_ERROR_TEMPLATE", ""),
+		OAuthErrorMessagesFile: getEnv("OAUTH_ERROR_MESSAGES_FILE", ""),
+		OAuthErrorDefaultLang:  getEnv("OAUTH_ERROR_DEFAULT_LANG", "en"),
+
+		MaxPendingStatesPerIP: getEnvInt("MAX_PENDING_STATES_PER_IP", 0),
+
+		RateLimitMax:    getEnvInt("RATE_LIMIT_MAX", 0),
+		RateLimitWindow: getEnvDuration("RATE_LIMIT_WINDOW", time.Minute),
+
+		OAuthStartRateLimit: getEnvInt("OAUTH_START_RATE_LIMIT", 0),
+		TrustProxyHeaders:   getEnvBool("TRUST_PROXY_HEADERS", false),
+
+		RetryAfterUnavailable:   getEnvDuration("RETRY_AFTER_UNAVAILABLE", 10*time.Second),
+		RetryAfterMisconfigured: getEnvDuration("RETRY_AFTER_MISCONFIGURED", 5*time.Minute),
+
+		CacheMaxEntries: getEnvInt("CACHE_MAX_ENTRIES", 10000),
+		CacheDefaultTTL: getEnvDuration("CACHE_DEFAULT_TTL", 5*time.Minute),
+
+		TokenEncKeyB64:   getEnv("TOKEN_ENC_KEY_B64", ""),
+		TokenEncKeysB64:  getEnv("TOKEN_ENC_KEYS_B64", ""),
+		StoreGitHubToken: getEnvBool("STORE_GITHUB_TOKEN", true),
+		TokenVault:       strings.ToLower(strings.TrimSpace(getEnv("TOKEN_VAULT", "db"))),
+		VaultAddr:        getEnv("VAULT_ADDR", ""),
+		VaultToken:       getEnv("VAULT_TOKEN", ""),
+		VaultKVMount:     getEnv("VAULT_KV_MOUNT", "secret"),
+		VaultTokenPath:   getEnv("VAULT_TOKEN_PATH", "grainlify/github-tokens"),
 
 		AdminBootstrapToken: strings.TrimSpace(getEnv("ADMIN_BOOTSTRAP_TOKEN", "")),
 
+		SMTPHost:     getEnv("SMTP_HOST", ""),
+		SMTPPort:     getEnvInt("SMTP_PORT", 587),
+		SMTPUsername: getEnv("SMTP_USERNAME", ""),
+		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+		SMTPFrom:     getEnv("SMTP_FROM", ""),
+
+		NotifyOnLink: getEnvBool("NOTIFY_ON_LINK", false),
+
+		RefreshTokenTTL: getEnvDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+
+		SingleSessionPerUser: getEnvBool("SINGLE_SESSION_PER_USER", false),
+		ForceReauthInterval:  getEnvDuration("FORCE_REAUTH_INTERVAL", 0),
+		GitHubOAuthUsePKCE:   getEnvBool("GITHUB_OAUTH_USE_PKCE", false),
+		StatelessLinkState:   getEnvBool("STATELESS_LINK_STATE", false),
+		MinGitHubAccountAge:  getEnvDuration("MIN_GITHUB_ACCOUNT_AGE", 0),
+
+		SlidingSession:            getEnvBool("SLIDING_SESSION", false),
+		SlidingSessionThreshold:   getEnvDuration("SLIDING_SESSION_THRESHOLD", 15*time.Minute),
+		SlidingSessionTTL:         getEnvDuration("SLIDING_SESSION_TTL", 60*time.Minute),
+		SlidingSessionMaxLifetime: getEnvDuration("SLIDING_SESSION_MAX_LIFETIME", 24*time.Hour),
+
+		GitHubIdentityReconcileInterval: getEnvDuration("GITHUB_IDENTITY_RECONCILE_INTERVAL", time.Hour),
+		GitHubIdentityReconcileFix:      getEnvBool("GITHUB_IDENTITY_RECONCILE_FIX", false),
+
+		AutoUnlinkDeadTokenAfter: getEnvDuration("AUTO_UNLINK_DEAD_TOKEN_AFTER", 0),
+
+		OAuthStateCleanupInterval: getEnvDuration("OAUTH_STATE_CLEANUP_INTERVAL", 15*time.Minute),
+
+		WorkerLeaderElection: getEnvBool("WORKER_LEADER_ELECTION", false),
+
+		AuditRetentionDays:           getEnvInt("AUDIT_RETENTION_DAYS", 0),
+		LoginHistoryRetentionDays:    getEnvInt("LOGIN_HISTORY_RETENTION_DAYS", 0),
+		WebhookDeliveryRetentionDays: getEnvInt("WEBHOOK_DELIVERY_RETENTION_DAYS", 0),
+
+		LoginEventWebhookURL:    strings.TrimSpace(getEnv("LOGIN_EVENT_WEBHOOK_URL", "")),
+		LoginEventWebhookSecret: getEnv("LOGIN_EVENT_WEBHOOK_SECRET", ""),
+		LoginEventWebhooks:      parseLoginEventWebhooks(getEnv("LOGIN_EVENT_WEBHOOKS", "")),
+
 		DiditAPIKey:        getEnv("DIDIT_API_KEY", ""),
 		DiditWorkflowID:    getEnv("DIDIT_WORKFLOW_ID", ""),
 		DiditWebhookSecret: getEnv("DIDIT_WEBHOOK_SECRET", ""),
@@ -124,6 +450,15 @@ func Load() Config {
 		ProgramEscrowContractID:  getEnv("PROGRAM_ESCROW_CONTRACT_ID", ""),
 		TokenContractID:          getEnv("TOKEN_CONTRACT_ID", ""),
 	}
+	cfg.AllowLocalhostOrigins = getEnvBool("ALLOW_LOCALHOST_ORIGINS", !cfg.IsProduction())
+	cfg.AllowVercelOrigins = getEnvBool("ALLOW_VERCEL_ORIGINS", !cfg.IsProduction())
+	cfg.originAllowlist = NewOriginAllowlist(cfg.CORSOrigins, cfg.FrontendBaseURL, cfg.AllowLocalhostOrigins, cfg.AllowVercelOrigins)
+	var patternErrs []error
+	cfg.redirectPatterns, patternErrs = CompileRedirectPatterns(cfg.RedirectURIAllowedPatterns)
+	for _, err := range patternErrs {
+		slog.Warn("ignoring invalid REDIRECT_URI_ALLOWED_PATTERNS entry", "error", err)
+	}
+	return cfg
 }
 
 func (c Config) LogLevel() slog.Leveler {
@@ -145,6 +480,263 @@ func (c Config) LogLevel() slog.Leveler {
 	}
 }
 
+// ScopesForRole returns the default access-token scopes for role.
+func (c Config) ScopesForRole(role string) []string {
+	return c.RoleScopes[role]
+}
+
+// LoginEventWebhook is one receiver of login events and the secret its payloads are signed with.
+type LoginEventWebhook struct {
+	URL    string
+	Secret string
+}
+
+// LoginEventDestinations lists every login event receiver: LOGIN_EVENT_WEBHOOK_URL first,
+// then LOGIN_EVENT_WEBHOOKS, without duplicate URLs.
+func (c Config) LoginEventDestinations() []LoginEventWebhook {
+	var out []LoginEventWebhook
+	seen := map[string]bool{}
+	add := func(w LoginEventWebhook) {
+		if w.URL == "" || seen[w.URL] {
+			return
+		}
+		seen[w.URL] = true
+		out = append(out, w)
+	}
+	add(LoginEventWebhook{URL: c.LoginEventWebhookURL, Secret: c.LoginEventWebhookSecret})
+	for _, w := range c.LoginEventWebhooks {
+		add(w)
+	}
+	return out
+}
+
+// Bounds for JWT_ACCESS_TTL.
+const (
+	MinJWTAccessTTL = time.Minute
+	MaxJWTAccessTTL = 24 * time.Hour
+)
+
+// ValidateJWTAccessTTL rejects access-token lifetimes outside 1 minute to 24 hours; the API
+// refuses to start with one.
+func (c Config) ValidateJWTAccessTTL() error {
+	if c.JWTAccessTTL < MinJWTAccessTTL || c.JWTAccessTTL > MaxJWTAccessTTL {
+		return fmt.Errorf("JWT_ACCESS_TTL must be between %s and %s, got %s", MinJWTAccessTTL, MaxJWTAccessTTL, c.JWTAccessTTL)
+	}
+	return nil
+}
+
+// Validate reports settings the API must not start with. Everything it checks would otherwise
+// only fail on first use, e.g. a malformed token key as token_encryption_not_configured in the
+// OAuth callback.
+func (c Config) Validate() error {
+	if err := c.ValidateJWTAccessTTL(); err != nil {
+		return err
+	}
+	if _, err := parseJWTExtraClaims(c.JWTExtraClaimsJSON); err != nil {
+		return err
+	}
+	if c.SessionMode != "" && c.SessionMode != SessionModeQuery && c.SessionMode != SessionModeCookie {
+		return fmt.Errorf("SESSION_MODE must be %q or %q, got %q", SessionModeQuery, SessionModeCookie, c.SessionMode)
+	}
+	if k := strings.TrimSpace(c.TokenEncKeyB64); k != "" {
+		if err := validateTokenEncKey(k); err != nil {
+			return fmt.Errorf("TOKEN_ENC_KEY_B64: %w", err)
+		}
+	}
+	if missing := c.missingGitHubScopes(); len(missing) > 0 {
+		return fmt.Errorf("GITHUB_OAUTH_SCOPES is missing scopes required by enabled features: %v", missing)
+	}
+	for _, entry := range parseList(c.TokenEncKeysB64) {
+		id, b64, named := strings.Cut(entry, ":")
+		if !named {
+			id, b64 = "", entry
+		}
+		if err := validateTokenEncKey(b64); err != nil {
+			return fmt.Errorf("TOKEN_ENC_KEYS_B64 key %q: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// SESSION_MODE values.
+const (
+	SessionModeQuery  = "query"
+	SessionModeCookie = "cookie"
+)
+
+// SessionCookies reports whether SESSION_MODE=cookie: login sets the session cookie and
+// authenticated routes accept it.
+func (c Config) SessionCookies() bool {
+	return c.SessionMode == SessionModeCookie
+}
+
+// DefaultGitHubOAuthScopes is requested when GITHUB_OAUTH_SCOPES is unset:
+// - read:user: link identity
+// - user:email: access user email addresses
+// - repo: access private repos + read repo metadata
+// - admin:repo_hook: create webhooks
+// - read:org: helps when dealing with org-owned repos
+const DefaultGitHubOAuthScopes = "read:user,user:email,repo,admin:repo_hook,read:org"
+
+// GitHubScopes is the scope list the OAuth flows request; the default one when unset.
+func (c Config) GitHubScopes() []string {
+	if len(c.GitHubOAuthScopes) == 0 {
+		return parseList(DefaultGitHubOAuthScopes)
+	}
+	return c.GitHubOAuthScopes
+}
+
+// RequiredGitHubScopes are the scopes the enabled features can't work without: repo to read
+// (private) repos for project verification, and admin:repo_hook when webhooks are registered
+// (PUBLIC_BASE_URL set). A token granted without them is refused in the callback.
+func (c Config) RequiredGitHubScopes() []string {
+	required := []string{"repo"}
+	if c.PublicBaseURL != "" {
+		required = append(required, "admin:repo_hook")
+	}
+	return required
+}
+
+// missingGitHubScopes returns the required scopes GitHubScopes doesn't request. No scope
+// implies repo or admin:repo_hook, so a plain membership check is enough.
+func (c Config) missingGitHubScopes() []string {
+	requested := make(map[string]bool)
+	for _, s := range c.GitHubScopes() {
+		requested[s] = true
+	}
+	var missing []string
+	for _, s := range c.RequiredGitHubScopes() {
+		if !requested[s] {
+			missing = append(missing, s)
+		}
+	}
+	return missing
+}
+
+// JWTExtraClaims is JWT_EXTRA_CLAIMS parsed, nil when unset. Validate refuses a value that
+// doesn't parse, so this only ignores errors the API wouldn't have started with.
+func (c Config) JWTExtraClaims() map[string]any {
+	claims, _ := parseJWTExtraClaims(c.JWTExtraClaimsJSON)
+	return claims
+}
+
+func parseJWTExtraClaims(v string) (map[string]any, error) {
+	if v == "" {
+		return nil, nil
+	}
+	var claims map[string]any
+	if err := json.Unmarshal([]byte(v), &claims); err != nil || claims == nil {
+		return nil, fmt.Errorf("JWT_EXTRA_CLAIMS must be a JSON object")
+	}
+	return claims, nil
+}
+
+// validateTokenEncKey mirrors cryptox.KeyFromB64, which config can't import.
+func validateTokenEncKey(b64 string) error {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(b64))
+	if err != nil {
+		return fmt.Errorf("invalid token encryption key: not base64: %w", err)
+	}
+	if len(key) != 32 {
+		return fmt.Errorf("invalid token encryption key: expected 32 bytes, got %d", len(key))
+	}
+	return nil
+}
+
+// TokenEncKeys is the key spec for cryptox.NewKeyring: TOKEN_ENC_KEYS_B64, then
+// TOKEN_ENC_KEY_B64 as the key for tokens encrypted before key ids. Empty when neither is set.
+func (c Config) TokenEncKeys() string {
+	keys, legacy := strings.TrimSpace(c.TokenEncKeysB64), strings.TrimSpace(c.TokenEncKeyB64)
+	if keys == "" || legacy == "" {
+		return keys + legacy
+	}
+	return keys + "," + legacy
+}
+
+// parseLoginEventWebhooks parses "url|secret;url|secret". The secret may be empty.
+func parseLoginEventWebhooks(v string) []LoginEventWebhook {
+	var out []LoginEventWebhook
+	for _, entry := range strings.Split(v, ";") {
+		u, secret, _ := strings.Cut(entry, "|")
+		if u = strings.TrimSpace(u); u != "" {
+			out = append(out, LoginEventWebhook{URL: u, Secret: strings.TrimSpace(secret)})
+		}
+	}
+	return out
+}
+
+// parseRoleScopes parses "role=scope,scope;role=scope" into a role -> scopes map.
+func parseRoleScopes(v string) map[string][]string {
+	out := map[string][]string{}
+	for _, entry := range strings.Split(v, ";") {
+		role, scopes, ok := strings.Cut(entry, "=")
+		role = strings.TrimSpace(role)
+		if !ok || role == "" {
+			continue
+		}
+		for _, s := range strings.Split(scopes, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				out[role] = append(out[role], s)
+			}
+		}
+	}
+	return out
+}
+
+// WebhookEventAccepted reports whether the webhook receiver should process event.
+func (c Config) WebhookEventAccepted(event string) bool {
+	if len(c.WebhookAcceptedEvents) == 0 || event == "ping" {
+		return true
+	}
+	for _, e := range c.WebhookAcceptedEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// RedirectHostDenied reports whether host matches REDIRECT_URI_DENYLIST.
+func (c Config) RedirectHostDenied(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	for _, d := range c.RedirectURIDenylist {
+		if suffix, ok := strings.CutPrefix(d, "*."); ok {
+			if strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+			continue
+		}
+		if host == d {
+			return true
+		}
+	}
+	return false
+}
+
+// parseList splits a comma-separated env value, dropping empty entries.
+func parseList(v string) []string {
+	var out []string
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// AuthPath returns p (e.g. "/auth/github/login/callback") under the configured AuthRoutePrefix.
+func (c Config) AuthPath(p string) string {
+	return c.AuthRoutePrefix + p
+}
+
+func normalizeRoutePrefix(p string) string {
+	p = strings.Trim(strings.TrimSpace(p), "/")
+	if p == "" {
+		return ""
+	}
+	return "/" + p
+}
+
 func getEnv(key, fallback string) string {
 	v := os.Getenv(key)
 	if strings.TrimSpace(v) == "" {
@@ -167,3 +759,27 @@ func getEnvBool(key string, fallback bool) bool {
 		return fallback
 	}
 }
+
+func getEnvInt(key string, fallback int) int {
+	v := strings.TrimSpace(os.Getenv(key))
+	if v == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return fallback
+	}
+	return n
+}
+
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	v := strings.TrimSpace(os.Getenv(key))
+	if v == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return fallback
+	}
+	return d
+}
@@ -0,0 +1,27 @@
+package config
+
+import (
+	"net"
+	"strings"
+)
+
+// IsTrustedProxy reports whether ip (the direct peer, never a forwarded address) is listed in
+// EXTERNAL_ID_TRUSTED_PROXIES, as a single address or a CIDR range.
+func (c Config) IsTrustedProxy(ip string) bool {
+	addr := net.ParseIP(strings.TrimSpace(ip))
+	if addr == nil {
+		return false
+	}
+	for _, entry := range c.ExternalIDTrustedProxies {
+		if strings.Contains(entry, "/") {
+			if _, n, err := net.ParseCIDR(entry); err == nil && n.Contains(addr) {
+				return true
+			}
+			continue
+		}
+		if p := net.ParseIP(entry); p != nil && p.Equal(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -5,10 +5,16 @@ import (
 	"crypto/cipher"
 	"crypto/rand"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
+
+	"github.com/jagadeesh/grainlify/backend/internal/config"
 )
 
+// ErrDecryptFailed means the ciphertext did not authenticate: wrong key or tampered data.
+var ErrDecryptFailed = errors.New("aes-gcm authentication failed")
+
 func KeyFromB64(b64 string) ([]byte, error) {
 	if b64 == "" {
 		return nil, fmt.Errorf("TOKEN_ENC_KEY_B64 is required")
@@ -44,7 +50,8 @@ func EncryptAESGCM(key []byte, plaintext []byte) ([]byte, error) {
 	return out, nil
 }
 
-func DecryptAESGCM(key []byte, blob []byte) ([]byte, error) {
+// DecryptAESGCM is the inverse of EncryptAESGCM: it splits nonce||ciphertext and opens it.
+func DecryptAESGCM(key []byte, ciphertext []byte) ([]byte, error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
 		return nil, err
@@ -53,31 +60,26 @@ func DecryptAESGCM(key []byte, blob []byte) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	if len(blob) < gcm.NonceSize() {
-		return nil, fmt.Errorf("ciphertext too short")
+	if len(ciphertext) < gcm.NonceSize()+gcm.Overhead() {
+		return nil, fmt.Errorf("ciphertext too short: %d bytes, need at least %d", len(ciphertext), gcm.NonceSize()+gcm.Overhead())
+	}
+	nonce := ciphertext[:gcm.NonceSize()]
+	pt, err := gcm.Open(nil, nonce, ciphertext[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, ErrDecryptFailed
 	}
-	nonce := blob[:gcm.NonceSize()]
-	ct := blob[gcm.NonceSize():]
-	return gcm.Open(nil, nonce, ct, nil)
+	return pt, nil
 }
 
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
+// DecryptToken decrypts a stored access token with the configured token keys.
+func DecryptToken(cfg config.Config, encrypted []byte) (string, error) {
+	keys, err := NewKeyring(cfg.TokenEncKeys())
+	if err != nil {
+		return "", err
+	}
+	pt, err := keys.Decrypt(encrypted)
+	if err != nil {
+		return "", err
+	}
+	return string(pt), nil
+}
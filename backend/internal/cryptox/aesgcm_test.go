@@ -0,0 +1,53 @@
+package cryptox
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"github.com/jagadeesh/grainlify/backend/internal/config"
+)
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, 32)
+}
+
+func TestDecryptAESGCMRoundTrip(t *testing.T) {
+	ct, err := EncryptAESGCM(testKey(1), []byte("gho_token"))
+	if err != nil {
+		t.Fatalf("EncryptAESGCM: %v", err)
+	}
+	pt, err := DecryptAESGCM(testKey(1), ct)
+	if err != nil || string(pt) != "gho_token" {
+		t.Fatalf("DecryptAESGCM = %q, %v", pt, err)
+	}
+
+	cfg := config.Config{TokenEncKeyB64: base64.StdEncoding.EncodeToString(testKey(1))}
+	tok, err := DecryptToken(cfg, ct)
+	if err != nil || tok != "gho_token" {
+		t.Fatalf("DecryptToken = %q, %v", tok, err)
+	}
+}
+
+func TestDecryptAESGCMTruncated(t *testing.T) {
+	ct, err := EncryptAESGCM(testKey(1), []byte("gho_token"))
+	if err != nil {
+		t.Fatalf("EncryptAESGCM: %v", err)
+	}
+	for _, n := range []int{0, 5, 12, 27} {
+		if _, err := DecryptAESGCM(testKey(1), ct[:n]); err == nil {
+			t.Errorf("truncated to %d bytes: expected error", n)
+		}
+	}
+}
+
+func TestDecryptAESGCMWrongKey(t *testing.T) {
+	ct, err := EncryptAESGCM(testKey(1), []byte("gho_token"))
+	if err != nil {
+		t.Fatalf("EncryptAESGCM: %v", err)
+	}
+	if _, err := DecryptAESGCM(testKey(2), ct); !errors.Is(err, ErrDecryptFailed) {
+		t.Fatalf("expected ErrDecryptFailed, got %v", err)
+	}
+}
@@ -0,0 +1,134 @@
+package cryptox
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// keyIDHeader starts a ciphertext written by a Keyring with a key id:
+// keyIDHeader || len(id) || id || nonce || ciphertext. Ciphertexts from EncryptAESGCM with a
+// single key (before key ids) have no header.
+var keyIDHeader = []byte{0x00, 'k', 'r'}
+
+var keyIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+// Keyring holds the AES-256-GCM keys tokens may be encrypted with, so the encryption key can
+// be rotated without re-encrypting what is stored: new ciphertexts use the active key and
+// name it, older ones are still opened with the key they name.
+type Keyring struct {
+	active string
+	keys   map[string][]byte
+	// legacy opens ciphertexts without a key id and, when no key has an id, encrypts.
+	legacy []byte
+}
+
+// NewKeyring parses a comma-separated key spec. "id:base64key" entries are named keys, the
+// first of which is the active one. An entry without an id is the single key from before key
+// ids (TOKEN_ENC_KEY_B64): it decrypts ciphertexts that carry no id, and encrypts only when
+// there are no named keys. Every key must decode to 32 bytes.
+func NewKeyring(spec string) (*Keyring, error) {
+	k := &Keyring{keys: map[string][]byte{}}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, b64, named := strings.Cut(entry, ":")
+		if !named {
+			if k.legacy != nil {
+				return nil, fmt.Errorf("more than one key without an id")
+			}
+			key, err := decodeKey(entry)
+			if err != nil {
+				return nil, err
+			}
+			k.legacy = key
+			continue
+		}
+		if !keyIDPattern.MatchString(id) {
+			return nil, fmt.Errorf("invalid key id %q", id)
+		}
+		if _, dup := k.keys[id]; dup {
+			return nil, fmt.Errorf("duplicate key id %q", id)
+		}
+		key, err := decodeKey(b64)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", id, err)
+		}
+		k.keys[id] = key
+		if k.active == "" {
+			k.active = id
+		}
+	}
+	if k.active == "" && k.legacy == nil {
+		return nil, fmt.Errorf("TOKEN_ENC_KEY_B64 or TOKEN_ENC_KEYS_B64 is required")
+	}
+	return k, nil
+}
+
+func decodeKey(b64 string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(b64))
+	if err != nil {
+		return nil, fmt.Errorf("decode key: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("key must decode to 32 bytes")
+	}
+	return key, nil
+}
+
+// ActiveID is the id of the key new ciphertexts are encrypted with ("" for the legacy key).
+func (k *Keyring) ActiveID() string { return k.active }
+
+// Encrypt seals plaintext with the active key, prefixed with its id.
+func (k *Keyring) Encrypt(plaintext []byte) ([]byte, error) {
+	if k.active == "" {
+		return EncryptAESGCM(k.legacy, plaintext)
+	}
+	ct, err := EncryptAESGCM(k.keys[k.active], plaintext)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, 0, len(keyIDHeader)+1+len(k.active)+len(ct))
+	out = append(out, keyIDHeader...)
+	out = append(out, byte(len(k.active)))
+	out = append(out, k.active...)
+	return append(out, ct...), nil
+}
+
+// Decrypt opens a ciphertext from Encrypt with the key it names, or one without an id with
+// the legacy key. A named key that is no longer in the ring fails with an error naming it.
+func (k *Keyring) Decrypt(ciphertext []byte) ([]byte, error) {
+	if id, rest, ok := splitKeyID(ciphertext); ok {
+		if key, known := k.keys[id]; known {
+			return DecryptAESGCM(key, rest)
+		}
+		if k.legacy == nil {
+			return nil, fmt.Errorf("ciphertext encrypted with unknown key %q", id)
+		}
+		// A legacy nonce can start with the header by chance; fall through to the legacy key.
+	}
+	if k.legacy == nil {
+		return nil, fmt.Errorf("ciphertext has no key id and no legacy key is configured")
+	}
+	return DecryptAESGCM(k.legacy, ciphertext)
+}
+
+func splitKeyID(ciphertext []byte) (id string, rest []byte, ok bool) {
+	if !bytes.HasPrefix(ciphertext, keyIDHeader) || len(ciphertext) <= len(keyIDHeader) {
+		return "", nil, false
+	}
+	n := int(ciphertext[len(keyIDHeader)])
+	start := len(keyIDHeader) + 1
+	if n == 0 || len(ciphertext) < start+n {
+		return "", nil, false
+	}
+	id = string(ciphertext[start : start+n])
+	if !keyIDPattern.MatchString(id) {
+		return "", nil, false
+	}
+	return id, ciphertext[start+n:], true
+}
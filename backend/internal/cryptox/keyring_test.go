@@ -0,0 +1,102 @@
+package cryptox
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/jagadeesh/grainlify/backend/internal/config"
+)
+
+func keyB64(b byte) string {
+	return base64.StdEncoding.EncodeToString(testKey(b))
+}
+
+func TestKeyringRotation(t *testing.T) {
+	before, err := NewKeyring("a:" + keyB64(1))
+	if err != nil {
+		t.Fatalf("NewKeyring: %v", err)
+	}
+	old, err := before.Encrypt([]byte("gho_old"))
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+
+	// Rotate: b becomes active, a stays listed for what it encrypted.
+	after, err := NewKeyring("b:" + keyB64(2) + ",a:" + keyB64(1))
+	if err != nil {
+		t.Fatalf("NewKeyring: %v", err)
+	}
+	if after.ActiveID() != "b" {
+		t.Fatalf("ActiveID = %q, want b", after.ActiveID())
+	}
+	if pt, err := after.Decrypt(old); err != nil || string(pt) != "gho_old" {
+		t.Fatalf("Decrypt old = %q, %v", pt, err)
+	}
+	fresh, err := after.Encrypt([]byte("gho_new"))
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if pt, err := after.Decrypt(fresh); err != nil || string(pt) != "gho_new" {
+		t.Fatalf("Decrypt new = %q, %v", pt, err)
+	}
+	if _, err := before.Decrypt(fresh); err == nil {
+		t.Fatal("keyring without b decrypted a ciphertext encrypted with b")
+	}
+
+	// Once a is dropped its ciphertexts no longer open.
+	dropped, err := NewKeyring("b:" + keyB64(2))
+	if err != nil {
+		t.Fatalf("NewKeyring: %v", err)
+	}
+	if _, err := dropped.Decrypt(old); err == nil {
+		t.Fatal("expected error after dropping key a")
+	}
+}
+
+func TestKeyringLegacyKey(t *testing.T) {
+	legacy, err := EncryptAESGCM(testKey(1), []byte("gho_legacy"))
+	if err != nil {
+		t.Fatalf("EncryptAESGCM: %v", err)
+	}
+	cfg := config.Config{TokenEncKeysB64: "k2:" + keyB64(2), TokenEncKeyB64: keyB64(1)}
+	keys, err := NewKeyring(cfg.TokenEncKeys())
+	if err != nil {
+		t.Fatalf("NewKeyring: %v", err)
+	}
+	if keys.ActiveID() != "k2" {
+		t.Fatalf("ActiveID = %q, want k2", keys.ActiveID())
+	}
+	if pt, err := keys.Decrypt(legacy); err != nil || string(pt) != "gho_legacy" {
+		t.Fatalf("Decrypt legacy = %q, %v", pt, err)
+	}
+	if tok, err := DecryptToken(cfg, legacy); err != nil || tok != "gho_legacy" {
+		t.Fatalf("DecryptToken = %q, %v", tok, err)
+	}
+
+	// A legacy-only ring keeps writing unprefixed ciphertexts, as KeyFromB64 setups did.
+	only, err := NewKeyring(keyB64(1))
+	if err != nil {
+		t.Fatalf("NewKeyring: %v", err)
+	}
+	ct, err := only.Encrypt([]byte("gho_token"))
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if pt, err := DecryptAESGCM(testKey(1), ct); err != nil || string(pt) != "gho_token" {
+		t.Fatalf("DecryptAESGCM = %q, %v", pt, err)
+	}
+}
+
+func TestNewKeyringInvalid(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"a:" + keyB64(1) + ",a:" + keyB64(2),
+		"bad id:" + keyB64(1),
+		"a:" + base64.StdEncoding.EncodeToString([]byte("short")),
+		keyB64(1) + "," + keyB64(2),
+	} {
+		if _, err := NewKeyring(spec); err == nil {
+			t.Errorf("NewKeyring(%q): expected error", spec)
+		}
+	}
+}
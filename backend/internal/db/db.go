@@ -11,6 +11,8 @@ import (
 
 type DB struct {
 	Pool *pgxpool.Pool
+	// MaxRetries is how often WithRetry repeats a call that failed transiently.
+	MaxRetries int
 }
 
 func Connect(ctx context.Context, dbURL string) (*DB, error) {
@@ -103,7 +105,3 @@ func (d *DB) Close() {
 	}
 	d.Pool.Close()
 }
-
-
-
-
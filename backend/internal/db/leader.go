@@ -0,0 +1,134 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// leaderCheckInterval is how often a leader verifies it still holds its lock, and how often
+// the other instances retry to take it over. leaderPingTimeout bounds that check, so a
+// connection that hangs instead of failing still counts as lost. Variables for tests.
+var (
+	leaderCheckInterval = 30 * time.Second
+	leaderPingTimeout   = 5 * time.Second
+)
+
+// leaderConn is the part of *pgx.Conn a LeaderLock uses.
+type leaderConn interface {
+	Ping(ctx context.Context) error
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Close(ctx context.Context) error
+}
+
+// LeaderLock is a held pg_try_advisory_lock. It lives on its own connection (outside the pool),
+// so Postgres releases it as soon as the holder's connection drops.
+type LeaderLock struct {
+	key  string
+	conn leaderConn
+}
+
+// TryLeaderLock takes the session advisory lock for key if no other instance holds it.
+// ok is false (with a nil error) when the lock is taken elsewhere.
+func (d *DB) TryLeaderLock(ctx context.Context, key string) (*LeaderLock, bool, error) {
+	if d == nil || d.Pool == nil {
+		return nil, false, fmt.Errorf("db not configured")
+	}
+	conn, err := pgx.ConnectConfig(ctx, d.Pool.Config().ConnConfig.Copy())
+	if err != nil {
+		return nil, false, err
+	}
+	var ok bool
+	if err := conn.QueryRow(ctx, `SELECT pg_try_advisory_lock($1)`, leaderLockID(key)).Scan(&ok); err != nil {
+		_ = conn.Close(ctx)
+		return nil, false, err
+	}
+	if !ok {
+		_ = conn.Close(ctx)
+		return nil, false, nil
+	}
+	return &LeaderLock{key: key, conn: conn}, true, nil
+}
+
+// Held reports whether the lock's connection is still alive, i.e. the lock is still ours.
+func (l *LeaderLock) Held(ctx context.Context) bool {
+	ctx, cancel := context.WithTimeout(ctx, leaderPingTimeout)
+	defer cancel()
+	return l.conn.Ping(ctx) == nil
+}
+
+// Release gives up the lock. Closing the connection releases it even if the unlock fails.
+func (l *LeaderLock) Release(ctx context.Context) {
+	_, _ = l.conn.Exec(ctx, `SELECT pg_advisory_unlock($1)`, leaderLockID(l.key))
+	_ = l.conn.Close(ctx)
+}
+
+// RunAsLeader runs a singleton worker on at most one instance of the cluster. Instances that
+// don't hold the lock for key retry periodically, so another one takes over when the leader
+// dies. If the leader loses its lock connection, run's context is cancelled and it competes
+// again. RunAsLeader returns when ctx is done or run returns on its own (e.g. disabled).
+func (d *DB) RunAsLeader(ctx context.Context, key string, run func(context.Context)) {
+	runAsLeader(ctx, key, d.TryLeaderLock, run)
+}
+
+func runAsLeader(ctx context.Context, key string, tryLock func(context.Context, string) (*LeaderLock, bool, error), run func(context.Context)) {
+	for {
+		lock, ok, err := tryLock(ctx, key)
+		if err != nil {
+			slog.Error("leader election failed", "worker", key, "error", err)
+		}
+		if ok {
+			slog.Info("leader lock acquired", "worker", key)
+			if lost := runWhileHeld(ctx, lock, run); !lost {
+				lock.Release(context.Background())
+				return
+			}
+			slog.Warn("leader lock lost, re-electing", "worker", key)
+			lock.Release(context.Background())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(leaderCheckInterval):
+		}
+	}
+}
+
+// runWhileHeld runs run until it returns or the lock is lost; it reports the latter.
+func runWhileHeld(ctx context.Context, lock *LeaderLock, run func(context.Context)) (lost bool) {
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		run(runCtx)
+	}()
+
+	ticker := time.NewTicker(leaderCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return false
+		case <-ticker.C:
+			if !lock.Held(ctx) && ctx.Err() == nil {
+				cancel()
+				<-done
+				return true
+			}
+		}
+	}
+}
+
+// leaderLockID maps a worker name to the bigint advisory lock key.
+func leaderLockID(key string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte("grainlify:leader:" + key))
+	return int64(h.Sum64())
+}
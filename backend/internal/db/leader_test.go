@@ -0,0 +1,119 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestLeaderLockID(t *testing.T) {
+	if leaderLockID("retention_reaper") != leaderLockID("retention_reaper") {
+		t.Fatal("lock id must be stable across instances")
+	}
+	if leaderLockID("retention_reaper") == leaderLockID("oauth_state_janitor") {
+		t.Fatal("workers must not share a lock")
+	}
+}
+
+// fakeLeaderConn is a lock connection that is alive until killed; hang makes Ping block
+// until its context ends, like a connection that stopped answering.
+type fakeLeaderConn struct {
+	dead     atomic.Bool
+	hang     bool
+	unlocked atomic.Bool
+	closed   atomic.Bool
+}
+
+func (c *fakeLeaderConn) Ping(ctx context.Context) error {
+	if c.hang {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	if c.dead.Load() {
+		return errors.New("conn closed")
+	}
+	return nil
+}
+
+func (c *fakeLeaderConn) Exec(context.Context, string, ...any) (pgconn.CommandTag, error) {
+	c.unlocked.Store(true)
+	return pgconn.CommandTag{}, nil
+}
+
+func (c *fakeLeaderConn) Close(context.Context) error {
+	c.closed.Store(true)
+	return nil
+}
+
+// fastLeaderChecks shortens the check interval and ping timeout for the test.
+func fastLeaderChecks(t *testing.T) {
+	t.Helper()
+	interval, timeout := leaderCheckInterval, leaderPingTimeout
+	leaderCheckInterval, leaderPingTimeout = 5*time.Millisecond, 20*time.Millisecond
+	t.Cleanup(func() { leaderCheckInterval, leaderPingTimeout = interval, timeout })
+}
+
+func TestLeaderLockHeldTimesOut(t *testing.T) {
+	fastLeaderChecks(t)
+	lock := &LeaderLock{key: "k", conn: &fakeLeaderConn{hang: true}}
+	start := time.Now()
+	if lock.Held(context.Background()) {
+		t.Error("a hanging connection must not count as held")
+	}
+	if time.Since(start) > time.Second {
+		t.Error("Held must give up after the ping timeout")
+	}
+}
+
+func TestRunAsLeaderLosesAndRegainsLock(t *testing.T) {
+	fastLeaderChecks(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Attempt 0 wins the lock, attempt 1 finds it held elsewhere after we lost it, and
+	// attempt 2 takes it back.
+	conns := map[int32]*fakeLeaderConn{0: {}, 2: {}}
+	var attempts atomic.Int32
+	tryLock := func(context.Context, string) (*LeaderLock, bool, error) {
+		conn, ok := conns[attempts.Add(1)-1]
+		if !ok {
+			return nil, false, nil
+		}
+		return &LeaderLock{key: "k", conn: conn}, true, nil
+	}
+
+	var started atomic.Int32
+	run := func(runCtx context.Context) {
+		if started.Add(1) == 1 {
+			conns[0].dead.Store(true)
+			<-runCtx.Done()
+			return
+		}
+		cancel()
+		<-runCtx.Done()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		runAsLeader(ctx, "k", tryLock, run)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("runAsLeader did not return")
+	}
+
+	if got := started.Load(); got != 2 {
+		t.Fatalf("run started %d times, want 2 (before losing the lock and after regaining it)", got)
+	}
+	for i, c := range conns {
+		if !c.unlocked.Load() || !c.closed.Load() {
+			t.Errorf("lock %d not released (unlocked=%v closed=%v)", i, c.unlocked.Load(), c.closed.Load())
+		}
+	}
+}
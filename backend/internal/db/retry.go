@@ -0,0 +1,80 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Backoff between WithRetry attempts: retryBaseDelay, doubling, capped at retryMaxDelay.
+const (
+	retryBaseDelay = 50 * time.Millisecond
+	retryMaxDelay  = time.Second
+)
+
+// retrySleep waits d or until ctx is done; tests replace it.
+var retrySleep = func(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// WithRetry runs fn, and runs it again up to MaxRetries times while it fails with a transient
+// error: a lost or refused connection (e.g. during a failover) or a serialization failure.
+// fn must be safe to repeat, i.e. a read or a whole transaction. Any other error, including
+// pgx.ErrNoRows, is returned at once.
+func (d *DB) WithRetry(ctx context.Context, fn func(ctx context.Context) error) error {
+	return withRetry(ctx, d.MaxRetries, fn)
+}
+
+func withRetry(ctx context.Context, maxRetries int, fn func(ctx context.Context) error) error {
+	delay := retryBaseDelay
+	for attempt := 0; ; attempt++ {
+		err := fn(ctx)
+		if err == nil || attempt >= maxRetries || !IsTransient(err) || ctx.Err() != nil {
+			return err
+		}
+		if sleepErr := retrySleep(ctx, delay); sleepErr != nil {
+			return err
+		}
+		delay = min(delay*2, retryMaxDelay)
+	}
+}
+
+// IsTransient reports whether err is worth retrying: the connection failed or was lost, the
+// server is shutting down or starting up, or the transaction hit a serialization failure or
+// deadlock.
+func IsTransient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "40001", // serialization_failure
+			"40P01", // deadlock_detected
+			"57P01", // admin_shutdown
+			"57P02", // crash_shutdown
+			"57P03": // cannot_connect_now
+			return true
+		}
+		// Class 08: connection exceptions.
+		return strings.HasPrefix(pgErr.Code, "08")
+	}
+	var connectErr *pgconn.ConnectError
+	if errors.As(err, &connectErr) || pgconn.SafeToRetry(err) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
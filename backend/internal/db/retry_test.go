@@ -0,0 +1,112 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// fakePool fails its first failures queries with err, then succeeds.
+type fakePool struct {
+	failures int
+	err      error
+	calls    int
+}
+
+func (p *fakePool) query(ctx context.Context) error {
+	p.calls++
+	if p.calls <= p.failures {
+		return p.err
+	}
+	return nil
+}
+
+func recordSleeps(t *testing.T) *[]time.Duration {
+	t.Helper()
+	var sleeps []time.Duration
+	orig := retrySleep
+	retrySleep = func(ctx context.Context, d time.Duration) error {
+		sleeps = append(sleeps, d)
+		return nil
+	}
+	t.Cleanup(func() { retrySleep = orig })
+	return &sleeps
+}
+
+func TestWithRetrySucceedsAfterTransientFailures(t *testing.T) {
+	sleeps := recordSleeps(t)
+	pool := &fakePool{failures: 2, err: &pgconn.PgError{Code: "40001"}}
+	d := &DB{MaxRetries: 3}
+
+	if err := d.WithRetry(context.Background(), pool.query); err != nil {
+		t.Fatalf("WithRetry = %v, want success", err)
+	}
+	if pool.calls != 3 {
+		t.Errorf("calls = %d, want 3", pool.calls)
+	}
+	if fmt.Sprint(*sleeps) != "[50ms 100ms]" {
+		t.Errorf("backoff = %v, want [50ms 100ms]", *sleeps)
+	}
+}
+
+func TestWithRetryGivesUpAfterMaxRetries(t *testing.T) {
+	sleeps := recordSleeps(t)
+	failure := &pgconn.PgError{Code: "57P01"}
+	pool := &fakePool{failures: 100, err: failure}
+
+	err := withRetry(context.Background(), 6, pool.query)
+	if !errors.Is(err, failure) {
+		t.Fatalf("WithRetry = %v, want the last failure", err)
+	}
+	if pool.calls != 7 {
+		t.Errorf("calls = %d, want 7", pool.calls)
+	}
+	if fmt.Sprint(*sleeps) != "[50ms 100ms 200ms 400ms 800ms 1s]" {
+		t.Errorf("backoff = %v, want capped at 1s", *sleeps)
+	}
+}
+
+func TestWithRetryReturnsPermanentErrorsAtOnce(t *testing.T) {
+	recordSleeps(t)
+	for _, failure := range []error{
+		pgx.ErrNoRows,
+		&pgconn.PgError{Code: "23505"},
+		context.Canceled,
+	} {
+		pool := &fakePool{failures: 1, err: failure}
+		if err := withRetry(context.Background(), 3, pool.query); !errors.Is(err, failure) || pool.calls != 1 {
+			t.Errorf("%v: err = %v after %d calls, want it returned after 1", failure, err, pool.calls)
+		}
+	}
+}
+
+func TestWithRetryZeroRetries(t *testing.T) {
+	recordSleeps(t)
+	pool := &fakePool{failures: 1, err: &pgconn.PgError{Code: "08006"}}
+	if err := (&DB{}).WithRetry(context.Background(), pool.query); err == nil || pool.calls != 1 {
+		t.Errorf("err = %v after %d calls, want one failed attempt", err, pool.calls)
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	cases := map[error]bool{
+		&pgconn.PgError{Code: "40001"}:                  true,
+		&pgconn.PgError{Code: "40P01"}:                  true,
+		&pgconn.PgError{Code: "08006"}:                  true,
+		&pgconn.PgError{Code: "57P03"}:                  true,
+		&pgconn.PgError{Code: "23503"}:                  false,
+		fmt.Errorf("query: %w", &pgconn.ConnectError{}): true,
+		pgx.ErrNoRows:                                   false,
+		context.DeadlineExceeded:                        false,
+	}
+	for err, want := range cases {
+		if got := IsTransient(err); got != want {
+			t.Errorf("IsTransient(%v) = %v, want %v", err, got, want)
+		}
+	}
+}
@@ -5,19 +5,45 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 )
 
 type Client struct {
 	HTTP      *http.Client
 	UserAgent string
+	// BaseURL overrides https://api.github.com (tests). Only ListUserRepos, GetUserEmails and
+	// CreateRepoWebhook honour it so far.
+	BaseURL string
+
+	breaker *circuitBreaker
 }
 
-func NewClient() *Client {
-	return &Client{
-		HTTP:      &http.Client{Timeout: 10 * time.Second},
+// apiBaseURL is BaseURL without a trailing slash, or the public API.
+func (c *Client) apiBaseURL() string {
+	if base := strings.TrimRight(c.BaseURL, "/"); base != "" {
+		return base
+	}
+	return defaultAPIBaseURL
+}
+
+func NewClient(opts ...ClientOption) *Client {
+	c := &Client{
+		HTTP: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: rateLimitTransport{base: http.DefaultTransport},
+		},
 		UserAgent: "patchwork-backend",
+		breaker:   sharedCircuitBreaker(defaultBreakerThreshold, defaultBreakerCooldown),
+	}
+	for _, opt := range opts {
+		opt(c)
 	}
+	// Outermost, so a call retried by WithRateLimitRetry counts once.
+	if c.breaker != nil {
+		c.HTTP.Transport = circuitBreakerTransport{base: c.HTTP.Transport, breaker: c.breaker}
+	}
+	return c
 }
 
 type User struct {
@@ -29,6 +55,12 @@ type User struct {
 	Location  string `json:"location"`
 	Bio       string `json:"bio"`
 	Blog      string `json:"blog"` // Website URL
+	// Repository counts. total_private_repos is only sent for the token's own user and only
+	// with a scope that can see private repos, hence the pointer.
+	PublicRepos       int  `json:"public_repos"`
+	TotalPrivateRepos *int `json:"total_private_repos"`
+	// When the GitHub account was created; zero if GitHub didn't say.
+	CreatedAt time.Time `json:"created_at"`
 }
 
 type Email struct {
@@ -56,7 +88,7 @@ func (c *Client) GetUser(ctx context.Context, accessToken string) (User, error)
 	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return User{}, fmt.Errorf("github /user failed: status %d", resp.StatusCode)
+		return User{}, parseGitHubAPIError(resp)
 	}
 
 	var u User
@@ -69,10 +101,40 @@ func (c *Client) GetUser(ctx context.Context, accessToken string) (User, error)
 	return u, nil
 }
 
+// GetUserRaw returns GitHub's /user response verbatim, for callers that need fields
+// User doesn't map.
+func (c *Client) GetUserRaw(ctx context.Context, accessToken string) (json.RawMessage, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://api.github.com/user", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, parseGitHubAPIError(resp)
+	}
+
+	var raw json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
+
 // GetUserEmails fetches the user's email addresses from GitHub
 // Requires user:email scope
 func (c *Client) GetUserEmails(ctx context.Context, accessToken string) ([]Email, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://api.github.com/user/emails", nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.apiBaseURL()+"/user/emails", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -99,32 +161,33 @@ func (c *Client) GetUserEmails(ctx context.Context, accessToken string) ([]Email
 	return emails, nil
 }
 
-// GetPrimaryEmail gets the primary email from the user's emails list
-func (c *Client) GetPrimaryEmail(ctx context.Context, accessToken string) (string, error) {
+// GetPrimaryEmail gets the primary email from the user's emails list.
+// ok is false (with a nil error) when the account has no verified email; callers decide
+// whether to fall back to the public profile email or store nothing.
+func (c *Client) GetPrimaryEmail(ctx context.Context, accessToken string) (email string, ok bool, err error) {
 	emails, err := c.GetUserEmails(ctx, accessToken)
 	if err != nil {
-		return "", err
+		return "", false, err
 	}
-	
-	// Find primary email
+	email, ok = PrimaryEmail(emails)
+	return email, ok, nil
+}
+
+// PrimaryEmail picks the contact address deterministically: the primary verified email, else the
+// first verified email in GitHub's order. Unverified addresses are never returned.
+func PrimaryEmail(emails []Email) (string, bool) {
 	for _, email := range emails {
 		if email.Primary && email.Verified {
-			return email.Email, nil
+			return email.Email, true
 		}
 	}
-	
-	// If no primary verified email, return first verified email
+
+	// Some accounts have several verified emails but none flagged primary.
 	for _, email := range emails {
 		if email.Verified {
-			return email.Email, nil
+			return email.Email, true
 		}
 	}
-	
-	// If no verified email, return first email
-	if len(emails) > 0 {
-		return emails[0].Email, nil
-	}
-	
-	return "", fmt.Errorf("no email found")
-}
 
+	return "", false
+}
@@ -0,0 +1,108 @@
+package github
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPrimaryEmail(t *testing.T) {
+	cases := []struct {
+		name   string
+		emails []Email
+		want   string
+		wantOK bool
+	}{
+		{
+			name: "primary and verified",
+			emails: []Email{
+				{Email: "other@example.com", Verified: true},
+				{Email: "main@example.com", Primary: true, Verified: true},
+			},
+			want:   "main@example.com",
+			wantOK: true,
+		},
+		{
+			name: "verified but none primary",
+			emails: []Email{
+				{Email: "unverified@example.com"},
+				{Email: "first@example.com", Verified: true},
+				{Email: "second@example.com", Verified: true},
+			},
+			want:   "first@example.com",
+			wantOK: true,
+		},
+		{
+			name: "unverified only",
+			emails: []Email{
+				{Email: "main@example.com", Primary: true},
+				{Email: "other@example.com"},
+			},
+			want:   "",
+			wantOK: false,
+		},
+		{
+			name:   "empty list",
+			emails: nil,
+			want:   "",
+			wantOK: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := PrimaryEmail(tc.emails)
+			if got != tc.want || ok != tc.wantOK {
+				t.Errorf("PrimaryEmail() = (%q, %v), want (%q, %v)", got, ok, tc.want, tc.wantOK)
+			}
+		})
+	}
+}
+
+func TestGetPrimaryEmailFromEndpoint(t *testing.T) {
+	cases := []struct {
+		name   string
+		body   string
+		want   string
+		wantOK bool
+	}{
+		{
+			name:   "primary verified",
+			body:   `[{"email":"old@example.com","verified":true},{"email":"main@example.com","primary":true,"verified":true}]`,
+			want:   "main@example.com",
+			wantOK: true,
+		},
+		{
+			name:   "no verified email",
+			body:   `[{"email":"unverified@example.com","primary":true,"verified":false}]`,
+			wantOK: false,
+		},
+		{
+			name:   "no emails",
+			body:   `[]`,
+			wantOK: false,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/user/emails" || r.Header.Get("Authorization") != "Bearer tok" {
+					t.Errorf("unexpected request %s with %q", r.URL.Path, r.Header.Get("Authorization"))
+				}
+				_, _ = w.Write([]byte(tc.body))
+			}))
+			defer srv.Close()
+
+			c := NewClient()
+			c.BaseURL = srv.URL
+			got, ok, err := c.GetPrimaryEmail(context.Background(), "tok")
+			if err != nil {
+				t.Fatalf("GetPrimaryEmail: %v", err)
+			}
+			if got != tc.want || ok != tc.wantOK {
+				t.Errorf("GetPrimaryEmail() = (%q, %v), want (%q, %v)", got, ok, tc.want, tc.wantOK)
+			}
+		})
+	}
+}
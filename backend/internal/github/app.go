@@ -18,6 +18,8 @@ type GitHubAppClient struct {
 	PrivateKey *rsa.PrivateKey
 	HTTP       *http.Client
 	UserAgent  string
+	// BaseURL overrides https://api.github.com for the installation token endpoint.
+	BaseURL string
 }
 
 // NewGitHubAppClient creates a new GitHub App client
@@ -71,15 +73,28 @@ type InstallationTokenResponse struct {
 
 // GetInstallationToken gets an installation access token for a specific installation
 func (c *GitHubAppClient) GetInstallationToken(ctx context.Context, installationID string) (string, error) {
+	tokenResp, err := c.CreateInstallationToken(ctx, installationID)
+	if err != nil {
+		return "", err
+	}
+	return tokenResp.Token, nil
+}
+
+// CreateInstallationToken is GetInstallationToken that also returns when the token expires.
+func (c *GitHubAppClient) CreateInstallationToken(ctx context.Context, installationID string) (InstallationTokenResponse, error) {
 	jwtToken, err := c.GenerateJWT()
 	if err != nil {
-		return "", fmt.Errorf("failed to generate JWT: %w", err)
+		return InstallationTokenResponse{}, fmt.Errorf("failed to generate JWT: %w", err)
 	}
 
-	url := fmt.Sprintf("https://api.github.com/app/installations/%s/access_tokens", installationID)
+	baseURL := c.BaseURL
+	if baseURL == "" {
+		baseURL = defaultAPIBaseURL
+	}
+	url := fmt.Sprintf("%s/app/installations/%s/access_tokens", baseURL, installationID)
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, nil)
 	if err != nil {
-		return "", err
+		return InstallationTokenResponse{}, err
 	}
 
 	req.Header.Set("Authorization", "Bearer "+jwtToken)
@@ -90,22 +105,22 @@ func (c *GitHubAppClient) GetInstallationToken(ctx context.Context, installation
 
 	resp, err := c.HTTP.Do(req)
 	if err != nil {
-		return "", err
+		return InstallationTokenResponse{}, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		var errBody map[string]interface{}
 		json.NewDecoder(resp.Body).Decode(&errBody)
-		return "", fmt.Errorf("failed to get installation token: status %d, error: %v", resp.StatusCode, errBody)
+		return InstallationTokenResponse{}, fmt.Errorf("failed to get installation token: status %d, error: %v", resp.StatusCode, errBody)
 	}
 
 	var tokenResp InstallationTokenResponse
 	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
-		return "", err
+		return InstallationTokenResponse{}, err
 	}
 
-	return tokenResp.Token, nil
+	return tokenResp, nil
 }
 
 // InstallationRepository represents a repository in a GitHub App installation
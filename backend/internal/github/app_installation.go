@@ -0,0 +1,58 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// installationTokenRefreshMargin renews a cached installation token this long before GitHub
+// expires it (tokens live one hour), so a request never starts with an almost-dead token.
+const installationTokenRefreshMargin = 5 * time.Minute
+
+// AppClient authenticates as one GitHub App installation. Installation tokens carry only the
+// permissions granted to the app on the installed repositories, unlike a user's OAuth token
+// with its full repo scope. The token is cached and refreshed shortly before it expires.
+type AppClient struct {
+	App            *GitHubAppClient
+	InstallationID int64
+
+	now       func() time.Time
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+// NewAppClient builds an installation client. privateKey is the app's PEM key, raw or base64.
+func NewAppClient(appID int64, privateKey []byte, installationID int64) (*AppClient, error) {
+	if appID <= 0 || installationID <= 0 {
+		return nil, fmt.Errorf("github app id and installation id are required")
+	}
+	app, err := NewGitHubAppClient(strconv.FormatInt(appID, 10), string(privateKey))
+	if err != nil {
+		return nil, err
+	}
+	return &AppClient{App: app, InstallationID: installationID, now: time.Now}, nil
+}
+
+// Token returns a valid installation access token, minting a new one when the cached token is
+// missing or within installationTokenRefreshMargin of expiry.
+func (c *AppClient) Token(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token != "" && c.now().Add(installationTokenRefreshMargin).Before(c.expiresAt) {
+		return c.token, nil
+	}
+	resp, err := c.App.CreateInstallationToken(ctx, strconv.FormatInt(c.InstallationID, 10))
+	if err != nil {
+		return "", err
+	}
+	if resp.Token == "" {
+		return "", fmt.Errorf("github returned an empty installation token")
+	}
+	c.token, c.expiresAt = resp.Token, resp.ExpiresAt
+	return c.token, nil
+}
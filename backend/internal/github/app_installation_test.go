@@ -0,0 +1,88 @@
+package github
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testAppPrivateKey(t *testing.T) []byte {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+}
+
+func TestAppClientTokenCachingAndRefresh(t *testing.T) {
+	now := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/app/installations/42/access_tokens" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+			t.Error("token request must carry the app JWT")
+		}
+		n := atomic.AddInt32(&calls, 1)
+		_ = json.NewEncoder(w).Encode(InstallationTokenResponse{
+			Token:     fmt.Sprintf("ghs_%d", n),
+			ExpiresAt: now.Add(time.Hour),
+		})
+	}))
+	defer srv.Close()
+
+	c, err := NewAppClient(1234, testAppPrivateKey(t), 42)
+	if err != nil {
+		t.Fatalf("NewAppClient: %v", err)
+	}
+	c.App.BaseURL = srv.URL
+	c.now = func() time.Time { return now }
+
+	tok, err := c.Token(context.Background())
+	if err != nil || tok != "ghs_1" {
+		t.Fatalf("first token = %q, %v", tok, err)
+	}
+
+	now = now.Add(30 * time.Minute)
+	if tok, _ := c.Token(context.Background()); tok != "ghs_1" || atomic.LoadInt32(&calls) != 1 {
+		t.Fatalf("token must be cached while valid, got %q after %d calls", tok, calls)
+	}
+
+	// Within the refresh margin of the one-hour expiry a new token is minted.
+	now = now.Add(26 * time.Minute)
+	if tok, _ := c.Token(context.Background()); tok != "ghs_2" || atomic.LoadInt32(&calls) != 2 {
+		t.Fatalf("token must refresh near expiry, got %q after %d calls", tok, calls)
+	}
+}
+
+func TestAppClientTokenError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"message":"Not Found"}`))
+	}))
+	defer srv.Close()
+
+	c, err := NewAppClient(1234, testAppPrivateKey(t), 42)
+	if err != nil {
+		t.Fatalf("NewAppClient: %v", err)
+	}
+	c.App.BaseURL = srv.URL
+	if _, err := c.Token(context.Background()); err == nil {
+		t.Fatal("expected error for a missing installation")
+	}
+	if _, err := NewAppClient(0, testAppPrivateKey(t), 42); err == nil {
+		t.Fatal("expected error without an app id")
+	}
+}
@@ -0,0 +1,167 @@
+package github
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without calling GitHub while the circuit breaker for the host is
+// open: the last requests failed and the cooldown hasn't passed yet.
+var ErrCircuitOpen = errors.New("github circuit breaker open")
+
+// Defaults used by NewClient; WithCircuitBreaker overrides them.
+const (
+	defaultBreakerThreshold = 5
+	defaultBreakerCooldown  = 30 * time.Second
+)
+
+// maxBreakerCooldown caps the doubling of the cooldown after failed probes (unless the base
+// cooldown is already longer).
+const maxBreakerCooldown = 5 * time.Minute
+
+// WithCircuitBreaker opens the circuit for a host after threshold consecutive failures
+// (transport errors and 5xx responses): requests then fail fast with ErrCircuitOpen for
+// cooldown, after which one probe request is let through. A successful probe closes the
+// circuit; each failed one opens it again for twice the previous cooldown, up to
+// maxBreakerCooldown, so a long outage isn't probed at a fixed rate. threshold <= 0 disables
+// the breaker.
+//
+// Clients with the same settings share one breaker, so its state survives the short-lived
+// clients built per request.
+func WithCircuitBreaker(threshold int, cooldown time.Duration) ClientOption {
+	return func(c *Client) {
+		if threshold <= 0 {
+			c.breaker = nil
+			return
+		}
+		c.breaker = sharedCircuitBreaker(threshold, cooldown)
+	}
+}
+
+type breakerSettings struct {
+	threshold int
+	cooldown  time.Duration
+}
+
+var (
+	breakersMu sync.Mutex
+	breakers   = map[breakerSettings]*circuitBreaker{}
+)
+
+func sharedCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+	key := breakerSettings{threshold: threshold, cooldown: cooldown}
+	b, ok := breakers[key]
+	if !ok {
+		b = newCircuitBreaker(threshold, cooldown)
+		breakers[key] = b
+	}
+	return b
+}
+
+// circuitBreaker keeps one circuit per host, so an outage of github.com (OAuth) doesn't cut
+// off api.github.com and vice versa.
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu    sync.Mutex
+	hosts map[string]*circuitState
+}
+
+type circuitState struct {
+	failures  int
+	opens     int // times opened since the circuit was last closed
+	openUntil time.Time
+	probing   bool
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown, now: time.Now, hosts: map[string]*circuitState{}}
+}
+
+// allow reports whether a request to host may go out. Once the cooldown has passed, the
+// first caller becomes the probe and everyone else keeps failing fast until it reports.
+func (b *circuitBreaker) allow(host string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s := b.hosts[host]
+	if s == nil || s.failures < b.threshold {
+		return true
+	}
+	if s.probing || b.now().Before(s.openUntil) {
+		return false
+	}
+	s.probing = true
+	return true
+}
+
+// record reports the outcome of a request that allow let through.
+func (b *circuitBreaker) record(host string, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s := b.hosts[host]
+	if !failed {
+		if s != nil {
+			delete(b.hosts, host)
+		}
+		return
+	}
+	if s == nil {
+		s = &circuitState{}
+		b.hosts[host] = s
+	}
+	s.failures++
+	s.probing = false
+	if s.failures >= b.threshold {
+		s.openUntil = b.now().Add(b.cooldownAfter(s.opens))
+		s.opens++
+	}
+}
+
+// cooldownAfter is how long the circuit stays open once it has already been opened n times
+// without closing: the cooldown doubled n times, capped.
+func (b *circuitBreaker) cooldownAfter(n int) time.Duration {
+	limit := max(maxBreakerCooldown, b.cooldown)
+	d := b.cooldown
+	for i := 0; i < n && d < limit; i++ {
+		d *= 2
+	}
+	return min(d, limit)
+}
+
+// release ends a probe without an outcome, so the next request probes again.
+func (b *circuitBreaker) release(host string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if s := b.hosts[host]; s != nil {
+		s.probing = false
+	}
+}
+
+type circuitBreakerTransport struct {
+	base    http.RoundTripper
+	breaker *circuitBreaker
+}
+
+func (t circuitBreakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+	if !t.breaker.allow(host) {
+		return nil, ErrCircuitOpen
+	}
+	resp, err := t.base.RoundTrip(req)
+	switch {
+	case err != nil && req.Context().Err() != nil:
+		// The caller gave up; that says nothing about GitHub. Release a probe slot, if held.
+		t.breaker.release(host)
+	case err != nil:
+		t.breaker.record(host, true)
+	default:
+		t.breaker.record(host, resp.StatusCode >= 500)
+	}
+	return resp, err
+}
@@ -0,0 +1,161 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// withIsolatedCircuitBreaker gives the client a breaker of its own, so a test can drive its
+// clock without touching the breakers shared by every client with the same settings.
+func withIsolatedCircuitBreaker(threshold int, cooldown time.Duration) ClientOption {
+	return func(c *Client) {
+		c.breaker = newCircuitBreaker(threshold, cooldown)
+	}
+}
+
+func TestCircuitBreakerOpensAndRecovers(t *testing.T) {
+	var hits atomic.Int32
+	var healthy atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	defer srv.Close()
+
+	c := NewClient(withIsolatedCircuitBreaker(3, time.Minute))
+	c.BaseURL = srv.URL
+	now := time.Now()
+	c.breaker.now = func() time.Time { return now }
+	call := func() error {
+		_, err := c.GetUserEmails(context.Background(), "tok")
+		return err
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := call(); err == nil || errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("call %d: err = %v, want the upstream failure", i, err)
+		}
+	}
+
+	// Open: fail fast without reaching GitHub.
+	for i := 0; i < 2; i++ {
+		if err := call(); !errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("open circuit: err = %v, want ErrCircuitOpen", err)
+		}
+	}
+	if got := hits.Load(); got != 3 {
+		t.Fatalf("GitHub got %d requests, want 3", got)
+	}
+
+	// After the cooldown a probe goes out; it succeeds and closes the circuit.
+	now = now.Add(time.Minute)
+	healthy.Store(true)
+	if err := call(); err != nil {
+		t.Fatalf("probe: %v", err)
+	}
+	if err := call(); err != nil {
+		t.Fatalf("closed circuit: %v", err)
+	}
+	if got := hits.Load(); got != 5 {
+		t.Errorf("GitHub got %d requests, want 5", got)
+	}
+}
+
+func TestCircuitBreakerHalfOpen(t *testing.T) {
+	b := newCircuitBreaker(2, time.Minute)
+	now := time.Now()
+	b.now = func() time.Time { return now }
+
+	b.record("api.github.com", true)
+	if !b.allow("api.github.com") {
+		t.Fatal("one failure must not open the circuit")
+	}
+	b.record("api.github.com", true)
+	if b.allow("api.github.com") {
+		t.Fatal("circuit should be open after the threshold")
+	}
+	if !b.allow("github.com") {
+		t.Error("other hosts must not be affected")
+	}
+
+	now = now.Add(time.Minute)
+	if !b.allow("api.github.com") {
+		t.Fatal("cooldown over: the probe should be let through")
+	}
+	if b.allow("api.github.com") {
+		t.Error("only one probe at a time")
+	}
+
+	// A failed probe opens the circuit for twice the cooldown.
+	b.record("api.github.com", true)
+	if b.allow("api.github.com") {
+		t.Error("failed probe must reopen the circuit")
+	}
+	now = now.Add(time.Minute)
+	if b.allow("api.github.com") {
+		t.Error("the cooldown must double after a failed probe")
+	}
+	now = now.Add(time.Minute)
+	if !b.allow("api.github.com") {
+		t.Error("doubled cooldown over: probe again")
+	}
+	b.record("api.github.com", false)
+	if !b.allow("api.github.com") || !b.allow("api.github.com") {
+		t.Error("successful probe must close the circuit")
+	}
+}
+
+func TestCircuitBreakerIgnoresClientErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	c := NewClient(withIsolatedCircuitBreaker(1, time.Minute))
+	c.BaseURL = srv.URL
+	for i := 0; i < 3; i++ {
+		if _, err := c.GetUserEmails(context.Background(), "tok"); errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("call %d: 4xx responses must not open the circuit", i)
+		}
+	}
+}
+
+func TestCircuitBreakerCooldownBackoff(t *testing.T) {
+	b := newCircuitBreaker(1, time.Minute)
+	want := []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, maxBreakerCooldown, maxBreakerCooldown}
+	for n, w := range want {
+		if got := b.cooldownAfter(n); got != w {
+			t.Errorf("cooldownAfter(%d) = %v, want %v", n, got, w)
+		}
+	}
+	// A base cooldown above the cap is used as is.
+	long := newCircuitBreaker(1, 10*time.Minute)
+	if got := long.cooldownAfter(3); got != 10*time.Minute {
+		t.Errorf("long cooldownAfter(3) = %v, want 10m", got)
+	}
+
+	// A successful probe resets the backoff.
+	now := time.Now()
+	b.now = func() time.Time { return now }
+	b.record("github.com", true)
+	now = now.Add(time.Minute)
+	b.allow("github.com")
+	b.record("github.com", true)
+	now = now.Add(2 * time.Minute)
+	b.allow("github.com")
+	b.record("github.com", false)
+	b.record("github.com", true)
+	now = now.Add(time.Minute)
+	if !b.allow("github.com") {
+		t.Error("after the circuit closed, the next opening must use the base cooldown")
+	}
+}
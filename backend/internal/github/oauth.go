@@ -4,9 +4,11 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 )
 
@@ -14,9 +16,46 @@ type OAuthConfig struct {
 	ClientID     string
 	ClientSecret string
 	RedirectURL  string
+	// TokenURL overrides https://github.com/login/oauth/access_token (tests).
+	TokenURL string
+}
+
+const defaultTokenURL = "https://github.com/login/oauth/access_token"
+
+// OAuthError is an error GitHub reports in the body of a token exchange. GitHub answers a
+// failed exchange with 200 and {"error": ..., "error_description": ...}, e.g.
+// bad_verification_code for an expired or already used code.
+type OAuthError struct {
+	Code        string
+	Description string
+	URI         string
+}
+
+func (e *OAuthError) Error() string {
+	if e.Description != "" {
+		return fmt.Sprintf("github oauth error %s: %s", e.Code, e.Description)
+	}
+	return "github oauth error " + e.Code
 }
 
 func AuthorizeURL(clientID string, redirectURL string, state string, scopes []string) (string, error) {
+	return AuthorizeURLWithOptions(clientID, redirectURL, state, scopes, AuthorizeOptions{})
+}
+
+// PromptSelectAccount makes GitHub show its account picker instead of silently reusing the
+// signed-in account. It is the only prompt value GitHub supports.
+const PromptSelectAccount = "select_account"
+
+// AuthorizeOptions are the optional authorize parameters.
+type AuthorizeOptions struct {
+	// Prompt is sent as prompt=, e.g. PromptSelectAccount.
+	Prompt string
+	// CodeChallenge is the PKCE S256 challenge (see PKCEChallengeS256).
+	CodeChallenge string
+}
+
+// AuthorizeURLWithOptions is AuthorizeURL with the optional parameters in opts.
+func AuthorizeURLWithOptions(clientID string, redirectURL string, state string, scopes []string, opts AuthorizeOptions) (string, error) {
 	if clientID == "" || redirectURL == "" {
 		return "", fmt.Errorf("github oauth not configured")
 	}
@@ -29,6 +68,13 @@ func AuthorizeURL(clientID string, redirectURL string, state string, scopes []st
 		// GitHub expects space-separated scopes
 		q.Set("scope", joinScopes(scopes))
 	}
+	if opts.Prompt != "" {
+		q.Set("prompt", opts.Prompt)
+	}
+	if opts.CodeChallenge != "" {
+		q.Set("code_challenge", opts.CodeChallenge)
+		q.Set("code_challenge_method", PKCEMethodS256)
+	}
 	u.RawQuery = q.Encode()
 	return u.String(), nil
 }
@@ -44,6 +90,63 @@ func joinScopes(scopes []string) string {
 	return out
 }
 
+// impliedScopes lists the scopes GitHub grants implicitly with a broader parent scope.
+var impliedScopes = map[string][]string{
+	"repo":            {"repo:status", "repo_deployment", "public_repo", "repo:invite", "security_events"},
+	"user":            {"read:user", "user:email", "user:follow"},
+	"admin:org":       {"write:org", "read:org"},
+	"write:org":       {"read:org"},
+	"admin:repo_hook": {"write:repo_hook", "read:repo_hook"},
+	"write:repo_hook": {"read:repo_hook"},
+}
+
+// SplitScopes parses a granted scope string as stored in github_accounts.scope.
+// GitHub returns scopes comma-separated; space separation is accepted as well.
+func SplitScopes(scope string) []string {
+	fields := strings.FieldsFunc(scope, func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+	out := make([]string, 0, len(fields))
+	seen := map[string]struct{}{}
+	for _, f := range fields {
+		if _, ok := seen[f]; ok {
+			continue
+		}
+		seen[f] = struct{}{}
+		out = append(out, f)
+	}
+	return out
+}
+
+// ParseScopes returns the set of scopes in a granted scope string (comma or space separated,
+// see SplitScopes). Implied scopes are not added; MissingScopes accounts for those.
+func ParseScopes(scope string) map[string]bool {
+	set := map[string]bool{}
+	for _, s := range SplitScopes(scope) {
+		set[s] = true
+	}
+	return set
+}
+
+// MissingScopes returns the requested scopes not covered by granted, taking GitHub's
+// scope hierarchy into account (e.g. "repo" covers "public_repo").
+func MissingScopes(requested, granted []string) []string {
+	have := map[string]struct{}{}
+	for _, g := range granted {
+		have[g] = struct{}{}
+		for _, implied := range impliedScopes[g] {
+			have[implied] = struct{}{}
+		}
+	}
+	missing := []string{}
+	for _, r := range requested {
+		if _, ok := have[r]; !ok {
+			missing = append(missing, r)
+		}
+	}
+	return missing
+}
+
 type TokenResponse struct {
 	AccessToken string `json:"access_token"`
 	TokenType   string `json:"token_type"`
@@ -51,6 +154,12 @@ type TokenResponse struct {
 }
 
 func ExchangeCode(ctx context.Context, code string, cfg OAuthConfig) (TokenResponse, error) {
+	return ExchangeCodeWithVerifier(ctx, code, "", cfg)
+}
+
+// ExchangeCodeWithVerifier is ExchangeCode for a PKCE flow: codeVerifier, when set, is sent as
+// code_verifier and GitHub checks it against the challenge from the authorize request.
+func ExchangeCodeWithVerifier(ctx context.Context, code, codeVerifier string, cfg OAuthConfig) (TokenResponse, error) {
 	if cfg.ClientID == "" || cfg.ClientSecret == "" || cfg.RedirectURL == "" {
 		return TokenResponse{}, fmt.Errorf("github oauth not configured")
 	}
@@ -64,9 +173,16 @@ func ExchangeCode(ctx context.Context, code string, cfg OAuthConfig) (TokenRespo
 		"code":          code,
 		"redirect_uri":  cfg.RedirectURL,
 	}
+	if codeVerifier != "" {
+		body["code_verifier"] = codeVerifier
+	}
 	b, _ := json.Marshal(body)
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://github.com/login/oauth/access_token", bytes.NewReader(b))
+	tokenURL := cfg.TokenURL
+	if tokenURL == "" {
+		tokenURL = defaultTokenURL
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, bytes.NewReader(b))
 	if err != nil {
 		return TokenResponse{}, err
 	}
@@ -84,33 +200,107 @@ func ExchangeCode(ctx context.Context, code string, cfg OAuthConfig) (TokenRespo
 		return TokenResponse{}, fmt.Errorf("token exchange failed: status %d", resp.StatusCode)
 	}
 
-	var tr TokenResponse
-	if err := json.NewDecoder(resp.Body).Decode(&tr); err != nil {
+	var out struct {
+		TokenResponse
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+		ErrorURI         string `json:"error_uri"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
 		return TokenResponse{}, err
 	}
+	if out.Error != "" {
+		return TokenResponse{}, &OAuthError{Code: out.Error, Description: out.ErrorDescription, URI: out.ErrorURI}
+	}
+	tr := out.TokenResponse
 	if tr.AccessToken == "" {
 		return TokenResponse{}, fmt.Errorf("token exchange returned empty token")
 	}
 	return tr, nil
 }
 
+// TokenCheck is GitHub's answer to "check a token" for our OAuth app.
+type TokenCheck struct {
+	Scopes []string `json:"scopes"`
+	User   struct {
+		ID    int64  `json:"id"`
+		Login string `json:"login"`
+	} `json:"user"`
+}
 
+// ErrTokenInvalid means GitHub does not recognize the token as a live token of this app.
+var ErrTokenInvalid = errors.New("github token is not valid for this app")
 
+// CheckToken asks GitHub whether accessToken is a live token issued to this OAuth app
+// (POST /applications/{client_id}/token). Unlike a plain API call it fails for tokens that
+// belong to a different app, and reports the scopes GitHub actually granted.
+func CheckToken(ctx context.Context, cfg OAuthConfig, accessToken string) (TokenCheck, error) {
+	if cfg.ClientID == "" || cfg.ClientSecret == "" {
+		return TokenCheck{}, fmt.Errorf("github oauth not configured")
+	}
+	b, _ := json.Marshal(map[string]string{"access_token": accessToken})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://api.github.com/applications/"+url.PathEscape(cfg.ClientID)+"/token", bytes.NewReader(b))
+	if err != nil {
+		return TokenCheck{}, err
+	}
+	req.SetBasicAuth(cfg.ClientID, cfg.ClientSecret)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Content-Type", "application/json")
 
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return TokenCheck{}, err
+	}
+	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusUnprocessableEntity {
+		return TokenCheck{}, ErrTokenInvalid
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return TokenCheck{}, parseGitHubAPIError(resp)
+	}
 
+	var tc TokenCheck
+	if err := json.NewDecoder(resp.Body).Decode(&tc); err != nil {
+		return TokenCheck{}, err
+	}
+	return tc, nil
+}
 
+// VerifyFor checks that the token belongs to the GitHub user we are about to link.
+func (tc TokenCheck) VerifyFor(githubUserID int64) error {
+	if tc.User.ID == 0 || tc.User.ID != githubUserID {
+		return fmt.Errorf("token belongs to github user %d, expected %d", tc.User.ID, githubUserID)
+	}
+	return nil
+}
 
+// RevokeGrant deletes the user's whole authorization of this OAuth app
+// (DELETE /applications/{client_id}/grant): every token is invalidated and the next
+// authorization shows GitHub's consent screen again.
+func RevokeGrant(ctx context.Context, cfg OAuthConfig, accessToken string) error {
+	if cfg.ClientID == "" || cfg.ClientSecret == "" {
+		return fmt.Errorf("github oauth not configured")
+	}
+	b, _ := json.Marshal(map[string]string{"access_token": accessToken})
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, "https://api.github.com/applications/"+url.PathEscape(cfg.ClientID)+"/grant", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(cfg.ClientID, cfg.ClientSecret)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Content-Type", "application/json")
 
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
 
-
-
-
-
-
-
-
-
-
-
-
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return parseGitHubAPIError(resp)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package github
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/jagadeesh/grainlify/backend/internal/oauthprovider"
+)
+
+// OAuthProvider is GitHub behind the generic oauthprovider.Provider interface. The GitHub login
+// and link flows keep their own handler (they also store the token and link the account);
+// this is the default entry of the provider registry.
+type OAuthProvider struct {
+	Config OAuthConfig
+	Scopes []string
+}
+
+// NewOAuthProvider returns the GitHub provider for cfg, requesting scopes at authorize time.
+func NewOAuthProvider(cfg OAuthConfig, scopes []string) *OAuthProvider {
+	return &OAuthProvider{Config: cfg, Scopes: scopes}
+}
+
+func (p *OAuthProvider) Name() string { return "github" }
+
+func (p *OAuthProvider) AuthorizeURL(state, codeChallenge string) (string, error) {
+	return AuthorizeURLWithOptions(p.Config.ClientID, p.Config.RedirectURL, state, p.Scopes, AuthorizeOptions{CodeChallenge: codeChallenge})
+}
+
+func (p *OAuthProvider) ExchangeCode(ctx context.Context, code, codeVerifier string) (oauthprovider.Token, error) {
+	tr, err := ExchangeCodeWithVerifier(ctx, code, codeVerifier, p.Config)
+	if err != nil {
+		return oauthprovider.Token{}, err
+	}
+	return oauthprovider.Token{AccessToken: tr.AccessToken, TokenType: tr.TokenType, Scope: tr.Scope}, nil
+}
+
+func (p *OAuthProvider) GetUser(ctx context.Context, accessToken string) (oauthprovider.User, error) {
+	u, err := NewClient().GetUser(ctx, accessToken)
+	if err != nil {
+		return oauthprovider.User{}, err
+	}
+	return oauthprovider.User{
+		ID:        strconv.FormatInt(u.ID, 10),
+		Login:     u.Login,
+		Email:     u.Email,
+		AvatarURL: u.AvatarURL,
+	}, nil
+}
@@ -0,0 +1,103 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestTokenCheckVerifyFor(t *testing.T) {
+	var tc TokenCheck
+	tc.User.ID = 42
+	if err := tc.VerifyFor(42); err != nil {
+		t.Fatalf("VerifyFor(42) = %v, want nil", err)
+	}
+	if err := tc.VerifyFor(7); err == nil {
+		t.Fatal("VerifyFor(7) = nil, want mismatch error")
+	}
+	if err := (TokenCheck{}).VerifyFor(0); err == nil {
+		t.Fatal("empty check must not verify")
+	}
+}
+
+func TestAuthorizeURLWithOptions_Prompt(t *testing.T) {
+	raw, err := AuthorizeURLWithOptions("cid", "https://api.example.com/cb", "st", []string{"read:user"}, AuthorizeOptions{Prompt: PromptSelectAccount})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse(raw)
+	if got := u.Query().Get("prompt"); got != "select_account" {
+		t.Errorf("prompt = %q, want select_account", got)
+	}
+
+	raw, _ = AuthorizeURL("cid", "https://api.example.com/cb", "st", nil)
+	u, _ = url.Parse(raw)
+	if u.Query().Has("prompt") {
+		t.Errorf("AuthorizeURL must not set prompt: %s", raw)
+	}
+}
+
+func TestParseScopes(t *testing.T) {
+	for _, in := range []string{
+		"repo,admin:repo_hook,read:org",
+		"repo admin:repo_hook read:org",
+		" repo, admin:repo_hook ,read:org,repo",
+	} {
+		got := ParseScopes(in)
+		if len(got) != 3 || !got["repo"] || !got["admin:repo_hook"] || !got["read:org"] {
+			t.Errorf("ParseScopes(%q) = %v", in, got)
+		}
+	}
+	if got := ParseScopes(""); len(got) != 0 {
+		t.Errorf("ParseScopes(\"\") = %v, want empty", got)
+	}
+}
+
+func TestMissingScopes(t *testing.T) {
+	required := []string{"repo", "admin:repo_hook"}
+	if got := MissingScopes(required, SplitScopes("repo,admin:repo_hook,read:user")); len(got) != 0 {
+		t.Errorf("all granted: missing = %v", got)
+	}
+	got := MissingScopes(required, SplitScopes("public_repo,write:repo_hook"))
+	if len(got) != 2 || got[0] != "repo" || got[1] != "admin:repo_hook" {
+		t.Errorf("downgraded grant: missing = %v, want [repo admin:repo_hook]", got)
+	}
+	// Broader scopes cover the narrower ones they imply.
+	if got := MissingScopes([]string{"read:org", "public_repo"}, SplitScopes("admin:org repo")); len(got) != 0 {
+		t.Errorf("implied scopes: missing = %v", got)
+	}
+}
+
+func TestExchangeCodeErrorInBody(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("fail") != "" {
+			_, _ = w.Write([]byte(`{"error":"bad_verification_code","error_description":"The code passed is incorrect or expired.","error_uri":"https://docs.github.com/apps/troubleshooting"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"access_token":"gho_abc","token_type":"bearer","scope":"repo,read:user"}`))
+	}))
+	defer srv.Close()
+	cfg := OAuthConfig{ClientID: "cid", ClientSecret: "secret", RedirectURL: "https://api.example.com/cb", TokenURL: srv.URL}
+
+	tr, err := ExchangeCode(context.Background(), "code", cfg)
+	if err != nil {
+		t.Fatalf("ExchangeCode = %v", err)
+	}
+	if tr.AccessToken != "gho_abc" || tr.Scope != "repo,read:user" {
+		t.Errorf("token = %+v", tr)
+	}
+
+	cfg.TokenURL = srv.URL + "?fail=1"
+	_, err = ExchangeCode(context.Background(), "code", cfg)
+	var oe *OAuthError
+	if !errors.As(err, &oe) {
+		t.Fatalf("err = %v, want *OAuthError", err)
+	}
+	if oe.Code != "bad_verification_code" || oe.Description != "The code passed is incorrect or expired." {
+		t.Errorf("OAuthError = %+v", oe)
+	}
+}
@@ -0,0 +1,142 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+type OrgMembership struct {
+	State        string `json:"state"` // "active" or "pending" (invitation not yet accepted)
+	Role         string `json:"role"`  // "admin", "member" or "billing_manager"
+	Organization struct {
+		ID          int64  `json:"id"`
+		Login       string `json:"login"`
+		AvatarURL   string `json:"avatar_url"`
+		Description string `json:"description"`
+	} `json:"organization"`
+}
+
+// CanAdmin reports whether the membership lets the user manage the org (e.g. create webhooks).
+// Pending invitations grant nothing until accepted.
+func (m OrgMembership) CanAdmin() bool {
+	return m.State == "active" && m.Role == "admin"
+}
+
+// ListOrgMembershipsPage lists the user's org memberships (active and pending) from
+// /user/memberships/orgs. hasNext reports whether GitHub advertised a next page.
+func (c *Client) ListOrgMembershipsPage(ctx context.Context, accessToken string, page, perPage int) (memberships []OrgMembership, hasNext bool, err error) {
+	u, _ := url.Parse("https://api.github.com/user/memberships/orgs")
+	q := u.Query()
+	q.Set("per_page", strconv.Itoa(perPage))
+	q.Set("page", strconv.Itoa(page))
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, false, err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, false, parseGitHubAPIError(resp)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&memberships); err != nil {
+		return nil, false, err
+	}
+	return memberships, hasNextLink(resp.Header.Get("Link")), nil
+}
+
+// hasNextLink reports whether a GitHub Link header contains rel="next".
+func hasNextLink(link string) bool {
+	for _, part := range strings.Split(link, ",") {
+		if strings.Contains(part, `rel="next"`) {
+			return true
+		}
+	}
+	return false
+}
+
+// Org is an organization from /user/orgs.
+type Org struct {
+	ID          int64  `json:"id"`
+	Login       string `json:"login"`
+	AvatarURL   string `json:"avatar_url"`
+	Description string `json:"description"`
+}
+
+// ListUserOrgs returns every organization the token's user belongs to. GitHub only lists orgs
+// the OAuth app may see, which for private memberships takes the read:org scope.
+func (c *Client) ListUserOrgs(ctx context.Context, accessToken string) ([]Org, error) {
+	u, err := url.Parse(c.apiBaseURL() + "/user/orgs")
+	if err != nil {
+		return nil, err
+	}
+	q := u.Query()
+	q.Set("per_page", "100")
+	u.RawQuery = q.Encode()
+	return listAllPages[Org](ctx, c, accessToken, u)
+}
+
+// ListOrgReposOptions filters /orgs/{org}/repos. Empty fields leave GitHub's defaults.
+type ListOrgReposOptions struct {
+	PerPage int    // 1-100; 0 means 100
+	Type    string // "all", "public", "private", "forks", "sources" or "member"
+}
+
+// ListOrgRepos returns every repository in org that the token's user can see.
+func (c *Client) ListOrgRepos(ctx context.Context, accessToken, org string, opts ListOrgReposOptions) ([]Repo, error) {
+	u, err := url.Parse(c.apiBaseURL() + "/orgs/" + url.PathEscape(org) + "/repos")
+	if err != nil {
+		return nil, err
+	}
+	perPage := opts.PerPage
+	if perPage <= 0 || perPage > 100 {
+		perPage = 100
+	}
+	q := u.Query()
+	q.Set("per_page", strconv.Itoa(perPage))
+	if opts.Type != "" {
+		q.Set("type", opts.Type)
+	}
+	u.RawQuery = q.Encode()
+	return listAllPages[Repo](ctx, c, accessToken, u)
+}
+
+// ListUserOrgRepos returns the repositories of every org the user belongs to. Orgs that refuse
+// the token (403, e.g. SAML SSO not authorized or OAuth app access restricted) are skipped and
+// reported in skipped; any other error, including rate limiting, fails the whole listing.
+func (c *Client) ListUserOrgRepos(ctx context.Context, accessToken string, opts ListOrgReposOptions) (repos []Repo, skipped []string, err error) {
+	orgs, err := c.ListUserOrgs(ctx, accessToken)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, org := range orgs {
+		batch, err := c.ListOrgRepos(ctx, accessToken, org.Login, opts)
+		if errors.Is(ClassifyRepoAccessError(err), ErrRepoForbidden) {
+			skipped = append(skipped, org.Login)
+			continue
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("list repos of org %s: %w", org.Login, err)
+		}
+		repos = append(repos, batch...)
+	}
+	return repos, skipped, nil
+}
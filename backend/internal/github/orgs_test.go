@@ -0,0 +1,110 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOrgMembershipCanAdmin(t *testing.T) {
+	cases := []struct {
+		state, role string
+		want        bool
+	}{
+		{"active", "admin", true},
+		{"active", "member", false},
+		{"active", "billing_manager", false},
+		{"pending", "admin", false},
+	}
+	for _, tc := range cases {
+		m := OrgMembership{State: tc.state, Role: tc.role}
+		if got := m.CanAdmin(); got != tc.want {
+			t.Errorf("CanAdmin(state=%s role=%s) = %v, want %v", tc.state, tc.role, got, tc.want)
+		}
+	}
+}
+
+func TestHasNextLink(t *testing.T) {
+	next := `<https://api.github.com/user/memberships/orgs?page=2>; rel="next", <https://api.github.com/user/memberships/orgs?page=3>; rel="last"`
+	if !hasNextLink(next) {
+		t.Error("expected next page")
+	}
+	last := `<https://api.github.com/user/memberships/orgs?page=1>; rel="prev", <https://api.github.com/user/memberships/orgs?page=1>; rel="first"`
+	if hasNextLink(last) || hasNextLink("") {
+		t.Error("expected no next page")
+	}
+}
+
+func orgReposServer(t *testing.T, rateLimited bool) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/user/orgs":
+			fmt.Fprint(w, `[{"id":1,"login":"acme"},{"id":2,"login":"sso-corp"},{"id":3,"login":"octo"}]`)
+		case "/orgs/acme/repos":
+			if r.URL.Query().Get("type") != "member" {
+				t.Errorf("unexpected query %s", r.URL.RawQuery)
+			}
+			fmt.Fprint(w, `[{"id":10,"full_name":"acme/api"},{"id":11,"full_name":"acme/web"}]`)
+		case "/orgs/sso-corp/repos":
+			if rateLimited {
+				w.Header().Set("X-RateLimit-Remaining", "0")
+				w.Header().Set("X-RateLimit-Reset", "1700000000")
+			}
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"message":"Resource protected by organization SAML enforcement."}`)
+		case "/orgs/octo/repos":
+			fmt.Fprint(w, `[{"id":30,"full_name":"octo/cli"}]`)
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestListUserOrgs(t *testing.T) {
+	srv := orgReposServer(t, false)
+	defer srv.Close()
+
+	c := &Client{HTTP: srv.Client(), BaseURL: srv.URL}
+	orgs, err := c.ListUserOrgs(context.Background(), "tok")
+	if err != nil {
+		t.Fatalf("ListUserOrgs: %v", err)
+	}
+	if len(orgs) != 3 || orgs[0].Login != "acme" || orgs[2].ID != 3 {
+		t.Errorf("unexpected orgs %+v", orgs)
+	}
+}
+
+func TestListUserOrgReposSkipsForbiddenOrg(t *testing.T) {
+	srv := orgReposServer(t, false)
+	defer srv.Close()
+
+	c := &Client{HTTP: srv.Client(), BaseURL: srv.URL}
+	repos, skipped, err := c.ListUserOrgRepos(context.Background(), "tok", ListOrgReposOptions{Type: "member"})
+	if err != nil {
+		t.Fatalf("ListUserOrgRepos: %v", err)
+	}
+	var names []string
+	for _, r := range repos {
+		names = append(names, r.FullName)
+	}
+	if fmt.Sprint(names) != "[acme/api acme/web octo/cli]" {
+		t.Errorf("repos = %v", names)
+	}
+	if len(skipped) != 1 || skipped[0] != "sso-corp" {
+		t.Errorf("skipped = %v, want [sso-corp]", skipped)
+	}
+}
+
+func TestListUserOrgReposFailsWhenRateLimited(t *testing.T) {
+	srv := orgReposServer(t, true)
+	defer srv.Close()
+
+	c := &Client{HTTP: srv.Client(), BaseURL: srv.URL}
+	if _, _, err := c.ListUserOrgRepos(context.Background(), "tok", ListOrgReposOptions{Type: "member"}); err == nil {
+		t.Error("expected a rate-limited 403 to fail the listing instead of skipping the org")
+	}
+}
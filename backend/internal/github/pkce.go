@@ -0,0 +1,35 @@
+package github
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+)
+
+// PKCEMethodS256 is the only code_challenge_method we send (RFC 7636 section 4.2).
+const PKCEMethodS256 = "S256"
+
+// NewPKCEVerifier returns a random code_verifier: 32 bytes, base64url without padding
+// (43 characters, within RFC 7636's 43-128).
+func NewPKCEVerifier() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// PKCEChallengeS256 derives the S256 code_challenge for verifier.
+func PKCEChallengeS256(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// VerifyPKCE reports whether challenge is the S256 challenge of verifier.
+func VerifyPKCE(verifier, challenge string) bool {
+	if verifier == "" || challenge == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(PKCEChallengeS256(verifier)), []byte(challenge)) == 1
+}
@@ -0,0 +1,43 @@
+package github
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestPKCERoundTrip(t *testing.T) {
+	verifier, err := NewPKCEVerifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(verifier) < 43 || len(verifier) > 128 {
+		t.Fatalf("verifier length %d outside RFC 7636 bounds", len(verifier))
+	}
+
+	challenge := PKCEChallengeS256(verifier)
+	raw, err := AuthorizeURLWithOptions("cid", "https://api.example.com/cb", "st", nil, AuthorizeOptions{CodeChallenge: challenge})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse(raw)
+	q := u.Query()
+	if q.Get("code_challenge") != challenge || q.Get("code_challenge_method") != PKCEMethodS256 {
+		t.Fatalf("authorize URL missing PKCE params: %s", raw)
+	}
+	if !VerifyPKCE(verifier, q.Get("code_challenge")) {
+		t.Fatal("verifier does not match the challenge it produced")
+	}
+
+	tampered := []byte(challenge)
+	tampered[0] ^= 1
+	if VerifyPKCE(verifier, string(tampered)) {
+		t.Fatal("tampered challenge accepted")
+	}
+	other, _ := NewPKCEVerifier()
+	if VerifyPKCE(other, challenge) {
+		t.Fatal("different verifier accepted")
+	}
+	if VerifyPKCE("", "") {
+		t.Fatal("empty verifier accepted")
+	}
+}
@@ -0,0 +1,72 @@
+package github
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type rateLimitKey struct{}
+
+// RateLimitContextKey is the context key for a *RateLimit collecting the GitHub budget seen
+// while serving one request. Fiber's c.Locals writes to the fasthttp context handlers pass as
+// ctx, so c.Locals(RateLimitContextKey, rl) is enough for calls made with c.Context().
+var RateLimitContextKey = rateLimitKey{}
+
+// RateLimit is the most recent GitHub rate-limit state observed during a request.
+type RateLimit struct {
+	mu        sync.Mutex
+	seen      bool
+	limit     int
+	remaining int
+	resetUnix int64
+}
+
+// Snapshot returns the observed limit, remaining and reset (unix seconds); ok is false
+// when no GitHub response carried rate-limit headers.
+func (r *RateLimit) Snapshot() (limit, remaining int, resetUnix int64, ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.limit, r.remaining, r.resetUnix, r.seen
+}
+
+// observe keeps the tightest budget: a later window replaces an earlier one, and within the
+// same window the lowest remaining wins (responses may complete out of order).
+func (r *RateLimit) observe(h http.Header) {
+	remaining, err := strconv.Atoi(strings.TrimSpace(h.Get("X-RateLimit-Remaining")))
+	if err != nil {
+		return
+	}
+	reset, err := strconv.ParseInt(strings.TrimSpace(h.Get("X-RateLimit-Reset")), 10, 64)
+	if err != nil {
+		return
+	}
+	limit, _ := strconv.Atoi(strings.TrimSpace(h.Get("X-RateLimit-Limit")))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.seen && (reset < r.resetUnix || (reset == r.resetUnix && remaining >= r.remaining)) {
+		return
+	}
+	r.seen = true
+	r.limit = limit
+	r.remaining = remaining
+	r.resetUnix = reset
+}
+
+// rateLimitTransport records GitHub's rate-limit headers into the *RateLimit found on the
+// request context, if any.
+type rateLimitTransport struct {
+	base http.RoundTripper
+}
+
+func (t rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err == nil && resp != nil {
+		if rl, ok := req.Context().Value(RateLimitContextKey).(*RateLimit); ok && rl != nil {
+			rl.observe(resp.Header)
+		}
+	}
+	return resp, err
+}
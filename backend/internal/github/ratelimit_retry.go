@@ -0,0 +1,89 @@
+package github
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ClientOption customizes a Client built by NewClient.
+type ClientOption func(*Client)
+
+// WithRateLimitRetry retries idempotent requests (GET, HEAD) that GitHub rejects with a rate
+// limit (403/429 with Retry-After or X-RateLimit-Remaining: 0), up to max times. It waits as
+// long as GitHub asks, but never more than maxWait per attempt: if GitHub asks for longer,
+// the rate-limit response is returned as is.
+func WithRateLimitRetry(max int, maxWait time.Duration) ClientOption {
+	return func(c *Client) {
+		if max <= 0 || maxWait <= 0 {
+			return
+		}
+		base := c.HTTP.Transport
+		if base == nil {
+			base = http.DefaultTransport
+		}
+		c.HTTP.Transport = rateLimitRetryTransport{base: base, max: max, maxWait: maxWait, now: time.Now}
+		// The client timeout covers the whole call, waits included.
+		if c.HTTP.Timeout > 0 {
+			c.HTTP.Timeout += time.Duration(max) * maxWait
+		}
+	}
+}
+
+type rateLimitRetryTransport struct {
+	base    http.RoundTripper
+	max     int
+	maxWait time.Duration
+	now     func() time.Time
+}
+
+func (t rateLimitRetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return resp, err
+	}
+	for attempt := 0; attempt < t.max && err == nil; attempt++ {
+		wait, limited := rateLimitWait(resp, t.now())
+		if !limited || wait > t.maxWait {
+			return resp, nil
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+		resp, err = t.base.RoundTrip(req)
+	}
+	return resp, err
+}
+
+// rateLimitWait reports whether resp is a GitHub rate-limit rejection and how long to wait
+// before retrying: Retry-After (secondary limits) wins over X-RateLimit-Reset (primary).
+func rateLimitWait(resp *http.Response, now time.Time) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(strings.TrimSpace(resp.Header.Get("Retry-After"))); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if strings.TrimSpace(resp.Header.Get("X-RateLimit-Remaining")) != "0" {
+		return 0, false
+	}
+	reset, err := strconv.ParseInt(strings.TrimSpace(resp.Header.Get("X-RateLimit-Reset")), 10, 64)
+	if err != nil {
+		// Exhausted with no reset given: GitHub recommends waiting at least a minute.
+		return time.Minute, true
+	}
+	wait := time.Unix(reset, 0).Sub(now)
+	if wait < 0 {
+		wait = 0
+	}
+	return wait, true
+}
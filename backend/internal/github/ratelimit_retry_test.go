@@ -0,0 +1,90 @@
+package github
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithRateLimitRetry_RetriesAfterRetryAfter(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"message":"You have exceeded a secondary rate limit."}`))
+			return
+		}
+		_, _ = w.Write([]byte(`[{"id":1,"full_name":"octo/a"}]`))
+	}))
+	defer srv.Close()
+
+	c := NewClient(WithRateLimitRetry(2, 5*time.Second))
+	c.BaseURL = srv.URL
+	start := time.Now()
+	repos, err := c.ListUserRepos(context.Background(), "tok", ListReposOptions{})
+	if err != nil {
+		t.Fatalf("expected the retried call to succeed, got %v", err)
+	}
+	if len(repos) != 1 || calls.Load() != 2 {
+		t.Errorf("expected 1 repo after 2 calls, got %d repos after %d calls", len(repos), calls.Load())
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("expected to wait for Retry-After, returned after %s", elapsed)
+	}
+}
+
+func TestWithRateLimitRetry_GivesUpBeyondMaxWait(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"message":"API rate limit exceeded"}`))
+	}))
+	defer srv.Close()
+
+	c := NewClient(WithRateLimitRetry(3, time.Second))
+	c.BaseURL = srv.URL
+	if _, err := c.ListUserRepos(context.Background(), "tok", ListReposOptions{}); err == nil {
+		t.Fatal("expected the rate-limit error to surface")
+	}
+	if calls.Load() != 1 {
+		t.Errorf("expected no retry when the reset is beyond max wait, got %d calls", calls.Load())
+	}
+}
+
+func TestRateLimitWait(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	header := func(kv ...string) http.Header {
+		h := http.Header{}
+		for i := 0; i < len(kv); i += 2 {
+			h.Set(kv[i], kv[i+1])
+		}
+		return h
+	}
+	cases := []struct {
+		name    string
+		status  int
+		header  http.Header
+		wait    time.Duration
+		limited bool
+	}{
+		{"ok", http.StatusOK, header("Retry-After", "5"), 0, false},
+		{"retry after", http.StatusForbidden, header("Retry-After", "3"), 3 * time.Second, true},
+		{"secondary 429", http.StatusTooManyRequests, header("Retry-After", "1"), time.Second, true},
+		{"primary exhausted", http.StatusForbidden, header("X-RateLimit-Remaining", "0", "X-RateLimit-Reset", "1700000010"), 10 * time.Second, true},
+		{"plain forbidden", http.StatusForbidden, header("X-RateLimit-Remaining", "4999"), 0, false},
+	}
+	for _, tc := range cases {
+		wait, limited := rateLimitWait(&http.Response{StatusCode: tc.status, Header: tc.header}, now)
+		if wait != tc.wait || limited != tc.limited {
+			t.Errorf("%s: got (%s, %v), want (%s, %v)", tc.name, wait, limited, tc.wait, tc.limited)
+		}
+	}
+}
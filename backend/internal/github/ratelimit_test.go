@@ -0,0 +1,38 @@
+package github
+
+import (
+	"net/http"
+	"testing"
+)
+
+func rateLimitHeader(remaining, reset string) http.Header {
+	h := http.Header{}
+	h.Set("X-RateLimit-Limit", "5000")
+	h.Set("X-RateLimit-Remaining", remaining)
+	h.Set("X-RateLimit-Reset", reset)
+	return h
+}
+
+func TestRateLimitObserve(t *testing.T) {
+	var rl RateLimit
+	if _, _, _, ok := rl.Snapshot(); ok {
+		t.Fatal("expected no snapshot before any response")
+	}
+
+	rl.observe(http.Header{})
+	if _, _, _, ok := rl.Snapshot(); ok {
+		t.Fatal("responses without rate-limit headers must be ignored")
+	}
+
+	rl.observe(rateLimitHeader("4990", "1700000000"))
+	rl.observe(rateLimitHeader("4995", "1700000000")) // completed out of order
+	if _, remaining, reset, _ := rl.Snapshot(); remaining != 4990 || reset != 1700000000 {
+		t.Errorf("same window: got remaining=%d reset=%d", remaining, reset)
+	}
+
+	rl.observe(rateLimitHeader("4999", "1700003600")) // window rolled over
+	limit, remaining, reset, ok := rl.Snapshot()
+	if !ok || limit != 5000 || remaining != 4999 || reset != 1700003600 {
+		t.Errorf("new window: got limit=%d remaining=%d reset=%d ok=%v", limit, remaining, reset, ok)
+	}
+}
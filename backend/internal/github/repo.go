@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -19,19 +20,16 @@ type Repo struct {
 		Login     string `json:"login"`
 		AvatarURL string `json:"avatar_url"`
 	} `json:"owner"`
-	FullName        string `json:"full_name"`
-	HTMLURL         string `json:"html_url"`
-	Homepage        string `json:"homepage"`
-	Private         bool   `json:"private"`
-	StargazersCount int    `json:"stargazers_count"`
-	ForksCount      int    `json:"forks_count"`
-	OpenIssuesCount int    `json:"open_issues_count"`
-	Description     string `json:"description"`
-	Permissions struct {
-		Admin bool `json:"admin"`
-		Push  bool `json:"push"`
-		Pull  bool `json:"pull"`
-	} `json:"permissions"`
+	FullName        string          `json:"full_name"`
+	HTMLURL         string          `json:"html_url"`
+	Homepage        string          `json:"homepage"`
+	Private         bool            `json:"private"`
+	DefaultBranch   string          `json:"default_branch"`
+	StargazersCount int             `json:"stargazers_count"`
+	ForksCount      int             `json:"forks_count"`
+	OpenIssuesCount int             `json:"open_issues_count"`
+	Description     string          `json:"description"`
+	Permissions     RepoPermissions `json:"permissions"`
 }
 
 type GitHubAPIError struct {
@@ -57,6 +55,13 @@ func (e *GitHubAPIError) Error() string {
 	return msg
 }
 
+// IsBadCredentials reports whether err is GitHub rejecting the access token itself
+// (revoked, expired, or the OAuth app's access removed).
+func IsBadCredentials(err error) bool {
+	var apiErr *GitHubAPIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnauthorized
+}
+
 func parseGitHubAPIError(resp *http.Response) error {
 	if resp == nil {
 		return fmt.Errorf("github api error: nil response")
@@ -0,0 +1,92 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Repository roles, highest first, as GitHub names them.
+const (
+	RepoPermissionAdmin    = "admin"
+	RepoPermissionMaintain = "maintain"
+	RepoPermissionWrite    = "write"
+	RepoPermissionTriage   = "triage"
+	RepoPermissionRead     = "read"
+)
+
+var repoPermissionRank = map[string]int{
+	RepoPermissionRead:     1,
+	RepoPermissionTriage:   2,
+	RepoPermissionWrite:    3,
+	RepoPermissionMaintain: 4,
+	RepoPermissionAdmin:    5,
+}
+
+var (
+	// ErrRepoNotFound: GitHub answered 404, which it also does for private repos the token can't see.
+	ErrRepoNotFound = errors.New("github repo not found or not visible to this token")
+	// ErrRepoForbidden: the repo exists but GitHub refused access (e.g. SAML SSO enforcement or
+	// organization OAuth app restrictions).
+	ErrRepoForbidden = errors.New("github repo access forbidden")
+)
+
+// RepoPermissions is the "permissions" object GitHub returns on a repository for the
+// authenticated user.
+type RepoPermissions struct {
+	Admin    bool `json:"admin"`
+	Maintain bool `json:"maintain"`
+	Push     bool `json:"push"`
+	Triage   bool `json:"triage"`
+	Pull     bool `json:"pull"`
+}
+
+// Level returns the highest role the flags grant, or "" for none.
+func (p RepoPermissions) Level() string {
+	switch {
+	case p.Admin:
+		return RepoPermissionAdmin
+	case p.Maintain:
+		return RepoPermissionMaintain
+	case p.Push:
+		return RepoPermissionWrite
+	case p.Triage:
+		return RepoPermissionTriage
+	case p.Pull:
+		return RepoPermissionRead
+	default:
+		return ""
+	}
+}
+
+// RepoPermissionAtLeast reports whether role have is want or higher.
+func RepoPermissionAtLeast(have, want string) bool {
+	return repoPermissionRank[have] > 0 && repoPermissionRank[have] >= repoPermissionRank[want]
+}
+
+// GetRepoPermission returns the token owner's role on owner/repo (admin, maintain, write,
+// triage or read). An inaccessible repo yields ErrRepoNotFound or ErrRepoForbidden.
+func (c *Client) GetRepoPermission(ctx context.Context, accessToken, owner, repo string) (string, error) {
+	r, err := c.GetRepo(ctx, accessToken, owner+"/"+repo)
+	if err != nil {
+		return "", ClassifyRepoAccessError(err)
+	}
+	return r.Permissions.Level(), nil
+}
+
+// ClassifyRepoAccessError wraps a 404 in ErrRepoNotFound and a 403 that isn't rate limiting in
+// ErrRepoForbidden; other errors are returned unchanged.
+func ClassifyRepoAccessError(err error) error {
+	var apiErr *GitHubAPIError
+	if !errors.As(err, &apiErr) {
+		return err
+	}
+	switch {
+	case apiErr.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("%w: %v", ErrRepoNotFound, err)
+	case apiErr.StatusCode == http.StatusForbidden && (apiErr.RateLimitRemaining == nil || *apiErr.RateLimitRemaining > 0):
+		return fmt.Errorf("%w: %v", ErrRepoForbidden, err)
+	}
+	return err
+}
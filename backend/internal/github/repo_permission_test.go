@@ -0,0 +1,47 @@
+package github
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestRepoPermissionsLevel(t *testing.T) {
+	cases := []struct {
+		p    RepoPermissions
+		want string
+	}{
+		{RepoPermissions{Admin: true, Maintain: true, Push: true, Triage: true, Pull: true}, RepoPermissionAdmin},
+		{RepoPermissions{Maintain: true, Push: true, Triage: true, Pull: true}, RepoPermissionMaintain},
+		{RepoPermissions{Push: true, Triage: true, Pull: true}, RepoPermissionWrite},
+		{RepoPermissions{Triage: true, Pull: true}, RepoPermissionTriage},
+		{RepoPermissions{Pull: true}, RepoPermissionRead},
+		{RepoPermissions{}, ""},
+	}
+	for _, tc := range cases {
+		if got := tc.p.Level(); got != tc.want {
+			t.Errorf("%+v.Level() = %q, want %q", tc.p, got, tc.want)
+		}
+	}
+
+	if !RepoPermissionAtLeast(RepoPermissionMaintain, RepoPermissionWrite) || RepoPermissionAtLeast(RepoPermissionTriage, RepoPermissionWrite) {
+		t.Error("RepoPermissionAtLeast ordering wrong")
+	}
+	if RepoPermissionAtLeast("", RepoPermissionRead) {
+		t.Error("no permission must not satisfy read")
+	}
+}
+
+func TestClassifyRepoAccessError(t *testing.T) {
+	zero := 0
+	if err := ClassifyRepoAccessError(&GitHubAPIError{StatusCode: http.StatusNotFound}); !errors.Is(err, ErrRepoNotFound) {
+		t.Errorf("404: got %v", err)
+	}
+	if err := ClassifyRepoAccessError(&GitHubAPIError{StatusCode: http.StatusForbidden}); !errors.Is(err, ErrRepoForbidden) {
+		t.Errorf("403: got %v", err)
+	}
+	rateLimited := &GitHubAPIError{StatusCode: http.StatusForbidden, RateLimitRemaining: &zero}
+	if err := ClassifyRepoAccessError(rateLimited); errors.Is(err, ErrRepoForbidden) {
+		t.Errorf("rate limited 403 must not be ErrRepoForbidden")
+	}
+}
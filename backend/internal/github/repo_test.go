@@ -0,0 +1,24 @@
+package github
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestIsBadCredentials(t *testing.T) {
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{&GitHubAPIError{StatusCode: 401, Message: "Bad credentials"}, true},
+		{fmt.Errorf("fetch user: %w", &GitHubAPIError{StatusCode: 401}), true},
+		{&GitHubAPIError{StatusCode: 403, Message: "rate limited"}, false},
+		{fmt.Errorf("github /user failed: status 401"), false},
+		{nil, false},
+	}
+	for _, tc := range cases {
+		if got := IsBadCredentials(tc.err); got != tc.want {
+			t.Errorf("IsBadCredentials(%v) = %v, want %v", tc.err, got, tc.want)
+		}
+	}
+}
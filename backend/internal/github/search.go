@@ -0,0 +1,103 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// GitHub rejects search queries longer than 256 characters, qualifiers included.
+const maxSearchTermLen = 128
+
+var ErrInvalidSearchQuery = errors.New("invalid search query")
+
+type RepoSearchResult struct {
+	TotalCount        int    `json:"total_count"`
+	IncompleteResults bool   `json:"incomplete_results"`
+	Items             []Repo `json:"items"`
+}
+
+// SanitizeSearchTerm reduces free text to plain search words: anything that could form search
+// syntax (qualifiers like "user:", quotes, parentheses, boolean operators) is dropped, so the
+// caller's own qualifiers can't be overridden or broken.
+func SanitizeSearchTerm(term string) (string, error) {
+	cleaned := strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r), r == '-', r == '_', r == '.':
+			return r
+		default:
+			return ' '
+		}
+	}, term)
+
+	words := make([]string, 0, 4)
+	for _, w := range strings.Fields(cleaned) {
+		switch strings.ToUpper(w) {
+		case "AND", "OR", "NOT":
+			continue
+		}
+		words = append(words, w)
+	}
+	out := strings.Join(words, " ")
+	if out == "" || len(out) > maxSearchTermLen {
+		return "", ErrInvalidSearchQuery
+	}
+	return out, nil
+}
+
+// SearchUserRepos searches repositories owned by owner whose name matches term via
+// /search/repositories. term must already be sanitized with SanitizeSearchTerm.
+func (c *Client) SearchUserRepos(ctx context.Context, accessToken, owner, term string, page, perPage int) (result RepoSearchResult, hasNext bool, err error) {
+	u, _ := url.Parse("https://api.github.com/search/repositories")
+	q := u.Query()
+	q.Set("q", term+" in:name user:"+owner)
+	q.Set("per_page", strconv.Itoa(perPage))
+	q.Set("page", strconv.Itoa(page))
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return RepoSearchResult{}, false, err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return RepoSearchResult{}, false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return RepoSearchResult{}, false, parseGitHubAPIError(resp)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return RepoSearchResult{}, false, err
+	}
+	return result, hasNextLink(resp.Header.Get("Link")), nil
+}
+
+// IsSearchRateLimited reports whether err is GitHub throttling search, which has its own much
+// smaller budget (30 requests/minute) than the core API, or a secondary rate limit.
+func IsSearchRateLimited(err error) bool {
+	var apiErr *GitHubAPIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	if apiErr.StatusCode != http.StatusForbidden && apiErr.StatusCode != http.StatusTooManyRequests {
+		return false
+	}
+	if apiErr.RateLimitRemaining != nil && *apiErr.RateLimitRemaining == 0 {
+		return true
+	}
+	return strings.Contains(strings.ToLower(apiErr.Message), "rate limit")
+}
@@ -0,0 +1,51 @@
+package github
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeSearchTerm(t *testing.T) {
+	cases := []struct {
+		in, want string
+	}{
+		{"grainlify", "grainlify"},
+		{"  my-repo_v2.0 ", "my-repo_v2.0"},
+		{`user:someone-else "quoted" (x OR y)`, "user someone-else quoted x y"},
+		{"repo NOT fork", "repo fork"},
+	}
+	for _, tc := range cases {
+		got, err := SanitizeSearchTerm(tc.in)
+		if err != nil || got != tc.want {
+			t.Errorf("SanitizeSearchTerm(%q) = %q, %v; want %q", tc.in, got, err, tc.want)
+		}
+	}
+
+	for _, in := range []string{"", "  ", ":::", "AND OR", strings.Repeat("a", maxSearchTermLen+1)} {
+		if _, err := SanitizeSearchTerm(in); err != ErrInvalidSearchQuery {
+			t.Errorf("SanitizeSearchTerm(%q) err = %v, want ErrInvalidSearchQuery", in, err)
+		}
+	}
+}
+
+func TestIsSearchRateLimited(t *testing.T) {
+	zero := 0
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{&GitHubAPIError{StatusCode: http.StatusForbidden, RateLimitRemaining: &zero}, true},
+		{&GitHubAPIError{StatusCode: http.StatusForbidden, Message: "You have exceeded a secondary rate limit."}, true},
+		{&GitHubAPIError{StatusCode: http.StatusTooManyRequests, Message: "API rate limit exceeded"}, true},
+		{&GitHubAPIError{StatusCode: http.StatusForbidden, Message: "Resource not accessible"}, false},
+		{&GitHubAPIError{StatusCode: http.StatusUnprocessableEntity}, false},
+		{fmt.Errorf("network down"), false},
+	}
+	for _, tc := range cases {
+		if got := IsSearchRateLimited(tc.err); got != tc.want {
+			t.Errorf("IsSearchRateLimited(%v) = %v, want %v", tc.err, got, tc.want)
+		}
+	}
+}
@@ -10,15 +10,22 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"github.com/jagadeesh/grainlify/backend/internal/cryptox"
+	"github.com/jagadeesh/grainlify/backend/internal/tokenvault"
 )
 
+// ErrReauthRequired means the stored token is known to be dead and the user must re-link.
+var ErrReauthRequired = errors.New("reauth_required")
+
+// ErrTokenNotStored means the account was linked with STORE_GITHUB_TOKEN=false.
+var ErrTokenNotStored = errors.New("token_not_stored")
+
 type LinkedAccount struct {
 	GitHubUserID int64
 	Login        string
 	AccessToken  string
 }
 
-func GetLinkedAccount(ctx context.Context, pool *pgxpool.Pool, userID uuid.UUID, tokenEncKeyB64 string) (LinkedAccount, error) {
+func GetLinkedAccount(ctx context.Context, pool *pgxpool.Pool, userID uuid.UUID, tokenEncKeys string) (LinkedAccount, error) {
 	if pool == nil {
 		return LinkedAccount{}, fmt.Errorf("db not configured")
 	}
@@ -26,23 +33,34 @@ func GetLinkedAccount(ctx context.Context, pool *pgxpool.Pool, userID uuid.UUID,
 	var githubUserID int64
 	var login string
 	var encToken []byte
+	var reauthRequired bool
 	err := pool.QueryRow(ctx, `
-SELECT github_user_id, login, access_token
+SELECT github_user_id, login, access_token, reauth_required
 FROM github_accounts
 WHERE user_id = $1
-`, userID).Scan(&githubUserID, &login, &encToken)
+`, userID).Scan(&githubUserID, &login, &encToken, &reauthRequired)
 	if errors.Is(err, pgx.ErrNoRows) {
 		return LinkedAccount{}, fmt.Errorf("github_not_linked")
 	}
 	if err != nil {
 		return LinkedAccount{}, err
 	}
+	if reauthRequired {
+		return LinkedAccount{}, ErrReauthRequired
+	}
+	if encToken == nil {
+		return LinkedAccount{}, ErrTokenNotStored
+	}
 
-	key, err := cryptox.KeyFromB64(tokenEncKeyB64)
+	keys, err := cryptox.NewKeyring(tokenEncKeys)
 	if err != nil {
 		return LinkedAccount{}, err
 	}
-	tokenBytes, err := cryptox.DecryptAESGCM(key, encToken)
+	ciphertext, err := tokenvault.Default().Get(ctx, userID, encToken)
+	if err != nil {
+		return LinkedAccount{}, fmt.Errorf("token vault read failed: %w", err)
+	}
+	tokenBytes, err := keys.Decrypt(ciphertext)
 	if err != nil {
 		return LinkedAccount{}, fmt.Errorf("decrypt github token failed")
 	}
@@ -54,23 +72,17 @@ WHERE user_id = $1
 	}, nil
 }
 
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
+// MarkReauthRequired flags the user's stored token as dead (see IsBadCredentials).
+func MarkReauthRequired(ctx context.Context, pool *pgxpool.Pool, userID uuid.UUID) error {
+	if pool == nil {
+		return fmt.Errorf("db not configured")
+	}
+	_, err := pool.Exec(ctx, `
+UPDATE github_accounts
+SET reauth_required = true,
+    reauth_required_at = COALESCE(reauth_required_at, now()),
+    updated_at = now()
+WHERE user_id = $1
+`, userID)
+	return err
+}
@@ -0,0 +1,42 @@
+package github
+
+import (
+	"errors"
+	"log/slog"
+)
+
+// Storage limits for profile fields copied from GitHub. The columns are TEXT, but login is
+// btree-indexed (rows over ~2.7kB fail to insert), and no legitimate email (RFC 5321) or avatar
+// URL comes close to these.
+const (
+	MaxLoginLength     = 255 // github.com caps logins at 39; Enterprise SSO logins run longer
+	MaxEmailLength     = 254
+	MaxAvatarURLLength = 2048
+)
+
+// ErrLoginTooLong means the GitHub login can't be stored. The login is an identifier, so it is
+// never truncated.
+var ErrLoginTooLong = errors.New("github login too long")
+
+// ClampForStorage checks u against the storage limits before it is written to github_accounts
+// or pending_links. An over-long login is an error; an over-long email or avatar URL is
+// dropped with a warning, since a truncated one would be wrong rather than merely short.
+func (u *User) ClampForStorage() error {
+	if len(u.Login) > MaxLoginLength {
+		return ErrLoginTooLong
+	}
+	if len(u.Email) > MaxEmailLength {
+		slog.Warn("dropping over-long github email", "github_user_id", u.ID, "length", len(u.Email))
+		u.Email = ""
+	}
+	if len(u.AvatarURL) > MaxAvatarURLLength {
+		slog.Warn("dropping over-long github avatar url", "github_user_id", u.ID, "length", len(u.AvatarURL))
+		u.AvatarURL = ""
+	}
+	return nil
+}
+
+// EmailFitsStorage reports whether an address (e.g. from /user/emails) fits MaxEmailLength.
+func EmailFitsStorage(email string) bool {
+	return len(email) <= MaxEmailLength
+}
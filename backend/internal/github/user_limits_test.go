@@ -0,0 +1,36 @@
+package github
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestUserClampForStorage(t *testing.T) {
+	u := User{
+		ID:        1,
+		Login:     "octocat",
+		Email:     strings.Repeat("a", MaxEmailLength) + "@example.com",
+		AvatarURL: "https://avatars.githubusercontent.com/u/1?" + strings.Repeat("x", MaxAvatarURLLength),
+	}
+	if err := u.ClampForStorage(); err != nil {
+		t.Fatalf("ClampForStorage: %v", err)
+	}
+	if u.Email != "" || u.AvatarURL != "" {
+		t.Errorf("expected over-long email and avatar url to be dropped, got %q / %q", u.Email, u.AvatarURL)
+	}
+	if u.Login != "octocat" {
+		t.Errorf("login changed to %q", u.Login)
+	}
+
+	ok := User{ID: 2, Login: "octocat", Email: "octocat@github.com", AvatarURL: "https://avatars.githubusercontent.com/u/2"}
+	want := ok
+	if err := ok.ClampForStorage(); err != nil || ok != want {
+		t.Errorf("expected in-limit user unchanged, got %+v (err %v)", ok, err)
+	}
+
+	long := User{ID: 3, Login: strings.Repeat("l", MaxLoginLength+1)}
+	if err := long.ClampForStorage(); !errors.Is(err, ErrLoginTooLong) {
+		t.Errorf("expected ErrLoginTooLong, got %v", err)
+	}
+}
@@ -0,0 +1,120 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const defaultAPIBaseURL = "https://api.github.com"
+
+// maxListPages stops paginated listings from walking forever if GitHub (or a proxy) keeps
+// advertising a next page. 100 pages of 100 is far beyond any real account.
+const maxListPages = 100
+
+// ListReposOptions filters /user/repos. Empty fields leave GitHub's defaults.
+type ListReposOptions struct {
+	PerPage     int    // 1-100; 0 means 100
+	Visibility  string // "all", "public" or "private"
+	Affiliation string // comma-separated: "owner", "collaborator", "organization_member"
+}
+
+// ListUserRepos returns every repository the token's user can access, following the Link
+// rel="next" header until GitHub stops advertising one.
+func (c *Client) ListUserRepos(ctx context.Context, accessToken string, opts ListReposOptions) ([]Repo, error) {
+	u, err := url.Parse(c.apiBaseURL() + "/user/repos")
+	if err != nil {
+		return nil, err
+	}
+	perPage := opts.PerPage
+	if perPage <= 0 || perPage > 100 {
+		perPage = 100
+	}
+	q := u.Query()
+	q.Set("per_page", strconv.Itoa(perPage))
+	if opts.Visibility != "" {
+		q.Set("visibility", opts.Visibility)
+	}
+	if opts.Affiliation != "" {
+		q.Set("affiliation", opts.Affiliation)
+	}
+	u.RawQuery = q.Encode()
+
+	return listAllPages[Repo](ctx, c, accessToken, u)
+}
+
+// listAllPages GETs u and every page after it, following the Link rel="next" header until
+// GitHub stops advertising one.
+func listAllPages[T any](ctx context.Context, c *Client, accessToken string, u *url.URL) ([]T, error) {
+	var items []T
+	next := u.String()
+	for page := 0; next != ""; page++ {
+		if page == maxListPages {
+			return nil, fmt.Errorf("github %s: more than %d pages", u.Path, maxListPages)
+		}
+		batch, link, err := getPage[T](ctx, c, accessToken, next)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, batch...)
+
+		next = nextLink(link)
+		// The token goes along with every request, so never follow a link off the API host.
+		if next != "" {
+			nu, err := url.Parse(next)
+			if err != nil || nu.Scheme != u.Scheme || nu.Host != u.Host {
+				return nil, fmt.Errorf("github %s: unexpected next link %q", u.Path, next)
+			}
+		}
+	}
+	return items, nil
+}
+
+func getPage[T any](ctx context.Context, c *Client, accessToken, pageURL string) ([]T, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, "", parseGitHubAPIError(resp)
+	}
+
+	var items []T
+	if err := json.NewDecoder(resp.Body).Decode(&items); err != nil {
+		return nil, "", err
+	}
+	return items, resp.Header.Get("Link"), nil
+}
+
+// nextLink returns the rel="next" URL of a GitHub Link header, or "" on the last page.
+func nextLink(link string) string {
+	for _, part := range strings.Split(link, ",") {
+		segs := strings.Split(part, ";")
+		if len(segs) < 2 {
+			continue
+		}
+		for _, param := range segs[1:] {
+			if strings.TrimSpace(param) == `rel="next"` {
+				target := strings.TrimSpace(segs[0])
+				return strings.TrimSuffix(strings.TrimPrefix(target, "<"), ">")
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,73 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestListUserRepos_FollowsNextLink(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/user/repos" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer tok" {
+			t.Errorf("unexpected Authorization %q", got)
+		}
+		q := r.URL.Query()
+		if q.Get("per_page") != "2" || q.Get("visibility") != "private" || q.Get("affiliation") != "owner" {
+			t.Errorf("unexpected query %s", r.URL.RawQuery)
+		}
+		switch q.Get("page") {
+		case "":
+			w.Header().Set("Link", fmt.Sprintf(`<%s/user/repos?per_page=2&visibility=private&affiliation=owner&page=2>; rel="next", <%s/user/repos?per_page=2&visibility=private&affiliation=owner&page=2>; rel="last"`, srv.URL, srv.URL))
+			fmt.Fprint(w, `[{"id":1,"full_name":"octo/a","private":true,"default_branch":"main"},{"id":2,"full_name":"octo/b","private":true,"default_branch":"master"}]`)
+		case "2":
+			w.Header().Set("Link", fmt.Sprintf(`<%s/user/repos?per_page=2&visibility=private&affiliation=owner&page=1>; rel="prev", <%s/user/repos?per_page=2&visibility=private&affiliation=owner&page=1>; rel="first"`, srv.URL, srv.URL))
+			fmt.Fprint(w, `[{"id":3,"full_name":"octo/c","private":true,"default_branch":"trunk"}]`)
+		default:
+			t.Errorf("unexpected page %q", q.Get("page"))
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	c := &Client{HTTP: srv.Client(), BaseURL: srv.URL}
+	repos, err := c.ListUserRepos(context.Background(), "tok", ListReposOptions{PerPage: 2, Visibility: "private", Affiliation: "owner"})
+	if err != nil {
+		t.Fatalf("ListUserRepos: %v", err)
+	}
+	if len(repos) != 3 {
+		t.Fatalf("expected 3 repos across 2 pages, got %d", len(repos))
+	}
+	last := repos[2]
+	if last.ID != 3 || last.FullName != "octo/c" || !last.Private || last.DefaultBranch != "trunk" {
+		t.Errorf("unexpected repo %+v", last)
+	}
+}
+
+func TestListUserRepos_RejectsForeignNextLink(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Link", `<https://evil.example/user/repos?page=2>; rel="next"`)
+		fmt.Fprint(w, `[]`)
+	}))
+	defer srv.Close()
+
+	c := &Client{HTTP: srv.Client(), BaseURL: srv.URL}
+	if _, err := c.ListUserRepos(context.Background(), "tok", ListReposOptions{}); err == nil {
+		t.Error("expected a next link to another host to be refused")
+	}
+}
+
+func TestNextLink(t *testing.T) {
+	link := `<https://api.github.com/user/repos?page=3>; rel="next", <https://api.github.com/user/repos?page=5>; rel="last"`
+	if got := nextLink(link); got != "https://api.github.com/user/repos?page=3" {
+		t.Errorf("nextLink = %q", got)
+	}
+	if got := nextLink(`<https://api.github.com/user/repos?page=1>; rel="prev"`); got != "" {
+		t.Errorf("expected no next link, got %q", got)
+	}
+}
@@ -3,12 +3,36 @@ package github
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
+	"strings"
 )
 
+// ErrWebhookExists is returned when the repository already has a hook for the callback URL
+// (GitHub answers 422 "Hook already exists on this repository").
+var ErrWebhookExists = errors.New("github webhook already exists")
+
+// defaultWebhookEvents are the events a hook subscribes to when none are given.
+var defaultWebhookEvents = []string{"issues", "pull_request", "pull_request_review", "push"}
+
+// WebhookConfig describes a repository hook to register.
+type WebhookConfig struct {
+	CallbackURL string
+	// Secret signs deliveries (X-Hub-Signature-256). Empty generates a random one, returned in
+	// Webhook.Secret so it can be stored.
+	Secret string
+	// Events defaults to issues, pull_request, pull_request_review and push.
+	Events []string
+	// Inactive registers the hook without delivering events yet.
+	Inactive bool
+}
+
 type CreateWebhookRequest struct {
 	URL    string
 	Secret string
@@ -18,30 +42,60 @@ type CreateWebhookRequest struct {
 
 type Webhook struct {
 	ID int64 `json:"id"`
+	// Secret is the signing secret the hook was created with.
+	Secret string `json:"-"`
 }
 
 func (c *Client) CreateWebhook(ctx context.Context, accessToken string, fullName string, req CreateWebhookRequest) (Webhook, error) {
 	if req.URL == "" || req.Secret == "" {
 		return Webhook{}, fmt.Errorf("webhook url and secret are required")
 	}
-	if len(req.Events) == 0 {
-		req.Events = []string{"issues", "pull_request", "pull_request_review", "push"}
-	}
-
 	owner, repo, err := splitFullName(fullName)
 	if err != nil {
 		return Webhook{}, err
 	}
-	u := "https://api.github.com/repos/" + url.PathEscape(owner) + "/" + url.PathEscape(repo) + "/hooks"
+	wh, err := c.CreateRepoWebhook(ctx, accessToken, owner, repo, WebhookConfig{
+		CallbackURL: req.URL,
+		Secret:      req.Secret,
+		Events:      req.Events,
+		Inactive:    !req.Active,
+	})
+	if err != nil {
+		return Webhook{}, err
+	}
+	return *wh, nil
+}
+
+// CreateRepoWebhook registers a JSON web hook on owner/repo (POST /repos/{owner}/{repo}/hooks,
+// needs admin:repo_hook) and returns its ID and signing secret.
+func (c *Client) CreateRepoWebhook(ctx context.Context, accessToken, owner, repo string, cfg WebhookConfig) (*Webhook, error) {
+	if cfg.CallbackURL == "" {
+		return nil, fmt.Errorf("webhook callback url is required")
+	}
+	if owner == "" || repo == "" {
+		return nil, fmt.Errorf("owner and repo are required")
+	}
+	events := cfg.Events
+	if len(events) == 0 {
+		events = defaultWebhookEvents
+	}
+	secret := cfg.Secret
+	if secret == "" {
+		var err error
+		if secret, err = newWebhookSecret(); err != nil {
+			return nil, err
+		}
+	}
 
+	u := c.apiBaseURL() + "/repos/" + url.PathEscape(owner) + "/" + url.PathEscape(repo) + "/hooks"
 	body := map[string]any{
 		"name":   "web",
-		"active": req.Active,
-		"events": req.Events,
+		"active": !cfg.Inactive,
+		"events": events,
 		"config": map[string]any{
-			"url":          req.URL,
+			"url":          cfg.CallbackURL,
 			"content_type": "json",
-			"secret":       req.Secret,
+			"secret":       secret,
 			"insecure_ssl": "0",
 		},
 	}
@@ -49,7 +103,7 @@ func (c *Client) CreateWebhook(ctx context.Context, accessToken string, fullName
 
 	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(b))
 	if err != nil {
-		return Webhook{}, err
+		return nil, err
 	}
 	httpReq.Header.Set("Authorization", "Bearer "+accessToken)
 	httpReq.Header.Set("Accept", "application/vnd.github+json")
@@ -60,22 +114,37 @@ func (c *Client) CreateWebhook(ctx context.Context, accessToken string, fullName
 
 	resp, err := c.HTTP.Do(httpReq)
 	if err != nil {
-		return Webhook{}, err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusUnprocessableEntity {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		if strings.Contains(strings.ToLower(string(respBody)), "already exists") {
+			return nil, ErrWebhookExists
+		}
+		return nil, fmt.Errorf("github webhook create failed: status %d", resp.StatusCode)
+	}
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return Webhook{}, fmt.Errorf("github webhook create failed: status %d", resp.StatusCode)
+		return nil, fmt.Errorf("github webhook create failed: status %d", resp.StatusCode)
 	}
 
 	var wh Webhook
 	if err := json.NewDecoder(resp.Body).Decode(&wh); err != nil {
-		return Webhook{}, err
+		return nil, err
 	}
 	if wh.ID == 0 {
-		return Webhook{}, fmt.Errorf("invalid github webhook response")
+		return nil, fmt.Errorf("invalid github webhook response")
 	}
-	return wh, nil
+	wh.Secret = secret
+	return &wh, nil
 }
 
-
+// newWebhookSecret returns 32 random bytes, hex-encoded.
+func newWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
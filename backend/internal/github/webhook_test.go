@@ -0,0 +1,81 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCreateRepoWebhook(t *testing.T) {
+	var got struct {
+		Active bool     `json:"active"`
+		Events []string `json:"events"`
+		Config struct {
+			URL    string `json:"url"`
+			Secret string `json:"secret"`
+		} `json:"config"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/repos/octo/app/hooks" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode body: %v", err)
+		}
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id":4242,"active":true}`))
+	}))
+	defer srv.Close()
+
+	c := NewClient()
+	c.BaseURL = srv.URL
+	wh, err := c.CreateRepoWebhook(context.Background(), "tok", "octo", "app", WebhookConfig{
+		CallbackURL: "https://api.example.com/webhooks/github",
+		Events:      []string{"push"},
+	})
+	if err != nil {
+		t.Fatalf("CreateRepoWebhook: %v", err)
+	}
+	if wh.ID != 4242 {
+		t.Errorf("ID = %d, want 4242", wh.ID)
+	}
+	if len(wh.Secret) != 64 || got.Config.Secret != wh.Secret {
+		t.Errorf("expected a generated 64-char secret sent to GitHub, got %q (sent %q)", wh.Secret, got.Config.Secret)
+	}
+	if !got.Active || got.Config.URL != "https://api.example.com/webhooks/github" || len(got.Events) != 1 || got.Events[0] != "push" {
+		t.Errorf("unexpected hook body %+v", got)
+	}
+}
+
+func TestCreateRepoWebhookAlreadyExists(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_, _ = w.Write([]byte(`{"message":"Validation Failed","errors":[{"resource":"Hook","code":"custom","message":"Hook already exists on this repository"}]}`))
+	}))
+	defer srv.Close()
+
+	c := NewClient()
+	c.BaseURL = srv.URL
+	_, err := c.CreateRepoWebhook(context.Background(), "tok", "octo", "app", WebhookConfig{CallbackURL: "https://api.example.com/webhooks/github", Secret: "s"})
+	if !errors.Is(err, ErrWebhookExists) {
+		t.Fatalf("expected ErrWebhookExists, got %v", err)
+	}
+}
+
+func TestCreateRepoWebhookOtherValidationError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_, _ = w.Write([]byte(`{"message":"Validation Failed","errors":[{"resource":"Hook","code":"custom","message":"Invalid event"}]}`))
+	}))
+	defer srv.Close()
+
+	c := NewClient()
+	c.BaseURL = srv.URL
+	_, err := c.CreateRepoWebhook(context.Background(), "tok", "octo", "app", WebhookConfig{CallbackURL: "https://api.example.com/webhooks/github", Secret: "s"})
+	if err == nil || errors.Is(err, ErrWebhookExists) {
+		t.Fatalf("expected a plain error, got %v", err)
+	}
+}
@@ -2,16 +2,23 @@ package handlers
 
 import (
 	"errors"
+	"log/slog"
 	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 
+	"github.com/jagadeesh/grainlify/backend/internal/audit"
 	"github.com/jagadeesh/grainlify/backend/internal/auth"
+	"github.com/jagadeesh/grainlify/backend/internal/cache"
+	"github.com/jagadeesh/grainlify/backend/internal/clientip"
 	"github.com/jagadeesh/grainlify/backend/internal/config"
+	"github.com/jagadeesh/grainlify/backend/internal/cryptox"
 	"github.com/jagadeesh/grainlify/backend/internal/db"
+	"github.com/jagadeesh/grainlify/backend/internal/github"
 )
 
 type AdminHandler struct {
@@ -97,6 +104,216 @@ WHERE id = $1
 	}
 }
 
+// CacheStats reports hit/miss/eviction counters for every registered in-memory cache.
+func (h *AdminHandler) CacheStats() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"max_entries": h.cfg.CacheMaxEntries,
+			"default_ttl": h.cfg.CacheDefaultTTL.String(),
+			"caches":      cache.AllStats(),
+		})
+	}
+}
+
+// RevokeUserSessions is the admin counterpart of AuthHandler.RevokeAllSessions, used when an
+// account is suspected to be compromised.
+func (h *AdminHandler) RevokeUserSessions() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if h.db == nil || h.db.Pool == nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "db_not_configured"})
+		}
+		userID, err := uuid.Parse(c.Params("id"))
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_user_id"})
+		}
+		sub, _ := c.Locals(auth.LocalUserID).(string)
+		adminID, err := uuid.Parse(sub)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid_user"})
+		}
+
+		revokedAt, err := auth.RevokeAllSessions(c.Context(), h.db.Pool, userID)
+		if err != nil {
+			if errors.Is(err, auth.ErrUserNotFound) {
+				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "user_not_found"})
+			}
+			slog.Error("admin failed to revoke sessions", "error", err, "user_id", userID, "admin_id", adminID)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "session_revoke_failed"})
+		}
+
+		if err := audit.Record(c.Context(), h.db, audit.Event{
+			ActorUserID: &adminID,
+			UserID:      &userID,
+			Action:      audit.ActionSessionsRevoked,
+			IP:          clientip.FromRequest(c, h.cfg),
+			UserAgent:   c.Get("User-Agent"),
+			Metadata:    map[string]any{"triggered_by": "admin"},
+		}); err != nil {
+			slog.Error("failed to record audit event", "error", err, "action", audit.ActionSessionsRevoked, "user_id", userID)
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"ok":         true,
+			"revoked_at": revokedAt,
+		})
+	}
+}
+
+type importGitHubAccountRequest struct {
+	UserID       string `json:"user_id"`
+	GitHubUserID int64  `json:"github_user_id"`
+	Login        string `json:"login"`
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	Scope        string `json:"scope"`
+}
+
+// ImportGitHubAccount links a GitHub account using a token obtained outside our OAuth flow
+// (migration from another system). The token is checked against GitHub and must belong to
+// the claimed account. Every attempt is audited; the token itself is never logged.
+func (h *AdminHandler) ImportGitHubAccount() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if h.db == nil || h.db.Pool == nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "db_not_configured"})
+		}
+		sub, _ := c.Locals(auth.LocalUserID).(string)
+		adminID, err := uuid.Parse(sub)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid_user"})
+		}
+
+		if !h.cfg.StoreGitHubToken {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "token_storage_disabled"})
+		}
+
+		var req importGitHubAccountRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_json"})
+		}
+		userID, err := uuid.Parse(strings.TrimSpace(req.UserID))
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_user_id"})
+		}
+		req.AccessToken = strings.TrimSpace(req.AccessToken)
+		if req.GitHubUserID <= 0 || req.AccessToken == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "github_user_id_and_access_token_required"})
+		}
+
+		recordImport := func(outcome, reason string) {
+			meta := map[string]any{"github_user_id": req.GitHubUserID, "login": req.Login}
+			if reason != "" {
+				meta["reason"] = reason
+			}
+			if err := audit.Record(c.Context(), h.db, audit.Event{
+				ActorUserID: &adminID,
+				UserID:      &userID,
+				Action:      audit.ActionGitHubAccountImported,
+				Outcome:     outcome,
+				IP:          clientip.FromRequest(c, h.cfg),
+				UserAgent:   c.Get("User-Agent"),
+				Metadata:    meta,
+			}); err != nil {
+				slog.Error("failed to record audit event", "error", err, "action", audit.ActionGitHubAccountImported, "user_id", userID)
+			}
+		}
+
+		u, err := github.NewClient().GetUser(c.Context(), req.AccessToken)
+		if err != nil {
+			recordImport(audit.OutcomeFailure, "token_validation_failed")
+			if github.IsBadCredentials(err) {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_github_token"})
+			}
+			return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": "github_user_fetch_failed"})
+		}
+		if u.ID != req.GitHubUserID || (req.Login != "" && !strings.EqualFold(u.Login, strings.TrimSpace(req.Login))) {
+			recordImport(audit.OutcomeFailure, "github_identity_mismatch")
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "github_identity_mismatch"})
+		}
+		if err := u.ClampForStorage(); err != nil {
+			recordImport(audit.OutcomeFailure, "github_login_too_long")
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": "github_login_too_long"})
+		}
+
+		keys, err := cryptox.NewKeyring(h.cfg.TokenEncKeys())
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "token_encryption_not_configured"})
+		}
+		encToken, err := keys.Encrypt([]byte(req.AccessToken))
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "token_encrypt_failed"})
+		}
+		storedToken, err := putVaultToken(c.Context(), userID, encToken)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "token_store_failed"})
+		}
+		committed := false
+		defer func() {
+			if !committed {
+				discardVaultToken(c.Context(), userID, storedToken)
+			}
+		}()
+		tokenType := strings.TrimSpace(req.TokenType)
+		if tokenType == "" {
+			tokenType = "bearer"
+		}
+
+		tx, err := h.db.Pool.Begin(c.Context())
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "github_account_import_failed"})
+		}
+		defer func() { _ = tx.Rollback(c.Context()) }()
+
+		ct, err := tx.Exec(c.Context(), `UPDATE users SET github_user_id = $2, updated_at = now() WHERE id = $1`, userID, u.ID)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			recordImport(audit.OutcomeFailure, "github_account_already_linked")
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "github_account_already_linked"})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "github_account_import_failed"})
+		}
+		if ct.RowsAffected() == 0 {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "user_not_found"})
+		}
+
+		_, err = tx.Exec(c.Context(), `
+INSERT INTO github_accounts (user_id, github_user_id, login, avatar_url, access_token, token_type, scope)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (user_id) DO UPDATE SET
+  github_user_id = EXCLUDED.github_user_id,
+  login = EXCLUDED.login,
+  avatar_url = EXCLUDED.avatar_url,
+  access_token = EXCLUDED.access_token,
+  token_type = EXCLUDED.token_type,
+  scope = EXCLUDED.scope,
+  reauth_required = false,
+  reauth_required_at = NULL,
+  updated_at = now()
+`, userID, u.ID, u.Login, u.AvatarURL, storedToken, tokenType, strings.TrimSpace(req.Scope))
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			recordImport(audit.OutcomeFailure, "github_account_already_linked")
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "github_account_already_linked"})
+		}
+		if err != nil {
+			slog.Error("admin github account import failed", "error", err, "user_id", userID, "admin_id", adminID)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "github_account_import_failed"})
+		}
+		if err := tx.Commit(c.Context()); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "github_account_import_failed"})
+		}
+		committed = true
+
+		recordImport(audit.OutcomeSuccess, "")
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"ok": true,
+			"github": fiber.Map{
+				"id":    u.ID,
+				"login": u.Login,
+			},
+		})
+	}
+}
+
 // BootstrapAdmin promotes the currently authenticated user to admin if they know the bootstrap token.
 // This allows any authenticated user with the correct bootstrap token to become an admin.
 //
@@ -136,7 +353,7 @@ func (h *AdminHandler) BootstrapAdmin() fiber.Handler {
 
 		// If user is already an admin, no need to update
 		if currentRole == "admin" {
-			jwtToken, err := auth.IssueJWT(h.cfg.JWTSecret, userID, "admin", "", "", 60*time.Minute)
+			jwtToken, err := auth.IssueJWT(h.cfg.JWTSecret, userID, "admin", "", "", h.cfg.JWTAccessTTL, auth.WithIssuer(h.cfg.JWTIssuer), auth.WithScopes(h.cfg.ScopesForRole("admin")), auth.WithExtraClaims(h.cfg.JWTExtraClaims()), auth.WithContext(c.Context()))
 			if err != nil {
 				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "token_issue_failed"})
 			}
@@ -153,7 +370,7 @@ func (h *AdminHandler) BootstrapAdmin() fiber.Handler {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "bootstrap_failed"})
 		}
 
-		jwtToken, err := auth.IssueJWT(h.cfg.JWTSecret, userID, "admin", "", "", 60*time.Minute)
+		jwtToken, err := auth.IssueJWT(h.cfg.JWTSecret, userID, "admin", "", "", h.cfg.JWTAccessTTL, auth.WithIssuer(h.cfg.JWTIssuer), auth.WithScopes(h.cfg.ScopesForRole("admin")), auth.WithExtraClaims(h.cfg.JWTExtraClaims()), auth.WithContext(c.Context()))
 		if err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "token_issue_failed"})
 		}
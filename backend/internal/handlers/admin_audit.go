@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type auditEventEntry struct {
+	ID          uuid.UUID       `json:"id"`
+	ActorUserID *uuid.UUID      `json:"actor_user_id"`
+	UserID      *uuid.UUID      `json:"user_id"`
+	Action      string          `json:"action"`
+	Outcome     string          `json:"outcome"`
+	IP          *string         `json:"ip"`
+	UserAgent   *string         `json:"user_agent"`
+	Metadata    json.RawMessage `json:"metadata"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// ListAuditEvents returns audit_events newest first, paginated with limit/offset. Optional
+// filters: action (e.g. github_login), outcome (success or failure) and user_id.
+func (h *AdminHandler) ListAuditEvents() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if h.db == nil || h.db.Pool == nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "db_not_configured"})
+		}
+
+		var userID *uuid.UUID
+		if raw := c.Query("user_id"); raw != "" {
+			id, err := uuid.Parse(raw)
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_user_id"})
+			}
+			userID = &id
+		}
+		limit := c.QueryInt("limit", 50)
+		if limit < 1 || limit > 200 {
+			limit = 50
+		}
+		offset := c.QueryInt("offset", 0)
+		if offset < 0 {
+			offset = 0
+		}
+
+		// One extra row tells whether there is another page.
+		rows, err := h.db.Pool.Query(c.Context(), `
+SELECT id, actor_user_id, user_id, action, outcome, ip, user_agent, metadata, created_at
+FROM audit_events
+WHERE ($1 = '' OR action = $1)
+  AND ($2 = '' OR outcome = $2)
+  AND ($3::uuid IS NULL OR user_id = $3)
+ORDER BY created_at DESC, id DESC
+LIMIT $4 OFFSET $5
+`, c.Query("action"), c.Query("outcome"), userID, limit+1, offset)
+		if err != nil {
+			slog.Error("failed to list audit events", "error", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "audit_fetch_failed"})
+		}
+		defer rows.Close()
+
+		events := []auditEventEntry{}
+		for rows.Next() {
+			var e auditEventEntry
+			if err := rows.Scan(&e.ID, &e.ActorUserID, &e.UserID, &e.Action, &e.Outcome, &e.IP, &e.UserAgent, &e.Metadata, &e.CreatedAt); err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "audit_fetch_failed"})
+			}
+			events = append(events, e)
+		}
+		if err := rows.Err(); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "audit_fetch_failed"})
+		}
+
+		hasMore := len(events) > limit
+		if hasMore {
+			events = events[:limit]
+		}
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"events":   events,
+			"limit":    limit,
+			"offset":   offset,
+			"has_more": hasMore,
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/jagadeesh/grainlify/backend/internal/audit"
+	"github.com/jagadeesh/grainlify/backend/internal/auth"
+	"github.com/jagadeesh/grainlify/backend/internal/clientip"
+)
+
+// ListUserFeatures returns the feature flags enabled for a user.
+func (h *AdminHandler) ListUserFeatures() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if h.db == nil || h.db.Pool == nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "db_not_configured"})
+		}
+		userID, err := uuid.Parse(c.Params("id"))
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_user_id"})
+		}
+		features, err := auth.UserFeatures(c.Context(), h.db.Pool, userID)
+		if err != nil {
+			slog.Error("failed to list user features", "error", err, "user_id", userID)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "features_fetch_failed"})
+		}
+		if features == nil {
+			features = []string{}
+		}
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{"features": features})
+	}
+}
+
+// EnableUserFeature turns a feature flag on for a user (PUT); DisableUserFeature turns it off
+// (DELETE). Either takes effect with the user's next access token.
+func (h *AdminHandler) EnableUserFeature() fiber.Handler {
+	return h.setUserFeature(true)
+}
+
+func (h *AdminHandler) DisableUserFeature() fiber.Handler {
+	return h.setUserFeature(false)
+}
+
+func (h *AdminHandler) setUserFeature(enabled bool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if h.db == nil || h.db.Pool == nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "db_not_configured"})
+		}
+		userID, err := uuid.Parse(c.Params("id"))
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_user_id"})
+		}
+		feature := c.Params("feature")
+		if !auth.ValidFeatureName(feature) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_feature"})
+		}
+		sub, _ := c.Locals(auth.LocalUserID).(string)
+		adminID, err := uuid.Parse(sub)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid_user"})
+		}
+
+		if err := auth.SetUserFeature(c.Context(), h.db.Pool, userID, feature, enabled, &adminID); err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "user_not_found"})
+			}
+			slog.Error("failed to set user feature", "error", err, "user_id", userID, "feature", feature)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "feature_update_failed"})
+		}
+
+		if err := audit.Record(c.Context(), h.db, audit.Event{
+			ActorUserID: &adminID,
+			UserID:      &userID,
+			Action:      audit.ActionUserFeatureSet,
+			IP:          clientip.FromRequest(c, h.cfg),
+			UserAgent:   c.Get("User-Agent"),
+			Metadata:    map[string]any{"feature": feature, "enabled": enabled},
+		}); err != nil {
+			slog.Error("failed to record audit event", "error", err, "action", audit.ActionUserFeatureSet, "user_id", userID)
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{"ok": true, "feature": feature, "enabled": enabled})
+	}
+}
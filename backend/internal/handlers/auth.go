@@ -1,13 +1,17 @@
 package handlers
 
 import (
+	"errors"
 	"log/slog"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 
+	"github.com/jagadeesh/grainlify/backend/internal/audit"
 	"github.com/jagadeesh/grainlify/backend/internal/auth"
+	"github.com/jagadeesh/grainlify/backend/internal/clientip"
 	"github.com/jagadeesh/grainlify/backend/internal/config"
 	"github.com/jagadeesh/grainlify/backend/internal/db"
 	"github.com/jagadeesh/grainlify/backend/internal/github"
@@ -119,7 +123,7 @@ func (h *AuthHandler) Verify() fiber.Handler {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "auth_failed"})
 		}
 
-		token, err := auth.IssueJWT(h.cfg.JWTSecret, res.User.ID, res.User.Role, res.Wallet.WalletType, res.Wallet.Address, 15*time.Minute)
+		token, err := auth.IssueJWT(h.cfg.JWTSecret, res.User.ID, res.User.Role, res.Wallet.WalletType, res.Wallet.Address, 15*time.Minute, auth.WithIssuer(h.cfg.JWTIssuer), auth.WithScopes(h.cfg.ScopesForRole(res.User.Role)), auth.WithExtraClaims(h.cfg.JWTExtraClaims()), auth.WithContext(c.Context()))
 		if err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "token_issue_failed"})
 		}
@@ -142,164 +146,42 @@ func (h *AuthHandler) Me() fiber.Handler {
 		}
 
 		userIDStr, _ := c.Locals(auth.LocalUserID).(string)
-		role, _ := c.Locals(auth.LocalRole).(string)
 		userID, err := uuid.Parse(userIDStr)
 		if err != nil {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid_user"})
 		}
 
-		// Get user profile fields from database
-		var firstName, lastName, location, website, bio, avatarURL, telegram, linkedin, whatsapp, twitter, discord *string
-		err = h.db.Pool.QueryRow(c.Context(), `
-SELECT first_name, last_name, location, website, bio, avatar_url, telegram, linkedin, whatsapp, twitter, discord
-FROM users
-WHERE id = $1
-`, userID).Scan(&firstName, &lastName, &location, &website, &bio, &avatarURL, &telegram, &linkedin, &whatsapp, &twitter, &discord)
-		if err != nil {
-			slog.Warn("failed to fetch user profile fields", "error", err, "user_id", userID)
-		}
-
-		response := fiber.Map{
-			"id":   userIDStr,
-			"role": role,
-		}
-
-		// Try to get GitHub access token and fetch full profile
-		linkedAccount, err := github.GetLinkedAccount(c.Context(), h.db.Pool, userID, h.cfg.TokenEncKeyB64)
-		if err == nil {
-			// Fetch full GitHub user profile
-			gh := github.NewClient()
-			ghUser, err := gh.GetUser(c.Context(), linkedAccount.AccessToken)
-			if err == nil {
-				githubMap := fiber.Map{
-					"login": ghUser.Login,
-				}
-				// Use database avatar_url if set, otherwise use GitHub avatar
-				if avatarURL != nil && *avatarURL != "" {
-					githubMap["avatar_url"] = *avatarURL
-				} else {
-					githubMap["avatar_url"] = ghUser.AvatarURL
-				}
-				// Add optional fields if available
-				if ghUser.Name != "" {
-					githubMap["name"] = ghUser.Name
-				}
-				// Try to get email from GitHub emails endpoint (more reliable)
-				email, err := gh.GetPrimaryEmail(c.Context(), linkedAccount.AccessToken)
-				if err == nil && email != "" {
-					githubMap["email"] = email
-				} else if ghUser.Email != "" {
-					// Fallback to email from /user endpoint
-					githubMap["email"] = ghUser.Email
-				}
-				// Use database location if set, otherwise use GitHub location
-				if location != nil && *location != "" {
-					githubMap["location"] = *location
-				} else if ghUser.Location != "" {
-					githubMap["location"] = ghUser.Location
-				}
-				// Use database bio if set, otherwise use GitHub bio
-				if bio != nil && *bio != "" {
-					githubMap["bio"] = *bio
-				} else if ghUser.Bio != "" {
-					githubMap["bio"] = ghUser.Bio
-				}
-				// Use database website if set, otherwise use GitHub blog
-				if website != nil && *website != "" {
-					githubMap["website"] = *website
-				} else if ghUser.Blog != "" {
-					githubMap["website"] = ghUser.Blog
-				}
-				response["github"] = githubMap
-			} else {
-				// Fallback to database values if GitHub API fails
-				var githubLogin *string
-				var githubAvatarURL *string
-				_ = h.db.Pool.QueryRow(c.Context(), `
-SELECT login, avatar_url
-FROM github_accounts
-WHERE user_id = $1
-`, userID).Scan(&githubLogin, &githubAvatarURL)
-				if githubLogin != nil {
-					githubMap := fiber.Map{
-						"login": *githubLogin,
-					}
-					// Use database avatar_url if set, otherwise use GitHub account avatar
-					if avatarURL != nil && *avatarURL != "" {
-						githubMap["avatar_url"] = *avatarURL
-					} else if githubAvatarURL != nil && *githubAvatarURL != "" {
-						githubMap["avatar_url"] = *githubAvatarURL
-					}
-					// Add profile fields from database
-					if location != nil && *location != "" {
-						githubMap["location"] = *location
-					}
-					if bio != nil && *bio != "" {
-						githubMap["bio"] = *bio
-					}
-					if website != nil && *website != "" {
-						githubMap["website"] = *website
-					}
-					response["github"] = githubMap
-				}
-			}
-		} else {
-			// No GitHub account linked, try to get from database anyway
-			var githubLogin *string
-			var githubAvatarURL *string
-			_ = h.db.Pool.QueryRow(c.Context(), `
-SELECT login, avatar_url
-FROM github_accounts
-WHERE user_id = $1
-`, userID).Scan(&githubLogin, &githubAvatarURL)
-			if githubLogin != nil {
-				githubMap := fiber.Map{
-					"login": *githubLogin,
-				}
-				// Use database avatar_url if set, otherwise use GitHub account avatar
-				if avatarURL != nil && *avatarURL != "" {
-					githubMap["avatar_url"] = *avatarURL
-				} else if githubAvatarURL != nil && *githubAvatarURL != "" {
-					githubMap["avatar_url"] = *githubAvatarURL
-				}
-				// Add profile fields from database
-				if location != nil && *location != "" {
-					githubMap["location"] = *location
-				}
-				if bio != nil && *bio != "" {
-					githubMap["bio"] = *bio
-				}
-				if website != nil && *website != "" {
-					githubMap["website"] = *website
-				}
-				response["github"] = githubMap
-			}
+		profile, err := loadMeProfile(c.Context(), h.db.Pool, userID)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			slog.Error("failed to load user for /me", "error", err, "user_id", userID)
 		}
-
-		// Add user profile fields to response (for first_name, last_name, social links)
-		if firstName != nil && *firstName != "" {
-			response["first_name"] = *firstName
+		status, response := meResult(userID, profile, err)
+		if status != fiber.StatusOK || profile.githubLogin == nil {
+			return c.Status(status).JSON(response)
 		}
-		if lastName != nil && *lastName != "" {
-			response["last_name"] = *lastName
-		}
-		if telegram != nil && *telegram != "" {
-			response["telegram"] = *telegram
-		}
-		if linkedin != nil && *linkedin != "" {
-			response["linkedin"] = *linkedin
+
+		// Refresh the GitHub part from GitHub when the token still works; the stored values stand otherwise.
+		linkedAccount, err := github.GetLinkedAccount(c.Context(), h.db.Pool, userID, h.cfg.TokenEncKeys())
+		if err != nil {
+			return c.Status(status).JSON(response)
 		}
-		if whatsapp != nil && *whatsapp != "" {
-			response["whatsapp"] = *whatsapp
+		gh := github.NewClient()
+		ghUser, err := gh.GetUser(c.Context(), linkedAccount.AccessToken)
+		if err != nil {
+			return c.Status(status).JSON(response)
 		}
-		if twitter != nil && *twitter != "" {
-			response["twitter"] = *twitter
+		// The emails endpoint is more reliable than the (often hidden) /user email.
+		primaryEmail, ok, err := gh.GetPrimaryEmail(c.Context(), linkedAccount.AccessToken)
+		if err != nil || !ok {
+			primaryEmail = ""
 		}
-		if discord != nil && *discord != "" {
-			response["discord"] = *discord
+		overlayLiveGitHub(response, profile, ghUser, primaryEmail)
+		if wantsInclude(c, "raw_profile") {
+			if raw, err := rawGitHubProfile(c.Context(), h.cfg, gh, userID, linkedAccount.AccessToken); err == nil {
+				response["raw_profile"] = raw
+			}
 		}
-
-		return c.Status(fiber.StatusOK).JSON(response)
+		return c.Status(status).JSON(response)
 	}
 }
 
@@ -317,7 +199,13 @@ func (h *AuthHandler) ResyncGitHubProfile() fiber.Handler {
 		}
 
 		// Get GitHub access token
-		linkedAccount, err := github.GetLinkedAccount(c.Context(), h.db.Pool, userID, h.cfg.TokenEncKeyB64)
+		linkedAccount, err := github.GetLinkedAccount(c.Context(), h.db.Pool, userID, h.cfg.TokenEncKeys())
+		if isDeadGitHubToken(err) {
+			return respondReauthRequired(c, h.cfg, h.db, userID, err)
+		}
+		if errors.Is(err, github.ErrTokenNotStored) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "token_not_stored"})
+		}
 		if err != nil {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "github_not_linked"})
 		}
@@ -327,14 +215,19 @@ func (h *AuthHandler) ResyncGitHubProfile() fiber.Handler {
 		ghUser, err := gh.GetUser(c.Context(), linkedAccount.AccessToken)
 		if err != nil {
 			slog.Error("failed to fetch GitHub user", "error", err, "user_id", userID)
+			if isDeadGitHubToken(err) {
+				return respondReauthRequired(c, h.cfg, h.db, userID, err)
+			}
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "github_fetch_failed"})
 		}
 
 		// Get primary email from GitHub
-		email, err := gh.GetPrimaryEmail(c.Context(), linkedAccount.AccessToken)
+		email, ok, err := gh.GetPrimaryEmail(c.Context(), linkedAccount.AccessToken)
 		if err != nil {
 			slog.Warn("failed to fetch GitHub email", "error", err, "user_id", userID)
 			// Continue without email if email fetch fails
+		} else if !ok {
+			slog.Warn("GitHub account has no verified email", "user_id", userID)
 		}
 
 		// Update github_accounts table with fresh data
@@ -377,4 +270,87 @@ WHERE user_id = $3
 	}
 }
 
+// RevokeAllSessions signs the current user out everywhere: every token issued up to now is rejected
+// and the user has to authenticate again.
+func (h *AuthHandler) RevokeAllSessions() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if h.db == nil || h.db.Pool == nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "db_not_configured"})
+		}
+
+		userIDStr, _ := c.Locals(auth.LocalUserID).(string)
+		userID, err := uuid.Parse(userIDStr)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid_user"})
+		}
+
+		revokedAt, err := auth.RevokeAllSessions(c.Context(), h.db.Pool, userID)
+		if err != nil {
+			if errors.Is(err, auth.ErrUserNotFound) {
+				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "user_not_found"})
+			}
+			slog.Error("failed to revoke sessions", "error", err, "user_id", userID)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "session_revoke_failed"})
+		}
+
+		if err := audit.Record(c.Context(), h.db, audit.Event{
+			ActorUserID: &userID,
+			UserID:      &userID,
+			Action:      audit.ActionSessionsRevoked,
+			IP:          clientip.FromRequest(c, h.cfg),
+			UserAgent:   c.Get("User-Agent"),
+			Metadata:    map[string]any{"triggered_by": "self"},
+		}); err != nil {
+			slog.Error("failed to record audit event", "error", err, "action", audit.ActionSessionsRevoked, "user_id", userID)
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"ok":         true,
+			"revoked_at": revokedAt,
+		})
+	}
+}
+
+// Logout revokes the access token used for this request (by jti) so it stops working before it
+// expires. Other sessions of the user are unaffected; see RevokeAllSessions for that.
+func (h *AuthHandler) Logout() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if h.db == nil || h.db.Pool == nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "db_not_configured"})
+		}
+
+		claims, _ := c.Locals(auth.LocalClaims).(*auth.Claims)
+		if claims == nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid_token"})
+		}
+		userID, err := uuid.Parse(claims.Subject)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid_user"})
+		}
+		if claims.ID == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "token_not_revocable"})
+		}
+
+		// The entry must outlive every copy of the token. A sliding renewal keeps the jti and
+		// can no longer happen once the token is revoked, so the last renewal handed out before
+		// now expires at most one SlidingSessionTTL from now.
+		expiresAt := time.Now()
+		if claims.ExpiresAt != nil {
+			expiresAt = claims.ExpiresAt.Time
+		}
+		if h.cfg.SlidingSession {
+			if renewedUntil := time.Now().Add(h.cfg.SlidingSessionTTL); renewedUntil.After(expiresAt) {
+				expiresAt = renewedUntil
+			}
+		}
 
+		if err := auth.NewDBRevokedTokens(h.db.Pool).RevokeToken(c.Context(), claims.ID, userID, expiresAt); err != nil {
+			slog.Error("failed to revoke token", "error", err, "user_id", userID)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "logout_failed"})
+		}
+		if h.cfg.SessionCookies() {
+			auth.ClearSessionCookie(c)
+		}
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{"ok": true})
+	}
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/jagadeesh/grainlify/backend/internal/auth"
+	"github.com/jagadeesh/grainlify/backend/internal/github"
+)
+
+// capabilityAccount is the stored account state capabilities are derived from.
+type capabilityAccount struct {
+	githubLinked   bool
+	reauthRequired bool
+	tokenStored    bool
+	grantedScopes  []string
+	verifiedEmail  bool
+}
+
+type capabilities struct {
+	Role              string   `json:"role"`
+	Scopes            []string `json:"scopes"`
+	GitHubLinked      bool     `json:"github_linked"`
+	NeedsReauth       bool     `json:"needs_reauth"`
+	CanCreateWebhooks bool     `json:"can_create_webhooks"`
+	VerifiedEmail     bool     `json:"verified_email"`
+}
+
+// computeCapabilities is the single place that decides what a user can do. Creating webhooks
+// needs the webhooks:write capability on our side and a usable GitHub token granted
+// admin:repo_hook on GitHub's side.
+func computeCapabilities(role string, scopes []string, acct capabilityAccount) capabilities {
+	if scopes == nil {
+		scopes = []string{}
+	}
+	usableToken := acct.githubLinked && acct.tokenStored && !acct.reauthRequired
+	return capabilities{
+		Role:              role,
+		Scopes:            scopes,
+		GitHubLinked:      acct.githubLinked,
+		NeedsReauth:       acct.githubLinked && acct.reauthRequired,
+		CanCreateWebhooks: hasCapability(scopes, "webhooks:write") && usableToken && len(github.MissingScopes([]string{"admin:repo_hook"}, acct.grantedScopes)) == 0,
+		VerifiedEmail:     acct.verifiedEmail,
+	}
+}
+
+// hasCapability mirrors auth.RequireScope.
+func hasCapability(scopes []string, want string) bool {
+	for _, s := range scopes {
+		if s == want || s == auth.ScopeAll {
+			return true
+		}
+	}
+	return false
+}
+
+// Capabilities returns what the current user can do, so the frontend does not have to piece it
+// together from the token, /auth/github/status and role defaults.
+func (h *AuthHandler) Capabilities() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if h.db == nil || h.db.Pool == nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "db_not_configured"})
+		}
+
+		sub, _ := c.Locals(auth.LocalUserID).(string)
+		userID, err := uuid.Parse(sub)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid_user"})
+		}
+		role, _ := c.Locals(auth.LocalRole).(string)
+		scopes, _ := c.Locals(auth.LocalScopes).([]string)
+
+		var acct capabilityAccount
+		var notificationEmail *string
+		if err := h.db.Pool.QueryRow(c.Context(), `SELECT notification_email FROM users WHERE id = $1`, userID).Scan(&notificationEmail); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "user_not_found"})
+			}
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "capabilities_failed"})
+		}
+		// notification_email is only ever set from a verified GitHub address.
+		acct.verifiedEmail = notificationEmail != nil && *notificationEmail != ""
+
+		var scope *string
+		err = h.db.Pool.QueryRow(c.Context(), `
+SELECT scope, reauth_required, access_token IS NOT NULL
+FROM github_accounts
+WHERE user_id = $1
+`, userID).Scan(&scope, &acct.reauthRequired, &acct.tokenStored)
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+		case err != nil:
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "capabilities_failed"})
+		default:
+			acct.githubLinked = true
+			if scope != nil {
+				acct.grantedScopes = github.SplitScopes(*scope)
+			}
+		}
+
+		return c.Status(fiber.StatusOK).JSON(computeCapabilities(role, scopes, acct))
+	}
+}
@@ -0,0 +1,36 @@
+package handlers
+
+import "testing"
+
+func TestComputeCapabilities(t *testing.T) {
+	linked := capabilityAccount{githubLinked: true, tokenStored: true, grantedScopes: []string{"repo", "admin:repo_hook"}, verifiedEmail: true}
+
+	got := computeCapabilities("maintainer", []string{"repos:read", "webhooks:write"}, linked)
+	if !got.GitHubLinked || got.NeedsReauth || !got.CanCreateWebhooks || !got.VerifiedEmail {
+		t.Fatalf("healthy maintainer: %+v", got)
+	}
+
+	if got := computeCapabilities("contributor", []string{"repos:read"}, linked); got.CanCreateWebhooks {
+		t.Error("webhooks need the webhooks:write capability")
+	}
+	if got := computeCapabilities("admin", []string{"*"}, linked); !got.CanCreateWebhooks {
+		t.Error("wildcard scope covers webhooks:write")
+	}
+
+	noHookScope := linked
+	noHookScope.grantedScopes = []string{"repo"}
+	if got := computeCapabilities("maintainer", []string{"webhooks:write"}, noHookScope); got.CanCreateWebhooks {
+		t.Error("webhooks need admin:repo_hook granted on GitHub")
+	}
+
+	dead := linked
+	dead.reauthRequired = true
+	if got := computeCapabilities("maintainer", []string{"webhooks:write"}, dead); !got.NeedsReauth || got.CanCreateWebhooks {
+		t.Errorf("dead token: %+v", got)
+	}
+
+	got = computeCapabilities("contributor", nil, capabilityAccount{})
+	if got.GitHubLinked || got.NeedsReauth || got.CanCreateWebhooks || got.Scopes == nil {
+		t.Errorf("unlinked user: %+v", got)
+	}
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// Longest external id accepted from EXTERNAL_ID_HEADER.
+const maxExternalIDLen = 255
+
+// errExternalIDConflict means the external id and the GitHub account already belong to two
+// different users (or the external user is linked to another GitHub account).
+var errExternalIDConflict = errors.New("external id conflicts with github account")
+
+// externalID returns the caller's external id from EXTERNAL_ID_HEADER, or "" when the feature is
+// off, the header is absent, or the request didn't come straight from a trusted proxy. The
+// direct peer is checked rather than c.IP(), which a client can influence via X-Forwarded-For.
+func (h *GitHubOAuthHandler) externalID(c *fiber.Ctx) string {
+	if h.cfg.ExternalIDHeader == "" {
+		return ""
+	}
+	id := strings.TrimSpace(c.Get(h.cfg.ExternalIDHeader))
+	if id == "" {
+		return ""
+	}
+	peer := c.Context().RemoteIP().String()
+	if !h.cfg.IsTrustedProxy(peer) {
+		slog.Warn("ignoring external id header from untrusted peer", "header", h.cfg.ExternalIDHeader, "peer", peer)
+		return ""
+	}
+	if len(id) > maxExternalIDLen {
+		slog.Warn("ignoring oversized external id header", "header", h.cfg.ExternalIDHeader, "length", len(id))
+		return ""
+	}
+	return id
+}
+
+// resolveLoginUser finds or creates the user for a GitHub login. With an external id, the
+// GitHub identity is linked to the user that already holds that id, and a GitHub-only user
+// logging in through the IdP for the first time adopts the external id. Without one, a first
+// GitHub login whose verified email belongs to an existing account without GitHub is merged
+// into that account (merged is true) instead of creating a duplicate user.
+//
+// Two callbacks racing for the same new GitHub account both end up with the user the first one
+// created: the loser's insert hits the unique github_user_id and it resolves again.
+func (h *GitHubOAuthHandler) resolveLoginUser(ctx context.Context, githubUserID int64, externalID, verifiedEmail string) (userID uuid.UUID, role string, isNew, merged bool, err error) {
+	r, err := retryLoginRace(func() (loginResolution, error) {
+		var r loginResolution
+		var err error
+		r.userID, r.role, r.isNew, r.merged, err = h.resolveLoginUserOnce(ctx, githubUserID, externalID, verifiedEmail)
+		return r, err
+	})
+	return r.userID, r.role, r.isNew, r.merged, err
+}
+
+func (h *GitHubOAuthHandler) resolveLoginUserOnce(ctx context.Context, githubUserID int64, externalID, verifiedEmail string) (userID uuid.UUID, role string, isNew, merged bool, err error) {
+	gh, ghFound, err := h.users.userByGitHubID(ctx, githubUserID)
+	if err != nil {
+		return uuid.Nil, "", false, false, err
+	}
+
+	if externalID == "" {
+		var byGitHubID *loginCandidate
+		if ghFound {
+			byGitHubID = &loginCandidate{id: gh.id, role: gh.role}
+		}
+		var byEmail *loginCandidate
+		if byGitHubID == nil {
+			if byEmail, err = h.emailMergeCandidate(ctx, verifiedEmail); err != nil {
+				return uuid.Nil, "", false, false, err
+			}
+		}
+		if user, merge := chooseLoginUser(byGitHubID, byEmail); user != nil {
+			if !merge {
+				return user.id, user.role, false, false, nil
+			}
+			ok, err := h.mergeGitHubIdentity(ctx, user.id, githubUserID)
+			if err != nil {
+				return uuid.Nil, "", false, false, err
+			}
+			if ok {
+				return user.id, user.role, false, true, nil
+			}
+			// Linked to another GitHub account in the meantime; fall through to a new user.
+		}
+		userID, role, err = h.users.insertUser(ctx, githubUserID, "")
+		err = loginInsertErr(err)
+		return userID, role, err == nil, false, err
+	}
+
+	ext, extFound, err := h.users.userByExternalID(ctx, externalID)
+	if err != nil {
+		return uuid.Nil, "", false, false, err
+	}
+	if extFound {
+		if ghFound && gh.id != ext.id {
+			return uuid.Nil, "", false, false, errExternalIDConflict
+		}
+		if ext.githubUserID != nil && *ext.githubUserID != githubUserID {
+			return uuid.Nil, "", false, false, errExternalIDConflict
+		}
+		return ext.id, ext.role, false, false, nil
+	}
+
+	if ghFound {
+		if gh.externalID != nil && *gh.externalID != externalID {
+			return uuid.Nil, "", false, false, errExternalIDConflict
+		}
+		if err := h.users.setExternalID(ctx, gh.id, externalID); err != nil {
+			return uuid.Nil, "", false, false, err
+		}
+		return gh.id, gh.role, false, false, nil
+	}
+
+	userID, role, err = h.users.insertUser(ctx, githubUserID, externalID)
+	err = loginInsertErr(err)
+	return userID, role, err == nil, false, err
+}
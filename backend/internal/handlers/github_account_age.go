@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/jagadeesh/grainlify/backend/internal/github"
+)
+
+// githubAccountTooNew reports whether an account created at createdAt is younger than minAge
+// at now. An unknown creation time counts as too new, so the check can't be skipped by
+// GitHub omitting the field.
+func githubAccountTooNew(createdAt time.Time, minAge time.Duration, now time.Time) bool {
+	if minAge <= 0 {
+		return false
+	}
+	return createdAt.IsZero() || now.Sub(createdAt) < minAge
+}
+
+// refuseSignup reports whether a github_login for u must be refused under
+// MIN_GITHUB_ACCOUNT_AGE: the account is too new and the login would create a user. Users that
+// already exist, by GitHub id or by the external id the login links to, are let through.
+func (h *GitHubOAuthHandler) refuseSignup(ctx context.Context, u github.User, externalID string) (bool, error) {
+	if !githubAccountTooNew(u.CreatedAt, h.cfg.MinGitHubAccountAge, time.Now()) {
+		return false, nil
+	}
+	var exists bool
+	err := h.db.Pool.QueryRow(ctx, `
+SELECT EXISTS (
+  SELECT 1 FROM users
+  WHERE github_user_id = $1 OR (external_id = $2 AND $2 <> '')
+)
+`, u.ID, externalID).Scan(&exists)
+	if err != nil {
+		return false, err
+	}
+	return !exists, nil
+}
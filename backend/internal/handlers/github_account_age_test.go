@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGitHubAccountTooNew(t *testing.T) {
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	month := 30 * 24 * time.Hour
+	cases := []struct {
+		name      string
+		createdAt time.Time
+		minAge    time.Duration
+		want      bool
+	}{
+		{"disabled", now, 0, false},
+		{"old enough", now.Add(-2 * month), month, false},
+		{"exactly min age", now.Add(-month), month, false},
+		{"too new", now.Add(-time.Hour), month, true},
+		{"unknown creation time", time.Time{}, month, true},
+	}
+	for _, tc := range cases {
+		if got := githubAccountTooNew(tc.createdAt, tc.minAge, now); got != tc.want {
+			t.Errorf("%s: githubAccountTooNew = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
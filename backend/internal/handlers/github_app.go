@@ -50,12 +50,15 @@ func (h *GitHubAppHandler) StartInstallation() fiber.Handler {
 
 		// Generate state for installation callback
 		state := randomState(32)
-		expiresAt := time.Now().UTC().Add(10 * time.Minute)
+		expiresAt, err := newOAuthStateExpiry(time.Now(), oauthStateTTL)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "state_create_failed"})
+		}
 
 		_, err = h.db.Pool.Exec(c.Context(), `
 INSERT INTO oauth_states (state, user_id, kind, expires_at)
 VALUES ($1, $2, 'github_app_install', $3)
-`, state, userID, expiresAt)
+`, oauthStateKey(state), userID, expiresAt)
 		if err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "state_create_failed"})
 		}
@@ -80,7 +83,7 @@ VALUES ($1, $2, 'github_app_install', $3)
 				// keep state too (harmless if GitHub returns it)
 				q.Set("state", state)
 				// redirect back to our callback with state baked in
-				cb := strings.TrimSuffix(h.cfg.PublicBaseURL, "/") + "/auth/github/app/install/callback"
+				cb := strings.TrimSuffix(h.cfg.PublicBaseURL, "/") + h.cfg.AuthPath("/auth/github/app/install/callback")
 				cbURL, cbErr := url.Parse(cb)
 				if cbErr == nil {
 					cbQ := cbURL.Query()
@@ -100,7 +103,7 @@ VALUES ($1, $2, 'github_app_install', $3)
 			"app_id", h.cfg.GitHubAppID,
 			"state", state,
 			"install_url", installURL,
-			"expected_callback_url", h.cfg.PublicBaseURL+"/auth/github/app/install/callback",
+			"expected_callback_url", strings.TrimSuffix(h.cfg.PublicBaseURL, "/")+h.cfg.AuthPath("/auth/github/app/install/callback"),
 		)
 
 		return c.Status(fiber.StatusOK).JSON(fiber.Map{
@@ -174,14 +177,17 @@ func (h *GitHubAppHandler) HandleInstallationCallback() fiber.Handler {
 		var userID uuid.UUID
 		if state != "" {
 			var storedUserID *uuid.UUID
-			var storedKind string
+			var storedKind, storedState string
 			err := h.db.Pool.QueryRow(c.Context(), `
-SELECT user_id, kind
+SELECT state, user_id, kind
 FROM oauth_states
-WHERE state = $1
+WHERE state = ANY($1)
   AND expires_at > now()
   AND kind = 'github_app_install'
-`, state).Scan(&storedUserID, &storedKind)
+`, oauthStateKeys(state)).Scan(&storedState, &storedUserID, &storedKind)
+			if err == nil && !oauthStateMatches(storedState, state) {
+				err = pgx.ErrNoRows
+			}
 			if errors.Is(err, pgx.ErrNoRows) {
 				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_or_expired_state"})
 			}
@@ -194,7 +200,7 @@ WHERE state = $1
 			}
 
 			// Clean up state
-			_, _ = h.db.Pool.Exec(c.Context(), `DELETE FROM oauth_states WHERE state = $1`, state)
+			_, _ = h.db.Pool.Exec(c.Context(), `DELETE FROM oauth_states WHERE state = $1`, storedState)
 		}
 
 		// If we don't have userID, we can't create projects - just redirect
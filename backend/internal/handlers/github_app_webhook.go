@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"github.com/jagadeesh/grainlify/backend/internal/github"
+)
+
+// installationClients keeps one AppClient per installation so their cached tokens survive
+// across verification runs.
+var installationClients = struct {
+	mu      sync.Mutex
+	clients map[int64]*github.AppClient
+}{clients: map[int64]*github.AppClient{}}
+
+// webhookAccessToken picks the token used to manage a project's repo webhook. With
+// GITHUB_WEBHOOKS_VIA_APP set and the project installed through the GitHub App, that is an
+// installation token scoped to the app's permissions; otherwise it is the owner's OAuth token.
+func (h *ProjectsHandler) webhookAccessToken(ctx context.Context, projectID uuid.UUID, userToken string) string {
+	if !h.cfg.GitHubWebhooksViaApp {
+		return userToken
+	}
+
+	var installation *string
+	if err := h.db.Pool.QueryRow(ctx, `SELECT github_app_installation_id FROM projects WHERE id = $1`, projectID).Scan(&installation); err != nil {
+		slog.Warn("failed to load github app installation, using oauth token for webhook", "error", err, "project_id", projectID)
+		return userToken
+	}
+	if installation == nil || strings.TrimSpace(*installation) == "" {
+		return userToken
+	}
+
+	client, err := h.installationClient(strings.TrimSpace(*installation))
+	if err == nil {
+		var token string
+		if token, err = client.Token(ctx); err == nil {
+			return token
+		}
+	}
+	slog.Warn("github app installation token unavailable, using oauth token for webhook", "error", err, "project_id", projectID, "installation_id", *installation)
+	return userToken
+}
+
+func (h *ProjectsHandler) installationClient(installation string) (*github.AppClient, error) {
+	installationID, err := strconv.ParseInt(installation, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	appID, err := strconv.ParseInt(strings.TrimSpace(h.cfg.GitHubAppID), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	installationClients.mu.Lock()
+	defer installationClients.mu.Unlock()
+	if c, ok := installationClients.clients[installationID]; ok {
+		return c, nil
+	}
+	c, err := github.NewAppClient(appID, []byte(h.cfg.GitHubAppPrivateKey), installationID)
+	if err != nil {
+		return nil, err
+	}
+	installationClients.clients[installationID] = c
+	return c, nil
+}
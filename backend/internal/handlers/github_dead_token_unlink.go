@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/jagadeesh/grainlify/backend/internal/audit"
+	"github.com/jagadeesh/grainlify/backend/internal/config"
+	"github.com/jagadeesh/grainlify/backend/internal/db"
+	"github.com/jagadeesh/grainlify/backend/internal/tokenvault"
+)
+
+const deadTokenUnlinkInterval = time.Hour
+
+// GitHubDeadTokenUnlinker removes github_accounts rows whose token has been flagged dead
+// (reauth_required) for longer than AUTO_UNLINK_DEAD_TOKEN_AFTER. Only the link goes:
+// users.github_user_id is kept, so the user's next GitHub login finds them and links again.
+type GitHubDeadTokenUnlinker struct {
+	cfg config.Config
+	db  *db.DB
+}
+
+func NewGitHubDeadTokenUnlinker(cfg config.Config, d *db.DB) *GitHubDeadTokenUnlinker {
+	return &GitHubDeadTokenUnlinker{
+		cfg: cfg,
+		db:  d,
+	}
+}
+
+// RunPeriodic unlinks dead accounts hourly until ctx is done.
+func (u *GitHubDeadTokenUnlinker) RunPeriodic(ctx context.Context) {
+	if u.cfg.AutoUnlinkDeadTokenAfter <= 0 {
+		slog.Info("dead GitHub token auto-unlink disabled")
+		return
+	}
+
+	ticker := time.NewTicker(deadTokenUnlinkInterval)
+	defer ticker.Stop()
+
+	slog.Info("dead GitHub token auto-unlink started", "after", u.cfg.AutoUnlinkDeadTokenAfter.String())
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("dead GitHub token auto-unlink stopped")
+			return
+		case <-ticker.C:
+			if _, err := u.UnlinkDeadTokens(ctx); err != nil {
+				slog.Error("dead GitHub token auto-unlink failed", "error", err)
+			}
+		}
+	}
+}
+
+// UnlinkDeadTokens deletes the expired links and returns how many were removed.
+func (u *GitHubDeadTokenUnlinker) UnlinkDeadTokens(ctx context.Context) (int, error) {
+	if u.db == nil || u.db.Pool == nil {
+		return 0, nil
+	}
+
+	rows, err := u.db.Pool.Query(ctx, `
+DELETE FROM github_accounts
+WHERE reauth_required
+  AND reauth_required_at < now() - make_interval(secs => $1)
+RETURNING user_id, github_user_id, login, reauth_required_at
+`, u.cfg.AutoUnlinkDeadTokenAfter.Seconds())
+	if err != nil {
+		return 0, err
+	}
+
+	type unlinked struct {
+		userID       uuid.UUID
+		githubUserID int64
+		login        string
+		deadSince    time.Time
+	}
+	var removed []unlinked
+	for rows.Next() {
+		var r unlinked
+		if err := rows.Scan(&r.userID, &r.githubUserID, &r.login, &r.deadSince); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		removed = append(removed, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, r := range removed {
+		slog.Info("unlinked GitHub account with dead token",
+			"user_id", r.userID,
+			"github_user_id", r.githubUserID,
+			"github_login", r.login,
+			"dead_since", r.deadSince,
+		)
+		if err := tokenvault.Default().Delete(ctx, r.userID); err != nil {
+			slog.Error("failed to delete token from vault after auto-unlink", "error", err, "user_id", r.userID)
+		}
+		if err := audit.Record(ctx, u.db, audit.Event{
+			UserID: &r.userID,
+			Action: audit.ActionGitHubAutoUnlinked,
+			Metadata: map[string]any{
+				"github_user_id": r.githubUserID,
+				"github_login":   r.login,
+				"dead_since":     r.deadSince,
+				"after":          u.cfg.AutoUnlinkDeadTokenAfter.String(),
+			},
+		}); err != nil {
+			slog.Error("failed to record audit event", "error", err, "action", audit.ActionGitHubAutoUnlinked, "user_id", r.userID)
+		}
+	}
+	return len(removed), nil
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/jagadeesh/grainlify/backend/internal/config"
+)
+
+// Counters from the identity reconciler, reported by AdminHandler.GitHubIdentityStats.
+var (
+	identityMismatchesLastRun atomic.Int64
+	identityMismatchesTotal   atomic.Int64
+	identityMismatchesFixed   atomic.Int64
+	identityLastRunUnix       atomic.Int64
+)
+
+// GitHubIdentityReconciler keeps users.github_user_id in line with github_accounts, which is
+// the source of truth for which GitHub account a user is linked to. The OAuth callback
+// updates both, but not atomically, so a failure between the two writes can leave them apart.
+type GitHubIdentityReconciler struct {
+	cfg  config.Config
+	pool *pgxpool.Pool
+}
+
+func NewGitHubIdentityReconciler(cfg config.Config, pool *pgxpool.Pool) *GitHubIdentityReconciler {
+	return &GitHubIdentityReconciler{
+		cfg:  cfg,
+		pool: pool,
+	}
+}
+
+// RunPeriodic reconciles every GITHUB_IDENTITY_RECONCILE_INTERVAL until ctx is done.
+func (r *GitHubIdentityReconciler) RunPeriodic(ctx context.Context) {
+	if r.cfg.GitHubIdentityReconcileInterval <= 0 {
+		slog.Info("GitHub identity reconciliation disabled")
+		return
+	}
+
+	ticker := time.NewTicker(r.cfg.GitHubIdentityReconcileInterval)
+	defer ticker.Stop()
+
+	slog.Info("GitHub identity reconciliation started",
+		"interval", r.cfg.GitHubIdentityReconcileInterval.String(),
+		"fix", r.cfg.GitHubIdentityReconcileFix,
+	)
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("GitHub identity reconciliation stopped")
+			return
+		case <-ticker.C:
+			if _, err := r.ReconcileGitHubIdentities(ctx); err != nil {
+				slog.Error("GitHub identity reconciliation failed", "error", err)
+			}
+		}
+	}
+}
+
+// ReconcileGitHubIdentities logs every user whose users.github_user_id disagrees with their
+// github_accounts row and returns how many were found. With GITHUB_IDENTITY_RECONCILE_FIX,
+// users.github_user_id is overwritten from github_accounts.
+//
+// Users that carry a github_user_id but have no github_accounts row are reported but never
+// changed: clearing the id would detach them from their GitHub login.
+func (r *GitHubIdentityReconciler) ReconcileGitHubIdentities(ctx context.Context) (int, error) {
+	if r.pool == nil {
+		return 0, nil
+	}
+
+	rows, err := r.pool.Query(ctx, `
+SELECT u.id, u.github_user_id, ga.github_user_id
+FROM users u
+LEFT JOIN github_accounts ga ON ga.user_id = u.id
+WHERE (ga.user_id IS NOT NULL AND u.github_user_id IS DISTINCT FROM ga.github_user_id)
+   OR (ga.user_id IS NULL AND u.github_user_id IS NOT NULL)
+`)
+	if err != nil {
+		return 0, err
+	}
+
+	type mismatch struct {
+		userID   uuid.UUID
+		userGHID *int64
+		acctGHID *int64
+	}
+	var found []mismatch
+	for rows.Next() {
+		var m mismatch
+		if err := rows.Scan(&m.userID, &m.userGHID, &m.acctGHID); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		found = append(found, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	fixed := 0
+	for _, m := range found {
+		slog.Warn("GitHub identity mismatch",
+			"user_id", m.userID,
+			"users_github_user_id", m.userGHID,
+			"github_accounts_github_user_id", m.acctGHID,
+		)
+		if !r.cfg.GitHubIdentityReconcileFix || m.acctGHID == nil {
+			continue
+		}
+		// users.github_user_id is UNIQUE; leave it for a human if another user still holds the id.
+		ct, err := r.pool.Exec(ctx, `
+UPDATE users SET github_user_id = $2, updated_at = now()
+WHERE id = $1
+  AND NOT EXISTS (SELECT 1 FROM users other WHERE other.github_user_id = $2 AND other.id <> $1)
+`, m.userID, *m.acctGHID)
+		if err != nil {
+			slog.Error("failed to fix GitHub identity mismatch", "error", err, "user_id", m.userID)
+			continue
+		}
+		if ct.RowsAffected() == 0 {
+			slog.Warn("GitHub identity mismatch not fixed: github_user_id held by another user", "user_id", m.userID)
+			continue
+		}
+		fixed++
+	}
+
+	identityMismatchesLastRun.Store(int64(len(found)))
+	identityMismatchesTotal.Add(int64(len(found)))
+	identityMismatchesFixed.Add(int64(fixed))
+	identityLastRunUnix.Store(time.Now().Unix())
+
+	if len(found) > 0 {
+		slog.Info("GitHub identity reconciliation finished", "mismatches", len(found), "fixed", fixed)
+	}
+	return len(found), nil
+}
+
+// GitHubIdentityStats reports the reconciler's inconsistency counters.
+func (h *AdminHandler) GitHubIdentityStats() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		resp := fiber.Map{
+			"mismatches_last_run": identityMismatchesLastRun.Load(),
+			"mismatches_total":    identityMismatchesTotal.Load(),
+			"fixed_total":         identityMismatchesFixed.Load(),
+			"fix_enabled":         h.cfg.GitHubIdentityReconcileFix,
+			"interval":            h.cfg.GitHubIdentityReconcileInterval.String(),
+		}
+		if last := identityLastRunUnix.Load(); last > 0 {
+			resp["last_run_at"] = time.Unix(last, 0).UTC()
+		}
+		return c.Status(fiber.StatusOK).JSON(resp)
+	}
+}
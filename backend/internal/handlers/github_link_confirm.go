@@ -0,0 +1,263 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/jagadeesh/grainlify/backend/internal/apierr"
+	"github.com/jagadeesh/grainlify/backend/internal/auth"
+	"github.com/jagadeesh/grainlify/backend/internal/github"
+)
+
+const pendingLinkTTL = 10 * time.Minute
+
+// storePendingLink parks a github_link authorization until the user confirms it.
+// The frontend lands on GitHubOAuthSuccessRedirectURL with pending=true and renders
+// the confirmation page from LinkPreview.
+func (h *GitHubOAuthHandler) storePendingLink(c *fiber.Ctx, userID uuid.UUID, u github.User, encToken []byte, tr github.TokenResponse) error {
+	expiresAt := time.Now().UTC().Add(pendingLinkTTL)
+	_, err := h.db.Pool.Exec(c.Context(), `
+INSERT INTO pending_links (user_id, github_user_id, login, avatar_url, access_token, token_type, scope, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+ON CONFLICT (user_id) DO UPDATE SET
+  github_user_id = EXCLUDED.github_user_id,
+  login = EXCLUDED.login,
+  avatar_url = EXCLUDED.avatar_url,
+  access_token = EXCLUDED.access_token,
+  token_type = EXCLUDED.token_type,
+  scope = EXCLUDED.scope,
+  created_at = now(),
+  expires_at = EXCLUDED.expires_at
+`, userID, u.ID, u.Login, u.AvatarURL, encToken, tr.TokenType, tr.Scope, expiresAt)
+	if err != nil {
+		slog.Error("failed to store pending github link", "error", err, "user_id", userID)
+		return apierr.Respond(c, apierr.New(fiber.StatusInternalServerError, "pending_link_store_failed"))
+	}
+
+	if h.cfg.GitHubOAuthSuccessRedirectURL != "" {
+		ru, err := url.Parse(h.cfg.GitHubOAuthSuccessRedirectURL)
+		if err == nil {
+			q := ru.Query()
+			q.Set("pending", "true")
+			q.Set("github", u.Login)
+			ru.RawQuery = q.Encode()
+			return c.Redirect(ru.String(), fiber.StatusFound)
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"ok":      true,
+		"pending": true,
+		"github": fiber.Map{
+			"id":         u.ID,
+			"login":      u.Login,
+			"avatar_url": u.AvatarURL,
+		},
+		"expires_at": expiresAt,
+	})
+}
+
+// LinkPreview shows which GitHub account is about to be linked, without committing anything.
+func (h *GitHubOAuthHandler) LinkPreview() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if h.db == nil || h.db.Pool == nil {
+			return apierr.Respond(c, apierr.New(fiber.StatusServiceUnavailable, "db_not_configured"))
+		}
+
+		sub, _ := c.Locals(auth.LocalUserID).(string)
+		userID, err := uuid.Parse(sub)
+		if err != nil {
+			return apierr.Respond(c, apierr.New(fiber.StatusUnauthorized, "invalid_user"))
+		}
+
+		var githubUserID int64
+		var login string
+		var avatarURL *string
+		var expiresAt time.Time
+		err = h.db.Pool.QueryRow(c.Context(), `
+SELECT github_user_id, login, avatar_url, expires_at
+FROM pending_links
+WHERE user_id = $1
+  AND expires_at > now()
+`, userID).Scan(&githubUserID, &login, &avatarURL, &expiresAt)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return apierr.Respond(c, apierr.New(fiber.StatusNotFound, "no_pending_link"))
+		}
+		if err != nil {
+			return apierr.Respond(c, apierr.New(fiber.StatusInternalServerError, "pending_link_lookup_failed"))
+		}
+
+		githubMap := fiber.Map{
+			"id":    githubUserID,
+			"login": login,
+		}
+		if avatarURL != nil && *avatarURL != "" {
+			githubMap["avatar_url"] = *avatarURL
+		}
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"pending":    true,
+			"github":     githubMap,
+			"expires_at": expiresAt,
+		})
+	}
+}
+
+// errNoPendingLink means the user has no unexpired pending link to confirm.
+var errNoPendingLink = errors.New("no_pending_link")
+
+// confirmedLink is the link written by pendingLinkConfirmer.confirmPendingLink.
+type confirmedLink struct {
+	githubUserID int64
+	login        string
+	// previousGitHubUserID is the account linked before, nil if there was none.
+	previousGitHubUserID *int64
+}
+
+// pendingLinkConfirmer moves pending links into github_accounts; the database implementation
+// is dbPendingLinkConfirmer.
+type pendingLinkConfirmer interface {
+	// confirmPendingLink links the user's pending GitHub account in one committed transaction.
+	// It returns errNoPendingLink, errTokenVaultWrite, or the database error (a unique violation
+	// when the account is linked to another user).
+	confirmPendingLink(ctx context.Context, userID uuid.UUID) (confirmedLink, error)
+}
+
+// ConfirmLink commits the pending link into github_accounts.
+func (h *GitHubOAuthHandler) ConfirmLink() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if h.db == nil || h.db.Pool == nil {
+			return apierr.Respond(c, apierr.New(fiber.StatusServiceUnavailable, "db_not_configured"))
+		}
+
+		sub, _ := c.Locals(auth.LocalUserID).(string)
+		userID, err := uuid.Parse(sub)
+		if err != nil {
+			return apierr.Respond(c, apierr.New(fiber.StatusUnauthorized, "invalid_user"))
+		}
+
+		link, err := h.confirms.confirmPendingLink(c.Context(), userID)
+		switch {
+		case errors.Is(err, errNoPendingLink):
+			return apierr.Respond(c, apierr.New(fiber.StatusNotFound, "no_pending_link"))
+		case errors.Is(err, errTokenVaultWrite):
+			return apierr.Respond(c, apierr.New(fiber.StatusInternalServerError, "token_store_failed"))
+		case isUniqueViolation(err):
+			// The GitHub account is already linked to a different user.
+			return apierr.Respond(c, apierr.New(fiber.StatusConflict, "github_account_already_linked"))
+		case err != nil:
+			slog.Error("failed to confirm github link", "error", err, "user_id", userID)
+			return apierr.Respond(c, apierr.New(fiber.StatusInternalServerError, "link_confirm_failed"))
+		}
+
+		h.recordLink(c, userID, link.login)
+		h.notifyGitHubLinked(c.Context(), userID, link.previousGitHubUserID, github.User{ID: link.githubUserID, Login: link.login})
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"ok":     true,
+			"linked": true,
+			"github": fiber.Map{
+				"id":    link.githubUserID,
+				"login": link.login,
+			},
+		})
+	}
+}
+
+type dbPendingLinkConfirmer struct {
+	pool *pgxpool.Pool
+}
+
+func (s dbPendingLinkConfirmer) confirmPendingLink(ctx context.Context, userID uuid.UUID) (confirmedLink, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return confirmedLink{}, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	link := confirmedLink{previousGitHubUserID: previousGitHubUserID(ctx, tx, userID)}
+
+	// pending_links always holds ciphertext; the vault decides what github_accounts keeps.
+	var pendingToken []byte
+	err = tx.QueryRow(ctx, `SELECT access_token FROM pending_links WHERE user_id = $1 AND expires_at > now()`, userID).Scan(&pendingToken)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return confirmedLink{}, errNoPendingLink
+	}
+	if err != nil {
+		return confirmedLink{}, err
+	}
+	storedToken, err := putVaultToken(ctx, userID, pendingToken)
+	if err != nil {
+		return confirmedLink{}, err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			discardVaultToken(ctx, userID, storedToken)
+		}
+	}()
+
+	err = tx.QueryRow(ctx, `
+INSERT INTO github_accounts (user_id, github_user_id, login, avatar_url, access_token, token_type, scope)
+SELECT user_id, github_user_id, login, avatar_url, $2, token_type, scope
+FROM pending_links
+WHERE user_id = $1
+  AND expires_at > now()
+ON CONFLICT (user_id) DO UPDATE SET
+  github_user_id = EXCLUDED.github_user_id,
+  login = EXCLUDED.login,
+  avatar_url = EXCLUDED.avatar_url,
+  access_token = EXCLUDED.access_token,
+  token_type = EXCLUDED.token_type,
+  scope = EXCLUDED.scope,
+  reauth_required = false,
+  reauth_required_at = NULL,
+  updated_at = now()
+RETURNING github_user_id, login
+`, userID, storedToken).Scan(&link.githubUserID, &link.login)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return confirmedLink{}, errNoPendingLink
+	}
+	if err != nil {
+		return confirmedLink{}, err
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE users SET github_user_id = $2, updated_at = now() WHERE id = $1`, userID, link.githubUserID); err != nil {
+		return confirmedLink{}, err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM pending_links WHERE user_id = $1`, userID); err != nil {
+		return confirmedLink{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return confirmedLink{}, err
+	}
+	committed = true
+	return link, nil
+}
+
+// CancelPendingLink discards a pending link ("that's not my account").
+func (h *GitHubOAuthHandler) CancelPendingLink() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if h.db == nil || h.db.Pool == nil {
+			return apierr.Respond(c, apierr.New(fiber.StatusServiceUnavailable, "db_not_configured"))
+		}
+
+		sub, _ := c.Locals(auth.LocalUserID).(string)
+		userID, err := uuid.Parse(sub)
+		if err != nil {
+			return apierr.Respond(c, apierr.New(fiber.StatusUnauthorized, "invalid_user"))
+		}
+
+		if _, err := h.db.Pool.Exec(c.Context(), `DELETE FROM pending_links WHERE user_id = $1`, userID); err != nil {
+			return apierr.Respond(c, apierr.New(fiber.StatusInternalServerError, "pending_link_cancel_failed"))
+		}
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{"ok": true})
+	}
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/jagadeesh/grainlify/backend/internal/audit"
+	"github.com/jagadeesh/grainlify/backend/internal/auth"
+	"github.com/jagadeesh/grainlify/backend/internal/db"
+)
+
+// fakeConfirmer confirms the same pending link every time, or fails with err.
+type fakeConfirmer struct {
+	link confirmedLink
+	err  error
+}
+
+func (f fakeConfirmer) confirmPendingLink(context.Context, uuid.UUID) (confirmedLink, error) {
+	return f.link, f.err
+}
+
+func TestConfirmLinkRecordsLinkEvent(t *testing.T) {
+	events := captureAudit(t)
+	// The pool connects lazily and the fake confirmer never uses it.
+	pool, err := pgxpool.New(context.Background(), "postgres://grainlify@127.0.0.1:1/grainlify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	h := &GitHubOAuthHandler{db: &db.DB{Pool: pool}}
+	userID := uuid.New()
+	app := fiber.New()
+	app.Post("/auth/github/link/confirm", func(c *fiber.Ctx) error {
+		c.Locals(auth.LocalUserID, userID.String())
+		return c.Next()
+	}, h.ConfirmLink())
+	confirm := func() int {
+		t.Helper()
+		resp, err := app.Test(httptest.NewRequest("POST", "/auth/github/link/confirm", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode
+	}
+
+	h.confirms = fakeConfirmer{err: errUniqueViolation}
+	if status := confirm(); status != fiber.StatusConflict {
+		t.Fatalf("linked elsewhere: status %d, want 409", status)
+	}
+	h.confirms = fakeConfirmer{err: errNoPendingLink}
+	if status := confirm(); status != fiber.StatusNotFound {
+		t.Fatalf("no pending link: status %d, want 404", status)
+	}
+	if len(*events) != 0 {
+		t.Fatalf("failed confirmations recorded %v", *events)
+	}
+
+	h.confirms = fakeConfirmer{link: confirmedLink{githubUserID: 7, login: "octocat"}}
+	if status := confirm(); status != fiber.StatusOK {
+		t.Fatalf("status %d, want 200", status)
+	}
+	if len(*events) != 1 {
+		t.Fatalf("got %d audit events, want 1", len(*events))
+	}
+	e := (*events)[0]
+	if e.Action != audit.ActionGitHubLink || e.UserID == nil || *e.UserID != userID || e.Metadata["github_login"] != "octocat" {
+		t.Errorf("event = %+v, want a github_link event for the user", e)
+	}
+}
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/jagadeesh/grainlify/backend/internal/apierr"
+	"github.com/jagadeesh/grainlify/backend/internal/audit"
+	"github.com/jagadeesh/grainlify/backend/internal/auth"
+	"github.com/jagadeesh/grainlify/backend/internal/clientip"
+	"github.com/jagadeesh/grainlify/backend/internal/github"
+	"github.com/jagadeesh/grainlify/backend/internal/mail"
+	"github.com/jagadeesh/grainlify/backend/internal/tokenvault"
+)
+
+const (
+	linkRevocationTTL = 7 * 24 * time.Hour
+	// Frontend page that POSTs the token to RevokeLink; a GET on the backend could be
+	// triggered by mail scanners prefetching links.
+	frontendLinkRevokePath = "/auth/link-revoke"
+)
+
+// captureNotificationEmail remembers the verified address of the user's own GitHub login
+// (from loginEmail) for later security notices. An existing address is kept.
+func (h *GitHubOAuthHandler) captureNotificationEmail(ctx context.Context, email string, userID uuid.UUID) {
+	if email == "" {
+		return
+	}
+	if _, err := h.db.Pool.Exec(ctx, `
+UPDATE users SET notification_email = $2
+WHERE id = $1 AND notification_email IS NULL
+`, userID, email); err != nil {
+		slog.Warn("failed to store notification email", "error", err, "user_id", userID)
+	}
+}
+
+// rowQuerier is satisfied by both *pgxpool.Pool and pgx.Tx.
+type rowQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// previousGitHubUserID returns the GitHub account linked to userID before this link, if any.
+func previousGitHubUserID(ctx context.Context, q rowQuerier, userID uuid.UUID) *int64 {
+	var id int64
+	if err := q.QueryRow(ctx, `SELECT github_user_id FROM github_accounts WHERE user_id = $1`, userID).Scan(&id); err != nil {
+		return nil
+	}
+	return &id
+}
+
+// notifyGitHubLinked emails the user when a new or different GitHub account was linked
+// (NOTIFY_ON_LINK). The mail carries a one-time link that undoes it via RevokeLink.
+func (h *GitHubOAuthHandler) notifyGitHubLinked(ctx context.Context, userID uuid.UUID, previous *int64, u github.User) {
+	if !h.cfg.NotifyOnLink || (previous != nil && *previous == u.ID) {
+		return
+	}
+	mailer := mail.NewClient(h.cfg.SMTPHost, h.cfg.SMTPPort, h.cfg.SMTPUsername, h.cfg.SMTPPassword, h.cfg.SMTPFrom)
+	if mailer == nil || h.cfg.FrontendBaseURL == "" {
+		slog.Warn("NOTIFY_ON_LINK set but SMTP or FRONTEND_BASE_URL not configured", "user_id", userID)
+		return
+	}
+
+	var email *string
+	if err := h.db.Pool.QueryRow(ctx, `SELECT notification_email FROM users WHERE id = $1`, userID).Scan(&email); err != nil || email == nil || *email == "" {
+		slog.Info("no notification email for user, skipping link notice", "user_id", userID)
+		return
+	}
+
+	token := randomState(32)
+	if _, err := h.db.Pool.Exec(ctx, `
+INSERT INTO link_revocations (token_hash, user_id, github_user_id, expires_at)
+VALUES ($1, $2, $3, $4)
+`, hashOneTimeToken(token), userID, u.ID, time.Now().UTC().Add(linkRevocationTTL)); err != nil {
+		slog.Error("failed to create link revocation token", "error", err, "user_id", userID)
+		return
+	}
+
+	revokeURL := strings.TrimRight(h.cfg.FrontendBaseURL, "/") + frontendLinkRevokePath + "?token=" + url.QueryEscape(token)
+	subject := "A GitHub account was linked to your Grainlify account"
+	body := fmt.Sprintf(`The GitHub account @%s was just linked to your Grainlify account.
+
+If this was you, no action is needed.
+
+If this wasn't you, open the link below. It unlinks @%s and signs out every session
+on your account. The link is valid for 7 days.
+
+%s
+`, u.Login, u.Login, revokeURL)
+
+	// Don't hold up the OAuth redirect on the SMTP round-trip.
+	go func(to string) {
+		if err := mailer.Send(to, subject, body); err != nil {
+			slog.Error("failed to send link notification", "error", err, "user_id", userID)
+		}
+	}(*email)
+}
+
+func hashOneTimeToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+type revokeLinkRequest struct {
+	Token string `json:"token"`
+}
+
+// RevokeLink is the "this wasn't me" action from the link notification: it unlinks the
+// GitHub account named in the token and revokes all of the user's sessions. It needs no
+// JWT since the legitimate owner may no longer have a session the attacker doesn't.
+func (h *GitHubOAuthHandler) RevokeLink() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if h.db == nil || h.db.Pool == nil {
+			return apierr.Respond(c, apierr.New(fiber.StatusServiceUnavailable, "db_not_configured"))
+		}
+
+		var req revokeLinkRequest
+		if err := c.BodyParser(&req); err != nil || strings.TrimSpace(req.Token) == "" {
+			return apierr.Respond(c, apierr.New(fiber.StatusBadRequest, "token_required"))
+		}
+
+		tx, err := h.db.Pool.Begin(c.Context())
+		if err != nil {
+			return apierr.Respond(c, apierr.New(fiber.StatusInternalServerError, "link_revoke_failed"))
+		}
+		defer func() { _ = tx.Rollback(c.Context()) }()
+
+		var userID uuid.UUID
+		var githubUserID int64
+		err = tx.QueryRow(c.Context(), `
+UPDATE link_revocations
+SET used_at = now()
+WHERE token_hash = $1
+  AND used_at IS NULL
+  AND expires_at > now()
+RETURNING user_id, github_user_id
+`, hashOneTimeToken(strings.TrimSpace(req.Token))).Scan(&userID, &githubUserID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return apierr.Respond(c, apierr.New(fiber.StatusBadRequest, "invalid_or_expired_token"))
+		}
+		if err != nil {
+			return apierr.Respond(c, apierr.New(fiber.StatusInternalServerError, "link_revoke_failed"))
+		}
+
+		// Only undo the link the mail was about; a later legitimate re-link is left alone.
+		ct, err := tx.Exec(c.Context(), `DELETE FROM github_accounts WHERE user_id = $1 AND github_user_id = $2`, userID, githubUserID)
+		if err != nil {
+			return apierr.Respond(c, apierr.New(fiber.StatusInternalServerError, "link_revoke_failed"))
+		}
+		if _, err := tx.Exec(c.Context(), `
+UPDATE users SET github_user_id = NULL, updated_at = now()
+WHERE id = $1 AND github_user_id = $2
+`, userID, githubUserID); err != nil {
+			return apierr.Respond(c, apierr.New(fiber.StatusInternalServerError, "link_revoke_failed"))
+		}
+		if err := tx.Commit(c.Context()); err != nil {
+			return apierr.Respond(c, apierr.New(fiber.StatusInternalServerError, "link_revoke_failed"))
+		}
+
+		if ct.RowsAffected() > 0 {
+			if err := tokenvault.Default().Delete(c.Context(), userID); err != nil {
+				slog.Error("failed to delete token from vault after link revocation", "error", err, "user_id", userID)
+			}
+		}
+
+		if _, err := auth.RevokeAllSessions(c.Context(), h.db.Pool, userID); err != nil {
+			slog.Error("failed to revoke sessions after link revocation", "error", err, "user_id", userID)
+			return apierr.Respond(c, apierr.New(fiber.StatusInternalServerError, "session_revoke_failed"))
+		}
+
+		if err := audit.Record(c.Context(), h.db, audit.Event{
+			UserID:    &userID,
+			Action:    audit.ActionGitHubLinkRevoked,
+			IP:        clientip.FromRequest(c, h.cfg),
+			UserAgent: c.Get("User-Agent"),
+			Metadata: map[string]any{
+				"github_user_id": githubUserID,
+				"unlinked":       ct.RowsAffected() > 0,
+				"triggered_by":   "email_link",
+			},
+		}); err != nil {
+			slog.Error("failed to record audit event", "error", err, "action", audit.ActionGitHubLinkRevoked, "user_id", userID)
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"ok":               true,
+			"unlinked":         ct.RowsAffected() > 0,
+			"sessions_revoked": true,
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/jagadeesh/grainlify/backend/internal/auth"
+	"github.com/jagadeesh/grainlify/backend/internal/config"
+	"github.com/jagadeesh/grainlify/backend/internal/github"
+)
+
+// statelessLinkStatePrefix marks a signed link state. The '.' separators can't appear in
+// base64url, so decodeStateWithRedirect hands such a state through unchanged.
+const statelessLinkStatePrefix = "sl."
+
+var errInvalidLinkState = errors.New("invalid_link_state")
+
+// statelessLinkState is the content of a signed github_link state: the user starting the link,
+// the jti of the access token they started it with, and when the state stops being accepted.
+type statelessLinkState struct {
+	userID    uuid.UUID
+	jti       string
+	expiresAt time.Time
+}
+
+func (s statelessLinkState) payload() string {
+	return s.userID.String() + "|" + s.jti + "|" + strconv.FormatInt(s.expiresAt.Unix(), 10)
+}
+
+// linkStateMAC signs payload with JWT_SECRET. The label keeps these signatures apart from
+// anything else signed with the same secret.
+func linkStateMAC(secret, label, payload string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(label + "|" + payload))
+	return mac.Sum(nil)
+}
+
+// signStatelessLinkState encodes s as "sl.<payload>.<signature>".
+func signStatelessLinkState(secret string, s statelessLinkState) string {
+	p := s.payload()
+	return statelessLinkStatePrefix +
+		base64.RawURLEncoding.EncodeToString([]byte(p)) + "." +
+		base64.RawURLEncoding.EncodeToString(linkStateMAC(secret, "link-state", p))
+}
+
+func isStatelessLinkState(state string) bool {
+	return strings.HasPrefix(state, statelessLinkStatePrefix)
+}
+
+// parseStatelessLinkState checks the signature and expiry of a signed link state.
+func parseStatelessLinkState(secret, state string, now time.Time) (statelessLinkState, error) {
+	body, ok := strings.CutPrefix(state, statelessLinkStatePrefix)
+	if !ok || secret == "" {
+		return statelessLinkState{}, errInvalidLinkState
+	}
+	encPayload, encSig, ok := strings.Cut(body, ".")
+	if !ok {
+		return statelessLinkState{}, errInvalidLinkState
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encPayload)
+	if err != nil {
+		return statelessLinkState{}, errInvalidLinkState
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(encSig)
+	if err != nil || !hmac.Equal(sig, linkStateMAC(secret, "link-state", string(payload))) {
+		return statelessLinkState{}, errInvalidLinkState
+	}
+
+	parts := strings.Split(string(payload), "|")
+	if len(parts) != 3 || parts[1] == "" {
+		return statelessLinkState{}, errInvalidLinkState
+	}
+	userID, err := uuid.Parse(parts[0])
+	if err != nil {
+		return statelessLinkState{}, errInvalidLinkState
+	}
+	exp, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return statelessLinkState{}, errInvalidLinkState
+	}
+	s := statelessLinkState{userID: userID, jti: parts[1], expiresAt: time.Unix(exp, 0)}
+	if !now.Before(s.expiresAt) {
+		return statelessLinkState{}, errInvalidLinkState
+	}
+	return s, nil
+}
+
+// pkce derives the PKCE pair from the signed state, so nothing has to be stored for it either.
+// The verifier is secret (it needs JWT_SECRET) and 43 characters long, as RFC 7636 requires.
+func (s statelessLinkState) pkce(cfg config.Config) statePKCE {
+	if !cfg.GitHubOAuthUsePKCE {
+		return statePKCE{}
+	}
+	verifier := base64.RawURLEncoding.EncodeToString(linkStateMAC(cfg.JWTSecret, "link-pkce", s.payload()))
+	challenge := github.PKCEChallengeS256(verifier)
+	return statePKCE{verifier: &verifier, challenge: &challenge}
+}
+
+// statelessLinkAuthorizeURL builds the link authorize URL with a signed state bound to the
+// caller's token instead of an oauth_states row. The state expires with oauthStateTTL or the
+// token, whichever comes first.
+func statelessLinkAuthorizeURL(cfg config.Config, claims *auth.Claims, userID uuid.UUID, now time.Time) (string, error) {
+	expiresAt, err := newOAuthStateExpiry(now, oauthStateTTL)
+	if err != nil {
+		return "", errStateCreateFailed
+	}
+	if claims.ExpiresAt != nil && claims.ExpiresAt.Time.Before(expiresAt) {
+		expiresAt = claims.ExpiresAt.Time
+	}
+	s := statelessLinkState{userID: userID, jti: claims.ID, expiresAt: expiresAt}
+	state := signStatelessLinkState(cfg.JWTSecret, s)
+	return github.AuthorizeURLWithOptions(cfg.GitHubOAuthClientID, effectiveGitHubRedirect(cfg), state, cfg.GitHubScopes(), s.pkce(cfg).authorizeOptions())
+}
+
+// linkSessions answers whether the session a signed link state is bound to is still live.
+type linkSessions interface {
+	auth.RevokedTokenStore
+	SessionRevoked(ctx context.Context, userID string, issuedAt time.Time) (bool, error)
+}
+
+type dbLinkSessions struct {
+	auth.RevokedTokenStore
+	pool *pgxpool.Pool
+}
+
+func newDBLinkSessions(pool *pgxpool.Pool) dbLinkSessions {
+	return dbLinkSessions{RevokedTokenStore: auth.NewDBRevokedTokens(pool), pool: pool}
+}
+
+func (s dbLinkSessions) SessionRevoked(ctx context.Context, userID string, issuedAt time.Time) (bool, error) {
+	return auth.SessionRevoked(ctx, s.pool, userID, issuedAt)
+}
+
+// presentedSessionToken is the access token the callback request itself carries: the bearer
+// token (an SPA completing the flow with fetch) or, with SESSION_MODE=cookie, the session
+// cookie a browser sends along on GitHub's redirect. "" when there is none.
+func presentedSessionToken(c *fiber.Ctx, cfg config.Config) string {
+	if h := strings.TrimSpace(c.Get(fiber.HeaderAuthorization)); len(h) > len("bearer ") && strings.EqualFold(h[:len("bearer ")], "bearer ") {
+		return strings.TrimSpace(h[len("bearer "):])
+	}
+	if cfg.SessionCookies() {
+		return c.Cookies(auth.SessionCookieName)
+	}
+	return ""
+}
+
+// checkStatelessLinkState verifies a signed link state on the callback. The callback must
+// present the very session the state was signed for (same sub and jti), and that session must
+// not have been revoked since, by logout or by a revoke-all. Anyone else holding the state
+// can't use it to link their GitHub account into the user's.
+func checkStatelessLinkState(ctx context.Context, cfg config.Config, sessions linkSessions, state, sessionToken string) (statelessLinkState, error) {
+	s, err := parseStatelessLinkState(cfg.JWTSecret, state, time.Now())
+	if err != nil {
+		return statelessLinkState{}, err
+	}
+	if sessionToken == "" {
+		return statelessLinkState{}, errInvalidLinkState
+	}
+	claims, err := auth.ParseJWTForIssuer(cfg.JWTSecret, sessionToken, cfg.JWTIssuer)
+	if err != nil || claims.ID != s.jti || claims.Subject != s.userID.String() {
+		return statelessLinkState{}, errInvalidLinkState
+	}
+	isRevoked, err := sessions.IsTokenRevoked(ctx, s.jti)
+	if err != nil {
+		return statelessLinkState{}, err
+	}
+	if isRevoked {
+		return statelessLinkState{}, errInvalidLinkState
+	}
+	var issuedAt time.Time
+	if claims.IssuedAt != nil {
+		issuedAt = claims.IssuedAt.Time
+	}
+	revokedAll, err := sessions.SessionRevoked(ctx, claims.Subject, issuedAt)
+	if err != nil {
+		return statelessLinkState{}, err
+	}
+	if revokedAll {
+		return statelessLinkState{}, errInvalidLinkState
+	}
+	return s, nil
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/jagadeesh/grainlify/backend/internal/auth"
+	"github.com/jagadeesh/grainlify/backend/internal/config"
+	"github.com/jagadeesh/grainlify/backend/internal/github"
+)
+
+type revokedJTIs map[string]bool
+
+func (r revokedJTIs) RevokeToken(_ context.Context, jti string, _ uuid.UUID, _ time.Time) error {
+	r[jti] = true
+	return nil
+}
+
+func (r revokedJTIs) IsTokenRevoked(_ context.Context, jti string) (bool, error) {
+	return r[jti], nil
+}
+
+func TestStatelessLinkState(t *testing.T) {
+	now := time.Now()
+	s := statelessLinkState{userID: uuid.New(), jti: "jti-1", expiresAt: now.Add(10 * time.Minute).Truncate(time.Second)}
+	state := signStatelessLinkState("secret", s)
+
+	got, err := parseStatelessLinkState("secret", state, now)
+	if err != nil || got != s {
+		t.Fatalf("parse = %+v, %v; want %+v", got, err, s)
+	}
+	if _, err := parseStatelessLinkState("other-secret", state, now); !errors.Is(err, errInvalidLinkState) {
+		t.Errorf("wrong secret: err = %v", err)
+	}
+	if _, err := parseStatelessLinkState("secret", state, now.Add(time.Hour)); !errors.Is(err, errInvalidLinkState) {
+		t.Errorf("expired: err = %v", err)
+	}
+	forged := signStatelessLinkState("secret", statelessLinkState{userID: uuid.New(), jti: "jti-1", expiresAt: s.expiresAt})
+	if _, err := parseStatelessLinkState("secret", forged[:len(forged)-4]+state[len(state)-4:], now); !errors.Is(err, errInvalidLinkState) {
+		t.Errorf("tampered: err = %v", err)
+	}
+
+	cfg := config.Config{JWTSecret: "secret", GitHubOAuthUsePKCE: true}
+	p1, p2 := s.pkce(cfg), got.pkce(cfg)
+	if p1.verifier == nil || *p1.verifier != *p2.verifier || !github.VerifyPKCE(*p1.verifier, *p2.challenge) {
+		t.Error("derived PKCE pair should be stable and valid")
+	}
+}
+
+// fakeLinkSessions is a jti deny-list plus users.sessions_revoked_at for one user.
+type fakeLinkSessions struct {
+	revokedJTIs
+	revokedAllAt *time.Time
+}
+
+func (f fakeLinkSessions) SessionRevoked(_ context.Context, _ string, issuedAt time.Time) (bool, error) {
+	return f.revokedAllAt != nil && issuedAt.Unix() <= f.revokedAllAt.Unix(), nil
+}
+
+func TestCheckStatelessLinkState(t *testing.T) {
+	cfg := config.Config{JWTSecret: "secret"}
+	userID := uuid.New()
+	token, err := auth.IssueJWT(cfg.JWTSecret, userID, "contributor", "", "", time.Hour)
+	if err != nil {
+		t.Fatalf("IssueJWT: %v", err)
+	}
+	claims, err := auth.ParseJWT(cfg.JWTSecret, token)
+	if err != nil {
+		t.Fatalf("ParseJWT: %v", err)
+	}
+	s := statelessLinkState{userID: userID, jti: claims.ID, expiresAt: time.Now().Add(time.Minute)}
+	state := signStatelessLinkState(cfg.JWTSecret, s)
+	ctx := context.Background()
+	live := fakeLinkSessions{revokedJTIs: revokedJTIs{}}
+
+	if _, err := checkStatelessLinkState(ctx, cfg, live, state, token); err != nil {
+		t.Errorf("matching session: %v", err)
+	}
+	// A browser arriving from GitHub without the session (someone else holding the state).
+	if _, err := checkStatelessLinkState(ctx, cfg, live, state, ""); !errors.Is(err, errInvalidLinkState) {
+		t.Errorf("no session: err = %v", err)
+	}
+	other, _ := auth.IssueJWT(cfg.JWTSecret, userID, "contributor", "", "", time.Hour)
+	if _, err := checkStatelessLinkState(ctx, cfg, live, state, other); !errors.Is(err, errInvalidLinkState) {
+		t.Errorf("other session: err = %v", err)
+	}
+	if _, err := checkStatelessLinkState(ctx, cfg, fakeLinkSessions{revokedJTIs: revokedJTIs{claims.ID: true}}, state, token); !errors.Is(err, errInvalidLinkState) {
+		t.Errorf("revoked token: err = %v", err)
+	}
+	revokedAll := time.Now()
+	if _, err := checkStatelessLinkState(ctx, cfg, fakeLinkSessions{revokedJTIs: revokedJTIs{}, revokedAllAt: &revokedAll}, state, token); !errors.Is(err, errInvalidLinkState) {
+		t.Errorf("token issued before sessions_revoked_at: err = %v", err)
+	}
+}
+
+func TestPresentedSessionToken(t *testing.T) {
+	get := func(cfg config.Config, header, cookie string) string {
+		var got string
+		app := fiber.New()
+		app.Get("/", func(c *fiber.Ctx) error {
+			got = presentedSessionToken(c, cfg)
+			return nil
+		})
+		req := httptest.NewRequest("GET", "/", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		if cookie != "" {
+			req.Header.Set("Cookie", auth.SessionCookieName+"="+cookie)
+		}
+		if _, err := app.Test(req); err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+	cookieMode := config.Config{SessionMode: config.SessionModeCookie}
+	if got := get(config.Config{}, "Bearer abc", ""); got != "abc" {
+		t.Errorf("bearer: got %q", got)
+	}
+	if got := get(cookieMode, "", "from-cookie"); got != "from-cookie" {
+		t.Errorf("cookie mode: got %q", got)
+	}
+	if got := get(config.Config{}, "", "from-cookie"); got != "" {
+		t.Errorf("cookie outside cookie mode must be ignored, got %q", got)
+	}
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/google/uuid"
+
+	"github.com/jagadeesh/grainlify/backend/internal/github"
+)
+
+// storeLoginEmail records the user's primary verified GitHub address (from fetchLoginEmail)
+// on users.email at every login, so it follows changes made on GitHub. When GitHub has no
+// verified address (or the lookup failed) the column is left as it is.
+func (h *GitHubOAuthHandler) storeLoginEmail(ctx context.Context, email string, userID uuid.UUID) {
+	if email == "" {
+		return
+	}
+	if _, err := h.db.Pool.Exec(ctx, `UPDATE users SET email = $2 WHERE id = $1`, userID, email); err != nil {
+		slog.Warn("failed to store login email", "error", err, "user_id", userID)
+	}
+}
+
+// fetchLoginEmail fetches /user/emails and picks the primary verified address (see
+// github.PrimaryEmail). Unverified addresses are never used.
+func fetchLoginEmail(ctx context.Context, gh *github.Client, accessToken string, githubUserID int64) string {
+	emails, err := gh.GetUserEmails(ctx, accessToken)
+	if err != nil {
+		slog.Warn("failed to fetch github emails", "error", err, "github_user_id", githubUserID)
+		return ""
+	}
+	email, ok := github.PrimaryEmail(emails)
+	if !ok {
+		slog.Warn("github account has no verified email", "github_user_id", githubUserID)
+		return ""
+	}
+	if !github.EmailFitsStorage(email) {
+		slog.Warn("skipping over-long github email", "github_user_id", githubUserID, "length", len(email))
+		return ""
+	}
+	return email
+}
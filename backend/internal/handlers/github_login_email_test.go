@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jagadeesh/grainlify/backend/internal/github"
+)
+
+func TestFetchLoginEmail(t *testing.T) {
+	cases := []struct {
+		name   string
+		status int
+		body   string
+		want   string
+	}{
+		{"primary verified", http.StatusOK, `[{"email":"b@example.com","verified":true},{"email":"a@example.com","primary":true,"verified":true}]`, "a@example.com"},
+		{"only unverified", http.StatusOK, `[{"email":"a@example.com","primary":true,"verified":false}]`, ""},
+		{"endpoint fails", http.StatusForbidden, `{"message":"Resource not accessible"}`, ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.status)
+				_, _ = w.Write([]byte(tc.body))
+			}))
+			defer srv.Close()
+
+			gh := github.NewClient()
+			gh.BaseURL = srv.URL
+			if got := fetchLoginEmail(context.Background(), gh, "tok", 42); got != tc.want {
+				t.Errorf("fetchLoginEmail() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/jagadeesh/grainlify/backend/internal/audit"
+	"github.com/jagadeesh/grainlify/backend/internal/clientip"
+)
+
+// loginCandidate is an existing user a GitHub login may sign in to.
+type loginCandidate struct {
+	id   uuid.UUID
+	role string
+}
+
+// chooseLoginUser picks the account for a GitHub login: the user already holding the GitHub
+// id, else the user found by verified email (merge), else nil (create a new user).
+func chooseLoginUser(byGitHubID, byEmail *loginCandidate) (user *loginCandidate, merge bool) {
+	switch {
+	case byGitHubID != nil:
+		return byGitHubID, false
+	case byEmail != nil:
+		return byEmail, true
+	default:
+		return nil, false
+	}
+}
+
+// emailMergeCandidate finds the account a first GitHub login should be merged into: the one
+// user whose email is verifiedEmail and that has no GitHub account yet. verifiedEmail must
+// come from GitHub's verified addresses (loginEmail), never the public profile email, or
+// anyone could claim an account by putting its address on their GitHub profile. Several
+// matching users are ambiguous and merge into none.
+func (h *GitHubOAuthHandler) emailMergeCandidate(ctx context.Context, verifiedEmail string) (*loginCandidate, error) {
+	if verifiedEmail == "" {
+		return nil, nil
+	}
+	found, err := h.users.usersByEmail(ctx, verifiedEmail)
+	if err != nil {
+		return nil, err
+	}
+	if len(found) != 1 {
+		if len(found) > 1 {
+			slog.Warn("several accounts share the verified GitHub email; not merging")
+		}
+		return nil, nil
+	}
+	return &found[0], nil
+}
+
+// mergeGitHubIdentity links githubUserID to the existing user. It only claims a user that is
+// still without a GitHub account, so a concurrent link can't be overwritten.
+func (h *GitHubOAuthHandler) mergeGitHubIdentity(ctx context.Context, userID uuid.UUID, githubUserID int64) (bool, error) {
+	ok, err := h.users.claimGitHubID(ctx, userID, githubUserID)
+	if isUniqueViolation(err) {
+		// A concurrent login gave the GitHub account its own user first.
+		return false, errLoginUserRaced
+	}
+	return ok, err
+}
+
+// recordEmailMerge audits a GitHub login that was merged into an existing account by email.
+func (h *GitHubOAuthHandler) recordEmailMerge(c *fiber.Ctx, userID uuid.UUID, githubLogin string) {
+	if err := audit.Record(c.Context(), h.db, audit.Event{
+		ActorUserID: &userID,
+		UserID:      &userID,
+		Action:      audit.ActionGitHubMergedByEmail,
+		IP:          clientip.FromRequest(c, h.cfg),
+		UserAgent:   c.Get("User-Agent"),
+		Metadata:    map[string]any{"github_login": githubLogin},
+	}); err != nil {
+		slog.Error("failed to record audit event", "error", err, "action", audit.ActionGitHubMergedByEmail, "user_id", userID)
+	}
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/jagadeesh/grainlify/backend/internal/github"
+)
+
+func TestChooseLoginUser(t *testing.T) {
+	byGitHub := &loginCandidate{id: uuid.New(), role: "maintainer"}
+	byEmail := &loginCandidate{id: uuid.New(), role: "contributor"}
+
+	// New user: nothing matches, so the caller creates one.
+	if user, merge := chooseLoginUser(nil, nil); user != nil || merge {
+		t.Errorf("new user: got %v, merge=%v", user, merge)
+	}
+	// Existing GitHub id wins over any email match and is not a merge.
+	if user, merge := chooseLoginUser(byGitHub, byEmail); user != byGitHub || merge {
+		t.Errorf("existing github id: got %v, merge=%v", user, merge)
+	}
+	if user, merge := chooseLoginUser(byGitHub, nil); user != byGitHub || merge {
+		t.Errorf("existing github id without email match: got %v, merge=%v", user, merge)
+	}
+	// Email match without a GitHub match merges into that account.
+	if user, merge := chooseLoginUser(nil, byEmail); user != byEmail || !merge {
+		t.Errorf("email merge: got %v, merge=%v", user, merge)
+	}
+}
+
+func TestEmailMergeCandidateNeedsVerifiedEmail(t *testing.T) {
+	// fetchLoginEmail yields "" when GitHub has no verified address; that must never look up
+	// (let alone merge into) an account. The handler has no DB, so a lookup would panic.
+	h := &GitHubOAuthHandler{}
+	c, err := h.emailMergeCandidate(context.Background(), "")
+	if c != nil || err != nil {
+		t.Errorf("emailMergeCandidate(\"\") = %v, %v; want nil, nil", c, err)
+	}
+}
+
+func TestFirstLoginEmailMerge(t *testing.T) {
+	const ghID = 99
+	ctx := context.Background()
+	otherGitHubID := int64(7)
+
+	cases := []struct {
+		name      string
+		setup     func(s *memLoginUserStore) uuid.UUID // the account holding the email
+		extra     func(s *memLoginUserStore)
+		wantMerge bool
+	}{
+		{
+			name:      "verified email of an account without GitHub",
+			setup:     func(s *memLoginUserStore) uuid.UUID { return s.addUser("Ada@Example.com", nil) },
+			wantMerge: true,
+		},
+		{
+			name:  "email of an account already linked to another GitHub account",
+			setup: func(s *memLoginUserStore) uuid.UUID { return s.addUser("ada@example.com", &otherGitHubID) },
+		},
+		{
+			name:  "email shared by two accounts",
+			setup: func(s *memLoginUserStore) uuid.UUID { return s.addUser("ada@example.com", nil) },
+			extra: func(s *memLoginUserStore) { s.addUser("ada@example.com", nil) },
+		},
+	}
+	for _, tc := range cases {
+		store := newMemLoginUserStore()
+		existing := tc.setup(store)
+		if tc.extra != nil {
+			tc.extra(store)
+		}
+		h := &GitHubOAuthHandler{users: store}
+
+		userID, _, isNew, merged, err := h.resolveLoginUser(ctx, ghID, "", "ada@example.com")
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if merged != tc.wantMerge || (userID == existing) != tc.wantMerge || isNew == tc.wantMerge {
+			t.Errorf("%s: user %s (existing %s) isNew=%v merged=%v, want merge %v", tc.name, userID, existing, isNew, merged, tc.wantMerge)
+		}
+		if !tc.wantMerge {
+			if row := store.users[existing]; row.githubUserID != nil && *row.githubUserID == ghID {
+				t.Errorf("%s: the existing account was given the GitHub login", tc.name)
+			}
+		}
+	}
+}
+
+func TestFirstLoginUnverifiedEmailNeverMerges(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The address of an existing account, but GitHub hasn't verified it for this user.
+		_, _ = w.Write([]byte(`[{"email":"ada@example.com","primary":true,"verified":false}]`))
+	}))
+	defer srv.Close()
+	gh := github.NewClient()
+	gh.BaseURL = srv.URL
+
+	store := newMemLoginUserStore()
+	existing := store.addUser("ada@example.com", nil)
+	h := &GitHubOAuthHandler{users: store}
+
+	email := fetchLoginEmail(context.Background(), gh, "tok", 99)
+	if email != "" {
+		t.Fatalf("fetchLoginEmail = %q, want no address", email)
+	}
+	userID, _, isNew, merged, err := h.resolveLoginUser(context.Background(), 99, "", email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if merged || userID == existing || !isNew {
+		t.Errorf("user %s isNew=%v merged=%v, want a new user", userID, isNew, merged)
+	}
+	if store.users[existing].githubUserID != nil {
+		t.Error("the existing account was given the GitHub login")
+	}
+}
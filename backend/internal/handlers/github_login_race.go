@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// errLoginUserRaced means a concurrent callback for the same GitHub account created or claimed
+// the user between our lookup and our write. Resolving again finds that user.
+var errLoginUserRaced = errors.New("concurrent login claimed the github account")
+
+// loginResolution is what resolveLoginUser settles on.
+type loginResolution struct {
+	userID uuid.UUID
+	role   string
+	isNew  bool
+	merged bool
+}
+
+// retryLoginRace runs resolve, and once more if it lost a race to a concurrent login. The
+// second run sees the winner's committed row, so both callbacks agree on the user. A race lost
+// twice is returned as an error.
+func retryLoginRace[T any](resolve func() (T, error)) (T, error) {
+	v, err := resolve()
+	if errors.Is(err, errLoginUserRaced) {
+		v, err = resolve()
+	}
+	return v, err
+}
+
+// loginInsertErr maps the error of an INSERT ... ON CONFLICT DO NOTHING RETURNING: no row
+// means another login inserted the conflicting user first.
+func loginInsertErr(err error) error {
+	if errors.Is(err, pgx.ErrNoRows) || isUniqueViolation(err) {
+		return errLoginUserRaced
+	}
+	return err
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/jagadeesh/grainlify/backend/internal/github"
+)
+
+// memLoginUserStore keeps users and github_accounts in memory with the constraints the login
+// code relies on: unique users.github_user_id and external_id (inserted with ON CONFLICT DO
+// NOTHING), and github_accounts keyed on user_id with a unique github_user_id.
+type memLoginUserStore struct {
+	mu       sync.Mutex
+	users    map[uuid.UUID]*loginUser
+	emails   map[uuid.UUID]string
+	accounts map[uuid.UUID]int64
+	// afterLookup, if set, runs after every userByGitHubID, outside the lock.
+	afterLookup func()
+}
+
+func newMemLoginUserStore() *memLoginUserStore {
+	return &memLoginUserStore{users: map[uuid.UUID]*loginUser{}, emails: map[uuid.UUID]string{}, accounts: map[uuid.UUID]int64{}}
+}
+
+var errUniqueViolation = &pgconn.PgError{Code: "23505"}
+
+// addUser inserts a user directly, as if created before the login.
+func (s *memLoginUserStore) addUser(email string, githubUserID *int64) uuid.UUID {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := uuid.New()
+	s.users[id] = &loginUser{id: id, role: "contributor", githubUserID: githubUserID}
+	s.emails[id] = email
+	return id
+}
+
+func (s *memLoginUserStore) userByGitHubID(_ context.Context, githubUserID int64) (loginUser, bool, error) {
+	s.mu.Lock()
+	var u loginUser
+	var found bool
+	for _, row := range s.users {
+		if row.githubUserID != nil && *row.githubUserID == githubUserID {
+			u, found = *row, true
+		}
+	}
+	s.mu.Unlock()
+	if s.afterLookup != nil {
+		s.afterLookup()
+	}
+	return u, found, nil
+}
+
+func (s *memLoginUserStore) userByExternalID(_ context.Context, externalID string) (loginUser, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, row := range s.users {
+		if row.externalID != nil && *row.externalID == externalID {
+			return *row, true, nil
+		}
+	}
+	return loginUser{}, false, nil
+}
+
+func (s *memLoginUserStore) usersByEmail(_ context.Context, email string) ([]loginCandidate, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var found []loginCandidate
+	for id, row := range s.users {
+		if strings.EqualFold(s.emails[id], email) && row.githubUserID == nil && len(found) < 2 {
+			found = append(found, loginCandidate{id: id, role: row.role})
+		}
+	}
+	return found, nil
+}
+
+func (s *memLoginUserStore) claimGitHubID(_ context.Context, userID uuid.UUID, githubUserID int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, row := range s.users {
+		if row.githubUserID != nil && *row.githubUserID == githubUserID {
+			return false, errUniqueViolation
+		}
+	}
+	row := s.users[userID]
+	if row == nil || row.githubUserID != nil {
+		return false, nil
+	}
+	row.githubUserID = &githubUserID
+	return true, nil
+}
+
+func (s *memLoginUserStore) insertUser(_ context.Context, githubUserID int64, externalID string) (uuid.UUID, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, row := range s.users {
+		if (row.githubUserID != nil && *row.githubUserID == githubUserID) || (externalID != "" && row.externalID != nil && *row.externalID == externalID) {
+			return uuid.Nil, "", pgx.ErrNoRows
+		}
+	}
+	u := &loginUser{id: uuid.New(), role: "contributor", githubUserID: &githubUserID}
+	if externalID != "" {
+		u.externalID = &externalID
+	}
+	s.users[u.id] = u
+	return u.id, u.role, nil
+}
+
+func (s *memLoginUserStore) setExternalID(_ context.Context, userID uuid.UUID, externalID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[userID].externalID = &externalID
+	return nil
+}
+
+func (s *memLoginUserStore) upsertGitHubAccount(_ context.Context, userID uuid.UUID, u github.User, _ []byte, _ github.TokenResponse, _ bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for owner, ghID := range s.accounts {
+		if ghID == u.ID && owner != userID {
+			return errUniqueViolation
+		}
+	}
+	s.accounts[userID] = u.ID
+	return nil
+}
+
+func (s *memLoginUserStore) githubAccountOwner(_ context.Context, githubUserID int64) (uuid.UUID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for owner, ghID := range s.accounts {
+		if ghID == githubUserID {
+			return owner, nil
+		}
+	}
+	return uuid.Nil, pgx.ErrNoRows
+}
+
+func TestConcurrentFirstLoginsResolveToOneUser(t *testing.T) {
+	const ghID = 4242
+	store := newMemLoginUserStore()
+	h := &GitHubOAuthHandler{users: store}
+
+	// Both callbacks look the user up before either inserts: the window the race needs.
+	var missed sync.WaitGroup
+	missed.Add(2)
+	var lookups atomic.Int32
+	store.afterLookup = func() {
+		if lookups.Add(1) <= 2 {
+			missed.Done()
+			missed.Wait()
+		}
+	}
+
+	results := make([]loginResolution, 2)
+	errs := make([]error, 2)
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			r := &results[i]
+			r.userID, r.role, r.isNew, r.merged, errs[i] = h.resolveLoginUser(context.Background(), ghID, "", "")
+			if errs[i] == nil {
+				errs[i] = h.upsertGitHubAccount(context.Background(), r.userID, github.User{ID: ghID}, nil, github.TokenResponse{}, false)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("callback %d: %v", i, err)
+		}
+	}
+	if results[0].userID != results[1].userID {
+		t.Errorf("callbacks resolved to different users: %s, %s", results[0].userID, results[1].userID)
+	}
+	if results[0].isNew == results[1].isNew {
+		t.Errorf("exactly one callback should create the user, got isNew %v and %v", results[0].isNew, results[1].isNew)
+	}
+	if len(store.users) != 1 || len(store.accounts) != 1 {
+		t.Errorf("got %d users and %d accounts, want 1 and 1", len(store.users), len(store.accounts))
+	}
+}
+
+func TestUpsertGitHubAccountHeldByAnotherUser(t *testing.T) {
+	store := newMemLoginUserStore()
+	h := &GitHubOAuthHandler{users: store}
+	ctx := context.Background()
+	owner, other := uuid.New(), uuid.New()
+
+	if err := h.upsertGitHubAccount(ctx, owner, github.User{ID: 7}, nil, github.TokenResponse{}, false); err != nil {
+		t.Fatal(err)
+	}
+	// The owner re-linking updates its row.
+	if err := h.upsertGitHubAccount(ctx, owner, github.User{ID: 7}, nil, github.TokenResponse{}, false); err != nil {
+		t.Errorf("re-link by the owner: %v", err)
+	}
+	if err := h.upsertGitHubAccount(ctx, other, github.User{ID: 7}, nil, github.TokenResponse{}, false); !isUniqueViolation(err) {
+		t.Errorf("another user linking the account: err = %v, want a unique violation", err)
+	}
+}
+
+func TestRetryLoginRaceGivesUpAfterSecondLoss(t *testing.T) {
+	calls := 0
+	_, err := retryLoginRace(func() (loginResolution, error) {
+		calls++
+		return loginResolution{}, errLoginUserRaced
+	})
+	if !errors.Is(err, errLoginUserRaced) || calls != 2 {
+		t.Errorf("err = %v after %d calls, want errLoginUserRaced after 2", err, calls)
+	}
+}
+
+func TestLoginInsertErr(t *testing.T) {
+	if !errors.Is(loginInsertErr(pgx.ErrNoRows), errLoginUserRaced) {
+		t.Error("no row from ON CONFLICT DO NOTHING must count as a lost race")
+	}
+	if !errors.Is(loginInsertErr(&pgconn.PgError{Code: "23505"}), errLoginUserRaced) {
+		t.Error("a unique violation must count as a lost race")
+	}
+	other := &pgconn.PgError{Code: "23503"}
+	if !errors.Is(loginInsertErr(other), other) {
+		t.Error("other errors must pass through")
+	}
+	if loginInsertErr(nil) != nil {
+		t.Error("success must stay nil")
+	}
+}
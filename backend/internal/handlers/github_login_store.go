@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/jagadeesh/grainlify/backend/internal/github"
+)
+
+// loginUser is a users row as the GitHub login sees it.
+type loginUser struct {
+	id           uuid.UUID
+	role         string
+	githubUserID *int64
+	externalID   *string
+}
+
+// loginUserStore is the persistence behind resolveLoginUser and upsertGitHubAccount, so the
+// race and merge handling can be exercised without a database. Errors are passed through as
+// Postgres reports them (pgx.ErrNoRows, unique violations); the handler interprets them.
+type loginUserStore interface {
+	// userByGitHubID and userByExternalID report found=false when no user matches.
+	userByGitHubID(ctx context.Context, githubUserID int64) (u loginUser, found bool, err error)
+	userByExternalID(ctx context.Context, externalID string) (u loginUser, found bool, err error)
+	// usersByEmail returns up to two users with the address (case-insensitive) and no GitHub account.
+	usersByEmail(ctx context.Context, email string) ([]loginCandidate, error)
+	// claimGitHubID sets the user's github_user_id if it has none; false if it already had one.
+	claimGitHubID(ctx context.Context, userID uuid.UUID, githubUserID int64) (bool, error)
+	// insertUser creates a user with ON CONFLICT DO NOTHING: pgx.ErrNoRows when one exists.
+	insertUser(ctx context.Context, githubUserID int64, externalID string) (userID uuid.UUID, role string, err error)
+	setExternalID(ctx context.Context, userID uuid.UUID, externalID string) error
+	// upsertGitHubAccount writes github_accounts, keyed on user_id.
+	upsertGitHubAccount(ctx context.Context, userID uuid.UUID, u github.User, storedToken []byte, tr github.TokenResponse, forceConsent bool) error
+	githubAccountOwner(ctx context.Context, githubUserID int64) (uuid.UUID, error)
+}
+
+type dbLoginUserStore struct {
+	pool *pgxpool.Pool
+}
+
+func (s dbLoginUserStore) userByGitHubID(ctx context.Context, githubUserID int64) (loginUser, bool, error) {
+	u := loginUser{githubUserID: &githubUserID}
+	err := s.pool.QueryRow(ctx, `
+SELECT id, role, external_id
+FROM users
+WHERE github_user_id = $1
+`, githubUserID).Scan(&u.id, &u.role, &u.externalID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return loginUser{}, false, nil
+	}
+	return u, err == nil, err
+}
+
+func (s dbLoginUserStore) userByExternalID(ctx context.Context, externalID string) (loginUser, bool, error) {
+	u := loginUser{externalID: &externalID}
+	err := s.pool.QueryRow(ctx, `
+SELECT id, role, github_user_id
+FROM users
+WHERE external_id = $1
+`, externalID).Scan(&u.id, &u.role, &u.githubUserID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return loginUser{}, false, nil
+	}
+	return u, err == nil, err
+}
+
+func (s dbLoginUserStore) usersByEmail(ctx context.Context, email string) ([]loginCandidate, error) {
+	rows, err := s.pool.Query(ctx, `
+SELECT id, role
+FROM users
+WHERE lower(email) = lower($1) AND github_user_id IS NULL
+LIMIT 2
+`, email)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var found []loginCandidate
+	for rows.Next() {
+		var c loginCandidate
+		if err := rows.Scan(&c.id, &c.role); err != nil {
+			return nil, err
+		}
+		found = append(found, c)
+	}
+	return found, rows.Err()
+}
+
+func (s dbLoginUserStore) claimGitHubID(ctx context.Context, userID uuid.UUID, githubUserID int64) (bool, error) {
+	tag, err := s.pool.Exec(ctx, `
+UPDATE users SET github_user_id = $2, updated_at = now()
+WHERE id = $1 AND github_user_id IS NULL
+`, userID, githubUserID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (s dbLoginUserStore) insertUser(ctx context.Context, githubUserID int64, externalID string) (uuid.UUID, string, error) {
+	var userID uuid.UUID
+	var role string
+	err := s.pool.QueryRow(ctx, `
+INSERT INTO users (github_user_id, external_id) VALUES ($1, NULLIF($2, ''))
+ON CONFLICT DO NOTHING
+RETURNING id, role
+`, githubUserID, externalID).Scan(&userID, &role)
+	return userID, role, err
+}
+
+func (s dbLoginUserStore) setExternalID(ctx context.Context, userID uuid.UUID, externalID string) error {
+	_, err := s.pool.Exec(ctx, `
+UPDATE users SET external_id = $2, updated_at = now() WHERE id = $1
+`, userID, externalID)
+	return err
+}
+
+func (s dbLoginUserStore) upsertGitHubAccount(ctx context.Context, userID uuid.UUID, u github.User, storedToken []byte, tr github.TokenResponse, forceConsent bool) error {
+	_, err := s.pool.Exec(ctx, `
+INSERT INTO github_accounts (user_id, github_user_id, login, avatar_url, access_token, token_type, scope)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (user_id) DO UPDATE SET
+  github_user_id = EXCLUDED.github_user_id,
+  login = EXCLUDED.login,
+  avatar_url = EXCLUDED.avatar_url,
+  access_token = EXCLUDED.access_token,
+  token_type = EXCLUDED.token_type,
+  scope = EXCLUDED.scope,
+  reauth_required = false,
+  reauth_required_at = NULL,
+  -- GitHub skips the consent screen for an app that is already authorized, so a plain
+  -- re-login is not a new grant; only forced re-consent or a different account is.
+  token_granted_at = CASE
+    WHEN $8 OR github_accounts.github_user_id <> EXCLUDED.github_user_id THEN now()
+    ELSE github_accounts.token_granted_at
+  END,
+  updated_at = now()
+`, userID, u.ID, u.Login, u.AvatarURL, storedToken, tr.TokenType, tr.Scope, forceConsent)
+	return err
+}
+
+func (s dbLoginUserStore) githubAccountOwner(ctx context.Context, githubUserID int64) (uuid.UUID, error) {
+	var owner uuid.UUID
+	err := s.pool.QueryRow(ctx, `SELECT user_id FROM github_accounts WHERE github_user_id = $1`, githubUserID).Scan(&owner)
+	return owner, err
+}
@@ -1,20 +1,26 @@
 package handlers
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/base64"
 	"errors"
 	"fmt"
 	"log/slog"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 
+	"github.com/jagadeesh/grainlify/backend/internal/apierr"
+	"github.com/jagadeesh/grainlify/backend/internal/audit"
 	"github.com/jagadeesh/grainlify/backend/internal/auth"
+	"github.com/jagadeesh/grainlify/backend/internal/clientip"
 	"github.com/jagadeesh/grainlify/backend/internal/config"
 	"github.com/jagadeesh/grainlify/backend/internal/cryptox"
 	"github.com/jagadeesh/grainlify/backend/internal/db"
@@ -23,8 +29,8 @@ import (
 
 // isAllowedRedirectURI validates that a redirect URI is from an allowed origin.
 // This prevents open redirect vulnerabilities by only allowing:
-// - localhost origins (for development)
-// - *.vercel.app domains (for preview deployments)
+// - localhost origins (for development; ALLOW_LOCALHOST_ORIGINS, off by default in production)
+// - *.vercel.app domains (for preview deployments; ALLOW_VERCEL_ORIGINS, off by default in production)
 // - Explicit origins from CORS_ORIGINS config
 // - FrontendBaseURL (if configured)
 func isAllowedRedirectURI(redirectURI string, cfg config.Config) bool {
@@ -33,89 +39,80 @@ func isAllowedRedirectURI(redirectURI string, cfg config.Config) bool {
 		return false
 	}
 
-	// Extract origin (scheme + host)
-	origin := parsedURL.Scheme + "://" + parsedURL.Host
-
-	// Always allow localhost origins for development
-	if strings.HasPrefix(origin, "http://localhost:") ||
-		strings.HasPrefix(origin, "http://127.0.0.1:") ||
-		strings.HasPrefix(origin, "https://localhost:") ||
-		strings.HasPrefix(origin, "https://127.0.0.1:") {
-		return true
-	}
-
-	// Allow all Vercel preview deployments (*.vercel.app)
-	if strings.HasSuffix(origin, ".vercel.app") {
-		return true
+	// Credentials in a redirect target are never legitimate and only serve to make a URL
+	// look like it points somewhere it doesn't ("https://app.example.com@foo.vercel.app").
+	if parsedURL.User != nil {
+		return false
 	}
 
-	// Check explicit CORS origins
-	if strings.TrimSpace(cfg.CORSOrigins) != "" {
-		for _, o := range strings.Split(cfg.CORSOrigins, ",") {
-			o = strings.TrimSpace(o)
-			if o == "" {
-				continue
-			}
-			if origin == o || strings.HasPrefix(origin, o+"/") {
-				return true
-			}
-		}
+	// The denylist wins over every allow rule below, including localhost and *.vercel.app.
+	if cfg.RedirectHostDenied(parsedURL.Hostname()) {
+		return false
 	}
 
-	// If FrontendBaseURL is set, allow it
-	if cfg.FrontendBaseURL != "" {
-		if origin == cfg.FrontendBaseURL || strings.HasPrefix(origin, cfg.FrontendBaseURL+"/") {
-			return true
-		}
-	}
+	// Extract origin (scheme + host); localhost, *.vercel.app, CORS_ORIGINS, FRONTEND_BASE_URL
+	// and REDIRECT_URI_ALLOWED_PATTERNS are allowed.
+	origin := parsedURL.Scheme + "://" + parsedURL.Host
+	return cfg.OriginAllowlist().Allows(origin) || cfg.RedirectPatternAllows(origin)
+}
 
-	return false
+// scopes is what both the login and the link flow request (GITHUB_OAUTH_SCOPES).
+func (h *GitHubOAuthHandler) scopes() []string {
+	return h.cfg.GitHubScopes()
 }
 
+// frontendAuthCallbackPath is the SPA route that receives the login result. It belongs to the
+// frontend, so AUTH_ROUTE_PREFIX (which only moves our own routes) does not apply to it.
+const frontendAuthCallbackPath = "/auth/callback"
+
 type GitHubOAuthHandler struct {
-	cfg config.Config
-	db  *db.DB
+	cfg        config.Config
+	db         *db.DB
+	users      loginUserStore
+	unlinks    githubUnlinker
+	confirms   pendingLinkConfirmer
+	errorPages *oauthErrorPages
 }
 
 func NewGitHubOAuthHandler(cfg config.Config, d *db.DB) *GitHubOAuthHandler {
-	return &GitHubOAuthHandler{cfg: cfg, db: d}
+	h := &GitHubOAuthHandler{cfg: cfg, db: d, errorPages: newOAuthErrorPages(cfg)}
+	// Built here rather than on first use so /admin/cache/stats lists them from startup.
+	rawProfiles(cfg)
+	repoSummaries(cfg)
+	if d != nil && d.Pool != nil {
+		h.users = dbLoginUserStore{pool: d.Pool}
+		h.unlinks = dbGitHubUnlinker{pool: d.Pool, tokenEncKeys: cfg.TokenEncKeys()}
+		h.confirms = dbPendingLinkConfirmer{pool: d.Pool}
+	}
+	return h
 }
 
 func (h *GitHubOAuthHandler) Start() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		if h.db == nil || h.db.Pool == nil {
-			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "db_not_configured"})
+			return apierr.Respond(c, apierr.New(fiber.StatusServiceUnavailable, "db_not_configured"))
 		}
 		if h.cfg.GitHubOAuthClientID == "" || effectiveGitHubRedirect(h.cfg) == "" {
-			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "github_oauth_not_configured"})
+			return apierr.Respond(c, apierr.New(fiber.StatusServiceUnavailable, "github_oauth_not_configured"))
 		}
 
 		sub, _ := c.Locals(auth.LocalUserID).(string)
 		userID, err := uuid.Parse(sub)
 		if err != nil {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid_user"})
+			return apierr.Respond(c, apierr.New(fiber.StatusUnauthorized, "invalid_user"))
 		}
 
-		state := randomState(32)
-		expiresAt := time.Now().UTC().Add(10 * time.Minute)
-
-		_, err = h.db.Pool.Exec(c.Context(), `
-INSERT INTO oauth_states (state, user_id, kind, expires_at)
-VALUES ($1, $2, 'github_link', $3)
-`, state, userID, expiresAt)
-		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "state_create_failed"})
+		var authURL string
+		if claims, _ := c.Locals(auth.LocalClaims).(*auth.Claims); h.cfg.StatelessLinkState && claims != nil && claims.ID != "" {
+			authURL, err = statelessLinkAuthorizeURL(h.cfg, claims, userID, time.Now())
+		} else {
+			authURL, err = githubLinkAuthorizeURL(c.Context(), h.cfg, h.db, userID)
+		}
+		if errors.Is(err, errStateCreateFailed) {
+			return apierr.Respond(c, apierr.New(fiber.StatusInternalServerError, "state_create_failed"))
 		}
-
-		// Scopes:
-		// - read:user: link identity
-		// - user:email: access user email addresses
-		// - repo: access private repos + read repo metadata
-		// - admin:repo_hook: create webhooks
-		// - read:org: helps when dealing with org-owned repos
-		authURL, err := github.AuthorizeURL(h.cfg.GitHubOAuthClientID, effectiveGitHubRedirect(h.cfg), state, []string{"read:user", "user:email", "repo", "admin:repo_hook", "read:org"})
 		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "auth_url_failed"})
+			return apierr.Respond(c, apierr.New(fiber.StatusInternalServerError, "auth_url_failed"))
 		}
 
 		return c.Status(fiber.StatusOK).JSON(fiber.Map{"url": authURL})
@@ -125,13 +122,14 @@ VALUES ($1, $2, 'github_link', $3)
 // LoginStart begins GitHub-only login/signup (no prior JWT required).
 // Accepts optional 'redirect' query parameter to specify where to redirect after successful login.
 // This enables single OAuth callback URL to work with multiple frontend deployments (production, preview, etc.)
+// With ?mode=url (or Accept: application/json) it returns {"url": ...} instead of redirecting.
 func (h *GitHubOAuthHandler) LoginStart() fiber.Handler {
-	return func(c *fiber.Ctx) error {
+	return instrumentOAuth(oauthLoginStartTotal, nil, func(c *fiber.Ctx) error {
 		if h.db == nil || h.db.Pool == nil {
-			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "db_not_configured"})
+			return apierr.Respond(c, apierr.New(fiber.StatusServiceUnavailable, "db_not_configured"))
 		}
 		if h.cfg.GitHubOAuthClientID == "" || effectiveGitHubRedirect(h.cfg) == "" {
-			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "github_login_not_configured"})
+			return apierr.Respond(c, apierr.New(fiber.StatusServiceUnavailable, "github_login_not_configured"))
 		}
 
 		// Get redirect_uri from query parameter (frontend origin)
@@ -142,36 +140,78 @@ func (h *GitHubOAuthHandler) LoginStart() fiber.Handler {
 		if redirectURI != "" {
 			parsedURL, err := url.Parse(redirectURI)
 			if err != nil {
-				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_redirect_uri"})
+				return apierr.Respond(c, apierr.New(fiber.StatusBadRequest, "invalid_redirect_uri"))
 			}
 
 			// Security: Only allow redirects to whitelisted origins
 			// This prevents open redirect vulnerabilities
 			if !isAllowedRedirectURI(redirectURI, h.cfg) {
-				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-					"error":   "redirect_uri_not_allowed",
-					"message": "Redirect URI must be from an allowed origin (localhost, *.vercel.app, or configured CORS origins)",
-				})
+				return h.errorPages.respond(c, apierr.New(fiber.StatusForbidden, "redirect_uri_not_allowed").
+					WithMessage("Redirect URI must be from an allowed origin (localhost, *.vercel.app, or configured CORS origins)"))
 			}
 
 			// Ensure redirect URI uses http or https scheme
 			if parsedURL.Scheme != "http" && parsedURL.Scheme != "https" {
-				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_redirect_uri_scheme"})
+				return apierr.Respond(c, apierr.New(fiber.StatusBadRequest, "invalid_redirect_uri_scheme"))
+			}
+		}
+
+		// Multi-tenant deployments: with no explicit redirect, return the user to the host the
+		// request came in on, provided it is allowlisted. Otherwise the callback falls back to
+		// FrontendBaseURL as before.
+		if redirectURI == "" && h.cfg.RedirectDefaultFromHost {
+			if hostRedirect := c.BaseURL(); isAllowedRedirectURI(hostRedirect, h.cfg) {
+				redirectURI = hostRedirect
+				slog.Info("OAuth login start - defaulting redirect to request host", "redirect", redirectURI)
+			} else {
+				slog.Info("OAuth login start - request host not allowlisted, using configured fallback", "host", hostRedirect)
+			}
+		}
+
+		continuePath, err := sanitizeContinuePath(c.Query("continue"), h.cfg.ContinuePathPrefixes)
+		if err != nil {
+			return apierr.Respond(c, apierr.New(fiber.StatusBadRequest, "invalid_continue"))
+		}
+
+		// Keyed like the start rate limit: the proxy's address would lump every user together.
+		ip := clientip.FromRequest(c, h.cfg)
+		if h.cfg.MaxPendingStatesPerIP > 0 {
+			var pending int
+			if err := h.db.Pool.QueryRow(c.Context(), `
+SELECT count(*) FROM oauth_states WHERE client_ip = $1 AND expires_at > now()
+`, ip).Scan(&pending); err != nil {
+				slog.Error("OAuth login start - failed to count pending states", "error", err)
+				return apierr.Respond(c, apierr.New(fiber.StatusInternalServerError, "state_create_failed"))
+			}
+			if pending >= h.cfg.MaxPendingStatesPerIP {
+				slog.Warn("OAuth login start - too many pending flows", "ip", ip, "pending", pending)
+				c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(oauthStateTTL.Seconds())))
+				return apierr.Respond(c, apierr.New(fiber.StatusTooManyRequests, "too_many_pending_flows"))
 			}
 		}
 
 		// Generate CSRF token for state validation
 		csrfToken := randomState(32)
-		expiresAt := time.Now().UTC().Add(10 * time.Minute)
+		expiresAt, err := newOAuthStateExpiry(time.Now(), oauthStateTTL)
+		if err != nil {
+			slog.Error("OAuth login start - invalid state expiry", "error", err)
+			return apierr.Respond(c, apierr.New(fiber.StatusInternalServerError, "state_create_failed"))
+		}
+
+		pkce, err := newStatePKCE(h.cfg)
+		if err != nil {
+			slog.Error("OAuth login start - failed to create PKCE verifier", "error", err)
+			return apierr.Respond(c, apierr.New(fiber.StatusInternalServerError, "state_create_failed"))
+		}
 
 		// Store CSRF token in database for validation (OAuth 2.0 security requirement)
-		_, err := h.db.Pool.Exec(c.Context(), `
-INSERT INTO oauth_states (state, user_id, kind, expires_at, redirect_uri)
-VALUES ($1, NULL, 'github_login', $2, $3)
-`, csrfToken, expiresAt, redirectURI)
+		_, err = h.db.Pool.Exec(c.Context(), `
+INSERT INTO oauth_states (state, user_id, kind, expires_at, redirect_uri, client_ip, continue_path, code_verifier, code_challenge, user_agent_hash)
+VALUES ($1, NULL, 'github_login', $2, $3, $4, NULLIF($5, ''), $6, $7, $8)
+`, oauthStateKey(csrfToken), expiresAt, redirectURI, ip, continuePath, pkce.verifier, pkce.challenge, stateUserAgentHash(c, h.cfg))
 		if err != nil {
 			slog.Error("OAuth login start - failed to store state", "error", err)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "state_create_failed"})
+			return apierr.Respond(c, apierr.New(fiber.StatusInternalServerError, "state_create_failed"))
 		}
 
 		// Encode redirect_uri in state parameter (OAuth 2.0 spec recommendation)
@@ -179,20 +219,51 @@ VALUES ($1, NULL, 'github_login', $2, $3)
 		// This allows dynamic redirection while maintaining CSRF protection
 		state := encodeStateWithRedirect(csrfToken, redirectURI)
 		slog.Info("OAuth login start - encoded state with redirect",
-			"csrf_token", csrfToken,
+			"state_hash", stateLogHash(csrfToken),
 			"redirect_uri", redirectURI,
-			"encoded_state", state,
 		)
 
 		// Login scopes: identity + email + repo access for later project verification.
-		authURL, err := github.AuthorizeURL(h.cfg.GitHubOAuthClientID, effectiveGitHubRedirect(h.cfg), state, []string{"read:user", "user:email", "repo", "admin:repo_hook", "read:org"})
+		authURL, err := github.AuthorizeURLWithOptions(h.cfg.GitHubOAuthClientID, effectiveGitHubRedirect(h.cfg), state, h.scopes(), pkce.authorizeOptions())
 		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "auth_url_failed"})
+			return apierr.Respond(c, apierr.New(fiber.StatusInternalServerError, "auth_url_failed"))
+		}
+
+		// SPAs may ask for the URL and navigate themselves, as with Start.
+		if wantsAuthorizeURL(c) {
+			return c.Status(fiber.StatusOK).JSON(fiber.Map{"url": authURL})
 		}
 
 		// Redirect user to GitHub OAuth page
 		return c.Redirect(authURL, fiber.StatusFound)
+	})
+}
+
+// wantsAuthorizeURL reports whether LoginStart should answer with the authorize URL as JSON
+// instead of redirecting: ?mode=url, or an Accept header that names application/json ahead of
+// HTML. A bare */* (plain fetch, curl) keeps the redirect.
+func wantsAuthorizeURL(c *fiber.Ctx) bool {
+	switch c.Query("mode") {
+	case "url":
+		return true
+	case "redirect":
+		return false
+	}
+	if !strings.Contains(c.Get(fiber.HeaderAccept), fiber.MIMEApplicationJSON) {
+		return false
 	}
+	return c.Accepts(fiber.MIMEApplicationJSON, fiber.MIMETextHTML) == fiber.MIMEApplicationJSON
+}
+
+// tokenExchangeError maps a failed code exchange. An expired or already redeemed code
+// (GitHub's bad_verification_code) is the user's to fix by signing in again, so it's a 400
+// with its own page rather than the generic token_exchange_failed.
+func tokenExchangeError(err error) apierr.Error {
+	var oe *github.OAuthError
+	if errors.As(err, &oe) && oe.Code == "bad_verification_code" {
+		return apierr.New(fiber.StatusBadRequest, "bad_verification_code")
+	}
+	return apierr.New(fiber.StatusUnauthorized, "token_exchange_failed")
 }
 
 // CallbackUnified finishes either:
@@ -201,21 +272,26 @@ VALUES ($1, NULL, 'github_login', $2, $3)
 //
 // Recommended for production: configure ONE GitHub OAuth callback URL and point it to this handler.
 func (h *GitHubOAuthHandler) CallbackUnified() fiber.Handler {
-	return func(c *fiber.Ctx) error {
+	return instrumentOAuth(oauthLoginTotal, oauthCallbackSeconds, func(c *fiber.Ctx) error {
 		if h.db == nil || h.db.Pool == nil {
-			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "db_not_configured"})
+			return apierr.Respond(c, apierr.New(fiber.StatusServiceUnavailable, "db_not_configured"))
 		}
 		if h.cfg.GitHubOAuthClientID == "" || h.cfg.GitHubOAuthClientSecret == "" || effectiveGitHubRedirect(h.cfg) == "" {
-			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "github_oauth_not_configured"})
+			return apierr.Respond(c, apierr.New(fiber.StatusServiceUnavailable, "github_oauth_not_configured"))
 		}
 		if h.cfg.JWTSecret == "" {
-			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "jwt_not_configured"})
+			return apierr.Respond(c, apierr.New(fiber.StatusServiceUnavailable, "jwt_not_configured"))
+		}
+
+		// GitHub sends error=access_denied when the user clicks "Cancel" on the consent screen.
+		if c.Query("error") == "access_denied" {
+			return h.errorPages.respond(c, apierr.New(fiber.StatusBadRequest, "user_cancelled"))
 		}
 
 		code := c.Query("code")
 		encodedState := c.Query("state")
 		if code == "" || encodedState == "" {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "missing_code_or_state"})
+			return apierr.Respond(c, apierr.New(fiber.StatusBadRequest, "missing_code_or_state"))
 		}
 
 		// Decode state parameter to extract CSRF token and redirect_uri (OAuth 2.0 spec)
@@ -223,13 +299,13 @@ func (h *GitHubOAuthHandler) CallbackUnified() fiber.Handler {
 		if err != nil {
 			slog.Error("OAuth callback - failed to decode state",
 				"error", err,
-				"encoded_state", encodedState,
+				"encoded_state_hash", stateLogHash(encodedState),
 			)
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_state_format"})
+			return apierr.Respond(c, apierr.New(fiber.StatusBadRequest, "invalid_state_format"))
 		}
 
 		slog.Info("OAuth callback - decoded state",
-			"csrf_token", csrfToken,
+			"state_hash", stateLogHash(csrfToken),
 			"redirect_uri_from_state", redirectURIFromState,
 			"encoded_state_length", len(encodedState),
 		)
@@ -238,32 +314,68 @@ func (h *GitHubOAuthHandler) CallbackUnified() fiber.Handler {
 		var storedKind string
 		var stateUserID *uuid.UUID
 		var storedRedirectURI *string
-		err = h.db.Pool.QueryRow(c.Context(), `
-SELECT kind, user_id, redirect_uri
+		var forceConsent bool
+		var continuePath *string
+		var codeVerifier, codeChallenge *string
+		var storedUAHash *string
+		var storedState string
+		if h.cfg.StatelessLinkState && isStatelessLinkState(csrfToken) {
+			// Signed link state: no oauth_states row to look up or delete.
+			var sl statelessLinkState
+			sl, err = checkStatelessLinkState(c.Context(), h.cfg, newDBLinkSessions(h.db.Pool), csrfToken, presentedSessionToken(c, h.cfg))
+			if err == nil {
+				pkce := sl.pkce(h.cfg)
+				storedKind, stateUserID = "github_link", &sl.userID
+				codeVerifier, codeChallenge = pkce.verifier, pkce.challenge
+			} else if errors.Is(err, errInvalidLinkState) {
+				err = pgx.ErrNoRows
+			}
+		} else {
+			err = h.db.Pool.QueryRow(c.Context(), `
+SELECT state, kind, user_id, redirect_uri, force_consent, continue_path, code_verifier, code_challenge, user_agent_hash
 FROM oauth_states
-WHERE state = $1
+WHERE state = ANY($1)
   AND expires_at > now()
-`, csrfToken).Scan(&storedKind, &stateUserID, &storedRedirectURI)
+`, oauthStateKeys(csrfToken)).Scan(&storedState, &storedKind, &stateUserID, &storedRedirectURI, &forceConsent, &continuePath, &codeVerifier, &codeChallenge, &storedUAHash)
+			if err == nil && !oauthStateMatches(storedState, csrfToken) {
+				err = pgx.ErrNoRows
+			}
+		}
 		if errors.Is(err, pgx.ErrNoRows) {
 			slog.Warn("OAuth callback - state not found or expired",
-				"csrf_token", csrfToken,
-				"encoded_state", encodedState,
+				"state_hash", stateLogHash(csrfToken),
 			)
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_or_expired_state"})
+			h.recordCallbackFailure(c, storedKind, nil, "invalid_or_expired_state")
+			return h.errorPages.respond(c, apierr.New(fiber.StatusBadRequest, "invalid_or_expired_state"))
 		}
 		if err != nil {
 			slog.Error("OAuth callback - database error during state lookup",
 				"error", err,
-				"csrf_token", csrfToken,
-				"encoded_state", encodedState,
+				"state_hash", stateLogHash(csrfToken),
+			)
+			return apierr.Respond(c, apierr.New(fiber.StatusInternalServerError, "state_lookup_failed"))
+		}
+
+		if h.cfg.StateBindUserAgent && !stateUserAgentMatches(storedUAHash, c.Get(fiber.HeaderUserAgent)) {
+			slog.Warn("OAuth callback - browser differs from the one that started the login", "kind", storedKind)
+			h.recordCallbackFailure(c, storedKind, stateUserID, "state_user_agent_mismatch")
+			return h.errorPages.respond(c, apierr.New(fiber.StatusForbidden, "state_user_agent_mismatch"))
+		}
+
+		if h.cfg.StrictRedirectMatch && !strictRedirectMatches(redirectURIFromState, storedRedirectURI) {
+			slog.Warn("OAuth callback - redirect does not match the one recorded at login start",
+				"redirect_uri_from_state", redirectURIFromState,
+				"stored_redirect_uri", storedRedirectURI,
 			)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "state_lookup_failed"})
+			h.recordCallbackFailure(c, storedKind, stateUserID, "redirect_uri_mismatch")
+			return h.errorPages.respond(c, apierr.New(fiber.StatusForbidden, "redirect_uri_mismatch"))
 		}
 
 		// Use redirect_uri from state parameter (OAuth 2.0 spec), fallback to database if not in state
 		// Priority: state parameter > database > config
 		// IMPORTANT: Validate redirect_uri from state parameter for security (prevent open redirect)
 		var finalRedirectURI string
+		redirectSource := redirectSourceNone
 		if redirectURIFromState != "" {
 			// Security: Validate redirect_uri from state parameter against allowed origins
 			if !isAllowedRedirectURI(redirectURIFromState, h.cfg) {
@@ -272,12 +384,11 @@ WHERE state = $1
 					"allowed_origins", h.cfg.CORSOrigins,
 					"frontend_base_url", h.cfg.FrontendBaseURL,
 				)
-				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-					"error":   "redirect_uri_not_allowed",
-					"message": "Redirect URI from state parameter is not from an allowed origin",
-				})
+				return h.errorPages.respond(c, apierr.New(fiber.StatusForbidden, "redirect_uri_not_allowed").
+					WithMessage("Redirect URI from state parameter is not from an allowed origin"))
 			}
 			finalRedirectURI = redirectURIFromState
+			redirectSource = redirectSourceState
 			slog.Info("OAuth callback - using redirect_uri from state parameter",
 				"redirect_uri", finalRedirectURI,
 				"kind", storedKind,
@@ -291,6 +402,7 @@ WHERE state = $1
 				// Don't reject, just log and fall through to config
 			} else {
 				finalRedirectURI = *storedRedirectURI
+				redirectSource = redirectSourceDB
 				slog.Info("OAuth callback - using redirect_uri from database (fallback)",
 					"redirect_uri", finalRedirectURI,
 					"kind", storedKind,
@@ -309,78 +421,133 @@ WHERE state = $1
 		}
 
 		// Delete used state to prevent replay attacks
-		_, _ = h.db.Pool.Exec(c.Context(), `DELETE FROM oauth_states WHERE state = $1`, csrfToken)
+		if storedState != "" {
+			_, _ = h.db.Pool.Exec(c.Context(), `DELETE FROM oauth_states WHERE state = $1`, storedState)
+		}
+
+		verifier, ok := checkStatePKCE(codeVerifier, codeChallenge)
+		if !ok {
+			slog.Warn("OAuth callback - PKCE verifier does not match stored challenge", "kind", storedKind)
+			h.recordCallbackFailure(c, storedKind, stateUserID, "invalid_or_expired_state")
+			return h.errorPages.respond(c, apierr.New(fiber.StatusBadRequest, "invalid_or_expired_state"))
+		}
 
-		tr, err := github.ExchangeCode(c.Context(), code, github.OAuthConfig{
+		tr, err := github.ExchangeCodeWithVerifier(c.Context(), code, verifier, github.OAuthConfig{
 			ClientID:     h.cfg.GitHubOAuthClientID,
 			ClientSecret: h.cfg.GitHubOAuthClientSecret,
 			RedirectURL:  effectiveGitHubRedirect(h.cfg),
 		})
 		if err != nil {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "token_exchange_failed"})
+			slog.Warn("OAuth callback - code exchange failed", "kind", storedKind, "error", err)
+			e := tokenExchangeError(err)
+			h.recordCallbackFailure(c, storedKind, stateUserID, e.Code)
+			return h.errorPages.respond(c, e)
 		}
-
-		encKey, err := cryptox.KeyFromB64(h.cfg.TokenEncKeyB64)
-		if err != nil {
-			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "token_encryption_not_configured"})
+		if missing := missingRequiredScopes(h.cfg, tr.Scope); len(missing) > 0 {
+			slog.Warn("OAuth callback - token lacks required scopes", "kind", storedKind, "granted", tr.Scope, "missing", missing)
+			h.recordCallbackFailure(c, storedKind, stateUserID, "insufficient_scope")
+			return h.respondInsufficientScope(c, missing)
 		}
-		encToken, err := cryptox.EncryptAESGCM(encKey, []byte(tr.AccessToken))
-		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "token_encrypt_failed"})
+
+		// With STORE_GITHUB_TOKEN=false encToken stays nil: the token is only used below to
+		// fetch the user and is dropped when the request ends.
+		var encToken []byte
+		if h.cfg.StoreGitHubToken {
+			keys, err := cryptox.NewKeyring(h.cfg.TokenEncKeys())
+			if err != nil {
+				return apierr.Respond(c, apierr.New(fiber.StatusServiceUnavailable, "token_encryption_not_configured"))
+			}
+			encToken, err = keys.Encrypt([]byte(tr.AccessToken))
+			if err != nil {
+				return apierr.Respond(c, apierr.New(fiber.StatusInternalServerError, "token_encrypt_failed"))
+			}
 		}
 
-		gh := github.NewClient()
+		// Login fires a burst of calls at GitHub; ride out secondary rate limits instead of failing.
+		gh := github.NewClient(github.WithRateLimitRetry(h.cfg.GitHubRateLimitRetries, h.cfg.GitHubRateLimitMaxWait))
 		u, err := gh.GetUser(c.Context(), tr.AccessToken)
 		if err != nil {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "github_user_fetch_failed"})
+			return apierr.Respond(c, apierr.New(fiber.StatusUnauthorized, "github_user_fetch_failed"))
+		}
+		if err := u.ClampForStorage(); err != nil {
+			slog.Warn("GitHub OAuth callback - login exceeds storage limit", "github_user_id", u.ID, "login_length", len(u.Login))
+			return apierr.Respond(c, apierr.New(fiber.StatusUnprocessableEntity, "github_login_too_long"))
 		}
 
 		var userID uuid.UUID
 		var role string
+		var previousLinkedID *int64
+		var isNewUser bool
+		var loginEmail string // primary verified address, "" when GitHub has none
 		switch storedKind {
 		case "github_login":
-			// Create-or-find user by github_user_id.
-			err = h.db.Pool.QueryRow(c.Context(), `
-SELECT id, role
-FROM users
-WHERE github_user_id = $1
-`, u.ID).Scan(&userID, &role)
-			if errors.Is(err, pgx.ErrNoRows) {
-				err = h.db.Pool.QueryRow(c.Context(), `
-INSERT INTO users (github_user_id) VALUES ($1)
-RETURNING id, role
-`, u.ID).Scan(&userID, &role)
+			externalID := h.externalID(c)
+			if refuse, err := h.refuseSignup(c.Context(), u, externalID); err != nil {
+				return apierr.Respond(c, apierr.New(fiber.StatusInternalServerError, "user_upsert_failed"))
+			} else if refuse {
+				slog.Warn("GitHub login - account too new for signup", "github_user_id", u.ID, "created_at", u.CreatedAt)
+				return h.errorPages.respond(c, apierr.New(fiber.StatusForbidden, "github_account_too_new"))
+			}
+			// Only verified addresses: the email may merge this login into an existing account.
+			loginEmail = fetchLoginEmail(c.Context(), gh, tr.AccessToken, u.ID)
+			// Create-or-find user by github_user_id (and external id, when fronted by an IdP).
+			var merged bool
+			userID, role, isNewUser, merged, err = h.resolveLoginUser(c.Context(), u.ID, externalID, loginEmail)
+			if errors.Is(err, errExternalIDConflict) {
+				slog.Warn("GitHub login - external id conflict", "github_user_id", u.ID)
+				return apierr.Respond(c, apierr.New(fiber.StatusConflict, "external_id_conflict"))
 			}
 			if err != nil {
-				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "user_upsert_failed"})
+				return apierr.Respond(c, apierr.New(fiber.StatusInternalServerError, "user_upsert_failed"))
+			}
+			if !isNewUser && !forceConsent && h.consentExpired(c.Context(), userID) {
+				return h.startReconsent(c, tr.AccessToken, finalRedirectURI, continuePath, userID)
+			}
+			if merged {
+				h.recordEmailMerge(c, userID, u.Login)
+			}
+			h.storeLoginEmail(c.Context(), loginEmail, userID)
+			if h.cfg.NotifyOnLink {
+				h.captureNotificationEmail(c.Context(), loginEmail, userID)
 			}
 		case "github_link":
 			if stateUserID == nil {
-				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_state_user"})
+				return apierr.Respond(c, apierr.New(fiber.StatusBadRequest, "invalid_state_user"))
 			}
 			userID = *stateUserID
+			// Don't link (or park) a token we can't show works: it must be live for this app
+			// and belong to the account GetUser returned.
+			if err := h.verifyLinkToken(c.Context(), tr.AccessToken, u.ID); err != nil {
+				slog.Warn("GitHub link - token verification failed", "error", err, "user_id", userID, "github_user_id", u.ID)
+				return apierr.Respond(c, apierr.New(fiber.StatusUnauthorized, "token_verification_failed"))
+			}
 			// Fetch role for JWT issuance.
 			if err := h.db.Pool.QueryRow(c.Context(), `SELECT role FROM users WHERE id = $1`, userID).Scan(&role); err != nil {
-				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "user_lookup_failed"})
+				return apierr.Respond(c, apierr.New(fiber.StatusInternalServerError, "user_lookup_failed"))
 			}
+			if h.cfg.RequireLinkConfirmation {
+				// Park the authorization; nothing is linked until the user confirms via ConfirmLink.
+				return h.storePendingLink(c, userID, u, encToken, tr)
+			}
+			previousLinkedID = previousGitHubUserID(c.Context(), h.db.Pool, userID)
 		default:
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "wrong_state_kind"})
+			return apierr.Respond(c, apierr.New(fiber.StatusBadRequest, "wrong_state_kind"))
 		}
 
-		_, err = h.db.Pool.Exec(c.Context(), `
-INSERT INTO github_accounts (user_id, github_user_id, login, avatar_url, access_token, token_type, scope)
-VALUES ($1, $2, $3, $4, $5, $6, $7)
-ON CONFLICT (user_id) DO UPDATE SET
-  github_user_id = EXCLUDED.github_user_id,
-  login = EXCLUDED.login,
-  avatar_url = EXCLUDED.avatar_url,
-  access_token = EXCLUDED.access_token,
-  token_type = EXCLUDED.token_type,
-  scope = EXCLUDED.scope,
-  updated_at = now()
-`, userID, u.ID, u.Login, u.AvatarURL, encToken, tr.TokenType, tr.Scope)
+		err = h.storeGitHubAccount(c.Context(), userID, u, encToken, tr, forceConsent)
+		if errors.Is(err, errTokenVaultWrite) {
+			return apierr.Respond(c, apierr.New(fiber.StatusInternalServerError, "token_store_failed"))
+		}
 		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "github_account_upsert_failed"})
+			// github_user_id is unique: this GitHub account is already linked to another user.
+			if isUniqueViolation(err) {
+				return apierr.Respond(c, apierr.New(fiber.StatusConflict, "github_account_already_linked"))
+			}
+			return apierr.Respond(c, apierr.New(fiber.StatusInternalServerError, "github_account_upsert_failed"))
+		}
+
+		if forceConsent {
+			h.recordReconsent(c, userID, u.Login)
 		}
 
 		// Ensure users.github_user_id is set (idempotent).
@@ -388,11 +555,34 @@ ON CONFLICT (user_id) DO UPDATE SET
 UPDATE users SET github_user_id = $2, updated_at = now() WHERE id = $1
 `, userID, u.ID)
 
+		if storedKind == "github_link" {
+			h.recordLink(c, userID, u.Login)
+			h.notifyGitHubLinked(c.Context(), userID, previousLinkedID, u)
+		}
+
 		// For login: issue JWT. For link: we can optionally redirect without token.
 		if storedKind == "github_login" {
-			jwtToken, err := auth.IssueJWT(h.cfg.JWTSecret, userID, role, "", "", 60*time.Minute)
+			if h.cfg.SingleSessionPerUser {
+				h.endPriorSessions(c, userID)
+			}
+			jwtToken, err := h.issueAccessToken(c.Context(), userID, role)
 			if err != nil {
-				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "token_issue_failed"})
+				return apierr.Respond(c, apierr.New(fiber.StatusInternalServerError, "token_issue_failed"))
+			}
+			h.recordLogin(c, userID, u.Login, isNewUser)
+			h.emitLoginEvent(userID, u.Login, isNewUser, clientip.FromRequest(c, h.cfg))
+
+			// Optional OIDC-style identity assertion so clients can render the profile without calling /me.
+			var idToken string
+			if h.cfg.IssueIDToken {
+				email := loginEmail
+				if email == "" {
+					email = u.Email
+				}
+				idToken, err = auth.IssueIDToken(h.cfg.JWTSecret, userID, u.Login, email, u.AvatarURL, h.cfg.IDTokenTTL)
+				if err != nil {
+					return apierr.Respond(c, apierr.New(fiber.StatusInternalServerError, "id_token_issue_failed"))
+				}
 			}
 
 			// Determine redirect URL priority (OAuth 2.0 spec: use state parameter):
@@ -406,7 +596,7 @@ UPDATE users SET github_user_id = $2, updated_at = now() WHERE id = $1
 			if finalRedirectURI != "" {
 				// Use the redirect_uri from state parameter (OAuth 2.0 spec)
 				// This is the primary source and should always be used when available
-				redirectURL = strings.TrimSuffix(finalRedirectURI, "/") + "/auth/callback"
+				redirectURL = withFrontendCallbackPath(finalRedirectURI)
 				slog.Info("OAuth redirect - using redirect_uri from state parameter",
 					"redirect_url", redirectURL,
 					"final_redirect_uri", finalRedirectURI,
@@ -420,18 +610,16 @@ UPDATE users SET github_user_id = $2, updated_at = now() WHERE id = $1
 				}
 
 				if h.cfg.GitHubLoginSuccessRedirectURL != "" && !isLocalhost(h.cfg.GitHubLoginSuccessRedirectURL) {
-					// If GitHubLoginSuccessRedirectURL doesn't already include /auth/callback, append it
-					redirectURL = strings.TrimSuffix(h.cfg.GitHubLoginSuccessRedirectURL, "/")
-					if !strings.HasSuffix(redirectURL, "/auth/callback") {
-						redirectURL = redirectURL + "/auth/callback"
-					}
+					redirectURL = withFrontendCallbackPath(h.cfg.GitHubLoginSuccessRedirectURL)
+					redirectSource = redirectSourceConfig
 					slog.Warn("OAuth redirect - using GitHubLoginSuccessRedirectURL (fallback - redirect_uri from state was empty)",
 						"redirect_url", redirectURL,
 						"redirect_uri_from_state", redirectURIFromState,
 						"stored_redirect_uri", storedRedirectURI,
 					)
 				} else if h.cfg.FrontendBaseURL != "" && !isLocalhost(h.cfg.FrontendBaseURL) {
-					redirectURL = strings.TrimSuffix(h.cfg.FrontendBaseURL, "/") + "/auth/callback"
+					redirectURL = withFrontendCallbackPath(h.cfg.FrontendBaseURL)
+					redirectSource = redirectSourceFrontendBase
 					slog.Warn("OAuth redirect - using FrontendBaseURL (fallback - redirect_uri from state was empty)",
 						"redirect_url", redirectURL,
 						"frontend_base_url", h.cfg.FrontendBaseURL,
@@ -442,7 +630,8 @@ UPDATE users SET github_user_id = $2, updated_at = now() WHERE id = $1
 					// Last resort: allow localhost only if explicitly in config (for development)
 					// But log a warning that redirect_uri should have been provided
 					if h.cfg.FrontendBaseURL != "" {
-						redirectURL = strings.TrimSuffix(h.cfg.FrontendBaseURL, "/") + "/auth/callback"
+						redirectURL = withFrontendCallbackPath(h.cfg.FrontendBaseURL)
+						redirectSource = redirectSourceFrontendBase
 						slog.Error("OAuth redirect - WARNING: Using localhost fallback (redirect_uri from state was empty)",
 							"redirect_url", redirectURL,
 							"redirect_uri_from_state", redirectURIFromState,
@@ -461,20 +650,31 @@ UPDATE users SET github_user_id = $2, updated_at = now() WHERE id = $1
 				}
 			}
 
+			logRedirectDecision(c, redirectSource, redirectURL, storedKind, userID)
+
 			// Always redirect if we have a URL (this overrides GitHub's Homepage URL)
 			if redirectURL != "" {
-				ru, err := url.Parse(redirectURL)
+				ru, err := parseLoginRedirectURL(redirectURL)
 				if err != nil {
-					slog.Error("OAuth redirect - failed to parse redirect URL", "error", err, "redirect_url", redirectURL)
-					// Fall through to JSON response
+					// Never 302 to a malformed URL; the JSON response below still carries the token.
+					slog.Error("OAuth redirect - unusable redirect URL, falling back to JSON response",
+						"error", err,
+						"redirect_url", redirectURL,
+					)
+				} else if h.cfg.SessionCookies() {
+					return redirectWithSessionCookie(c, h.cfg, ru, jwtToken, userID, continuePath)
+				} else if h.cfg.LoginCookieHandoff {
+					return h.redirectWithLoginHandoff(c, ru, userID, u.Login, idToken, continuePath)
 				} else {
-					// Ensure the path is set correctly (should be /auth/callback)
-					if ru.Path == "" || ru.Path == "/" {
-						ru.Path = "/auth/callback"
-					}
 					q := ru.Query()
 					q.Set("token", jwtToken)
+					if idToken != "" {
+						q.Set("id_token", idToken)
+					}
 					q.Set("github", u.Login)
+					if continuePath != nil {
+						q.Set("continue", *continuePath)
+					}
 					ru.RawQuery = q.Encode()
 					finalRedirectURL := ru.String()
 					slog.Info("OAuth redirect - redirecting user",
@@ -486,7 +686,7 @@ UPDATE users SET github_user_id = $2, updated_at = now() WHERE id = $1
 				}
 			}
 
-			return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			resp := fiber.Map{
 				"token": jwtToken,
 				"user": fiber.Map{
 					"id":   userID.String(),
@@ -497,10 +697,36 @@ UPDATE users SET github_user_id = $2, updated_at = now() WHERE id = $1
 					"login":      u.Login,
 					"avatar_url": u.AvatarURL,
 				},
-			})
+			}
+			if idToken != "" {
+				resp["id_token"] = idToken
+			}
+			if continuePath != nil {
+				resp["continue"] = *continuePath
+			}
+			if h.cfg.SessionCookies() {
+				auth.SetSessionCookie(c, jwtToken, h.cfg.JWTAccessTTL)
+			}
+			return c.Status(fiber.StatusOK).JSON(resp)
 		}
 
 		// github_link behavior (no new token required).
+		// A scope upgrade carries the page the user started from; send them back there.
+		if finalRedirectURI != "" {
+			if ru, err := url.Parse(finalRedirectURI); err == nil {
+				logRedirectDecision(c, redirectSource, finalRedirectURI, storedKind, userID)
+				q := ru.Query()
+				q.Set("linked", "true")
+				q.Set("github", u.Login)
+				ru.RawQuery = q.Encode()
+				return c.Redirect(ru.String(), fiber.StatusFound)
+			}
+		}
+		if h.cfg.GitHubOAuthSuccessRedirectURL != "" {
+			logRedirectDecision(c, redirectSourceConfig, h.cfg.GitHubOAuthSuccessRedirectURL, storedKind, userID)
+		} else {
+			logRedirectDecision(c, redirectSourceNone, "", storedKind, userID)
+		}
 		if h.cfg.GitHubOAuthSuccessRedirectURL != "" {
 			ru, err := url.Parse(h.cfg.GitHubOAuthSuccessRedirectURL)
 			if err == nil {
@@ -520,55 +746,151 @@ UPDATE users SET github_user_id = $2, updated_at = now() WHERE id = $1
 				"avatar_url": u.AvatarURL,
 			},
 		})
+	})
+}
+
+// Where the post-callback redirect came from, reported by logRedirectDecision.
+const (
+	redirectSourceState        = "state"
+	redirectSourceDB           = "db"
+	redirectSourceConfig       = "config"
+	redirectSourceFrontendBase = "frontend_base"
+	redirectSourceNone         = "none"
+)
+
+// logRedirectDecision emits the one line that explains where a user was sent after the callback.
+// resolvedURL is logged before the token is attached.
+func logRedirectDecision(c *fiber.Ctx, source, resolvedURL, kind string, userID uuid.UUID) {
+	slog.Info("oauth_redirect_decision",
+		"source", source,
+		"resolved_url", resolvedURL,
+		"kind", kind,
+		"user_id", userID,
+		"request_id", c.Locals("requestid"),
+	)
+}
+
+// withFrontendCallbackPath appends the SPA callback path to base unless base already ends with it,
+// so "https://app.example.com/auth/callback/" does not become ".../auth/callback/auth/callback".
+func withFrontendCallbackPath(base string) string {
+	u, err := url.Parse(strings.TrimSpace(base))
+	if err != nil {
+		trimmed := strings.TrimSuffix(base, "/")
+		if strings.HasSuffix(trimmed, frontendAuthCallbackPath) {
+			return trimmed
+		}
+		return trimmed + frontendAuthCallbackPath
+	}
+	p := strings.TrimRight(u.Path, "/")
+	if !strings.HasSuffix(p, frontendAuthCallbackPath) {
+		p += frontendAuthCallbackPath
+	}
+	u.Path = p
+	u.RawPath = ""
+	return u.String()
+}
+
+// parseLoginRedirectURL validates the resolved post-login redirect before we issue a 302.
+// The URL must parse, use http or https, and have a non-empty host; an empty path is
+// normalized to /auth/callback.
+func parseLoginRedirectURL(redirectURL string) (*url.URL, error) {
+	ru, err := url.Parse(strings.TrimSpace(redirectURL))
+	if err != nil {
+		return nil, err
+	}
+	if ru.Scheme != "http" && ru.Scheme != "https" {
+		return nil, fmt.Errorf("redirect url scheme must be http or https")
+	}
+	if ru.Host == "" || ru.Hostname() == "" {
+		return nil, fmt.Errorf("redirect url has no host")
 	}
+	// Ensure the path is set correctly (should be /auth/callback)
+	if ru.Path == "" || ru.Path == "/" {
+		ru.Path = frontendAuthCallbackPath
+	}
+	return ru, nil
 }
 
 func effectiveGitHubRedirect(cfg config.Config) string {
+	redirect, _ := ResolveGitHubRedirect(cfg)
+	return redirect
+}
+
+// Config sources ResolveGitHubRedirect reports.
+const (
+	GitHubRedirectSourceOAuthURL   = "GITHUB_OAUTH_REDIRECT_URL"
+	GitHubRedirectSourceLoginURL   = "GITHUB_LOGIN_REDIRECT_URL"
+	GitHubRedirectSourcePublicBase = "PUBLIC_BASE_URL"
+)
+
+// ResolveGitHubRedirect returns the OAuth callback URL sent to GitHub and the setting it came
+// from, or two empty strings when none is configured. GitHub rejects the authorization with
+// redirect_uri_mismatch unless this matches the app settings exactly.
+func ResolveGitHubRedirect(cfg config.Config) (redirect, source string) {
 	// Recommended: set GITHUB_OAUTH_REDIRECT_URL to the full callback URL
 	// Example: http://localhost:8080/auth/github/login/callback
 	// This must match exactly what's registered in your GitHub OAuth app settings
 	if strings.TrimSpace(cfg.GitHubOAuthRedirectURL) != "" {
-		return strings.TrimSpace(cfg.GitHubOAuthRedirectURL)
+		return strings.TrimSpace(cfg.GitHubOAuthRedirectURL), GitHubRedirectSourceOAuthURL
 	}
 	// Fallback to GitHubLoginRedirectURL for backwards compatibility
 	if strings.TrimSpace(cfg.GitHubLoginRedirectURL) != "" {
-		return strings.TrimSpace(cfg.GitHubLoginRedirectURL)
+		return strings.TrimSpace(cfg.GitHubLoginRedirectURL), GitHubRedirectSourceLoginURL
 	}
-	// If neither is set and we have PublicBaseURL, construct it
+	// If neither is set and we have PublicBaseURL, construct it (honoring AUTH_ROUTE_PREFIX)
 	if cfg.PublicBaseURL != "" {
 		baseURL := strings.TrimSuffix(cfg.PublicBaseURL, "/")
-		return baseURL + "/auth/github/login/callback"
+		return baseURL + cfg.AuthPath("/auth/github/login/callback"), GitHubRedirectSourcePublicBase
+	}
+	return "", ""
+}
+
+// GitHubRedirectDebug reports the resolved GitHub OAuth callback URL and which setting produced
+// it, for chasing redirect_uri_mismatch errors.
+func (h *AdminHandler) GitHubRedirectDebug() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		redirect, source := ResolveGitHubRedirect(h.cfg)
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"redirect_url":      redirect,
+			"source":            source,
+			"configured":        redirect != "",
+			"auth_route_prefix": h.cfg.AuthRoutePrefix,
+		})
 	}
-	return ""
 }
 
 func (h *GitHubOAuthHandler) Status() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		if h.db == nil || h.db.Pool == nil {
-			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "db_not_configured"})
+			return apierr.Respond(c, apierr.New(fiber.StatusServiceUnavailable, "db_not_configured"))
 		}
 
 		sub, _ := c.Locals(auth.LocalUserID).(string)
 		userID, err := uuid.Parse(sub)
 		if err != nil {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid_user"})
+			return apierr.Respond(c, apierr.New(fiber.StatusUnauthorized, "invalid_user"))
 		}
 
 		var githubUserID int64
 		var login string
 		var avatarURL *string
-		err = h.db.Pool.QueryRow(c.Context(), `
-SELECT github_user_id, login, avatar_url
+		var scope *string
+		var reauthRequired, tokenStored bool
+		err = h.db.WithRetry(c.Context(), func(ctx context.Context) error {
+			return h.db.Pool.QueryRow(ctx, `
+SELECT github_user_id, login, avatar_url, scope, reauth_required, access_token IS NOT NULL
 FROM github_accounts
 WHERE user_id = $1
-`, userID).Scan(&githubUserID, &login, &avatarURL)
+`, userID).Scan(&githubUserID, &login, &avatarURL, &scope, &reauthRequired, &tokenStored)
+		})
 		if errors.Is(err, pgx.ErrNoRows) {
 			return c.Status(fiber.StatusOK).JSON(fiber.Map{
-				"linked": false,
+				"linked":           false,
+				"requested_scopes": h.scopes(),
 			})
 		}
 		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "status_failed"})
+			return apierr.Respond(c, apierr.New(fiber.StatusInternalServerError, "status_failed"))
 		}
 
 		githubMap := fiber.Map{
@@ -578,11 +900,48 @@ WHERE user_id = $1
 		if avatarURL != nil && *avatarURL != "" {
 			githubMap["avatar_url"] = *avatarURL
 		}
-		return c.Status(fiber.StatusOK).JSON(fiber.Map{
-			"linked": true,
-			"github": githubMap,
-		})
+		// Lets the settings UI show "granted X of Y" and prompt a targeted re-link.
+		granted := []string{}
+		if scope != nil {
+			granted = github.SplitScopes(*scope)
+		}
+		resp := fiber.Map{
+			"linked":           true,
+			"github":           githubMap,
+			"requested_scopes": h.scopes(),
+			"granted_scopes":   granted,
+			"missing_scopes":   github.MissingScopes(h.scopes(), granted),
+			"reauth_required":  reauthRequired,
+			"token_stored":     tokenStored,
+		}
+		// Opt-in: costs a GitHub API call against the user's rate limit (cached briefly).
+		if wantsInclude(c, "raw_profile") {
+			if linked, err := github.GetLinkedAccount(c.Context(), h.db.Pool, userID, h.cfg.TokenEncKeys()); err == nil {
+				if raw, err := rawGitHubProfile(c.Context(), h.cfg, github.NewClient(), userID, linked.AccessToken); err == nil {
+					resp["raw_profile"] = raw
+				}
+			}
+		}
+		return c.Status(fiber.StatusOK).JSON(resp)
+	}
+}
+
+const (
+	oauthStateTTL = 10 * time.Minute
+	// Mirrors the oauth_states_expires_at_bound CHECK constraint.
+	maxOAuthStateLifetime = time.Hour
+)
+
+var errOAuthStateExpiryOutOfBounds = errors.New("oauth state expiry out of bounds")
+
+// newOAuthStateExpiry returns the expires_at for a state created now, refusing values that
+// are already past or further out than maxOAuthStateLifetime.
+func newOAuthStateExpiry(now time.Time, ttl time.Duration) (time.Time, error) {
+	expiresAt := now.UTC().Add(ttl)
+	if !expiresAt.After(now) || expiresAt.After(now.Add(maxOAuthStateLifetime)) {
+		return time.Time{}, errOAuthStateExpiryOutOfBounds
 	}
+	return expiresAt, nil
 }
 
 func randomState(n int) string {
@@ -610,6 +969,10 @@ func encodeStateWithRedirect(csrfToken, redirectURI string) string {
 // - Old format: state is just the CSRF token (base64-encoded random string from randomState)
 // - New format: state is base64(csrf_token|redirect_uri)
 func decodeStateWithRedirect(encodedState string) (string, string, error) {
+	if encodedState == "" {
+		return "", "", fmt.Errorf("empty state")
+	}
+
 	// Try to decode as base64
 	decoded, err := base64.RawURLEncoding.DecodeString(encodedState)
 	if err != nil {
@@ -620,8 +983,14 @@ func decodeStateWithRedirect(encodedState string) (string, string, error) {
 
 	decodedStr := string(decoded)
 	parts := strings.SplitN(decodedStr, "|", 2)
-	if len(parts) == 2 {
+	// A plain randomState decodes to random bytes that may contain '|'; only text is the
+	// csrf|redirect format.
+	if len(parts) == 2 && utf8.ValidString(decodedStr) {
 		// New format: csrf_token|redirect_uri
+		if parts[0] == "" {
+			// Never look up `WHERE state = ''` on attacker-controlled input.
+			return "", "", fmt.Errorf("empty csrf token in state")
+		}
 		return parts[0], parts[1], nil
 	}
 
@@ -632,3 +1001,100 @@ func decodeStateWithRedirect(encodedState string) (string, string, error) {
 	// This handles backward compatibility with old OAuth flows
 	return encodedState, "", nil
 }
+
+// verifyLinkToken confirms a freshly exchanged token via GitHub's token check endpoint.
+func (h *GitHubOAuthHandler) verifyLinkToken(ctx context.Context, accessToken string, githubUserID int64) error {
+	check, err := github.CheckToken(ctx, github.OAuthConfig{
+		ClientID:     h.cfg.GitHubOAuthClientID,
+		ClientSecret: h.cfg.GitHubOAuthClientSecret,
+	}, accessToken)
+	if err != nil {
+		return err
+	}
+	return check.VerifyFor(githubUserID)
+}
+
+// issueAccessToken mints the access token handed out at the end of a GitHub login.
+func (h *GitHubOAuthHandler) issueAccessToken(ctx context.Context, userID uuid.UUID, role string) (string, error) {
+	return issueLoginToken(ctx, h.cfg, userID, role)
+}
+
+// issueLoginToken mints the access token handed out at the end of any OAuth login.
+func issueLoginToken(ctx context.Context, cfg config.Config, userID uuid.UUID, role string) (string, error) {
+	return auth.IssueJWT(cfg.JWTSecret, userID, role, "", "", cfg.JWTAccessTTL, auth.WithIssuer(cfg.JWTIssuer), auth.WithScopes(cfg.ScopesForRole(role)), auth.WithExtraClaims(cfg.JWTExtraClaims()), auth.WithContext(ctx))
+}
+
+// recordLogin appends the login to the user's login history (audit_events rows with
+// ActionLogin, pruned after LOGIN_HISTORY_RETENTION_DAYS).
+func (h *GitHubOAuthHandler) recordLogin(c *fiber.Ctx, userID uuid.UUID, githubLogin string, isNewUser bool) {
+	if err := recordAudit(c.Context(), h.db, audit.Event{
+		ActorUserID: &userID,
+		UserID:      &userID,
+		Action:      audit.ActionLogin,
+		IP:          clientip.FromRequest(c, h.cfg),
+		UserAgent:   c.Get("User-Agent"),
+		Metadata:    map[string]any{"github_login": githubLogin, "is_new_user": isNewUser},
+	}); err != nil {
+		slog.Error("failed to record audit event", "error", err, "action", audit.ActionLogin, "user_id", userID)
+	}
+}
+
+// endPriorSessions enforces SINGLE_SESSION_PER_USER: every token issued before this login is
+// rejected from now on. Failure is logged rather than blocking the login.
+func (h *GitHubOAuthHandler) endPriorSessions(c *fiber.Ctx, userID uuid.UUID) {
+	if err := auth.RevokeSessionsIssuedBefore(c.Context(), h.db.Pool, userID, time.Now()); err != nil {
+		slog.Error("failed to end prior sessions", "error", err, "user_id", userID)
+		return
+	}
+	if err := audit.Record(c.Context(), h.db, audit.Event{
+		ActorUserID: &userID,
+		UserID:      &userID,
+		Action:      audit.ActionSessionsRevoked,
+		IP:          clientip.FromRequest(c, h.cfg),
+		UserAgent:   c.Get("User-Agent"),
+		Metadata:    map[string]any{"triggered_by": "single_session_login"},
+	}); err != nil {
+		slog.Error("failed to record audit event", "error", err, "action", audit.ActionSessionsRevoked, "user_id", userID)
+	}
+}
+
+// strictRedirectMatches implements STRICT_REDIRECT_MATCH: a redirect carried in the state
+// parameter must be byte-for-byte the one stored with the state, so no redirect the server
+// didn't record is ever used. States that carry none (link flows keep theirs only in the row)
+// pass; the stored redirect or the configured default is used as before.
+func strictRedirectMatches(fromState string, stored *string) bool {
+	if fromState == "" {
+		return true
+	}
+	return stored != nil && *stored == fromState
+}
+
+// storeGitHubAccount puts the encrypted token in the token vault and upserts the account with
+// what the vault returned (the ciphertext, or a reference to it). A failed upsert discards the
+// vault entry again, so the account the row still points at keeps its token.
+func (h *GitHubOAuthHandler) storeGitHubAccount(ctx context.Context, userID uuid.UUID, u github.User, encToken []byte, tr github.TokenResponse, forceConsent bool) error {
+	storedToken, err := putVaultToken(ctx, userID, encToken)
+	if err != nil {
+		return err
+	}
+	if err := h.upsertGitHubAccount(ctx, userID, u, storedToken, tr, forceConsent); err != nil {
+		discardVaultToken(ctx, userID, storedToken)
+		return err
+	}
+	return nil
+}
+
+// upsertGitHubAccount stores the GitHub account and token for userID. Two concurrent callbacks
+// for the same user can both miss the ON CONFLICT (user_id) arbiter and trip the unique
+// github_user_id instead; when the row that won is this user's, the upsert is run again and
+// updates it. A unique violation returned from here means another user holds the account.
+func (h *GitHubOAuthHandler) upsertGitHubAccount(ctx context.Context, userID uuid.UUID, u github.User, storedToken []byte, tr github.TokenResponse, forceConsent bool) error {
+	err := h.users.upsertGitHubAccount(ctx, userID, u, storedToken, tr, forceConsent)
+	if !isUniqueViolation(err) {
+		return err
+	}
+	if owner, lookupErr := h.users.githubAccountOwner(ctx, u.ID); lookupErr != nil || owner != userID {
+		return err
+	}
+	return h.users.upsertGitHubAccount(ctx, userID, u, storedToken, tr, forceConsent)
+}
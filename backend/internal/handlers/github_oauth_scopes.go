@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/jagadeesh/grainlify/backend/internal/apierr"
+	"github.com/jagadeesh/grainlify/backend/internal/config"
+	"github.com/jagadeesh/grainlify/backend/internal/github"
+)
+
+// missingRequiredScopes returns the scopes of cfg.RequiredGitHubScopes the granted scope
+// string doesn't cover. A token granted without them (the user or an org policy narrowed the
+// grant) is refused instead of stored.
+func missingRequiredScopes(cfg config.Config, granted string) []string {
+	return github.MissingScopes(cfg.RequiredGitHubScopes(), github.SplitScopes(granted))
+}
+
+// respondInsufficientScope answers a callback whose token lacks required scopes: 403
+// insufficient_scope with the missing list for API clients, the error page for browsers.
+func (h *GitHubOAuthHandler) respondInsufficientScope(c *fiber.Ctx, missing []string) error {
+	e := apierr.New(fiber.StatusForbidden, "insufficient_scope").
+		WithMessage("GitHub did not grant: " + strings.Join(missing, ", "))
+	if c.Accepts(fiber.MIMEApplicationJSON, fiber.MIMETextHTML) == fiber.MIMETextHTML {
+		return h.errorPages.respond(c, e)
+	}
+	body := e.Body()
+	body["missing_scopes"] = missing
+	c.Locals(apierr.LocalCode, e.Code)
+	return c.Status(e.HTTPStatus).JSON(body)
+}
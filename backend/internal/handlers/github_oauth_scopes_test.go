@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/jagadeesh/grainlify/backend/internal/auth"
+	"github.com/jagadeesh/grainlify/backend/internal/config"
+)
+
+func TestMissingRequiredScopes(t *testing.T) {
+	cfg := config.Config{PublicBaseURL: "https://api.example.com"}
+	cases := map[string]string{
+		"repo,admin:repo_hook,read:org,read:user,user:email": "",
+		"repo admin:repo_hook":                               "",
+		"public_repo,admin:repo_hook,read:user":              "repo",
+		"repo,write:repo_hook":                               "admin:repo_hook",
+		"":                                                   "repo,admin:repo_hook",
+	}
+	for granted, want := range cases {
+		if got := strings.Join(missingRequiredScopes(cfg, granted), ","); got != want {
+			t.Errorf("missingRequiredScopes(%q) = %q, want %q", granted, got, want)
+		}
+	}
+	// Without PUBLIC_BASE_URL no webhooks are registered, so admin:repo_hook isn't required.
+	if got := missingRequiredScopes(config.Config{}, "repo"); len(got) != 0 {
+		t.Errorf("without webhooks: missing = %v, want none", got)
+	}
+}
+
+func TestCustomScopesReachAuthorizeURL(t *testing.T) {
+	cfg := config.Config{
+		JWTSecret:           "secret",
+		GitHubOAuthClientID: "client",
+		PublicBaseURL:       "https://api.example.com",
+		GitHubOAuthScopes:   []string{"repo", "admin:repo_hook", "read:user"},
+	}
+	claims := &auth.Claims{}
+	claims.ID = "jti-1"
+	authURL, err := statelessLinkAuthorizeURL(cfg, claims, uuid.New(), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := u.Query().Get("scope"); got != "repo admin:repo_hook read:user" {
+		t.Errorf("scope = %q, want the configured list", got)
+	}
+	if got := (&GitHubOAuthHandler{cfg: cfg}).scopes(); strings.Join(got, ",") != "repo,admin:repo_hook,read:user" {
+		t.Errorf("h.scopes() = %v", got)
+	}
+}
+
+func TestRespondInsufficientScope(t *testing.T) {
+	h := &GitHubOAuthHandler{errorPages: newOAuthErrorPages(config.Config{})}
+	app := fiber.New()
+	app.Get("/cb", func(c *fiber.Ctx) error {
+		return h.respondInsufficientScope(c, missingRequiredScopes(config.Config{PublicBaseURL: "https://api.example.com"}, "public_repo"))
+	})
+
+	req := httptest.NewRequest("GET", "/cb", nil)
+	req.Header.Set("Accept", "application/json")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusForbidden {
+		t.Fatalf("status = %d, want 403", resp.StatusCode)
+	}
+	var body struct {
+		Error         string   `json:"error"`
+		MissingScopes []string `json:"missing_scopes"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Error != "insufficient_scope" || strings.Join(body.MissingScopes, ",") != "repo,admin:repo_hook" {
+		t.Errorf("body = %+v", body)
+	}
+
+	req = httptest.NewRequest("GET", "/cb", nil)
+	req.Header.Set("Accept", "text/html")
+	resp, err = app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != fiber.StatusForbidden || !strings.Contains(string(page), "approve all requested permissions") {
+		t.Errorf("browser got %d %q", resp.StatusCode, page)
+	}
+}
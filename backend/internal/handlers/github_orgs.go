@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/jagadeesh/grainlify/backend/internal/apierr"
+	"github.com/jagadeesh/grainlify/backend/internal/auth"
+	"github.com/jagadeesh/grainlify/backend/internal/github"
+)
+
+// ListOrgs lists the GitHub organizations the user belongs to, with their membership role,
+// so the dashboard can tell which orgs the user can manage through us.
+func (h *GitHubOAuthHandler) ListOrgs() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if h.db == nil || h.db.Pool == nil {
+			return apierr.Respond(c, apierr.New(fiber.StatusServiceUnavailable, "db_not_configured"))
+		}
+
+		sub, _ := c.Locals(auth.LocalUserID).(string)
+		userID, err := uuid.Parse(sub)
+		if err != nil {
+			return apierr.Respond(c, apierr.New(fiber.StatusUnauthorized, "invalid_user"))
+		}
+
+		page := c.QueryInt("page", 1)
+		if page < 1 {
+			page = 1
+		}
+		perPage := c.QueryInt("per_page", 30)
+		if perPage < 1 || perPage > 100 {
+			perPage = 30
+		}
+
+		linked, err := github.GetLinkedAccount(c.Context(), h.db.Pool, userID, h.cfg.TokenEncKeys())
+		if isDeadGitHubToken(err) {
+			return respondReauthRequired(c, h.cfg, h.db, userID, err)
+		}
+		if errors.Is(err, github.ErrTokenNotStored) {
+			return apierr.Respond(c, apierr.New(fiber.StatusForbidden, "token_not_stored"))
+		}
+		if err != nil {
+			return apierr.Respond(c, apierr.New(fiber.StatusNotFound, "github_not_linked"))
+		}
+
+		memberships, hasNext, err := github.NewClient().ListOrgMembershipsPage(c.Context(), linked.AccessToken, page, perPage)
+		if err != nil {
+			if isDeadGitHubToken(err) {
+				return respondReauthRequired(c, h.cfg, h.db, userID, err)
+			}
+			slog.Warn("failed to list github org memberships", "error", err, "user_id", userID)
+			return apierr.Respond(c, apierr.New(fiber.StatusBadGateway, "github_orgs_fetch_failed"))
+		}
+
+		orgs := make([]fiber.Map, 0, len(memberships))
+		for _, m := range memberships {
+			// GitHub calls org owners "admin".
+			role := m.Role
+			if role == "admin" {
+				role = "owner"
+			}
+			orgs = append(orgs, fiber.Map{
+				"id":          m.Organization.ID,
+				"login":       m.Organization.Login,
+				"avatar_url":  m.Organization.AvatarURL,
+				"description": m.Organization.Description,
+				"role":        role,
+				"state":       m.State,
+				"can_admin":   m.CanAdmin(),
+			})
+		}
+
+		resp := fiber.Map{
+			"orgs":     orgs,
+			"page":     page,
+			"per_page": perPage,
+		}
+		if hasNext {
+			resp["next_page"] = page + 1
+		}
+		return c.Status(fiber.StatusOK).JSON(resp)
+	}
+}
+
+// ListOrgRepos lists the repositories of every GitHub org the user belongs to, so org-owned
+// repos can be picked for import. Orgs that refuse the token (e.g. SAML SSO not yet authorized
+// for it) are listed in skipped_orgs rather than failing the request.
+func (h *GitHubOAuthHandler) ListOrgRepos() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if h.db == nil || h.db.Pool == nil {
+			return apierr.Respond(c, apierr.New(fiber.StatusServiceUnavailable, "db_not_configured"))
+		}
+
+		sub, _ := c.Locals(auth.LocalUserID).(string)
+		userID, err := uuid.Parse(sub)
+		if err != nil {
+			return apierr.Respond(c, apierr.New(fiber.StatusUnauthorized, "invalid_user"))
+		}
+
+		linked, err := github.GetLinkedAccount(c.Context(), h.db.Pool, userID, h.cfg.TokenEncKeys())
+		if isDeadGitHubToken(err) {
+			return respondReauthRequired(c, h.cfg, h.db, userID, err)
+		}
+		if errors.Is(err, github.ErrTokenNotStored) {
+			return apierr.Respond(c, apierr.New(fiber.StatusForbidden, "token_not_stored"))
+		}
+		if err != nil {
+			return apierr.Respond(c, apierr.New(fiber.StatusNotFound, "github_not_linked"))
+		}
+
+		found, skipped, err := github.NewClient().ListUserOrgRepos(c.Context(), linked.AccessToken, github.ListOrgReposOptions{Type: c.Query("type")})
+		if err != nil {
+			if isDeadGitHubToken(err) {
+				return respondReauthRequired(c, h.cfg, h.db, userID, err)
+			}
+			slog.Warn("failed to list github org repos", "error", err, "user_id", userID)
+			return apierr.Respond(c, apierr.New(fiber.StatusBadGateway, "github_org_repos_fetch_failed"))
+		}
+
+		repos := make([]fiber.Map, 0, len(found))
+		for _, r := range found {
+			repos = append(repos, fiber.Map{
+				"id":          r.ID,
+				"full_name":   r.FullName,
+				"org":         r.Owner.Login,
+				"html_url":    r.HTMLURL,
+				"description": r.Description,
+				"private":     r.Private,
+				"permission":  r.Permissions.Level(),
+			})
+		}
+		if skipped == nil {
+			skipped = []string{}
+		}
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"repos":        repos,
+			"skipped_orgs": skipped,
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"github.com/jagadeesh/grainlify/backend/internal/config"
+	"github.com/jagadeesh/grainlify/backend/internal/github"
+)
+
+// statePKCE is the PKCE pair stored with an oauth_states row. Both are nil when
+// GITHUB_OAUTH_USE_PKCE is off, which leaves the state and the authorize URL as before.
+type statePKCE struct {
+	verifier  *string
+	challenge *string
+}
+
+func newStatePKCE(cfg config.Config) (statePKCE, error) {
+	if !cfg.GitHubOAuthUsePKCE {
+		return statePKCE{}, nil
+	}
+	verifier, err := github.NewPKCEVerifier()
+	if err != nil {
+		return statePKCE{}, err
+	}
+	challenge := github.PKCEChallengeS256(verifier)
+	return statePKCE{verifier: &verifier, challenge: &challenge}, nil
+}
+
+func (p statePKCE) authorizeOptions() github.AuthorizeOptions {
+	if p.challenge == nil {
+		return github.AuthorizeOptions{}
+	}
+	return github.AuthorizeOptions{CodeChallenge: *p.challenge}
+}
+
+// checkStatePKCE validates the pair read back from oauth_states and returns the verifier to
+// send with the token exchange ("" for a state created without PKCE). A verifier that no
+// longer matches its challenge means the row was tampered with.
+func checkStatePKCE(verifier, challenge *string) (string, bool) {
+	if verifier == nil && challenge == nil {
+		return "", true
+	}
+	if verifier == nil || challenge == nil || !github.VerifyPKCE(*verifier, *challenge) {
+		return "", false
+	}
+	return *verifier, true
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/jagadeesh/grainlify/backend/internal/config"
+)
+
+func TestStatePKCE(t *testing.T) {
+	off, err := newStatePKCE(config.Config{})
+	if err != nil || off.verifier != nil || off.challenge != nil || off.authorizeOptions().CodeChallenge != "" {
+		t.Fatalf("PKCE disabled must produce nothing, got %+v, %v", off, err)
+	}
+	if v, ok := checkStatePKCE(nil, nil); !ok || v != "" {
+		t.Fatalf("state without PKCE must pass with no verifier")
+	}
+
+	p, err := newStatePKCE(config.Config{GitHubOAuthUsePKCE: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.authorizeOptions().CodeChallenge != *p.challenge {
+		t.Fatal("authorize options must carry the stored challenge")
+	}
+	if v, ok := checkStatePKCE(p.verifier, p.challenge); !ok || v != *p.verifier {
+		t.Fatalf("stored pair must round-trip, got %q, %v", v, ok)
+	}
+
+	b := []byte(*p.challenge)
+	b[0] ^= 1
+	tampered := string(b)
+	if _, ok := checkStatePKCE(p.verifier, &tampered); ok {
+		t.Fatal("tampered challenge accepted")
+	}
+	if _, ok := checkStatePKCE(p.verifier, nil); ok {
+		t.Fatal("verifier without challenge accepted")
+	}
+}
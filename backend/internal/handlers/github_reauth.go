@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/jagadeesh/grainlify/backend/internal/config"
+	"github.com/jagadeesh/grainlify/backend/internal/db"
+	"github.com/jagadeesh/grainlify/backend/internal/github"
+)
+
+var errStateCreateFailed = errors.New("state_create_failed")
+
+// githubLinkAuthorizeURL creates a github_link state for userID and returns the GitHub
+// authorize URL that starts (re-)linking. Shared by Start and the reauth_required response.
+func githubLinkAuthorizeURL(ctx context.Context, cfg config.Config, d *db.DB, userID uuid.UUID) (string, error) {
+	return githubLinkAuthorizeURLFor(ctx, cfg, d, userID, cfg.GitHubScopes(), "")
+}
+
+// githubLinkAuthorizeURLFor is githubLinkAuthorizeURL with an explicit scope set, and an
+// optional redirectURI the link callback returns the user to instead of
+// GitHubOAuthSuccessRedirectURL. redirectURI must already be validated.
+func githubLinkAuthorizeURLFor(ctx context.Context, cfg config.Config, d *db.DB, userID uuid.UUID, scopes []string, redirectURI string) (string, error) {
+	state := randomState(32)
+	expiresAt, err := newOAuthStateExpiry(time.Now(), oauthStateTTL)
+	if err != nil {
+		return "", errStateCreateFailed
+	}
+
+	var storedRedirect *string
+	if redirectURI != "" {
+		storedRedirect = &redirectURI
+	}
+	pkce, err := newStatePKCE(cfg)
+	if err != nil {
+		return "", errStateCreateFailed
+	}
+	_, err = d.Pool.Exec(ctx, `
+INSERT INTO oauth_states (state, user_id, kind, expires_at, redirect_uri, code_verifier, code_challenge)
+VALUES ($1, $2, 'github_link', $3, $4, $5, $6)
+`, oauthStateKey(state), userID, expiresAt, storedRedirect, pkce.verifier, pkce.challenge)
+	if err != nil {
+		return "", errStateCreateFailed
+	}
+
+	return github.AuthorizeURLWithOptions(cfg.GitHubOAuthClientID, effectiveGitHubRedirect(cfg), state, scopes, pkce.authorizeOptions())
+}
+
+// isDeadGitHubToken reports whether err means the user's stored GitHub token can no longer be used.
+func isDeadGitHubToken(err error) bool {
+	return errors.Is(err, github.ErrReauthRequired) || github.IsBadCredentials(err)
+}
+
+// respondReauthRequired answers an interactive request whose GitHub token is dead with
+// reauth_required plus an authorize_url the frontend can send the user to directly.
+// A token GitHub just rejected is flagged so later calls fail fast without hitting GitHub.
+func respondReauthRequired(c *fiber.Ctx, cfg config.Config, d *db.DB, userID uuid.UUID, cause error) error {
+	if github.IsBadCredentials(cause) {
+		if err := github.MarkReauthRequired(c.Context(), d.Pool, userID); err != nil {
+			slog.Error("failed to mark github reauth required", "error", err, "user_id", userID)
+		}
+	}
+
+	resp := fiber.Map{"error": "reauth_required"}
+	if cfg.GitHubOAuthClientID != "" && effectiveGitHubRedirect(cfg) != "" {
+		if authURL, err := githubLinkAuthorizeURL(c.Context(), cfg, d, userID); err == nil {
+			resp["authorize_url"] = authURL
+		} else {
+			slog.Warn("failed to build github reauth url", "error", err, "user_id", userID)
+		}
+	}
+	return c.Status(fiber.StatusForbidden).JSON(resp)
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/jagadeesh/grainlify/backend/internal/apierr"
+	"github.com/jagadeesh/grainlify/backend/internal/audit"
+	"github.com/jagadeesh/grainlify/backend/internal/clientip"
+	"github.com/jagadeesh/grainlify/backend/internal/github"
+)
+
+// consentExpired reports whether userID last went through GitHub's consent screen more than
+// FORCE_REAUTH_INTERVAL ago. Users without a linked account have nothing to re-confirm.
+func (h *GitHubOAuthHandler) consentExpired(ctx context.Context, userID uuid.UUID) bool {
+	if h.cfg.ForceReauthInterval <= 0 {
+		return false
+	}
+	var grantedAt time.Time
+	if err := h.db.Pool.QueryRow(ctx, `
+SELECT token_granted_at FROM github_accounts WHERE user_id = $1
+`, userID).Scan(&grantedAt); err != nil {
+		return false
+	}
+	return time.Since(grantedAt) > h.cfg.ForceReauthInterval
+}
+
+// startReconsent restarts the login so the user sees GitHub's consent screen again. GitHub
+// skips that screen while the app is still authorized and has no prompt value to force it, so
+// the grant is revoked first; prompt=select_account additionally lets the user confirm the
+// account. The new state is marked force_consent so the callback records the re-consent.
+func (h *GitHubOAuthHandler) startReconsent(c *fiber.Ctx, accessToken, redirectURI string, continuePath *string, userID uuid.UUID) error {
+	oauthCfg := github.OAuthConfig{
+		ClientID:     h.cfg.GitHubOAuthClientID,
+		ClientSecret: h.cfg.GitHubOAuthClientSecret,
+	}
+	if err := github.RevokeGrant(c.Context(), oauthCfg, accessToken); err != nil {
+		slog.Error("GitHub re-consent - failed to revoke grant", "error", err, "user_id", userID)
+		return apierr.Respond(c, apierr.New(fiber.StatusBadGateway, "reconsent_start_failed"))
+	}
+
+	csrfToken := randomState(32)
+	expiresAt, err := newOAuthStateExpiry(time.Now(), oauthStateTTL)
+	if err != nil {
+		return apierr.Respond(c, apierr.New(fiber.StatusInternalServerError, "state_create_failed"))
+	}
+	pkce, err := newStatePKCE(h.cfg)
+	if err != nil {
+		return apierr.Respond(c, apierr.New(fiber.StatusInternalServerError, "state_create_failed"))
+	}
+	if _, err := h.db.Pool.Exec(c.Context(), `
+INSERT INTO oauth_states (state, user_id, kind, expires_at, redirect_uri, force_consent, client_ip, continue_path, code_verifier, code_challenge, user_agent_hash)
+VALUES ($1, NULL, 'github_login', $2, $3, true, $4, $5, $6, $7, $8)
+`, oauthStateKey(csrfToken), expiresAt, redirectURI, clientip.FromRequest(c, h.cfg), continuePath, pkce.verifier, pkce.challenge, stateUserAgentHash(c, h.cfg)); err != nil {
+		slog.Error("GitHub re-consent - failed to store state", "error", err, "user_id", userID)
+		return apierr.Respond(c, apierr.New(fiber.StatusInternalServerError, "state_create_failed"))
+	}
+
+	opts := pkce.authorizeOptions()
+	opts.Prompt = github.PromptSelectAccount
+	authURL, err := github.AuthorizeURLWithOptions(h.cfg.GitHubOAuthClientID, effectiveGitHubRedirect(h.cfg),
+		encodeStateWithRedirect(csrfToken, redirectURI), h.scopes(), opts)
+	if err != nil {
+		return apierr.Respond(c, apierr.New(fiber.StatusInternalServerError, "auth_url_failed"))
+	}
+	slog.Info("GitHub re-consent required, restarting authorization", "user_id", userID, "interval", h.cfg.ForceReauthInterval.String())
+	return c.Redirect(authURL, fiber.StatusFound)
+}
+
+// recordReconsent audits a completed forced re-consent.
+func (h *GitHubOAuthHandler) recordReconsent(c *fiber.Ctx, userID uuid.UUID, githubLogin string) {
+	if err := audit.Record(c.Context(), h.db, audit.Event{
+		ActorUserID: &userID,
+		UserID:      &userID,
+		Action:      audit.ActionGitHubReconsent,
+		IP:          clientip.FromRequest(c, h.cfg),
+		UserAgent:   c.Get("User-Agent"),
+		Metadata:    map[string]any{"github_login": githubLogin, "interval": h.cfg.ForceReauthInterval.String()},
+	}); err != nil {
+		slog.Error("failed to record audit event", "error", err, "action", audit.ActionGitHubReconsent, "user_id", userID)
+	}
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/jagadeesh/grainlify/backend/internal/apierr"
+	"github.com/jagadeesh/grainlify/backend/internal/auth"
+	"github.com/jagadeesh/grainlify/backend/internal/github"
+)
+
+// SearchRepos searches the linked user's own GitHub repositories by name (?q=), so pickers
+// don't have to page through every repo.
+func (h *GitHubOAuthHandler) SearchRepos() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if h.db == nil || h.db.Pool == nil {
+			return apierr.Respond(c, apierr.New(fiber.StatusServiceUnavailable, "db_not_configured"))
+		}
+
+		sub, _ := c.Locals(auth.LocalUserID).(string)
+		userID, err := uuid.Parse(sub)
+		if err != nil {
+			return apierr.Respond(c, apierr.New(fiber.StatusUnauthorized, "invalid_user"))
+		}
+
+		term, err := github.SanitizeSearchTerm(c.Query("q"))
+		if err != nil {
+			return apierr.Respond(c, apierr.New(fiber.StatusBadRequest, "invalid_query"))
+		}
+		page := c.QueryInt("page", 1)
+		if page < 1 {
+			page = 1
+		}
+		perPage := c.QueryInt("per_page", 30)
+		if perPage < 1 || perPage > 100 {
+			perPage = 30
+		}
+
+		linked, err := github.GetLinkedAccount(c.Context(), h.db.Pool, userID, h.cfg.TokenEncKeys())
+		if isDeadGitHubToken(err) {
+			return respondReauthRequired(c, h.cfg, h.db, userID, err)
+		}
+		if errors.Is(err, github.ErrTokenNotStored) {
+			return apierr.Respond(c, apierr.New(fiber.StatusForbidden, "token_not_stored"))
+		}
+		if err != nil {
+			return apierr.Respond(c, apierr.New(fiber.StatusNotFound, "github_not_linked"))
+		}
+
+		result, hasNext, err := github.NewClient().SearchUserRepos(c.Context(), linked.AccessToken, linked.Login, term, page, perPage)
+		if err != nil {
+			if isDeadGitHubToken(err) {
+				return respondReauthRequired(c, h.cfg, h.db, userID, err)
+			}
+			if github.IsSearchRateLimited(err) {
+				return respondSearchRateLimited(c, err)
+			}
+			slog.Warn("github repo search failed", "error", err, "user_id", userID)
+			return apierr.Respond(c, apierr.New(fiber.StatusBadGateway, "github_search_failed"))
+		}
+
+		repos := make([]fiber.Map, 0, len(result.Items))
+		for _, r := range result.Items {
+			repos = append(repos, fiber.Map{
+				"id":          r.ID,
+				"full_name":   r.FullName,
+				"html_url":    r.HTMLURL,
+				"description": r.Description,
+				"private":     r.Private,
+				"stars":       r.StargazersCount,
+			})
+		}
+
+		resp := fiber.Map{
+			"repos":              repos,
+			"total_count":        result.TotalCount,
+			"incomplete_results": result.IncompleteResults,
+			"page":               page,
+			"per_page":           perPage,
+		}
+		if hasNext {
+			resp["next_page"] = page + 1
+		}
+		return c.Status(fiber.StatusOK).JSON(resp)
+	}
+}
+
+// respondSearchRateLimited reports GitHub's search throttling separately from our own
+// rate_limited, with a Retry-After when GitHub says when the budget resets.
+func respondSearchRateLimited(c *fiber.Ctx, err error) error {
+	body := fiber.Map{"error": "search_rate_limited"}
+	var apiErr *github.GitHubAPIError
+	if errors.As(err, &apiErr) && apiErr.RateLimitResetUnix != nil {
+		if wait := time.Until(time.Unix(*apiErr.RateLimitResetUnix, 0)); wait > 0 {
+			secs := int(wait.Seconds()) + 1
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(secs))
+			body["retry_after"] = secs
+		}
+	}
+	return c.Status(fiber.StatusTooManyRequests).JSON(body)
+}
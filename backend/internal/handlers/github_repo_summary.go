@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/jagadeesh/grainlify/backend/internal/apierr"
+	"github.com/jagadeesh/grainlify/backend/internal/auth"
+	"github.com/jagadeesh/grainlify/backend/internal/cache"
+	"github.com/jagadeesh/grainlify/backend/internal/config"
+	"github.com/jagadeesh/grainlify/backend/internal/github"
+)
+
+type repoSummary struct {
+	Public  int
+	Private *int
+}
+
+// Counts change rarely; a short cache keeps dashboard reloads from costing a GitHub call each.
+var (
+	repoSummaryCacheOnce sync.Once
+	repoSummaryCache     *cache.LRU[uuid.UUID, repoSummary]
+)
+
+// repoSummaries returns the repo summary cache, built from the CACHE_* settings on first use.
+func repoSummaries(cfg config.Config) *cache.LRU[uuid.UUID, repoSummary] {
+	repoSummaryCacheOnce.Do(func() {
+		repoSummaryCache = cache.New[uuid.UUID, repoSummary](cfg.CacheMaxEntries, cappedCacheTTL(cfg, 2*time.Minute))
+		cache.Register("github_repo_summary", repoSummaryCache)
+	})
+	return repoSummaryCache
+}
+
+// RepoSummary returns how many public and private repositories the linked GitHub user owns,
+// from the counters on GitHub's /user, without paging the repo list.
+func (h *GitHubOAuthHandler) RepoSummary() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if h.db == nil || h.db.Pool == nil {
+			return apierr.Respond(c, apierr.New(fiber.StatusServiceUnavailable, "db_not_configured"))
+		}
+
+		sub, _ := c.Locals(auth.LocalUserID).(string)
+		userID, err := uuid.Parse(sub)
+		if err != nil {
+			return apierr.Respond(c, apierr.New(fiber.StatusUnauthorized, "invalid_user"))
+		}
+
+		summary, ok := repoSummaries(h.cfg).Get(userID)
+		if !ok {
+			linked, err := github.GetLinkedAccount(c.Context(), h.db.Pool, userID, h.cfg.TokenEncKeys())
+			if isDeadGitHubToken(err) {
+				return respondReauthRequired(c, h.cfg, h.db, userID, err)
+			}
+			if errors.Is(err, github.ErrTokenNotStored) {
+				return apierr.Respond(c, apierr.New(fiber.StatusForbidden, "token_not_stored"))
+			}
+			if err != nil {
+				return apierr.Respond(c, apierr.New(fiber.StatusNotFound, "github_not_linked"))
+			}
+
+			u, err := github.NewClient().GetUser(c.Context(), linked.AccessToken)
+			if err != nil {
+				if isDeadGitHubToken(err) {
+					return respondReauthRequired(c, h.cfg, h.db, userID, err)
+				}
+				slog.Warn("github repo summary failed", "error", err, "user_id", userID)
+				return apierr.Respond(c, apierr.New(fiber.StatusBadGateway, "github_user_fetch_failed"))
+			}
+			summary = repoSummary{Public: u.PublicRepos, Private: u.TotalPrivateRepos}
+			repoSummaries(h.cfg).Set(userID, summary)
+		}
+
+		// Without a private-capable scope GitHub omits the private count; report it as unknown
+		// (null) rather than zero.
+		resp := fiber.Map{
+			"public":  summary.Public,
+			"private": summary.Private,
+			"total":   nil,
+		}
+		if summary.Private != nil {
+			resp["total"] = summary.Public + *summary.Private
+		}
+		return c.Status(fiber.StatusOK).JSON(resp)
+	}
+}
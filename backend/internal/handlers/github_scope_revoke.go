@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/jagadeesh/grainlify/backend/internal/audit"
+	"github.com/jagadeesh/grainlify/backend/internal/auth"
+	"github.com/jagadeesh/grainlify/backend/internal/clientip"
+	"github.com/jagadeesh/grainlify/backend/internal/github"
+)
+
+// scopeRevokeBatchSize bounds how many github_accounts rows one UPDATE flags.
+const scopeRevokeBatchSize = 500
+
+type revokeByScopeRequest struct {
+	Scope          string `json:"scope"`
+	RevokeOnGitHub bool   `json:"revoke_on_github"`
+}
+
+type scopeRevocation struct {
+	Flagged         int `json:"flagged"`
+	RevokedOnGitHub int `json:"revoked_on_github"`
+	RevokeFailures  int `json:"revoke_failures"`
+}
+
+// RevokeByScope is an incident-response lever for over-broad grants: every linked account whose
+// granted scope includes the given scope is flagged reauth_required, so the user has to link
+// again (with the scopes we request today). Optionally the grant is also revoked on GitHub.
+// The whole operation is recorded as one audit event.
+func (h *AdminHandler) RevokeByScope() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if h.db == nil || h.db.Pool == nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "db_not_configured"})
+		}
+		sub, _ := c.Locals(auth.LocalUserID).(string)
+		adminID, err := uuid.Parse(sub)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid_user"})
+		}
+
+		var req revokeByScopeRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_json"})
+		}
+		scope := strings.TrimSpace(req.Scope)
+		if scope == "" || strings.ContainsAny(scope, ", \t") {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_scope"})
+		}
+		if req.RevokeOnGitHub && (h.cfg.GitHubOAuthClientID == "" || h.cfg.GitHubOAuthClientSecret == "") {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "github_oauth_not_configured"})
+		}
+
+		res, err := h.revokeByScope(c.Context(), scope, req.RevokeOnGitHub)
+		outcome := audit.OutcomeSuccess
+		if err != nil {
+			outcome = audit.OutcomeFailure
+		}
+		if aerr := audit.Record(c.Context(), h.db, audit.Event{
+			ActorUserID: &adminID,
+			Action:      audit.ActionGitHubScopeRevoked,
+			Outcome:     outcome,
+			IP:          clientip.FromRequest(c, h.cfg),
+			UserAgent:   c.Get("User-Agent"),
+			Metadata: map[string]any{
+				"scope":             scope,
+				"revoke_on_github":  req.RevokeOnGitHub,
+				"flagged":           res.Flagged,
+				"revoked_on_github": res.RevokedOnGitHub,
+				"revoke_failures":   res.RevokeFailures,
+			},
+		}); aerr != nil {
+			slog.Error("failed to record audit event", "error", aerr, "action", audit.ActionGitHubScopeRevoked, "admin_id", adminID)
+		}
+		if err != nil {
+			slog.Error("revoke github accounts by scope failed", "error", err, "scope", scope, "flagged", res.Flagged)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "scope_revoke_failed", "result": res})
+		}
+
+		slog.Warn("github accounts flagged for re-link by scope", "scope", scope, "flagged", res.Flagged, "admin_id", adminID)
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{"ok": true, "scope": scope, "result": res})
+	}
+}
+
+// revokeByScope flags matching accounts batch by batch. Flagged rows drop out of the candidate
+// query, so the loop ends once a batch comes back short. The scope column holds what GitHub
+// granted, comma- or space-separated; the match is on whole scope names.
+func (h *AdminHandler) revokeByScope(ctx context.Context, scope string, revokeOnGitHub bool) (scopeRevocation, error) {
+	var res scopeRevocation
+	oauthCfg := github.OAuthConfig{
+		ClientID:     h.cfg.GitHubOAuthClientID,
+		ClientSecret: h.cfg.GitHubOAuthClientSecret,
+	}
+	for {
+		rows, err := h.db.Pool.Query(ctx, `
+SELECT user_id
+FROM github_accounts
+WHERE NOT reauth_required
+  AND $1 = ANY(regexp_split_to_array(COALESCE(scope, ''), '[,[:space:]]+'))
+ORDER BY user_id
+LIMIT $2
+`, scope, scopeRevokeBatchSize)
+		if err != nil {
+			return res, err
+		}
+		var batch []uuid.UUID
+		for rows.Next() {
+			var id uuid.UUID
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return res, err
+			}
+			batch = append(batch, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return res, err
+		}
+		if len(batch) == 0 {
+			return res, nil
+		}
+
+		// Revoke before flagging: a flagged account no longer hands out its token.
+		if revokeOnGitHub {
+			for _, userID := range batch {
+				linked, err := github.GetLinkedAccount(ctx, h.db.Pool, userID, h.cfg.TokenEncKeys())
+				if err == nil {
+					err = github.RevokeGrant(ctx, oauthCfg, linked.AccessToken)
+				}
+				if err != nil {
+					res.RevokeFailures++
+					slog.Warn("failed to revoke github grant during scope revocation", "error", err, "user_id", userID)
+					continue
+				}
+				res.RevokedOnGitHub++
+			}
+		}
+
+		ct, err := h.db.Pool.Exec(ctx, `
+UPDATE github_accounts
+SET reauth_required = true,
+    reauth_required_at = COALESCE(reauth_required_at, now()),
+    updated_at = now()
+WHERE user_id = ANY($1)
+`, batch)
+		if err != nil {
+			return res, err
+		}
+		res.Flagged += int(ct.RowsAffected())
+		if len(batch) < scopeRevokeBatchSize {
+			return res, nil
+		}
+	}
+}
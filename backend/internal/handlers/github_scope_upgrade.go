@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"errors"
+	"net/url"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/jagadeesh/grainlify/backend/internal/apierr"
+	"github.com/jagadeesh/grainlify/backend/internal/auth"
+	"github.com/jagadeesh/grainlify/backend/internal/github"
+)
+
+// ScopeUpgrade returns the authorize URL that grants just the scopes the user is missing for
+// an action (incremental consent), so the frontend can deep-link to the right GitHub screen
+// after an insufficient_scopes error. The optional redirect (the current page) is where the
+// link callback sends the user back to.
+//
+// Query: scopes (comma/space separated; defaults to everything we normally request), redirect.
+func (h *GitHubOAuthHandler) ScopeUpgrade() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if h.db == nil || h.db.Pool == nil {
+			return apierr.Respond(c, apierr.New(fiber.StatusServiceUnavailable, "db_not_configured"))
+		}
+		if h.cfg.GitHubOAuthClientID == "" || effectiveGitHubRedirect(h.cfg) == "" {
+			return apierr.Respond(c, apierr.New(fiber.StatusServiceUnavailable, "github_oauth_not_configured"))
+		}
+
+		sub, _ := c.Locals(auth.LocalUserID).(string)
+		userID, err := uuid.Parse(sub)
+		if err != nil {
+			return apierr.Respond(c, apierr.New(fiber.StatusUnauthorized, "invalid_user"))
+		}
+
+		redirectURI := c.Query("redirect")
+		if redirectURI != "" {
+			ru, err := url.Parse(redirectURI)
+			if err != nil || (ru.Scheme != "http" && ru.Scheme != "https") || ru.Host == "" {
+				return apierr.Respond(c, apierr.New(fiber.StatusBadRequest, "invalid_redirect_uri"))
+			}
+			if !isAllowedRedirectURI(redirectURI, h.cfg) {
+				return apierr.Respond(c, apierr.New(fiber.StatusForbidden, "redirect_uri_not_allowed"))
+			}
+		}
+
+		needed := github.SplitScopes(c.Query("scopes"))
+		if len(needed) == 0 {
+			needed = h.scopes()
+		}
+
+		var scope *string
+		err = h.db.Pool.QueryRow(c.Context(), `SELECT scope FROM github_accounts WHERE user_id = $1`, userID).Scan(&scope)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return apierr.Respond(c, apierr.New(fiber.StatusInternalServerError, "status_failed"))
+		}
+		granted := []string{}
+		if scope != nil {
+			granted = github.SplitScopes(*scope)
+		}
+
+		missing := github.MissingScopes(needed, granted)
+		if len(missing) == 0 {
+			return c.Status(fiber.StatusOK).JSON(fiber.Map{"missing_scopes": missing})
+		}
+
+		// Ask for what is already granted plus what is missing: the new token replaces the old
+		// one, and GitHub only prompts for the scopes not yet granted.
+		authURL, err := githubLinkAuthorizeURLFor(c.Context(), h.cfg, h.db, userID, append(granted, missing...), redirectURI)
+		if errors.Is(err, errStateCreateFailed) {
+			return apierr.Respond(c, apierr.New(fiber.StatusInternalServerError, "state_create_failed"))
+		}
+		if err != nil {
+			return apierr.Respond(c, apierr.New(fiber.StatusInternalServerError, "auth_url_failed"))
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"url":            authURL,
+			"missing_scopes": missing,
+		})
+	}
+}
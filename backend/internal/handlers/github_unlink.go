@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/jagadeesh/grainlify/backend/internal/apierr"
+	"github.com/jagadeesh/grainlify/backend/internal/audit"
+	"github.com/jagadeesh/grainlify/backend/internal/auth"
+	"github.com/jagadeesh/grainlify/backend/internal/clientip"
+	"github.com/jagadeesh/grainlify/backend/internal/github"
+	"github.com/jagadeesh/grainlify/backend/internal/tokenvault"
+)
+
+var (
+	errNothingLinked = errors.New("nothing_linked")
+	errOnlyIdentity  = errors.New("cannot_unlink_only_identity")
+)
+
+// checkUnlink decides whether the GitHub link may be removed. Wallets and other OAuth
+// providers are the other ways to sign in; without one, dropping GitHub would lock the user
+// out of the account.
+func checkUnlink(linked bool, otherLogins int) error {
+	if !linked {
+		return errNothingLinked
+	}
+	if otherLogins == 0 {
+		return errOnlyIdentity
+	}
+	return nil
+}
+
+// revokeGitHubGrant revokes the app's grant on GitHub; tests replace it.
+var revokeGitHubGrant = github.RevokeGrant
+
+// unlinkedGitHub is the link removed by githubUnlinker.unlinkGitHub.
+type unlinkedGitHub struct {
+	githubUserID int64
+	login        string
+	// accessToken is the account's token, read before the delete when asked for; "" if it
+	// couldn't be read.
+	accessToken string
+}
+
+// githubUnlinker removes GitHub links; the database implementation is dbGitHubUnlinker.
+type githubUnlinker interface {
+	// unlinkGitHub clears github_accounts and users.github_user_id for userID in one committed
+	// transaction, once checkUnlink allows it (its errors are returned as is).
+	unlinkGitHub(ctx context.Context, userID uuid.UUID, readToken bool) (unlinkedGitHub, error)
+}
+
+// Unlink disconnects the user's GitHub account. Both github_accounts and users.github_user_id
+// are cleared, so a later GitHub login with that account no longer lands in this one.
+// ?revoke=true also revokes the app's grant on GitHub (best-effort), only once the unlink is
+// committed: a failed unlink must not leave a link whose token was already revoked.
+// Unlinking when nothing is linked succeeds with unlinked=false.
+func (h *GitHubOAuthHandler) Unlink() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if h.db == nil || h.db.Pool == nil {
+			return apierr.Respond(c, apierr.New(fiber.StatusServiceUnavailable, "db_not_configured"))
+		}
+		sub, _ := c.Locals(auth.LocalUserID).(string)
+		userID, err := uuid.Parse(sub)
+		if err != nil {
+			return apierr.Respond(c, apierr.New(fiber.StatusUnauthorized, "invalid_user"))
+		}
+		revoke := c.QueryBool("revoke", false)
+
+		removed, err := h.unlinks.unlinkGitHub(c.Context(), userID, revoke)
+		switch {
+		case errors.Is(err, errNothingLinked):
+			return c.Status(fiber.StatusOK).JSON(fiber.Map{"ok": true, "unlinked": false})
+		case errors.Is(err, errOnlyIdentity):
+			return apierr.Respond(c, apierr.New(fiber.StatusConflict, errOnlyIdentity.Error()))
+		case err != nil:
+			return apierr.Respond(c, apierr.New(fiber.StatusInternalServerError, "unlink_failed"))
+		}
+
+		// Best-effort: the unlink stands whether or not GitHub accepts the revocation.
+		revokedOnGitHub := false
+		if revoke {
+			err := errors.New("token not readable")
+			if removed.accessToken != "" {
+				err = revokeGitHubGrant(c.Context(), github.OAuthConfig{
+					ClientID:     h.cfg.GitHubOAuthClientID,
+					ClientSecret: h.cfg.GitHubOAuthClientSecret,
+				}, removed.accessToken)
+			}
+			if err != nil {
+				slog.Warn("failed to revoke github grant on unlink", "error", err, "user_id", userID)
+			} else {
+				revokedOnGitHub = true
+			}
+		}
+
+		if err := tokenvault.Default().Delete(c.Context(), userID); err != nil {
+			slog.Error("failed to delete token from vault after unlink", "error", err, "user_id", userID)
+		}
+		if err := recordAudit(c.Context(), h.db, audit.Event{
+			ActorUserID: &userID,
+			UserID:      &userID,
+			Action:      audit.ActionGitHubUnlinked,
+			IP:          clientip.FromRequest(c, h.cfg),
+			UserAgent:   c.Get("User-Agent"),
+			Metadata: map[string]any{
+				"github_user_id":    removed.githubUserID,
+				"github_login":      removed.login,
+				"revoke_requested":  revoke,
+				"revoked_on_github": revokedOnGitHub,
+			},
+		}); err != nil {
+			slog.Error("failed to record audit event", "error", err, "action", audit.ActionGitHubUnlinked, "user_id", userID)
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"ok":                true,
+			"unlinked":          true,
+			"revoked_on_github": revokedOnGitHub,
+		})
+	}
+}
+
+type dbGitHubUnlinker struct {
+	pool         *pgxpool.Pool
+	tokenEncKeys string
+}
+
+func (u dbGitHubUnlinker) unlinkGitHub(ctx context.Context, userID uuid.UUID, readToken bool) (unlinkedGitHub, error) {
+	tx, err := u.pool.Begin(ctx)
+	if err != nil {
+		return unlinkedGitHub{}, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var removed unlinkedGitHub
+	linked := true
+	err = tx.QueryRow(ctx, `
+SELECT github_user_id, login FROM github_accounts WHERE user_id = $1 FOR UPDATE
+`, userID).Scan(&removed.githubUserID, &removed.login)
+	if errors.Is(err, pgx.ErrNoRows) {
+		linked = false
+	} else if err != nil {
+		return unlinkedGitHub{}, err
+	}
+
+	var otherLogins int
+	if err := tx.QueryRow(ctx, `
+SELECT (SELECT count(*) FROM wallets WHERE user_id = $1)
+     + (SELECT count(*) FROM oauth_identities WHERE user_id = $1 AND provider <> 'github')
+`, userID).Scan(&otherLogins); err != nil {
+		return unlinkedGitHub{}, err
+	}
+	if err := checkUnlink(linked, otherLogins); err != nil {
+		return unlinkedGitHub{}, err
+	}
+
+	// The token has to be read while it is still stored; it is only used after the commit.
+	if readToken {
+		acct, err := github.GetLinkedAccount(ctx, u.pool, userID, u.tokenEncKeys)
+		if err != nil {
+			slog.Warn("failed to read github token for revocation on unlink", "error", err, "user_id", userID)
+		} else {
+			removed.accessToken = acct.AccessToken
+		}
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM github_accounts WHERE user_id = $1`, userID); err != nil {
+		return unlinkedGitHub{}, err
+	}
+	if _, err := tx.Exec(ctx, `
+UPDATE users SET github_user_id = NULL, updated_at = now()
+WHERE id = $1 AND github_user_id = $2
+`, userID, removed.githubUserID); err != nil {
+		return unlinkedGitHub{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return unlinkedGitHub{}, err
+	}
+	return removed, nil
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/jagadeesh/grainlify/backend/internal/auth"
+	"github.com/jagadeesh/grainlify/backend/internal/db"
+	"github.com/jagadeesh/grainlify/backend/internal/github"
+)
+
+func TestCheckUnlink(t *testing.T) {
+	cases := []struct {
+		name   string
+		linked bool
+		others int
+		want   error
+	}{
+		{"linked with another login", true, 1, nil},
+		{"only identity", true, 0, errOnlyIdentity},
+		{"nothing linked", false, 0, errNothingLinked},
+		{"nothing linked with another login", false, 2, errNothingLinked},
+	}
+	for _, tc := range cases {
+		if got := checkUnlink(tc.linked, tc.others); !errors.Is(got, tc.want) {
+			t.Errorf("%s: checkUnlink = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+// fakeUnlinker appends "unlink" to steps when the unlink is committed, or fails with err.
+type fakeUnlinker struct {
+	steps *[]string
+	err   error
+}
+
+func (f fakeUnlinker) unlinkGitHub(_ context.Context, _ uuid.UUID, readToken bool) (unlinkedGitHub, error) {
+	if f.err != nil {
+		return unlinkedGitHub{}, f.err
+	}
+	*f.steps = append(*f.steps, "unlink")
+	removed := unlinkedGitHub{githubUserID: 7, login: "octocat"}
+	if readToken {
+		removed.accessToken = "gho_token"
+	}
+	return removed, nil
+}
+
+func TestUnlinkRevokesAfterCommit(t *testing.T) {
+	captureAudit(t)
+	var steps []string
+	var revokeErr error
+	orig := revokeGitHubGrant
+	revokeGitHubGrant = func(_ context.Context, _ github.OAuthConfig, token string) error {
+		steps = append(steps, "revoke:"+token)
+		return revokeErr
+	}
+	t.Cleanup(func() { revokeGitHubGrant = orig })
+
+	// The pool connects lazily and the fake unlinker never uses it.
+	pool, err := pgxpool.New(context.Background(), "postgres://grainlify@127.0.0.1:1/grainlify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	h := &GitHubOAuthHandler{db: &db.DB{Pool: pool}}
+	userID := uuid.New()
+	app := fiber.New()
+	app.Delete("/auth/github", func(c *fiber.Ctx) error {
+		c.Locals(auth.LocalUserID, userID.String())
+		return c.Next()
+	}, h.Unlink())
+
+	unlink := func(path string) (int, map[string]any) {
+		t.Helper()
+		resp, err := app.Test(httptest.NewRequest("DELETE", path, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var body map[string]any
+		_ = json.NewDecoder(resp.Body).Decode(&body)
+		return resp.StatusCode, body
+	}
+
+	h.unlinks = fakeUnlinker{steps: &steps}
+	status, body := unlink("/auth/github?revoke=true")
+	if status != fiber.StatusOK || body["revoked_on_github"] != true {
+		t.Fatalf("status %d body %v", status, body)
+	}
+	if len(steps) != 2 || steps[0] != "unlink" || steps[1] != "revoke:gho_token" {
+		t.Errorf("steps = %v, want the unlink committed before the revocation", steps)
+	}
+
+	// A failed revocation doesn't undo the unlink.
+	steps, revokeErr = nil, errors.New("github down")
+	status, body = unlink("/auth/github?revoke=true")
+	if status != fiber.StatusOK || body["unlinked"] != true || body["revoked_on_github"] != false {
+		t.Errorf("failed revoke: status %d body %v", status, body)
+	}
+
+	// A failed unlink never reaches GitHub.
+	steps = nil
+	h.unlinks = fakeUnlinker{steps: &steps, err: errors.New("tx failed")}
+	if status, _ := unlink("/auth/github?revoke=true"); status != fiber.StatusInternalServerError {
+		t.Errorf("failed unlink: status %d, want 500", status)
+	}
+	if len(steps) != 0 {
+		t.Errorf("steps = %v, want nothing revoked", steps)
+	}
+
+	h.unlinks = fakeUnlinker{steps: &steps, err: errOnlyIdentity}
+	if status, _ := unlink("/auth/github?revoke=true"); status != fiber.StatusConflict {
+		t.Errorf("only identity: status %d, want 409", status)
+	}
+}
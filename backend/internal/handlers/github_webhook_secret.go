@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/jagadeesh/grainlify/backend/internal/cryptox"
+)
+
+// webhookSecretFor returns the signing secret of the project hook hookID (the
+// X-GitHub-Hook-ID header). ok is false when the hook has no secret of its own, i.e. it was
+// created with the shared GITHUB_WEBHOOK_SECRET or isn't one of ours.
+func (h *GitHubWebhooksHandler) webhookSecretFor(ctx context.Context, hookIDHeader string) (secret string, ok bool, err error) {
+	hookID, err := strconv.ParseInt(hookIDHeader, 10, 64)
+	if err != nil || hookID <= 0 || h.db == nil || h.db.Pool == nil {
+		return "", false, nil
+	}
+	var enc []byte
+	err = h.db.Pool.QueryRow(ctx, `
+SELECT webhook_secret FROM projects
+WHERE webhook_id = $1 AND webhook_secret IS NOT NULL AND deleted_at IS NULL
+LIMIT 1
+`, hookID).Scan(&enc)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	keys, err := cryptox.NewKeyring(h.cfg.TokenEncKeys())
+	if err != nil {
+		return "", false, err
+	}
+	pt, err := keys.Decrypt(enc)
+	if err != nil {
+		return "", false, err
+	}
+	return string(pt), true, nil
+}
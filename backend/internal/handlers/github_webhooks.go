@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"crypto/subtle"
@@ -85,7 +86,20 @@ func (h *GitHubWebhooksHandler) Receive() fiber.Handler {
 			"body_size", bodySize,
 		)
 
-		if h.cfg.GitHubWebhookSecret == "" {
+		// Hooks created per project sign with their own secret; older ones with the shared one.
+		secret, perRepo, err := h.webhookSecretFor(c.Context(), hookID)
+		if err != nil {
+			slog.Error("failed to load webhook secret",
+				"delivery_id", delivery,
+				"hook_id", hookID,
+				"error", err,
+			)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "webhook_secret_lookup_failed"})
+		}
+		if !perRepo {
+			secret = h.cfg.GitHubWebhookSecret
+		}
+		if secret == "" {
 			slog.Error("GitHub webhook secret not configured - rejecting request",
 				"delivery_id", delivery,
 				"event", event,
@@ -96,6 +110,7 @@ func (h *GitHubWebhooksHandler) Receive() fiber.Handler {
 		slog.Info("GitHub webhook secret configured, proceeding with signature verification",
 			"delivery_id", delivery,
 			"event", event,
+			"per_repo_secret", perRepo,
 		)
 
 		// Prepare signature preview for logging
@@ -104,7 +119,7 @@ func (h *GitHubWebhooksHandler) Receive() fiber.Handler {
 			sigPreview = sigPreview[:20] + "..."
 		}
 
-		if !verifyGitHubSignature(h.cfg.GitHubWebhookSecret, body, sig) {
+		if !verifyGitHubSignature(secret, body, sig) {
 			slog.Warn("GitHub webhook signature verification FAILED",
 				"delivery_id", delivery,
 				"event", event,
@@ -120,6 +135,14 @@ func (h *GitHubWebhooksHandler) Receive() fiber.Handler {
 			"event", event,
 		)
 
+		if !h.cfg.WebhookEventAccepted(event) {
+			slog.Info("GitHub webhook event not in WEBHOOK_ACCEPTED_EVENTS - ignoring",
+				"delivery_id", delivery,
+				"event", event,
+			)
+			return c.Status(fiber.StatusOK).JSON(fiber.Map{"ok": true, "ignored": true})
+		}
+
 		var repoFullName string
 		var action string
 
@@ -131,6 +154,10 @@ func (h *GitHubWebhooksHandler) Receive() fiber.Handler {
 			action = strings.TrimSpace(env.Action)
 		}
 
+		if event == "ping" {
+			h.markWebhookVerified(c.Context(), delivery, env.HookID)
+		}
+
 		ev := events.GitHubWebhookReceived{
 			DeliveryID:   delivery,
 			Event:        event,
@@ -214,6 +241,33 @@ func (h *GitHubWebhooksHandler) Receive() fiber.Handler {
 	}
 }
 
+// markWebhookVerified records that the ping for hookID passed signature verification,
+// i.e. GitHub can reach us and signs with our secret.
+func (h *GitHubWebhooksHandler) markWebhookVerified(ctx context.Context, delivery string, hookID int64) {
+	if h.db == nil || h.db.Pool == nil || hookID == 0 {
+		return
+	}
+	tag, err := h.db.Pool.Exec(ctx, `
+UPDATE projects
+SET webhook_verified_at = now(), updated_at = now()
+WHERE webhook_id = $1
+  AND deleted_at IS NULL
+`, hookID)
+	if err != nil {
+		slog.Error("failed to mark webhook verified",
+			"delivery_id", delivery,
+			"hook_id", hookID,
+			"error", err,
+		)
+		return
+	}
+	slog.Info("GitHub webhook ping verified",
+		"delivery_id", delivery,
+		"hook_id", hookID,
+		"projects_updated", tag.RowsAffected(),
+	)
+}
+
 func verifyGitHubSignature(secret string, body []byte, header string) bool {
 	// GitHub uses: X-Hub-Signature-256: sha256=<hex>
 	if !strings.HasPrefix(header, "sha256=") {
@@ -240,6 +294,7 @@ func hexEncodeLower(b []byte) string {
 type ghWebhookEnvelope struct {
 	Action     string         `json:"action"`
 	Repository *ghRepoPayload `json:"repository"`
+	HookID     int64          `json:"hook_id"` // only set on ping
 }
 
 type ghRepoPayload struct {
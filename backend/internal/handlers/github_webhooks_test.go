@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/jagadeesh/grainlify/backend/internal/config"
+)
+
+// The example from GitHub's "Validating webhook deliveries" documentation.
+const (
+	knownWebhookSecret    = "It's a Secret to Everybody"
+	knownWebhookBody      = "Hello, World!"
+	knownWebhookSignature = "sha256=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17"
+)
+
+func TestVerifyGitHubSignatureKnownVector(t *testing.T) {
+	if !verifyGitHubSignature(knownWebhookSecret, []byte(knownWebhookBody), knownWebhookSignature) {
+		t.Error("valid signature rejected")
+	}
+	for name, tc := range map[string]struct{ secret, body, sig string }{
+		"wrong secret":   {"another secret", knownWebhookBody, knownWebhookSignature},
+		"tampered body":  {knownWebhookSecret, "Hello, World?", knownWebhookSignature},
+		"unsigned":       {knownWebhookSecret, knownWebhookBody, ""},
+		"sha1 only":      {knownWebhookSecret, knownWebhookBody, "sha1=01dc10d0c83e72ed246219cdd91669667fe2ca59"},
+		"truncated hash": {knownWebhookSecret, knownWebhookBody, knownWebhookSignature[:40]},
+	} {
+		if verifyGitHubSignature(tc.secret, []byte(tc.body), tc.sig) {
+			t.Errorf("%s: signature accepted", name)
+		}
+	}
+}
+
+func TestGitHubWebhookReceiveRejectsBadSignatures(t *testing.T) {
+	h := NewGitHubWebhooksHandler(config.Config{GitHubWebhookSecret: knownWebhookSecret}, nil, nil)
+	app := fiber.New()
+	app.Post("/webhooks/github", h.Receive())
+
+	for name, tc := range map[string]struct {
+		sig  string
+		want int
+	}{
+		"valid":    {knownWebhookSignature, fiber.StatusOK},
+		"invalid":  {"sha256=" + strings.Repeat("0", 64), fiber.StatusUnauthorized},
+		"unsigned": {"", fiber.StatusUnauthorized},
+	} {
+		req := httptest.NewRequest("POST", "/webhooks/github", strings.NewReader(knownWebhookBody))
+		req.Header.Set("X-GitHub-Event", "ping")
+		req.Header.Set("X-GitHub-Hook-ID", "4242")
+		if tc.sig != "" {
+			req.Header.Set("X-Hub-Signature-256", tc.sig)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("%s: request failed: %v", name, err)
+		}
+		if resp.StatusCode != tc.want {
+			t.Errorf("%s: status %d, want %d", name, resp.StatusCode, tc.want)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"strings"
 
@@ -34,7 +35,7 @@ func (h *IssueApplicationsHandler) Apply() fiber.Handler {
 		if h.db == nil || h.db.Pool == nil {
 			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "db_not_configured"})
 		}
-		if strings.TrimSpace(h.cfg.TokenEncKeyB64) == "" {
+		if h.cfg.TokenEncKeys() == "" {
 			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "token_encryption_not_configured"})
 		}
 
@@ -65,7 +66,13 @@ func (h *IssueApplicationsHandler) Apply() fiber.Handler {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "message_too_long"})
 		}
 
-		linked, err := github.GetLinkedAccount(c.Context(), h.db.Pool, userID, h.cfg.TokenEncKeyB64)
+		linked, err := github.GetLinkedAccount(c.Context(), h.db.Pool, userID, h.cfg.TokenEncKeys())
+		if isDeadGitHubToken(err) {
+			return respondReauthRequired(c, h.cfg, h.db, userID, err)
+		}
+		if errors.Is(err, github.ErrTokenNotStored) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "token_not_stored"})
+		}
 		if err != nil {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "github_not_linked"})
 		}
@@ -113,6 +120,9 @@ LIMIT 1
 				"github_login", linked.Login,
 				"error", err,
 			)
+			if isDeadGitHubToken(err) {
+				return respondReauthRequired(c, h.cfg, h.db, userID, err)
+			}
 			return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": "github_comment_create_failed"})
 		}
 
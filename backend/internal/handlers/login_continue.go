@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+)
+
+const maxContinuePathLen = 512
+
+var errInvalidContinuePath = errors.New("invalid continue path")
+
+// sanitizeContinuePath validates LoginStart's ?continue=, the frontend page (path plus optional
+// query) to resume after login. It must be a same-origin path: no scheme or host, no "//" or
+// backslash tricks, no ".." segments. With CONTINUE_PATH_PREFIXES set it must also sit under one
+// of them. The value travels in the oauth_states row, so it can't be altered on the way back.
+func sanitizeContinuePath(raw string, prefixes []string) (string, error) {
+	if raw == "" {
+		return "", nil
+	}
+	if len(raw) > maxContinuePathLen || !strings.HasPrefix(raw, "/") || strings.HasPrefix(raw, "//") ||
+		strings.ContainsAny(raw, "\\\r\n\t") {
+		return "", errInvalidContinuePath
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != "" || u.Host != "" || u.User != nil {
+		return "", errInvalidContinuePath
+	}
+	for _, seg := range strings.Split(u.Path, "/") {
+		if seg == ".." || seg == "." {
+			return "", errInvalidContinuePath
+		}
+	}
+	if strings.Contains(strings.ToLower(u.RawPath), "%2f") || strings.Contains(u.EscapedPath(), "..") {
+		return "", errInvalidContinuePath
+	}
+	if len(prefixes) == 0 {
+		return raw, nil
+	}
+	for _, p := range prefixes {
+		p = strings.TrimSuffix(p, "/")
+		if u.Path == p || strings.HasPrefix(u.Path, p+"/") {
+			return raw, nil
+		}
+	}
+	return "", errInvalidContinuePath
+}
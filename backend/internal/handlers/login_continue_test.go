@@ -0,0 +1,34 @@
+package handlers
+
+import "testing"
+
+func TestSanitizeContinuePath(t *testing.T) {
+	ok := []string{"", "/onboarding/terms", "/onboarding/plan?tier=pro", "/onboarding"}
+	for _, in := range ok {
+		if got, err := sanitizeContinuePath(in, []string{"/onboarding/"}); err != nil || got != in {
+			t.Errorf("sanitizeContinuePath(%q) = %q, %v; want unchanged", in, got, err)
+		}
+	}
+
+	bad := []string{
+		"https://evil.example.com/x",
+		"//evil.example.com/x",
+		"/\\evil.example.com",
+		"onboarding/terms",
+		"/onboarding/../admin",
+		"/onboarding/%2e%2e/admin",
+		"/onboarding%2f..%2fadmin",
+		"/settings",
+		"/onboardingx",
+		"/onboarding/\r\nx",
+	}
+	for _, in := range bad {
+		if got, err := sanitizeContinuePath(in, []string{"/onboarding"}); err == nil {
+			t.Errorf("sanitizeContinuePath(%q) = %q, want error", in, got)
+		}
+	}
+
+	if _, err := sanitizeContinuePath("/anything/here", nil); err != nil {
+		t.Errorf("without prefixes any safe path is allowed: %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// loginEventStats counts login event deliveries per destination since startup, reported by
+// AdminHandler.LoginEventWebhookStats.
+var loginEventStats = &loginEventDeliveryStats{byURL: map[string]*loginEventDestinationStats{}}
+
+type loginEventDestinationStats struct {
+	Destination   string     `json:"destination"`
+	Delivered     int64      `json:"delivered"`
+	Failed        int64      `json:"failed"`
+	LastError     string     `json:"last_error,omitempty"`
+	LastAttemptAt *time.Time `json:"last_attempt_at,omitempty"`
+	LastSuccessAt *time.Time `json:"last_success_at,omitempty"`
+}
+
+type loginEventDeliveryStats struct {
+	mu    sync.Mutex
+	byURL map[string]*loginEventDestinationStats
+}
+
+// record stores the outcome of one delivery (after its retries) to destination.
+func (s *loginEventDeliveryStats) record(destination string, err error, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, ok := s.byURL[destination]
+	if !ok {
+		st = &loginEventDestinationStats{Destination: redactWebhookURL(destination)}
+		s.byURL[destination] = st
+	}
+	at = at.UTC()
+	st.LastAttemptAt = &at
+	if err != nil {
+		st.Failed++
+		st.LastError = err.Error()
+		return
+	}
+	st.Delivered++
+	st.LastSuccessAt = &at
+}
+
+// snapshot returns a copy of the per-destination stats, ordered by destination.
+func (s *loginEventDeliveryStats) snapshot() []loginEventDestinationStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]loginEventDestinationStats, 0, len(s.byURL))
+	for _, st := range s.byURL {
+		out = append(out, *st)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Destination < out[j].Destination })
+	return out
+}
+
+// redactWebhookURL drops the query, fragment and credentials from a receiver URL, which may
+// carry tokens, so it can be logged and shown to admins.
+func redactWebhookURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "invalid-url"
+	}
+	return (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path}).String()
+}
+
+// LoginEventWebhookStats reports delivered/failed counts per login event destination since
+// the process started.
+func (h *AdminHandler) LoginEventWebhookStats() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		destinations := h.cfg.LoginEventDestinations()
+		configured := make([]string, 0, len(destinations))
+		for _, d := range destinations {
+			configured = append(configured, redactWebhookURL(d.URL))
+		}
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"configured":   configured,
+			"destinations": loginEventStats.snapshot(),
+		})
+	}
+}
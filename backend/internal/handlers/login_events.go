@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// Receivers verify X-Grainlify-Signature-256 the same way we verify GitHub's X-Hub-Signature-256.
+	loginEventSignatureHeader = "X-Grainlify-Signature-256"
+	loginEventAttempts        = 3
+)
+
+// loginEvent is the body POSTed to each login event webhook after each github_login.
+type loginEvent struct {
+	Event       string    `json:"event"`
+	UserID      uuid.UUID `json:"user_id"`
+	GitHubLogin string    `json:"github_login"`
+	IsNewUser   bool      `json:"is_new_user"`
+	IP          string    `json:"ip"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+var loginEventHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// emitLoginEvent delivers the event to every destination in the background, each with its
+// own retries; the login never waits on or fails because of a receiver, and a slow or failing
+// receiver doesn't hold up the others.
+func (h *GitHubOAuthHandler) emitLoginEvent(userID uuid.UUID, login string, isNew bool, ip string) {
+	destinations := h.cfg.LoginEventDestinations()
+	if len(destinations) == 0 {
+		return
+	}
+	ev := loginEvent{
+		Event:       "login",
+		UserID:      userID,
+		GitHubLogin: login,
+		IsNewUser:   isNew,
+		IP:          ip,
+		Timestamp:   time.Now().UTC(),
+	}
+	for _, dest := range destinations {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+			err := deliverLoginEvent(ctx, loginEventHTTPClient, dest.URL, dest.Secret, ev, time.Second)
+			loginEventStats.record(dest.URL, err, time.Now())
+			if err != nil {
+				slog.Warn("login event webhook delivery failed", "error", err, "user_id", userID, "destination", redactWebhookURL(dest.URL))
+			}
+		}()
+	}
+}
+
+// deliverLoginEvent POSTs ev, retrying network errors and 5xx/429 responses with
+// exponential backoff starting at backoff.
+func deliverLoginEvent(ctx context.Context, client *http.Client, url, secret string, ev loginEvent, backoff time.Duration) error {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	signature := signLoginEvent(secret, body)
+
+	var lastErr error
+	for attempt := 1; attempt <= loginEventAttempts; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(loginEventSignatureHeader, signature)
+
+		resp, err := client.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return nil
+		}
+		lastErr = fmt.Errorf("receiver returned status %d", resp.StatusCode)
+		if resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+			return lastErr
+		}
+	}
+	return fmt.Errorf("after %d attempts: %w", loginEventAttempts, lastErr)
+}
+
+func signLoginEvent(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write(body)
+	return "sha256=" + hexEncodeLower(mac.Sum(nil))
+}
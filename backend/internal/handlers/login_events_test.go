@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/jagadeesh/grainlify/backend/internal/config"
+)
+
+func TestDeliverLoginEvent_SignsAndRetries(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !verifyGitHubSignature("s3cret", body, r.Header.Get(loginEventSignatureHeader)) {
+			t.Errorf("bad signature %q", r.Header.Get(loginEventSignatureHeader))
+		}
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	ev := loginEvent{Event: "login", UserID: uuid.New(), GitHubLogin: "octocat", IP: "203.0.113.7", Timestamp: time.Now()}
+	if err := deliverLoginEvent(context.Background(), srv.Client(), srv.URL, "s3cret", ev, time.Millisecond); err != nil {
+		t.Fatalf("deliverLoginEvent: %v", err)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Fatalf("calls = %d, want 2", got)
+	}
+}
+
+func TestDeliverLoginEvent_NoRetryOnClientError(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	if err := deliverLoginEvent(context.Background(), srv.Client(), srv.URL, "s3cret", loginEvent{}, time.Millisecond); err == nil {
+		t.Fatal("expected error for 400 response")
+	}
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("calls = %d, want 1", got)
+	}
+}
+
+func TestEmitLoginEvent_FanOut(t *testing.T) {
+	var okCalls, failCalls atomic.Int32
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		okCalls.Add(1)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ok.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		failCalls.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer failing.Close()
+
+	h := &GitHubOAuthHandler{cfg: config.Config{
+		LoginEventWebhookURL: failing.URL + "/hook?token=abc",
+		LoginEventWebhooks:   []config.LoginEventWebhook{{URL: ok.URL + "/hook", Secret: "s"}},
+	}}
+	h.emitLoginEvent(uuid.New(), "octocat", false, "203.0.113.7")
+
+	deadline := time.Now().Add(5 * time.Second)
+	for okCalls.Load() == 0 || failCalls.Load() == 0 || len(loginEventStats.snapshot()) < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("deliveries: ok=%d failing=%d", okCalls.Load(), failCalls.Load())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	for _, st := range loginEventStats.snapshot() {
+		switch st.Destination {
+		case ok.URL + "/hook":
+			if st.Delivered != 1 || st.Failed != 0 {
+				t.Errorf("ok destination stats %+v", st)
+			}
+		case failing.URL + "/hook":
+			if st.Delivered != 0 || st.Failed != 1 || st.LastError == "" {
+				t.Errorf("failing destination stats %+v", st)
+			}
+		default:
+			t.Errorf("unexpected destination %q (query must be redacted)", st.Destination)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/jagadeesh/grainlify/backend/internal/apierr"
+	"github.com/jagadeesh/grainlify/backend/internal/auth"
+	"github.com/jagadeesh/grainlify/backend/internal/config"
+)
+
+const (
+	loginHandoffCookie = "grainlify_login_handoff"
+	loginHandoffTTL    = time.Minute
+)
+
+// redirectWithLoginHandoff is the LOGIN_COOKIE_HANDOFF alternative to putting the token in the
+// redirect query: it parks a one-time code in an HttpOnly cookie scoped to /auth/finalize and
+// sends the browser to redirectURL with no credentials in the query; only the non-secret
+// continue path, if any, is passed along.
+func (h *GitHubOAuthHandler) redirectWithLoginHandoff(c *fiber.Ctx, ru *url.URL, userID uuid.UUID, githubLogin, idToken string, continuePath *string) error {
+	code := randomState(32)
+	var storedIDToken *string
+	if idToken != "" {
+		storedIDToken = &idToken
+	}
+	if _, err := h.db.Pool.Exec(c.Context(), `
+INSERT INTO login_handoffs (code_hash, user_id, github_login, id_token, expires_at)
+VALUES ($1, $2, $3, $4, $5)
+`, hashOneTimeToken(code), userID, githubLogin, storedIDToken, time.Now().UTC().Add(loginHandoffTTL)); err != nil {
+		slog.Error("failed to store login handoff", "error", err, "user_id", userID)
+		return apierr.Respond(c, apierr.New(fiber.StatusInternalServerError, "login_handoff_failed"))
+	}
+	return sendLoginHandoff(c, h.cfg, ru, code, userID, continuePath)
+}
+
+// sendLoginHandoff sets the handoff cookie for a stored one-time code and redirects to ru,
+// keeping only the continue path in the query.
+func sendLoginHandoff(c *fiber.Ctx, cfg config.Config, ru *url.URL, code string, userID uuid.UUID, continuePath *string) error {
+	c.Cookie(&fiber.Cookie{
+		Name:     loginHandoffCookie,
+		Value:    code,
+		Path:     cfg.AuthPath("/auth/finalize"),
+		MaxAge:   int(loginHandoffTTL.Seconds()),
+		Secure:   handoffCookieSecure(c, cfg),
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+
+	clean := cleanLoginRedirect(ru, continuePath)
+	slog.Info("OAuth redirect - cookie handoff", "redirect_url", clean, "user_id", userID)
+	return c.Redirect(clean, fiber.StatusFound)
+}
+
+// redirectWithSessionCookie is the SESSION_MODE=cookie end of a login: the access token goes
+// into the session cookie and the browser is sent to redirectURL with only the continue path.
+// The SPA then calls the API with credentials included and fetches the profile from /me.
+func redirectWithSessionCookie(c *fiber.Ctx, cfg config.Config, ru *url.URL, jwtToken string, userID uuid.UUID, continuePath *string) error {
+	auth.SetSessionCookie(c, jwtToken, cfg.JWTAccessTTL)
+
+	clean := cleanLoginRedirect(ru, continuePath)
+	slog.Info("OAuth redirect - session cookie", "redirect_url", clean, "user_id", userID)
+	return c.Redirect(clean, fiber.StatusFound)
+}
+
+// cleanLoginRedirect drops any query from ru and passes along only the continue path.
+func cleanLoginRedirect(ru *url.URL, continuePath *string) string {
+	clean := url.URL{Scheme: ru.Scheme, Host: ru.Host, Path: ru.Path}
+	if continuePath != nil {
+		clean.RawQuery = url.Values{"continue": {*continuePath}}.Encode()
+	}
+	return clean.String()
+}
+
+func handoffCookieSecure(c *fiber.Ctx, cfg config.Config) bool {
+	return c.Protocol() == "https" || strings.HasPrefix(cfg.PublicBaseURL, "https://")
+}
+
+// Finalize completes a cookie-handoff login: it redeems the one-time code from the handoff
+// cookie for an access token and clears the cookie. The SPA calls it with credentials included.
+func (h *GitHubOAuthHandler) Finalize() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if h.db == nil || h.db.Pool == nil {
+			return apierr.Respond(c, apierr.New(fiber.StatusServiceUnavailable, "db_not_configured"))
+		}
+
+		code := c.Cookies(loginHandoffCookie)
+		// Single use either way: the cookie goes whether or not the code is still good.
+		c.Cookie(&fiber.Cookie{
+			Name:     loginHandoffCookie,
+			Value:    "",
+			Path:     h.cfg.AuthPath("/auth/finalize"),
+			Expires:  time.Unix(0, 0),
+			Secure:   handoffCookieSecure(c, h.cfg),
+			HTTPOnly: true,
+			SameSite: fiber.CookieSameSiteLaxMode,
+		})
+		if code == "" {
+			return apierr.Respond(c, apierr.New(fiber.StatusBadRequest, "handoff_cookie_missing"))
+		}
+
+		var userID uuid.UUID
+		var githubLogin, role string
+		var idToken *string
+		err := h.db.Pool.QueryRow(c.Context(), `
+UPDATE login_handoffs lh
+SET used_at = now()
+FROM users u
+WHERE lh.code_hash = $1
+  AND lh.used_at IS NULL
+  AND lh.expires_at > now()
+  AND u.id = lh.user_id
+RETURNING lh.user_id, lh.github_login, lh.id_token, u.role
+`, hashOneTimeToken(code)).Scan(&userID, &githubLogin, &idToken, &role)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return apierr.Respond(c, apierr.New(fiber.StatusUnauthorized, "invalid_or_expired_handoff"))
+		}
+		if err != nil {
+			return apierr.Respond(c, apierr.New(fiber.StatusInternalServerError, "finalize_failed"))
+		}
+
+		jwtToken, err := h.issueAccessToken(c.Context(), userID, role)
+		if err != nil {
+			return apierr.Respond(c, apierr.New(fiber.StatusInternalServerError, "token_issue_failed"))
+		}
+
+		resp := fiber.Map{
+			"token": jwtToken,
+			"user": fiber.Map{
+				"id":   userID.String(),
+				"role": role,
+			},
+			"github": fiber.Map{
+				"login": githubLogin,
+			},
+		}
+		if idToken != nil {
+			resp["id_token"] = *idToken
+		}
+		return c.Status(fiber.StatusOK).JSON(resp)
+	}
+}
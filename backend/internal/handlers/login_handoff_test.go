@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/jagadeesh/grainlify/backend/internal/auth"
+	"github.com/jagadeesh/grainlify/backend/internal/config"
+)
+
+func TestRedirectWithSessionCookie(t *testing.T) {
+	cfg := config.Config{SessionMode: config.SessionModeCookie, JWTAccessTTL: time.Hour}
+	const jwtToken = "header.payload.signature"
+	continuePath := "/projects/new"
+
+	app := fiber.New()
+	app.Get("/cb", func(c *fiber.Ctx) error {
+		ru, _ := url.Parse("https://app.example.com/auth/callback?token=stale")
+		return redirectWithSessionCookie(c, cfg, ru, jwtToken, uuid.New(), &continuePath)
+	})
+	resp, err := app.Test(httptest.NewRequest("GET", "/cb", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusFound {
+		t.Fatalf("status = %d, want 302", resp.StatusCode)
+	}
+
+	loc := resp.Header.Get("Location")
+	if strings.Contains(loc, jwtToken) || strings.Contains(loc, "token=") {
+		t.Errorf("token leaked into redirect: %s", loc)
+	}
+	if loc != "https://app.example.com/auth/callback?continue=%2Fprojects%2Fnew" {
+		t.Errorf("Location = %s", loc)
+	}
+
+	var cookie string
+	for _, v := range resp.Header.Values("Set-Cookie") {
+		if strings.HasPrefix(v, auth.SessionCookieName+"=") {
+			cookie = v
+		}
+	}
+	if !strings.HasPrefix(cookie, auth.SessionCookieName+"="+jwtToken+";") {
+		t.Fatalf("session cookie = %q", cookie)
+	}
+	lower := strings.ToLower(cookie)
+	for _, attr := range []string{"secure", "httponly", "samesite=lax", "path=/", "max-age=3600"} {
+		if !strings.Contains(lower, attr) {
+			t.Errorf("session cookie %q lacks %s", cookie, attr)
+		}
+	}
+}
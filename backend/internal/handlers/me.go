@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/jagadeesh/grainlify/backend/internal/github"
+)
+
+// meProfile is the stored side of GET /me: the user row and, when linked, its GitHub account.
+type meProfile struct {
+	role                                           string
+	email                                          *string
+	firstName, lastName, location, website, bio    *string
+	avatarURL                                      *string
+	telegram, linkedin, whatsapp, twitter, discord *string
+	githubLogin, githubAvatarURL                   *string
+}
+
+func loadMeProfile(ctx context.Context, pool *pgxpool.Pool, userID uuid.UUID) (meProfile, error) {
+	var p meProfile
+	err := pool.QueryRow(ctx, `
+SELECT u.role, u.email, u.first_name, u.last_name, u.location, u.website, u.bio, u.avatar_url,
+       u.telegram, u.linkedin, u.whatsapp, u.twitter, u.discord,
+       ga.login, ga.avatar_url
+FROM users u
+LEFT JOIN github_accounts ga ON ga.user_id = u.id
+WHERE u.id = $1
+`, userID).Scan(&p.role, &p.email, &p.firstName, &p.lastName, &p.location, &p.website, &p.bio, &p.avatarURL,
+		&p.telegram, &p.linkedin, &p.whatsapp, &p.twitter, &p.discord,
+		&p.githubLogin, &p.githubAvatarURL)
+	return p, err
+}
+
+// meResult answers GET /me from the stored profile, or the lookup error: user_not_found when
+// the token's subject no longer exists (e.g. the account was deleted).
+func meResult(userID uuid.UUID, p meProfile, err error) (int, fiber.Map) {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fiber.StatusNotFound, fiber.Map{"error": "user_not_found"}
+	}
+	if err != nil {
+		return fiber.StatusInternalServerError, fiber.Map{"error": "user_lookup_failed"}
+	}
+
+	resp := fiber.Map{
+		"id":   userID.String(),
+		"role": p.role,
+	}
+	setIfPresent(resp, "email", p.email)
+	setIfPresent(resp, "first_name", p.firstName)
+	setIfPresent(resp, "last_name", p.lastName)
+	setIfPresent(resp, "telegram", p.telegram)
+	setIfPresent(resp, "linkedin", p.linkedin)
+	setIfPresent(resp, "whatsapp", p.whatsapp)
+	setIfPresent(resp, "twitter", p.twitter)
+	setIfPresent(resp, "discord", p.discord)
+
+	if p.githubLogin != nil {
+		gh := fiber.Map{"login": *p.githubLogin}
+		// A profile picture set on Grainlify wins over the GitHub one.
+		if !setIfPresent(gh, "avatar_url", p.avatarURL) {
+			setIfPresent(gh, "avatar_url", p.githubAvatarURL)
+		}
+		setIfPresent(gh, "location", p.location)
+		setIfPresent(gh, "bio", p.bio)
+		setIfPresent(gh, "website", p.website)
+		resp["github"] = gh
+	}
+	return fiber.StatusOK, resp
+}
+
+// overlayLiveGitHub refreshes the github part of a /me response with the live GitHub profile.
+// Values the user set on Grainlify still win over GitHub's.
+func overlayLiveGitHub(resp fiber.Map, p meProfile, u github.User, primaryEmail string) {
+	gh, _ := resp["github"].(fiber.Map)
+	if gh == nil {
+		gh = fiber.Map{}
+	}
+	gh["login"] = u.Login
+	if !setIfPresent(gh, "avatar_url", p.avatarURL) && u.AvatarURL != "" {
+		gh["avatar_url"] = u.AvatarURL
+	}
+	if u.Name != "" {
+		gh["name"] = u.Name
+	}
+	if primaryEmail != "" {
+		gh["email"] = primaryEmail
+	} else if u.Email != "" {
+		gh["email"] = u.Email
+	}
+	if !setIfPresent(gh, "location", p.location) && u.Location != "" {
+		gh["location"] = u.Location
+	}
+	if !setIfPresent(gh, "bio", p.bio) && u.Bio != "" {
+		gh["bio"] = u.Bio
+	}
+	if !setIfPresent(gh, "website", p.website) && u.Blog != "" {
+		gh["website"] = u.Blog
+	}
+	resp["github"] = gh
+}
+
+// setIfPresent sets m[key] to *v when v is non-empty and reports whether it did.
+func setIfPresent(m fiber.Map, key string, v *string) bool {
+	if v == nil || *v == "" {
+		return false
+	}
+	m[key] = *v
+	return true
+}
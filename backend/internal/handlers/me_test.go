@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/jagadeesh/grainlify/backend/internal/github"
+)
+
+func strPtr(s string) *string { return &s }
+
+func TestMeResultLinkedUser(t *testing.T) {
+	id := uuid.New()
+	p := meProfile{
+		role:            "maintainer",
+		email:           strPtr("octo@example.com"),
+		firstName:       strPtr("Octo"),
+		bio:             strPtr("Set on Grainlify"),
+		githubLogin:     strPtr("octocat"),
+		githubAvatarURL: strPtr("https://avatars.githubusercontent.com/u/1"),
+	}
+	status, resp := meResult(id, p, nil)
+	if status != fiber.StatusOK {
+		t.Fatalf("status = %d, want 200", status)
+	}
+	if resp["id"] != id.String() || resp["role"] != "maintainer" || resp["email"] != "octo@example.com" || resp["first_name"] != "Octo" {
+		t.Errorf("user fields = %v", resp)
+	}
+	gh, _ := resp["github"].(fiber.Map)
+	if gh["login"] != "octocat" || gh["avatar_url"] != "https://avatars.githubusercontent.com/u/1" || gh["bio"] != "Set on Grainlify" {
+		t.Errorf("github = %v", gh)
+	}
+	if _, ok := resp["last_name"]; ok {
+		t.Error("unset fields must be omitted")
+	}
+}
+
+func TestMeResultUnlinkedUser(t *testing.T) {
+	status, resp := meResult(uuid.New(), meProfile{role: "contributor"}, nil)
+	if status != fiber.StatusOK {
+		t.Fatalf("status = %d, want 200", status)
+	}
+	if _, ok := resp["github"]; ok {
+		t.Errorf("unlinked user got a github profile: %v", resp)
+	}
+	if _, ok := resp["email"]; ok {
+		t.Errorf("user without email got one: %v", resp)
+	}
+}
+
+func TestMeResultDeletedUser(t *testing.T) {
+	status, resp := meResult(uuid.New(), meProfile{}, pgx.ErrNoRows)
+	if status != fiber.StatusNotFound || resp["error"] != "user_not_found" {
+		t.Errorf("deleted user: %d %v, want 404 user_not_found", status, resp)
+	}
+	status, resp = meResult(uuid.New(), meProfile{}, errors.New("connection reset"))
+	if status != fiber.StatusInternalServerError || resp["error"] != "user_lookup_failed" {
+		t.Errorf("lookup failure: %d %v, want 500 user_lookup_failed", status, resp)
+	}
+}
+
+func TestOverlayLiveGitHubKeepsGrainlifyValues(t *testing.T) {
+	p := meProfile{role: "contributor", avatarURL: strPtr("https://cdn.example/me.png"), githubLogin: strPtr("old-login")}
+	_, resp := meResult(uuid.New(), p, nil)
+	overlayLiveGitHub(resp, p, github.User{Login: "new-login", AvatarURL: "https://gh/avatar", Name: "Octo Cat", Bio: "From GitHub"}, "primary@example.com")
+
+	gh := resp["github"].(fiber.Map)
+	if gh["login"] != "new-login" || gh["name"] != "Octo Cat" || gh["email"] != "primary@example.com" || gh["bio"] != "From GitHub" {
+		t.Errorf("live fields not applied: %v", gh)
+	}
+	if gh["avatar_url"] != "https://cdn.example/me.png" {
+		t.Errorf("avatar_url = %v, want the Grainlify one", gh["avatar_url"])
+	}
+}
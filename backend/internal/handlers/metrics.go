@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/jagadeesh/grainlify/backend/internal/metrics"
+)
+
+// Metrics serves the metrics registry in the Prometheus text format. With a token, scrapers
+// must send it as "Authorization: Bearer <token>".
+func Metrics(token string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if token != "" {
+			got, ok := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid_metrics_token"})
+			}
+		}
+		var buf bytes.Buffer
+		metrics.Default.WritePrometheus(&buf)
+		c.Set(fiber.HeaderContentType, "text/plain; version=0.0.4; charset=utf-8")
+		return c.Status(fiber.StatusOK).Send(buf.Bytes())
+	}
+}
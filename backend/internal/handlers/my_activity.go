@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/jagadeesh/grainlify/backend/internal/auth"
+)
+
+// activityMetadataKeys are the audit metadata fields shown to the user themselves. Everything
+// else (admin ids, scopes of other accounts, internal reasons) stays admin-only.
+var activityMetadataKeys = []string{"github_login", "is_new_user", "triggered_by", "revoked_on_github"}
+
+type activityEntry struct {
+	Action      string         `json:"action"`
+	Outcome     string         `json:"outcome"`
+	InitiatedBy string         `json:"initiated_by"`
+	IPPrefix    string         `json:"ip_prefix,omitempty"`
+	Browser     string         `json:"browser,omitempty"`
+	Details     map[string]any `json:"details,omitempty"`
+	CreatedAt   time.Time      `json:"created_at"`
+}
+
+// MyActivity lists the security-relevant history of the caller's own account (logins, GitHub
+// links and unlinks, session revocations), newest first and paginated with limit/offset.
+// IPs are cut to their network (/24 or /48) and user agents to the browser family.
+func (h *AuthHandler) MyActivity() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if h.db == nil || h.db.Pool == nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "db_not_configured"})
+		}
+		userIDStr, _ := c.Locals(auth.LocalUserID).(string)
+		userID, err := uuid.Parse(userIDStr)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid_user"})
+		}
+
+		limit := c.QueryInt("limit", 20)
+		if limit < 1 || limit > 100 {
+			limit = 20
+		}
+		offset := c.QueryInt("offset", 0)
+		if offset < 0 {
+			offset = 0
+		}
+
+		// One extra row tells whether there is another page.
+		rows, err := h.db.Pool.Query(c.Context(), `
+SELECT action, outcome, actor_user_id, ip, user_agent, metadata, created_at
+FROM audit_events
+WHERE user_id = $1
+ORDER BY created_at DESC, id DESC
+LIMIT $2 OFFSET $3
+`, userID, limit+1, offset)
+		if err != nil {
+			slog.Error("failed to list account activity", "error", err, "user_id", userID)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "activity_fetch_failed"})
+		}
+		defer rows.Close()
+
+		entries := []activityEntry{}
+		for rows.Next() {
+			var e activityEntry
+			var actor *uuid.UUID
+			var ip, ua *string
+			var meta []byte
+			if err := rows.Scan(&e.Action, &e.Outcome, &actor, &ip, &ua, &meta, &e.CreatedAt); err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "activity_fetch_failed"})
+			}
+			e.InitiatedBy = activityInitiator(actor, userID)
+			if ip != nil {
+				e.IPPrefix = coarseIP(*ip)
+			}
+			if ua != nil {
+				e.Browser = userAgentFamily(*ua)
+			}
+			e.Details = activityDetails(meta)
+			entries = append(entries, e)
+		}
+		if err := rows.Err(); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "activity_fetch_failed"})
+		}
+
+		hasMore := len(entries) > limit
+		if hasMore {
+			entries = entries[:limit]
+		}
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"activity": entries,
+			"limit":    limit,
+			"offset":   offset,
+			"has_more": hasMore,
+		})
+	}
+}
+
+// activityInitiator says who caused an event without naming anyone else: the user, an admin
+// (any other actor) or the system (no actor, e.g. background jobs).
+func activityInitiator(actor *uuid.UUID, userID uuid.UUID) string {
+	switch {
+	case actor == nil:
+		return "system"
+	case *actor == userID:
+		return "you"
+	default:
+		return "admin"
+	}
+}
+
+// coarseIP reduces an address to its network: /24 for IPv4, /48 for IPv6. Unparseable values
+// are dropped rather than shown as stored.
+func coarseIP(raw string) string {
+	ip := net.ParseIP(raw)
+	if ip == nil {
+		return ""
+	}
+	if v4 := ip.To4(); v4 != nil {
+		return (&net.IPNet{IP: v4.Mask(net.CIDRMask(24, 32)), Mask: net.CIDRMask(24, 32)}).String()
+	}
+	return (&net.IPNet{IP: ip.Mask(net.CIDRMask(48, 128)), Mask: net.CIDRMask(48, 128)}).String()
+}
+
+func activityDetails(meta []byte) map[string]any {
+	var all map[string]any
+	if err := json.Unmarshal(meta, &all); err != nil {
+		return nil
+	}
+	out := map[string]any{}
+	for _, k := range activityMetadataKeys {
+		if v, ok := all[k]; ok {
+			out[k] = v
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestCoarseIP(t *testing.T) {
+	cases := map[string]string{
+		"203.0.113.57":           "203.0.113.0/24",
+		"::ffff:203.0.113.57":    "203.0.113.0/24",
+		"2001:db8:abcd:12:1::7":  "2001:db8:abcd::/48",
+		"not-an-ip":              "",
+		"203.0.113.57, 10.0.0.1": "",
+	}
+	for in, want := range cases {
+		if got := coarseIP(in); got != want {
+			t.Errorf("coarseIP(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestActivityInitiatorAndDetails(t *testing.T) {
+	me, other := uuid.New(), uuid.New()
+	if activityInitiator(&me, me) != "you" || activityInitiator(&other, me) != "admin" || activityInitiator(nil, me) != "system" {
+		t.Error("unexpected initiator mapping")
+	}
+
+	got := activityDetails([]byte(`{"github_login":"octocat","admin_id":"x","scope":"repo"}`))
+	if len(got) != 1 || got["github_login"] != "octocat" {
+		t.Errorf("activityDetails = %v, want only github_login", got)
+	}
+	if activityDetails([]byte(`{}`)) != nil {
+		t.Error("empty metadata should give no details")
+	}
+}
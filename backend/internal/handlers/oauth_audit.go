@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"log/slog"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/jagadeesh/grainlify/backend/internal/audit"
+	"github.com/jagadeesh/grainlify/backend/internal/clientip"
+)
+
+// recordAudit writes an audit event; tests replace it to capture events without a database.
+var recordAudit = audit.Record
+
+// callbackAuditAction is the audit action of an OAuth callback: github_link for the link flow,
+// github_login otherwise, including callbacks whose state never resolved to a flow.
+func callbackAuditAction(kind string) string {
+	if kind == "github_link" {
+		return audit.ActionGitHubLink
+	}
+	return audit.ActionLogin
+}
+
+// recordCallbackFailure records a rejected OAuth callback. userID is the account the flow
+// belongs to when the state already named one (the link flow), nil otherwise.
+func (h *GitHubOAuthHandler) recordCallbackFailure(c *fiber.Ctx, kind string, userID *uuid.UUID, reason string) {
+	action := callbackAuditAction(kind)
+	if err := recordAudit(c.Context(), h.db, audit.Event{
+		ActorUserID: userID,
+		UserID:      userID,
+		Action:      action,
+		Outcome:     audit.OutcomeFailure,
+		IP:          clientip.FromRequest(c, h.cfg),
+		UserAgent:   c.Get(fiber.HeaderUserAgent),
+		Metadata:    map[string]any{"reason": reason},
+	}); err != nil {
+		slog.Error("failed to record audit event", "error", err, "action", action, "reason", reason)
+	}
+}
+
+// recordLink records a GitHub account linked through the link flow's callback.
+func (h *GitHubOAuthHandler) recordLink(c *fiber.Ctx, userID uuid.UUID, githubLogin string) {
+	if err := recordAudit(c.Context(), h.db, audit.Event{
+		ActorUserID: &userID,
+		UserID:      &userID,
+		Action:      audit.ActionGitHubLink,
+		IP:          clientip.FromRequest(c, h.cfg),
+		UserAgent:   c.Get(fiber.HeaderUserAgent),
+		Metadata:    map[string]any{"github_login": githubLogin},
+	}); err != nil {
+		slog.Error("failed to record audit event", "error", err, "action", audit.ActionGitHubLink, "user_id", userID)
+	}
+}
+
+// recordLogin records a login through a provider other than GitHub in the login history.
+func (h *OAuthLoginHandler) recordLogin(c *fiber.Ctx, provider string, userID uuid.UUID, login string, isNewUser bool) {
+	if err := recordAudit(c.Context(), h.db, audit.Event{
+		ActorUserID: &userID,
+		UserID:      &userID,
+		Action:      audit.ActionLogin,
+		IP:          clientip.FromRequest(c, h.cfg),
+		UserAgent:   c.Get(fiber.HeaderUserAgent),
+		Metadata:    map[string]any{"provider": provider, "login": login, "is_new_user": isNewUser},
+	}); err != nil {
+		slog.Error("failed to record audit event", "error", err, "action", audit.ActionLogin, "user_id", userID)
+	}
+}
+
+// recordCallbackFailure records a rejected provider login callback.
+func (h *OAuthLoginHandler) recordCallbackFailure(c *fiber.Ctx, provider, reason string) {
+	if err := recordAudit(c.Context(), h.db, audit.Event{
+		Action:    audit.ActionLogin,
+		Outcome:   audit.OutcomeFailure,
+		IP:        clientip.FromRequest(c, h.cfg),
+		UserAgent: c.Get(fiber.HeaderUserAgent),
+		Metadata:  map[string]any{"provider": provider, "reason": reason},
+	}); err != nil {
+		slog.Error("failed to record audit event", "error", err, "action", audit.ActionLogin, "reason", reason)
+	}
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/jagadeesh/grainlify/backend/internal/audit"
+	"github.com/jagadeesh/grainlify/backend/internal/config"
+	"github.com/jagadeesh/grainlify/backend/internal/db"
+)
+
+// captureAudit replaces recordAudit for the test and returns the events written.
+func captureAudit(t *testing.T) *[]audit.Event {
+	t.Helper()
+	var events []audit.Event
+	orig := recordAudit
+	recordAudit = func(_ context.Context, _ *db.DB, e audit.Event) error {
+		events = append(events, e)
+		return nil
+	}
+	t.Cleanup(func() { recordAudit = orig })
+	return &events
+}
+
+func TestCallbackAuditEvents(t *testing.T) {
+	events := captureAudit(t)
+	h := &GitHubOAuthHandler{cfg: config.Config{TrustProxyHeaders: true}}
+	userID := uuid.New()
+
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		h.recordLogin(c, userID, "octocat", true)
+		h.recordLink(c, userID, "octocat")
+		h.recordCallbackFailure(c, "github_link", &userID, "token_exchange_failed")
+		h.recordCallbackFailure(c, "", nil, "invalid_or_expired_state")
+		return nil
+	})
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("User-Agent", "test-agent")
+	req.Header.Set("X-Forwarded-For", "203.0.113.7")
+	if _, err := app.Test(req); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		action, outcome, reason string
+		withUser                bool
+	}{
+		{audit.ActionLogin, "", "", true},
+		{audit.ActionGitHubLink, "", "", true},
+		{audit.ActionGitHubLink, audit.OutcomeFailure, "token_exchange_failed", true},
+		{audit.ActionLogin, audit.OutcomeFailure, "invalid_or_expired_state", false},
+	}
+	if len(*events) != len(want) {
+		t.Fatalf("got %d events, want %d", len(*events), len(want))
+	}
+	for i, w := range want {
+		e := (*events)[i]
+		if e.Action != w.action || e.Outcome != w.outcome || e.UserAgent != "test-agent" || e.IP != "203.0.113.7" {
+			t.Errorf("event %d = %+v, want action %s outcome %q", i, e, w.action, w.outcome)
+		}
+		if w.reason != "" && e.Metadata["reason"] != w.reason {
+			t.Errorf("event %d reason = %v, want %s", i, e.Metadata["reason"], w.reason)
+		}
+		if (e.UserID != nil) != w.withUser || (w.withUser && *e.UserID != userID) {
+			t.Errorf("event %d user = %v", i, e.UserID)
+		}
+	}
+}
+
+func TestCallbackRecordsInvalidState(t *testing.T) {
+	events := captureAudit(t)
+	// The pool connects lazily; a forged signed link state is rejected before any query.
+	pool, err := pgxpool.New(context.Background(), "postgres://grainlify@127.0.0.1:1/grainlify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	cfg := config.Config{
+		JWTSecret:               "secret",
+		GitHubOAuthClientID:     "cid",
+		GitHubOAuthClientSecret: "csecret",
+		GitHubOAuthRedirectURL:  "https://api.example.com/auth/github/login/callback",
+		StatelessLinkState:      true,
+	}
+	h := NewGitHubOAuthHandler(cfg, &db.DB{Pool: pool})
+
+	app := fiber.New()
+	app.Get("/cb", h.CallbackUnified())
+	req := httptest.NewRequest("GET", "/cb?code=abc&state=sl.forged", nil)
+	req.Header.Set("Accept", "application/json")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", resp.StatusCode)
+	}
+	if len(*events) != 1 {
+		t.Fatalf("got %d events, want 1", len(*events))
+	}
+	if e := (*events)[0]; e.Action != audit.ActionLogin || e.Outcome != audit.OutcomeFailure || e.Metadata["reason"] != "invalid_or_expired_state" {
+		t.Errorf("event = %+v", e)
+	}
+}
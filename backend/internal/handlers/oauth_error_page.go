@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"html/template"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/jagadeesh/grainlify/backend/internal/apierr"
+	"github.com/jagadeesh/grainlify/backend/internal/config"
+)
+
+// defaultOAuthErrorMessages are the built-in English texts for OAuth errors users can hit
+// mid-flow in the browser. OAUTH_ERROR_MESSAGES_FILE adds languages or overrides these.
+var defaultOAuthErrorMessages = map[string]map[string]string{
+	"en": {
+		"user_cancelled":            "You cancelled the GitHub sign-in. You can close this page or try again.",
+		"redirect_uri_not_allowed":  "This sign-in link points to a site we don't recognize, so we stopped it for your safety.",
+		"redirect_uri_mismatch":     "This sign-in link was changed after it was created, so we stopped it for your safety. Please start the sign-in again.",
+		"invalid_or_expired_state":  "This sign-in link has expired or was already used. Please start the sign-in again.",
+		"state_user_agent_mismatch": "This sign-in was started in a different browser. Please start the sign-in again here.",
+		"github_account_too_new":    "Your GitHub account is too new to sign up yet. Please try again once it is a little older.",
+		"bad_verification_code":     "This sign-in code has expired or was already used. Please start the sign-in again.",
+		"insufficient_scope":        "GitHub didn't grant the repository and webhook access Grainlify needs. Please sign in again and approve all requested permissions.",
+	},
+}
+
+const defaultOAuthErrorTemplate = `<!DOCTYPE html>
+<html lang="{{.Lang}}">
+<head><meta charset="utf-8"><title>Sign-in problem</title></head>
+<body>
+<h1>Sign-in problem</h1>
+<p>{{.Message}}</p>
+{{if .RetryURL}}<p><a href="{{.RetryURL}}">Back to Grainlify</a></p>{{end}}
+<p><small>Error code: {{.Code}}</small></p>
+</body>
+</html>`
+
+type oauthErrorPageData struct {
+	Lang     string
+	Code     string
+	Message  string
+	RetryURL string
+}
+
+// oauthErrorPages renders OAuth errors as HTML for browsers and JSON for everyone else.
+type oauthErrorPages struct {
+	tmpl        *template.Template
+	messages    map[string]map[string]string
+	defaultLang string
+	retryURL    string
+}
+
+// newOAuthErrorPages loads OAUTH_ERROR_TEMPLATE and OAUTH_ERROR_MESSAGES_FILE. A file that
+// can't be used is logged and the built-in default takes its place.
+func newOAuthErrorPages(cfg config.Config) *oauthErrorPages {
+	p := &oauthErrorPages{
+		tmpl:        template.Must(template.New("oauth_error").Parse(defaultOAuthErrorTemplate)),
+		messages:    map[string]map[string]string{},
+		defaultLang: strings.ToLower(cfg.OAuthErrorDefaultLang),
+		retryURL:    cfg.FrontendBaseURL,
+	}
+	if p.defaultLang == "" {
+		p.defaultLang = "en"
+	}
+	for lang, msgs := range defaultOAuthErrorMessages {
+		p.messages[lang] = map[string]string{}
+		for code, msg := range msgs {
+			p.messages[lang][code] = msg
+		}
+	}
+
+	if cfg.OAuthErrorTemplate != "" {
+		if t, err := template.ParseFiles(cfg.OAuthErrorTemplate); err != nil {
+			slog.Error("failed to load OAUTH_ERROR_TEMPLATE, using built-in page", "error", err, "path", cfg.OAuthErrorTemplate)
+		} else {
+			p.tmpl = t
+		}
+	}
+	if cfg.OAuthErrorMessagesFile != "" {
+		if err := p.loadMessages(cfg.OAuthErrorMessagesFile); err != nil {
+			slog.Error("failed to load OAUTH_ERROR_MESSAGES_FILE, using built-in messages", "error", err, "path", cfg.OAuthErrorMessagesFile)
+		}
+	}
+	return p
+}
+
+// loadMessages merges a {"<lang>": {"<error code>": "<message>"}} JSON file over the defaults.
+func (p *oauthErrorPages) loadMessages(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var extra map[string]map[string]string
+	if err := json.Unmarshal(b, &extra); err != nil {
+		return err
+	}
+	for lang, msgs := range extra {
+		lang = strings.ToLower(lang)
+		if p.messages[lang] == nil {
+			p.messages[lang] = map[string]string{}
+		}
+		for code, msg := range msgs {
+			p.messages[lang][code] = msg
+		}
+	}
+	return nil
+}
+
+// message picks the text for code in lang, falling back to the default language.
+// ok is false when no language has a text for code.
+func (p *oauthErrorPages) message(lang, code string) (resolvedLang, msg string, ok bool) {
+	for _, l := range []string{strings.ToLower(lang), p.defaultLang, "en"} {
+		if msg, ok := p.messages[l][code]; ok {
+			return l, msg, true
+		}
+	}
+	return p.defaultLang, "", false
+}
+
+// lang resolves ?lang=, then Accept-Language, then the configured default.
+func (p *oauthErrorPages) lang(c *fiber.Ctx) string {
+	if l := strings.TrimSpace(c.Query("lang")); l != "" {
+		return l
+	}
+	// Without the header AcceptsLanguages returns the first offer, which is arbitrary here.
+	if c.Get(fiber.HeaderAcceptLanguage) == "" {
+		return p.defaultLang
+	}
+	offered := make([]string, 0, len(p.messages))
+	for l := range p.messages {
+		offered = append(offered, l)
+	}
+	if l := c.AcceptsLanguages(offered...); l != "" {
+		return l
+	}
+	return p.defaultLang
+}
+
+// respond sends e as JSON, or as a localized HTML page when the client prefers HTML
+// and a message exists for the error code.
+func (p *oauthErrorPages) respond(c *fiber.Ctx, e apierr.Error) error {
+	code := e.Code
+	if c.Accepts(fiber.MIMEApplicationJSON, fiber.MIMETextHTML) != fiber.MIMETextHTML {
+		return apierr.Respond(c, e)
+	}
+	lang, msg, ok := p.message(p.lang(c), code)
+	if !ok {
+		return apierr.Respond(c, e)
+	}
+
+	var buf bytes.Buffer
+	if err := p.tmpl.Execute(&buf, oauthErrorPageData{Lang: lang, Code: code, Message: msg, RetryURL: p.retryURL}); err != nil {
+		slog.Error("failed to render oauth error page", "error", err, "code", code)
+		return apierr.Respond(c, e)
+	}
+	c.Locals(apierr.LocalCode, e.Code)
+	c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	return c.Status(e.HTTPStatus).Send(buf.Bytes())
+}
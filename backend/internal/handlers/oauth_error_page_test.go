@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/jagadeesh/grainlify/backend/internal/apierr"
+	"github.com/jagadeesh/grainlify/backend/internal/config"
+)
+
+func TestOAuthErrorPages_Respond(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "messages.json")
+	if err := os.WriteFile(path, []byte(`{"es": {"user_cancelled": "Cancelaste el inicio de sesión."}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	pages := newOAuthErrorPages(config.Config{OAuthErrorMessagesFile: path, OAuthErrorDefaultLang: "en"})
+
+	app := fiber.New()
+	app.Get("/err/:code", func(c *fiber.Ctx) error {
+		return pages.respond(c, apierr.New(fiber.StatusBadRequest, c.Params("code")))
+	})
+
+	cases := []struct {
+		name, path, accept, wantType, wantBody string
+	}{
+		{"api client gets json", "/err/user_cancelled", "application/json", fiber.MIMEApplicationJSON, `"user_cancelled"`},
+		{"browser gets english page", "/err/user_cancelled", "text/html,*/*;q=0.8", "text/html", "You cancelled"},
+		{"lang param localizes", "/err/user_cancelled?lang=es", "text/html", "text/html", "Cancelaste"},
+		{"missing translation falls back to default", "/err/invalid_or_expired_state?lang=es", "text/html", "text/html", "expired"},
+		{"unknown code stays json", "/err/state_lookup_failed", "text/html", fiber.MIMEApplicationJSON, `"state_lookup_failed"`},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest("GET", tc.path, nil)
+		req.Header.Set("Accept", tc.accept)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, tc.wantType) {
+			t.Errorf("%s: Content-Type = %q, want %q", tc.name, ct, tc.wantType)
+		}
+		if !strings.Contains(string(body), tc.wantBody) {
+			t.Errorf("%s: body %q does not contain %q", tc.name, body, tc.wantBody)
+		}
+		if resp.StatusCode != fiber.StatusBadRequest {
+			t.Errorf("%s: status = %d", tc.name, resp.StatusCode)
+		}
+	}
+}
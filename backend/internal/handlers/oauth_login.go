@@ -0,0 +1,341 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/jagadeesh/grainlify/backend/internal/apierr"
+	"github.com/jagadeesh/grainlify/backend/internal/clientip"
+	"github.com/jagadeesh/grainlify/backend/internal/config"
+	"github.com/jagadeesh/grainlify/backend/internal/db"
+	"github.com/jagadeesh/grainlify/backend/internal/github"
+	"github.com/jagadeesh/grainlify/backend/internal/oauthprovider"
+)
+
+var errOAuthLoginStateNotFound = errors.New("oauth login state not found")
+
+// oauthLoginState is what OAuthLoginHandler keeps between start and callback.
+type oauthLoginState struct {
+	redirectURI  string
+	codeVerifier string
+}
+
+// oauthLoginStore is the persistence OAuthLoginHandler needs, so the flow can be exercised
+// without a database.
+type oauthLoginStore interface {
+	saveState(ctx context.Context, state, kind string, st oauthLoginState, expiresAt time.Time, clientIP string) error
+	// takeState returns and deletes an unexpired state of the given kind.
+	takeState(ctx context.Context, state, kind string) (oauthLoginState, error)
+	// resolveIdentity finds the user signed in by the provider account, creating one on first login.
+	resolveIdentity(ctx context.Context, provider string, u oauthprovider.User) (userID uuid.UUID, role string, isNew bool, err error)
+	// pendingStates counts the unexpired states started from clientIP, of any kind.
+	pendingStates(ctx context.Context, clientIP string) (int, error)
+	// saveLoginHandoff stores a LOGIN_COOKIE_HANDOFF code for POST /auth/finalize.
+	saveLoginHandoff(ctx context.Context, codeHash string, userID uuid.UUID, expiresAt time.Time) error
+}
+
+// OAuthLoginHandler runs login for the providers in a registry under /auth/:provider/login.
+// GitHub keeps its dedicated GitHubOAuthHandler routes, which take precedence; this handler
+// serves every other registered provider.
+type OAuthLoginHandler struct {
+	cfg       config.Config
+	db        *db.DB
+	providers *oauthprovider.Registry
+	store     oauthLoginStore
+}
+
+func NewOAuthLoginHandler(cfg config.Config, d *db.DB, providers *oauthprovider.Registry) *OAuthLoginHandler {
+	h := &OAuthLoginHandler{cfg: cfg, db: d, providers: providers}
+	if d != nil && d.Pool != nil {
+		h.store = dbOAuthLoginStore{pool: d.Pool}
+	}
+	return h
+}
+
+// NewOAuthProviders returns the provider registry, with GitHub as the default provider.
+func NewOAuthProviders(cfg config.Config) *oauthprovider.Registry {
+	return oauthprovider.NewRegistry(github.NewOAuthProvider(github.OAuthConfig{
+		ClientID:     cfg.GitHubOAuthClientID,
+		ClientSecret: cfg.GitHubOAuthClientSecret,
+		RedirectURL:  effectiveGitHubRedirect(cfg),
+	}, cfg.GitHubScopes()))
+}
+
+// Start sends the browser to the provider (or, with ?mode=url, returns the URL). The optional
+// redirect parameter follows the same allowlist as the GitHub login.
+func (h *OAuthLoginHandler) Start() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if h.store == nil {
+			return apierr.Respond(c, apierr.New(fiber.StatusServiceUnavailable, "db_not_configured"))
+		}
+		name := c.Params("provider")
+		provider, ok := h.providers.Get(name)
+		if !ok {
+			return apierr.Respond(c, apierr.New(fiber.StatusNotFound, "unknown_provider"))
+		}
+
+		redirectURI := c.Query("redirect")
+		if redirectURI != "" {
+			u, err := url.Parse(redirectURI)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+				return apierr.Respond(c, apierr.New(fiber.StatusBadRequest, "invalid_redirect_uri"))
+			}
+			if !isAllowedRedirectURI(redirectURI, h.cfg) {
+				return apierr.Respond(c, apierr.New(fiber.StatusForbidden, "redirect_uri_not_allowed"))
+			}
+		}
+
+		// MAX_PENDING_STATES_PER_IP, as for the GitHub login start.
+		ip := clientip.FromRequest(c, h.cfg)
+		if h.cfg.MaxPendingStatesPerIP > 0 {
+			pending, err := h.store.pendingStates(c.Context(), ip)
+			if err != nil {
+				slog.Error("OAuth login start - failed to count pending states", "error", err, "provider", name)
+				return apierr.Respond(c, apierr.New(fiber.StatusInternalServerError, "state_create_failed"))
+			}
+			if pending >= h.cfg.MaxPendingStatesPerIP {
+				slog.Warn("OAuth login start - too many pending flows", "ip", ip, "pending", pending, "provider", name)
+				c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(oauthStateTTL.Seconds())))
+				return apierr.Respond(c, apierr.New(fiber.StatusTooManyRequests, "too_many_pending_flows"))
+			}
+		}
+
+		expiresAt, err := newOAuthStateExpiry(time.Now(), oauthStateTTL)
+		if err != nil {
+			return apierr.Respond(c, apierr.New(fiber.StatusInternalServerError, "state_create_failed"))
+		}
+		verifier, err := github.NewPKCEVerifier()
+		if err != nil {
+			return apierr.Respond(c, apierr.New(fiber.StatusInternalServerError, "state_create_failed"))
+		}
+		state := randomState(32)
+		st := oauthLoginState{redirectURI: redirectURI, codeVerifier: verifier}
+		if err := h.store.saveState(c.Context(), state, oauthprovider.LoginKind(name), st, expiresAt, ip); err != nil {
+			slog.Error("OAuth login start - failed to store state", "error", err, "provider", name)
+			return apierr.Respond(c, apierr.New(fiber.StatusInternalServerError, "state_create_failed"))
+		}
+
+		authURL, err := provider.AuthorizeURL(state, github.PKCEChallengeS256(verifier))
+		if err != nil {
+			return apierr.Respond(c, apierr.New(fiber.StatusServiceUnavailable, "oauth_provider_not_configured"))
+		}
+		if wantsAuthorizeURL(c) {
+			return c.Status(fiber.StatusOK).JSON(fiber.Map{"url": authURL})
+		}
+		return c.Redirect(authURL, fiber.StatusFound)
+	}
+}
+
+// Callback finishes a provider login: it checks the state, exchanges the code, resolves the
+// user through oauth_identities and issues a JWT, redirecting to the frontend callback when
+// the flow started with a redirect and answering with JSON otherwise. The redirect hands the
+// token over like the GitHub login does: session cookie, cookie handoff or query, per config.
+func (h *OAuthLoginHandler) Callback() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if h.store == nil {
+			return apierr.Respond(c, apierr.New(fiber.StatusServiceUnavailable, "db_not_configured"))
+		}
+		if h.cfg.JWTSecret == "" {
+			return apierr.Respond(c, apierr.New(fiber.StatusServiceUnavailable, "jwt_not_configured"))
+		}
+		name := c.Params("provider")
+		provider, ok := h.providers.Get(name)
+		if !ok {
+			return apierr.Respond(c, apierr.New(fiber.StatusNotFound, "unknown_provider"))
+		}
+		if c.Query("error") == "access_denied" {
+			return apierr.Respond(c, apierr.New(fiber.StatusBadRequest, "user_cancelled"))
+		}
+		code, state := c.Query("code"), c.Query("state")
+		if code == "" || state == "" {
+			return apierr.Respond(c, apierr.New(fiber.StatusBadRequest, "missing_code_or_state"))
+		}
+
+		st, err := h.store.takeState(c.Context(), state, oauthprovider.LoginKind(name))
+		if errors.Is(err, errOAuthLoginStateNotFound) {
+			h.recordCallbackFailure(c, name, "invalid_or_expired_state")
+			return apierr.Respond(c, apierr.New(fiber.StatusBadRequest, "invalid_or_expired_state"))
+		}
+		if err != nil {
+			return apierr.Respond(c, apierr.New(fiber.StatusInternalServerError, "state_lookup_failed"))
+		}
+
+		tok, err := provider.ExchangeCode(c.Context(), code, st.codeVerifier)
+		if err != nil {
+			slog.Warn("OAuth login callback - code exchange failed", "error", err, "provider", name)
+			h.recordCallbackFailure(c, name, "token_exchange_failed")
+			return apierr.Respond(c, apierr.New(fiber.StatusBadRequest, "token_exchange_failed"))
+		}
+		u, err := provider.GetUser(c.Context(), tok.AccessToken)
+		if err != nil || u.ID == "" {
+			h.recordCallbackFailure(c, name, "user_fetch_failed")
+			return apierr.Respond(c, apierr.New(fiber.StatusUnauthorized, "user_fetch_failed"))
+		}
+
+		userID, role, isNew, err := h.store.resolveIdentity(c.Context(), name, u)
+		if err != nil {
+			slog.Error("OAuth login callback - failed to resolve user", "error", err, "provider", name)
+			h.recordCallbackFailure(c, name, "user_upsert_failed")
+			return apierr.Respond(c, apierr.New(fiber.StatusInternalServerError, "user_upsert_failed"))
+		}
+		jwtToken, err := issueLoginToken(c.Context(), h.cfg, userID, role)
+		if err != nil {
+			return apierr.Respond(c, apierr.New(fiber.StatusInternalServerError, "token_issue_failed"))
+		}
+		slog.Info("OAuth login completed", "provider", name, "user_id", userID, "is_new_user", isNew)
+		h.recordLogin(c, name, userID, u.Login, isNew)
+
+		if st.redirectURI != "" {
+			if ru, err := parseLoginRedirectURL(withFrontendCallbackPath(st.redirectURI)); err == nil {
+				switch {
+				case h.cfg.SessionCookies():
+					return redirectWithSessionCookie(c, h.cfg, ru, jwtToken, userID, nil)
+				case h.cfg.LoginCookieHandoff:
+					return h.redirectWithLoginHandoff(c, ru, userID)
+				}
+				q := ru.Query()
+				q.Set("token", jwtToken)
+				q.Set("provider", name)
+				ru.RawQuery = q.Encode()
+				return c.Redirect(ru.String(), fiber.StatusFound)
+			}
+		}
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"token":    jwtToken,
+			"provider": name,
+			"user": fiber.Map{
+				"id":   userID.String(),
+				"role": role,
+			},
+			"identity": fiber.Map{
+				"id":         u.ID,
+				"login":      u.Login,
+				"avatar_url": u.AvatarURL,
+			},
+			"is_new_user": isNew,
+		})
+	}
+}
+
+// redirectWithLoginHandoff is the LOGIN_COOKIE_HANDOFF end of a provider login; see
+// GitHubOAuthHandler.redirectWithLoginHandoff. There is no GitHub login to hand back.
+func (h *OAuthLoginHandler) redirectWithLoginHandoff(c *fiber.Ctx, ru *url.URL, userID uuid.UUID) error {
+	code := randomState(32)
+	if err := h.store.saveLoginHandoff(c.Context(), hashOneTimeToken(code), userID, time.Now().UTC().Add(loginHandoffTTL)); err != nil {
+		slog.Error("failed to store login handoff", "error", err, "user_id", userID)
+		return apierr.Respond(c, apierr.New(fiber.StatusInternalServerError, "login_handoff_failed"))
+	}
+	return sendLoginHandoff(c, h.cfg, ru, code, userID, nil)
+}
+
+type dbOAuthLoginStore struct {
+	pool *pgxpool.Pool
+}
+
+func (s dbOAuthLoginStore) saveState(ctx context.Context, state, kind string, st oauthLoginState, expiresAt time.Time, clientIP string) error {
+	_, err := s.pool.Exec(ctx, `
+INSERT INTO oauth_states (state, user_id, kind, expires_at, redirect_uri, client_ip, code_verifier)
+VALUES ($1, NULL, $2, $3, NULLIF($4, ''), $5, $6)
+`, oauthStateKey(state), kind, expiresAt, st.redirectURI, clientIP, st.codeVerifier)
+	return err
+}
+
+func (s dbOAuthLoginStore) pendingStates(ctx context.Context, clientIP string) (int, error) {
+	var pending int
+	err := s.pool.QueryRow(ctx, `
+SELECT count(*) FROM oauth_states WHERE client_ip = $1 AND expires_at > now()
+`, clientIP).Scan(&pending)
+	return pending, err
+}
+
+func (s dbOAuthLoginStore) takeState(ctx context.Context, state, kind string) (oauthLoginState, error) {
+	var redirectURI, verifier *string
+	err := s.pool.QueryRow(ctx, `
+DELETE FROM oauth_states
+WHERE state = $1 AND kind = $2 AND expires_at > now()
+RETURNING redirect_uri, code_verifier
+`, oauthStateKey(state), kind).Scan(&redirectURI, &verifier)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return oauthLoginState{}, errOAuthLoginStateNotFound
+	}
+	if err != nil {
+		return oauthLoginState{}, err
+	}
+	var st oauthLoginState
+	if redirectURI != nil {
+		st.redirectURI = *redirectURI
+	}
+	if verifier != nil {
+		st.codeVerifier = *verifier
+	}
+	return st, nil
+}
+
+// resolveIdentity resolves once more when a concurrent first login for the same provider
+// account inserted its identity first; the retry finds that user.
+func (s dbOAuthLoginStore) resolveIdentity(ctx context.Context, provider string, u oauthprovider.User) (uuid.UUID, string, bool, error) {
+	r, err := retryLoginRace(func() (loginResolution, error) {
+		var r loginResolution
+		var err error
+		r.userID, r.role, r.isNew, err = s.resolveIdentityOnce(ctx, provider, u)
+		return r, err
+	})
+	return r.userID, r.role, r.isNew, err
+}
+
+func (s dbOAuthLoginStore) resolveIdentityOnce(ctx context.Context, provider string, u oauthprovider.User) (uuid.UUID, string, bool, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return uuid.Nil, "", false, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var userID uuid.UUID
+	var role string
+	err = tx.QueryRow(ctx, `
+UPDATE oauth_identities oi
+SET login = $3, updated_at = now()
+FROM users u
+WHERE oi.provider = $1 AND oi.provider_user_id = $2 AND u.id = oi.user_id
+RETURNING u.id, u.role
+`, provider, u.ID, u.Login).Scan(&userID, &role)
+	if err == nil {
+		return userID, role, false, tx.Commit(ctx)
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return uuid.Nil, "", false, err
+	}
+
+	if err := tx.QueryRow(ctx, `INSERT INTO users DEFAULT VALUES RETURNING id, role`).Scan(&userID, &role); err != nil {
+		return uuid.Nil, "", false, err
+	}
+	// The rollback on a lost race also drops the user created above.
+	var inserted bool
+	err = tx.QueryRow(ctx, `
+INSERT INTO oauth_identities (provider, provider_user_id, user_id, login)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT DO NOTHING
+RETURNING true
+`, provider, u.ID, userID, u.Login).Scan(&inserted)
+	if err := loginInsertErr(err); err != nil {
+		return uuid.Nil, "", false, err
+	}
+	return userID, role, true, tx.Commit(ctx)
+}
+
+func (s dbOAuthLoginStore) saveLoginHandoff(ctx context.Context, codeHash string, userID uuid.UUID, expiresAt time.Time) error {
+	_, err := s.pool.Exec(ctx, `
+INSERT INTO login_handoffs (code_hash, user_id, github_login, expires_at)
+VALUES ($1, $2, '', $3)
+`, codeHash, userID, expiresAt)
+	return err
+}
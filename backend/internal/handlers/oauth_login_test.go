@@ -0,0 +1,336 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/jagadeesh/grainlify/backend/internal/audit"
+	"github.com/jagadeesh/grainlify/backend/internal/auth"
+	"github.com/jagadeesh/grainlify/backend/internal/config"
+	"github.com/jagadeesh/grainlify/backend/internal/github"
+	"github.com/jagadeesh/grainlify/backend/internal/oauthprovider"
+)
+
+type fakeOAuthProvider struct {
+	challenge string
+}
+
+func (p *fakeOAuthProvider) Name() string { return "fakehub" }
+
+func (p *fakeOAuthProvider) AuthorizeURL(state, codeChallenge string) (string, error) {
+	p.challenge = codeChallenge
+	return "https://fakehub.example/authorize?" + url.Values{"state": {state}}.Encode(), nil
+}
+
+func (p *fakeOAuthProvider) ExchangeCode(_ context.Context, code, codeVerifier string) (oauthprovider.Token, error) {
+	if code != "good-code" || github.PKCEChallengeS256(codeVerifier) != p.challenge {
+		return oauthprovider.Token{}, errors.New("bad code")
+	}
+	return oauthprovider.Token{AccessToken: "fake-token"}, nil
+}
+
+func (p *fakeOAuthProvider) GetUser(_ context.Context, accessToken string) (oauthprovider.User, error) {
+	if accessToken != "fake-token" {
+		return oauthprovider.User{}, errors.New("bad token")
+	}
+	return oauthprovider.User{ID: "42", Login: "fake-octo"}, nil
+}
+
+type memOAuthLoginStore struct {
+	mu         sync.Mutex
+	states     map[string]oauthLoginState
+	kinds      map[string]string
+	identities map[string]uuid.UUID
+	handoffs   map[string]uuid.UUID
+	clientIPs  map[string]string
+}
+
+func newMemOAuthLoginStore() *memOAuthLoginStore {
+	return &memOAuthLoginStore{states: map[string]oauthLoginState{}, kinds: map[string]string{}, identities: map[string]uuid.UUID{}, handoffs: map[string]uuid.UUID{}, clientIPs: map[string]string{}}
+}
+
+func (s *memOAuthLoginStore) saveState(_ context.Context, state, kind string, st oauthLoginState, _ time.Time, clientIP string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.states[state], s.kinds[state], s.clientIPs[state] = st, kind, clientIP
+	return nil
+}
+
+func (s *memOAuthLoginStore) pendingStates(_ context.Context, clientIP string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pending := 0
+	for state := range s.states {
+		if s.clientIPs[state] == clientIP {
+			pending++
+		}
+	}
+	return pending, nil
+}
+
+func (s *memOAuthLoginStore) takeState(_ context.Context, state, kind string) (oauthLoginState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, ok := s.states[state]
+	if !ok || s.kinds[state] != kind {
+		return oauthLoginState{}, errOAuthLoginStateNotFound
+	}
+	delete(s.states, state)
+	return st, nil
+}
+
+func (s *memOAuthLoginStore) resolveIdentity(_ context.Context, provider string, u oauthprovider.User) (uuid.UUID, string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := provider + ":" + u.ID
+	if id, ok := s.identities[key]; ok {
+		return id, "contributor", false, nil
+	}
+	id := uuid.New()
+	s.identities[key] = id
+	return id, "contributor", true, nil
+}
+
+func TestOAuthLoginHandler_FullFlow(t *testing.T) {
+	captureAudit(t)
+	provider := &fakeOAuthProvider{}
+	h := &OAuthLoginHandler{
+		cfg:       config.Config{JWTSecret: "secret"},
+		providers: oauthprovider.NewRegistry(provider),
+		store:     newMemOAuthLoginStore(),
+	}
+	app := fiber.New()
+	app.Get("/auth/:provider/login/start", h.Start())
+	app.Get("/auth/:provider/login/callback", h.Callback())
+
+	get := func(path string) (int, map[string]any) {
+		t.Helper()
+		resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		var body map[string]any
+		_ = json.NewDecoder(resp.Body).Decode(&body)
+		return resp.StatusCode, body
+	}
+
+	if status, _ := get("/auth/nope/login/start?mode=url"); status != fiber.StatusNotFound {
+		t.Errorf("unknown provider: status %d", status)
+	}
+
+	login := func() (int, map[string]any) {
+		status, body := get("/auth/fakehub/login/start?mode=url")
+		if status != fiber.StatusOK {
+			t.Fatalf("start: status %d body %v", status, body)
+		}
+		authURL, _ := url.Parse(body["url"].(string))
+		state := authURL.Query().Get("state")
+		return get("/auth/fakehub/login/callback?" + url.Values{"code": {"good-code"}, "state": {state}}.Encode())
+	}
+
+	status, body := login()
+	if status != fiber.StatusOK {
+		t.Fatalf("callback: status %d body %v", status, body)
+	}
+	if body["provider"] != "fakehub" || body["is_new_user"] != true {
+		t.Errorf("unexpected callback body %v", body)
+	}
+	claims, err := auth.ParseJWT("secret", body["token"].(string))
+	if err != nil {
+		t.Fatalf("issued token does not parse: %v", err)
+	}
+	firstUser := claims.Subject
+
+	// Same provider account again: same user, not new.
+	_, body = login()
+	claims, err = auth.ParseJWT("secret", body["token"].(string))
+	if err != nil || claims.Subject != firstUser || body["is_new_user"] != false {
+		t.Errorf("second login: sub=%v is_new_user=%v err=%v", claims, body["is_new_user"], err)
+	}
+
+	// A state is single-use and bound to its provider.
+	_, body = get("/auth/fakehub/login/start?mode=url")
+	authURL, _ := url.Parse(body["url"].(string))
+	state := authURL.Query().Get("state")
+	callback := "/auth/fakehub/login/callback?" + url.Values{"code": {"good-code"}, "state": {state}}.Encode()
+	if status, _ := get(callback); status != fiber.StatusOK {
+		t.Fatalf("callback: status %d", status)
+	}
+	if status, body := get(callback); status != fiber.StatusBadRequest || body["error"] != "invalid_or_expired_state" {
+		t.Errorf("replayed state: status %d body %v", status, body)
+	}
+}
+
+func (s *memOAuthLoginStore) saveLoginHandoff(_ context.Context, codeHash string, userID uuid.UUID, _ time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handoffs[codeHash] = userID
+	return nil
+}
+
+// redirectLogin runs a provider login that started with a frontend redirect and returns the
+// callback response.
+func redirectLogin(t *testing.T, h *OAuthLoginHandler) *http.Response {
+	t.Helper()
+	app := fiber.New()
+	app.Get("/auth/:provider/login/start", h.Start())
+	app.Get("/auth/:provider/login/callback", h.Callback())
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/auth/fakehub/login/start?mode=url&redirect="+url.QueryEscape("https://app.example.com"), nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body map[string]string
+	_ = json.NewDecoder(resp.Body).Decode(&body)
+	authURL, err := url.Parse(body["url"])
+	if err != nil || authURL.Query().Get("state") == "" {
+		t.Fatalf("start: status %d body %v", resp.StatusCode, body)
+	}
+	callback := "/auth/fakehub/login/callback?" + url.Values{"code": {"good-code"}, "state": {authURL.Query().Get("state")}}.Encode()
+	resp, err = app.Test(httptest.NewRequest("GET", callback, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusFound {
+		t.Fatalf("callback: status %d, want 302", resp.StatusCode)
+	}
+	return resp
+}
+
+func TestOAuthLoginHandler_CookieHandoff(t *testing.T) {
+	events := captureAudit(t)
+	store := newMemOAuthLoginStore()
+	h := &OAuthLoginHandler{
+		cfg:       config.Config{JWTSecret: "secret", FrontendBaseURL: "https://app.example.com", LoginCookieHandoff: true},
+		providers: oauthprovider.NewRegistry(&fakeOAuthProvider{}),
+		store:     store,
+	}
+
+	resp := redirectLogin(t, h)
+	if loc := resp.Header.Get("Location"); loc != "https://app.example.com/auth/callback" {
+		t.Errorf("Location = %s, want no credentials in the query", loc)
+	}
+	var code string
+	for _, ck := range resp.Cookies() {
+		if ck.Name == loginHandoffCookie {
+			code = ck.Value
+		}
+	}
+	if code == "" {
+		t.Fatal("no handoff cookie set")
+	}
+	if _, ok := store.handoffs[hashOneTimeToken(code)]; !ok {
+		t.Error("handoff code was not stored")
+	}
+
+	if len(*events) != 1 {
+		t.Fatalf("got %d audit events, want 1", len(*events))
+	}
+	if e := (*events)[0]; e.Action != audit.ActionLogin || e.Outcome != "" || e.Metadata["provider"] != "fakehub" || e.Metadata["is_new_user"] != true {
+		t.Errorf("event = %+v", e)
+	}
+}
+
+func TestOAuthLoginHandler_AuditsRejectedCallback(t *testing.T) {
+	events := captureAudit(t)
+	h := &OAuthLoginHandler{
+		cfg:       config.Config{JWTSecret: "secret"},
+		providers: oauthprovider.NewRegistry(&fakeOAuthProvider{}),
+		store:     newMemOAuthLoginStore(),
+	}
+	app := fiber.New()
+	app.Get("/auth/:provider/login/callback", h.Callback())
+	resp, err := app.Test(httptest.NewRequest("GET", "/auth/fakehub/login/callback?code=good-code&state=forged", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", resp.StatusCode)
+	}
+	if len(*events) != 1 {
+		t.Fatalf("got %d audit events, want 1", len(*events))
+	}
+	if e := (*events)[0]; e.Outcome != audit.OutcomeFailure || e.Metadata["reason"] != "invalid_or_expired_state" || e.Metadata["provider"] != "fakehub" {
+		t.Errorf("event = %+v", e)
+	}
+}
+
+func TestOAuthLoginHandler_SessionCookie(t *testing.T) {
+	captureAudit(t)
+	h := &OAuthLoginHandler{
+		cfg: config.Config{
+			JWTSecret:       "secret",
+			JWTAccessTTL:    time.Hour,
+			FrontendBaseURL: "https://app.example.com",
+			SessionMode:     config.SessionModeCookie,
+			// The session cookie takes precedence over the handoff.
+			LoginCookieHandoff: true,
+		},
+		providers: oauthprovider.NewRegistry(&fakeOAuthProvider{}),
+		store:     newMemOAuthLoginStore(),
+	}
+
+	resp := redirectLogin(t, h)
+	if loc := resp.Header.Get("Location"); loc != "https://app.example.com/auth/callback" {
+		t.Errorf("Location = %s, want no token in the query", loc)
+	}
+	var session string
+	for _, ck := range resp.Cookies() {
+		switch ck.Name {
+		case auth.SessionCookieName:
+			session = ck.Value
+		case loginHandoffCookie:
+			t.Error("handoff cookie set in session cookie mode")
+		}
+	}
+	claims, err := auth.ParseJWT("secret", session)
+	if err != nil {
+		t.Fatalf("session cookie does not hold a valid token: %v", err)
+	}
+	if claims.Subject == "" {
+		t.Error("session token has no subject")
+	}
+}
+
+func TestOAuthLoginHandler_PendingStateCap(t *testing.T) {
+	h := &OAuthLoginHandler{
+		cfg:       config.Config{MaxPendingStatesPerIP: 2},
+		providers: oauthprovider.NewRegistry(&fakeOAuthProvider{}),
+		store:     newMemOAuthLoginStore(),
+	}
+	app := fiber.New()
+	app.Get("/auth/:provider/login/start", h.Start())
+
+	start := func() *http.Response {
+		t.Helper()
+		resp, err := app.Test(httptest.NewRequest("GET", "/auth/fakehub/login/start?mode=url", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	for i := 0; i < 2; i++ {
+		if resp := start(); resp.StatusCode != fiber.StatusOK {
+			t.Fatalf("start %d: status %d, want 200", i, resp.StatusCode)
+		}
+	}
+	resp := start()
+	var body map[string]any
+	_ = json.NewDecoder(resp.Body).Decode(&body)
+	if resp.StatusCode != fiber.StatusTooManyRequests || body["error"] != "too_many_pending_flows" {
+		t.Errorf("over the cap: status %d body %v, want 429 too_many_pending_flows", resp.StatusCode, body)
+	}
+	if resp.Header.Get(fiber.HeaderRetryAfter) == "" {
+		t.Error("over the cap: no Retry-After")
+	}
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/jagadeesh/grainlify/backend/internal/apierr"
+	"github.com/jagadeesh/grainlify/backend/internal/metrics"
+)
+
+// GitHub OAuth flow outcomes, served at /metrics. result is "success" or the error code the
+// client got (e.g. invalid_or_expired_state, token_exchange_failed).
+var (
+	oauthLoginStartTotal = metrics.Default.NewCounterVec("oauth_login_start_total",
+		"GitHub login starts by result.", "result")
+	oauthLoginTotal = metrics.Default.NewCounterVec("oauth_login_total",
+		"GitHub OAuth callbacks (login and link) by result.", "result")
+	oauthCallbackSeconds = metrics.Default.NewHistogram("oauth_callback_duration_seconds",
+		"Time to handle a GitHub OAuth callback, including the calls to GitHub.", metrics.DefBuckets)
+)
+
+// instrumentOAuth counts each request to next under its result and, with a histogram,
+// records how long it took.
+func instrumentOAuth(results *metrics.CounterVec, latency *metrics.Histogram, next fiber.Handler) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		err := next(c)
+		results.Inc(oauthResult(c, err))
+		if latency != nil {
+			latency.Observe(time.Since(start).Seconds())
+		}
+		return err
+	}
+}
+
+// oauthResult names the outcome of a handled request: the apierr code it answered with,
+// "error" for an error returned to fiber, an http_<status> for other failures, else "success".
+func oauthResult(c *fiber.Ctx, err error) string {
+	if code, _ := c.Locals(apierr.LocalCode).(string); code != "" {
+		return code
+	}
+	if err != nil {
+		return "error"
+	}
+	if status := c.Response().StatusCode(); status >= 400 {
+		return "http_" + strconv.Itoa(status)
+	}
+	return "success"
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/jagadeesh/grainlify/backend/internal/apierr"
+	"github.com/jagadeesh/grainlify/backend/internal/config"
+)
+
+func TestInstrumentOAuth(t *testing.T) {
+	app := fiber.New()
+	app.Get("/ok", instrumentOAuth(oauthLoginTotal, oauthCallbackSeconds, func(c *fiber.Ctx) error {
+		return c.Redirect("https://app.grainlify.xyz/auth/callback", fiber.StatusFound)
+	}))
+	app.Get("/fail", instrumentOAuth(oauthLoginTotal, oauthCallbackSeconds, func(c *fiber.Ctx) error {
+		return apierr.Respond(c, apierr.New(fiber.StatusBadRequest, "token_exchange_failed"))
+	}))
+
+	success, failed := oauthLoginTotal.Value("success"), oauthLoginTotal.Value("token_exchange_failed")
+	observed := oauthCallbackSeconds.Count()
+	for _, path := range []string{"/ok", "/fail", "/fail"} {
+		if _, err := app.Test(httptest.NewRequest("GET", path, nil)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := oauthLoginTotal.Value("success") - success; got != 1 {
+		t.Errorf("success count grew by %v, want 1", got)
+	}
+	if got := oauthLoginTotal.Value("token_exchange_failed") - failed; got != 2 {
+		t.Errorf("token_exchange_failed count grew by %v, want 2", got)
+	}
+	if got := oauthCallbackSeconds.Count() - observed; got != 3 {
+		t.Errorf("latency observations grew by %d, want 3", got)
+	}
+}
+
+func TestLoginStartCountsFailures(t *testing.T) {
+	h := NewGitHubOAuthHandler(config.Config{}, nil)
+	app := fiber.New()
+	app.Get("/start", h.LoginStart())
+
+	before := oauthLoginStartTotal.Value("db_not_configured")
+	if _, err := app.Test(httptest.NewRequest("GET", "/start", nil)); err != nil {
+		t.Fatal(err)
+	}
+	if got := oauthLoginStartTotal.Value("db_not_configured") - before; got != 1 {
+		t.Errorf("db_not_configured count grew by %v, want 1", got)
+	}
+}
+
+func TestMetricsToken(t *testing.T) {
+	app := fiber.New()
+	app.Get("/metrics", Metrics("s3cret"))
+
+	resp, _ := app.Test(httptest.NewRequest("GET", "/metrics", nil))
+	if resp.StatusCode != fiber.StatusUnauthorized {
+		t.Errorf("without token: status %d", resp.StatusCode)
+	}
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	resp, _ = app.Test(req)
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != fiber.StatusOK || !strings.Contains(string(body), "# TYPE oauth_login_total counter") {
+		t.Errorf("with token: status %d body %q", resp.StatusCode, body)
+	}
+}
@@ -0,0 +1,28 @@
+package handlers
+
+import "github.com/jagadeesh/grainlify/backend/internal/auth"
+
+// oauthStateKey is what oauth_states.state holds for a CSRF token: its SHA-256, so the raw
+// token never reaches the database and a lookup reveals nothing about which tokens exist.
+func oauthStateKey(csrfToken string) string {
+	return hashOneTimeToken(csrfToken)
+}
+
+// stateLogHash identifies a state in logs: a prefix of its oauthStateKey, enough to find the
+// oauth_states row without putting a usable token in the logs.
+func stateLogHash(state string) string {
+	return oauthStateKey(state)[:12]
+}
+
+// oauthStateKeys are the oauth_states.state values a callback may match: the hash, and the raw
+// token for flows started before states were hashed.
+func oauthStateKeys(csrfToken string) []string {
+	return []string{oauthStateKey(csrfToken), csrfToken}
+}
+
+// oauthStateMatches re-checks a fetched row's state against the presented token in constant time.
+func oauthStateMatches(stored, csrfToken string) bool {
+	hashed := auth.SecureCompare(stored, oauthStateKey(csrfToken))
+	legacy := auth.SecureCompare(stored, csrfToken)
+	return hashed || legacy
+}
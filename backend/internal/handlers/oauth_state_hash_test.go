@@ -0,0 +1,35 @@
+package handlers
+
+import "testing"
+
+func TestOAuthStateHashing(t *testing.T) {
+	csrf := randomState(32)
+	key := oauthStateKey(csrf)
+	if key == csrf || len(key) != 64 {
+		t.Fatalf("state must be stored as a SHA-256 hex digest, got %q", key)
+	}
+	if !oauthStateMatches(key, csrf) {
+		t.Error("hashed state must validate")
+	}
+	if !oauthStateMatches(csrf, csrf) {
+		t.Error("raw state from an in-flight login must still validate")
+	}
+	if oauthStateMatches(key, randomState(32)) {
+		t.Error("different token must not validate")
+	}
+
+	// States carrying a redirect still decode to the CSRF token that was hashed at login start.
+	encoded := encodeStateWithRedirect(csrf, "https://app.example.com/auth/callback")
+	decoded, _, err := decodeStateWithRedirect(encoded)
+	if err != nil || !oauthStateMatches(key, decoded) {
+		t.Errorf("decoded state must match the stored hash (err=%v)", err)
+	}
+}
+
+func TestStateLogHash(t *testing.T) {
+	csrf := randomState(32)
+	got := stateLogHash(csrf)
+	if len(got) != 12 || got != oauthStateKey(csrf)[:12] {
+		t.Errorf("stateLogHash = %q, want the first 12 characters of the stored key", got)
+	}
+}
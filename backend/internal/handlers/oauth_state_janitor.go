@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/jagadeesh/grainlify/backend/internal/db"
+)
+
+// execer is the slice of *pgxpool.Pool the janitor needs.
+type execer interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+// janitorPurges are what the janitor deletes: states of abandoned logins (callbacks remove the
+// rest), and login handoff codes that expired or were redeemed (Finalize only marks them used).
+var janitorPurges = []struct{ rows, sql string }{
+	{"oauth states", `DELETE FROM oauth_states WHERE expires_at < now()`},
+	{"login handoffs", `DELETE FROM login_handoffs WHERE expires_at < now() OR used_at IS NOT NULL`},
+}
+
+// StartOAuthStateJanitor deletes expired oauth_states and spent login_handoffs rows every
+// interval until ctx is done; without it both tables only grow. It blocks; run it in its own
+// goroutine.
+func StartOAuthStateJanitor(ctx context.Context, d *db.DB, interval time.Duration) {
+	if d == nil || d.Pool == nil || interval <= 0 {
+		slog.Info("OAuth state janitor disabled")
+		return
+	}
+	runOAuthStateJanitor(ctx, d.Pool, interval)
+}
+
+func runOAuthStateJanitor(ctx context.Context, q execer, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	slog.Info("OAuth state janitor started", "interval", interval.String())
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("OAuth state janitor stopped")
+			return
+		case <-ticker.C:
+			for _, p := range janitorPurges {
+				ct, err := q.Exec(ctx, p.sql)
+				if err != nil {
+					slog.Error("failed to purge "+p.rows, "error", err)
+					continue
+				}
+				slog.Debug("purged "+p.rows, "rows", ct.RowsAffected())
+			}
+		}
+	}
+}
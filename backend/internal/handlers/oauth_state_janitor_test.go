@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// fakeStates stands in for oauth_states: Exec purges the rows whose expiry has passed. Every
+// statement run is kept in sqls.
+type fakeStates struct {
+	mu      sync.Mutex
+	expires []time.Time
+	sqls    []string
+}
+
+func (f *fakeStates) Exec(_ context.Context, sql string, _ ...any) (pgconn.CommandTag, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sqls = append(f.sqls, sql)
+	if !strings.Contains(sql, "oauth_states") {
+		return pgconn.NewCommandTag("DELETE 0"), nil
+	}
+	now := time.Now()
+	kept := f.expires[:0]
+	for _, e := range f.expires {
+		if !e.Before(now) {
+			kept = append(kept, e)
+		}
+	}
+	f.expires = kept
+	return pgconn.NewCommandTag("DELETE 0"), nil
+}
+
+func (f *fakeStates) remaining() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.expires)
+}
+
+func TestOAuthStateJanitorPurgesExpiredRows(t *testing.T) {
+	now := time.Now()
+	states := &fakeStates{expires: []time.Time{now.Add(-time.Hour), now.Add(-time.Minute), now.Add(time.Hour)}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		runOAuthStateJanitor(ctx, states, 5*time.Millisecond)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for states.remaining() != 1 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("janitor did not stop after context cancel")
+	}
+	if got := states.remaining(); got != 1 {
+		t.Fatalf("%d states left, want only the unexpired one", got)
+	}
+	all := strings.Join(states.sqls, "\n")
+	for _, want := range []string{
+		"DELETE FROM oauth_states WHERE expires_at < now()",
+		"DELETE FROM login_handoffs WHERE expires_at < now() OR used_at IS NOT NULL",
+	} {
+		if !strings.Contains(all, want) {
+			t.Errorf("cleanup never ran %q; ran:\n%s", want, all)
+		}
+	}
+}
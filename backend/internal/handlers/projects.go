@@ -15,6 +15,7 @@ import (
 
 	"github.com/jagadeesh/grainlify/backend/internal/auth"
 	"github.com/jagadeesh/grainlify/backend/internal/config"
+	"github.com/jagadeesh/grainlify/backend/internal/cryptox"
 	"github.com/jagadeesh/grainlify/backend/internal/db"
 	"github.com/jagadeesh/grainlify/backend/internal/github"
 )
@@ -161,12 +162,14 @@ SELECT
   p.webhook_id, 
   p.webhook_url, 
   p.webhook_created_at, 
+  p.webhook_verified_at,
   p.created_at, 
   p.updated_at,
   e.name AS ecosystem_name,
   p.language,
   p.tags,
-  p.category
+  p.category,
+  p.repo_permission
 FROM projects p
 LEFT JOIN ecosystems e ON p.ecosystem_id = e.id
 WHERE p.owner_user_id = $1
@@ -184,7 +187,7 @@ ORDER BY p.created_at DESC
 		defer rows.Close()
 
 		// Get user's GitHub access token for fetching repo data
-		linkedAccount, err := github.GetLinkedAccount(c.Context(), h.db.Pool, userID, h.cfg.TokenEncKeyB64)
+		linkedAccount, err := github.GetLinkedAccount(c.Context(), h.db.Pool, userID, h.cfg.TokenEncKeys())
 		var accessToken string
 		if err == nil {
 			accessToken = linkedAccount.AccessToken
@@ -201,13 +204,15 @@ ORDER BY p.created_at DESC
 			var webhookID *int64
 			var webhookURL *string
 			var webhookCreatedAt *time.Time
+			var webhookVerifiedAt *time.Time
 			var createdAt, updatedAt time.Time
 			var ecosystemName *string
 			var language *string
 			var tagsJSON []byte
 			var category *string
+			var repoPermission *string
 
-			if err := rows.Scan(&id, &fullName, &status, &repoID, &verifiedAt, &verErr, &webhookID, &webhookURL, &webhookCreatedAt, &createdAt, &updatedAt, &ecosystemName, &language, &tagsJSON, &category); err != nil {
+			if err := rows.Scan(&id, &fullName, &status, &repoID, &verifiedAt, &verErr, &webhookID, &webhookURL, &webhookCreatedAt, &webhookVerifiedAt, &createdAt, &updatedAt, &ecosystemName, &language, &tagsJSON, &category, &repoPermission); err != nil {
 				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "projects_list_failed"})
 			}
 
@@ -245,21 +250,23 @@ WHERE id = $1
 			}
 
 			projectMap := fiber.Map{
-				"id":                 id.String(),
-				"github_full_name":   fullName,
-				"status":             status,
-				"github_repo_id":     repoID,
-				"verified_at":        verifiedAt,
-				"verification_error": verErr,
-				"webhook_id":         webhookID,
-				"webhook_url":        webhookURL,
-				"webhook_created_at": webhookCreatedAt,
-				"created_at":         createdAt,
-				"updated_at":         updatedAt,
-				"ecosystem_name":     ecosystemName,
-				"language":           language,
-				"tags":               tags,
-				"category":           category,
+				"id":                  id.String(),
+				"github_full_name":    fullName,
+				"status":              status,
+				"github_repo_id":      repoID,
+				"verified_at":         verifiedAt,
+				"verification_error":  verErr,
+				"webhook_id":          webhookID,
+				"webhook_url":         webhookURL,
+				"webhook_created_at":  webhookCreatedAt,
+				"webhook_verified_at": webhookVerifiedAt,
+				"created_at":          createdAt,
+				"updated_at":          updatedAt,
+				"ecosystem_name":      ecosystemName,
+				"language":            language,
+				"tags":                tags,
+				"category":            category,
+				"repo_permission":     repoPermission,
 			}
 
 			// Add owner avatar if available
@@ -345,7 +352,15 @@ func (h *ProjectsHandler) verifyAndWebhook(ctx context.Context, projectID uuid.U
 		return
 	}
 
-	linked, err := github.GetLinkedAccount(ctx, h.db.Pool, ownerUserID, h.cfg.TokenEncKeyB64)
+	linked, err := github.GetLinkedAccount(ctx, h.db.Pool, ownerUserID, h.cfg.TokenEncKeys())
+	if errors.Is(err, github.ErrReauthRequired) {
+		h.recordProjectError(ctx, projectID, "reauth_required")
+		return
+	}
+	if errors.Is(err, github.ErrTokenNotStored) {
+		h.recordProjectError(ctx, projectID, "token_not_stored")
+		return
+	}
 	if err != nil {
 		h.recordProjectError(ctx, projectID, "github_not_linked")
 		return
@@ -353,14 +368,32 @@ func (h *ProjectsHandler) verifyAndWebhook(ctx context.Context, projectID uuid.U
 
 	gh := github.NewClient()
 	repo, err := gh.GetRepo(ctx, linked.AccessToken, fullName)
+	if github.IsBadCredentials(err) {
+		_ = github.MarkReauthRequired(ctx, h.db.Pool, ownerUserID)
+		h.recordProjectError(ctx, projectID, "reauth_required")
+		return
+	}
 	if err != nil {
-		h.recordProjectError(ctx, projectID, fmt.Sprintf("repo_fetch_failed: %v", err))
+		err = github.ClassifyRepoAccessError(err)
+		switch {
+		case errors.Is(err, github.ErrRepoNotFound):
+			h.recordProjectError(ctx, projectID, "repo_not_found_or_no_access")
+		case errors.Is(err, github.ErrRepoForbidden):
+			h.recordProjectError(ctx, projectID, fmt.Sprintf("repo_access_forbidden: %v", err))
+		default:
+			h.recordProjectError(ctx, projectID, fmt.Sprintf("repo_fetch_failed: %v", err))
+		}
 		return
 	}
 
-	// Ownership/permission check: allow if the token has admin or push perms.
-	if !repo.Permissions.Admin && !repo.Permissions.Push {
-		h.recordProjectError(ctx, projectID, "insufficient_repo_permissions (need admin or push)")
+	permission := repo.Permissions.Level()
+	_, _ = h.db.Pool.Exec(ctx, `
+UPDATE projects SET repo_permission = NULLIF($2, ''), updated_at = now() WHERE id = $1
+`, projectID, permission)
+
+	// Ownership/permission check: write (push) or higher, i.e. write, maintain or admin.
+	if !github.RepoPermissionAtLeast(permission, github.RepoPermissionWrite) {
+		h.recordProjectError(ctx, projectID, fmt.Sprintf("insufficient_repo_permissions (have %q, need write or higher)", permission))
 		return
 	}
 
@@ -380,23 +413,46 @@ WHERE id = $1
 		return
 	}
 
-	if h.cfg.PublicBaseURL == "" || h.cfg.GitHubWebhookSecret == "" {
-		h.recordProjectError(ctx, projectID, "webhook_not_configured (PUBLIC_BASE_URL and GITHUB_WEBHOOK_SECRET required)")
+	// With token encryption configured every hook gets its own secret, stored encrypted;
+	// otherwise hooks share GITHUB_WEBHOOK_SECRET.
+	var secretKeys *cryptox.Keyring
+	if h.cfg.TokenEncKeys() != "" {
+		if secretKeys, err = cryptox.NewKeyring(h.cfg.TokenEncKeys()); err != nil {
+			h.recordProjectError(ctx, projectID, "token_encryption_not_configured")
+			return
+		}
+	}
+	if h.cfg.PublicBaseURL == "" || (secretKeys == nil && h.cfg.GitHubWebhookSecret == "") {
+		h.recordProjectError(ctx, projectID, "webhook_not_configured (PUBLIC_BASE_URL and TOKEN_ENC_KEY_B64 or GITHUB_WEBHOOK_SECRET required)")
 		return
 	}
 
 	webhookURL := strings.TrimRight(h.cfg.PublicBaseURL, "/") + "/webhooks/github"
+	hookCfg := github.WebhookConfig{
+		CallbackURL: webhookURL,
+		Events:      []string{"issues", "pull_request", "pull_request_review", "push"},
+	}
+	if secretKeys == nil {
+		hookCfg.Secret = h.cfg.GitHubWebhookSecret
+	}
 
-	wh, err := gh.CreateWebhook(ctx, linked.AccessToken, fullName, github.CreateWebhookRequest{
-		URL:    webhookURL,
-		Secret: h.cfg.GitHubWebhookSecret,
-		Events: []string{"issues", "pull_request", "pull_request_review", "push"},
-		Active: true,
-	})
+	owner, repoName, _ := strings.Cut(fullName, "/")
+	wh, err := gh.CreateRepoWebhook(ctx, h.webhookAccessToken(ctx, projectID, linked.AccessToken), owner, repoName, hookCfg)
+	if errors.Is(err, github.ErrWebhookExists) {
+		h.recordProjectError(ctx, projectID, "webhook_already_exists (remove the existing hook for "+webhookURL+" on GitHub and retry)")
+		return
+	}
 	if err != nil {
 		h.recordProjectError(ctx, projectID, fmt.Sprintf("webhook_create_failed: %v", err))
 		return
 	}
+	var encSecret []byte
+	if secretKeys != nil {
+		if encSecret, err = secretKeys.Encrypt([]byte(wh.Secret)); err != nil {
+			h.recordProjectError(ctx, projectID, "webhook_secret_encrypt_failed")
+			return
+		}
+	}
 
 	_, _ = h.db.Pool.Exec(ctx, `
 UPDATE projects
@@ -407,11 +463,46 @@ SET github_repo_id = $2,
     webhook_id = $3,
     webhook_url = $4,
     webhook_created_at = now(),
+    webhook_verified_at = NULL,
+    webhook_secret = $7,
     stars_count = $5,
     forks_count = $6,
     updated_at = now()
 WHERE id = $1
-`, projectID, repo.ID, wh.ID, webhookURL, repo.StargazersCount, repo.ForksCount)
+`, projectID, repo.ID, wh.ID, webhookURL, repo.StargazersCount, repo.ForksCount, encSecret)
+
+	if h.cfg.WebhookPingWait > 0 {
+		h.awaitWebhookPing(ctx, projectID, h.cfg.WebhookPingWait)
+	}
+}
+
+// awaitWebhookPing polls for the webhook_verified_at set by the receiver when GitHub's
+// ping arrives. A missing ping usually means PUBLIC_BASE_URL is unreachable or the
+// secret doesn't match; that is noted on the project without un-verifying it.
+func (h *ProjectsHandler) awaitWebhookPing(ctx context.Context, projectID uuid.UUID, wait time.Duration) {
+	ctx, cancel := context.WithTimeout(ctx, wait)
+	defer cancel()
+
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		var verifiedAt *time.Time
+		if err := h.db.Pool.QueryRow(ctx, `SELECT webhook_verified_at FROM projects WHERE id = $1`, projectID).Scan(&verifiedAt); err == nil && verifiedAt != nil {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			slog.Warn("webhook ping not received", "project_id", projectID, "wait", wait)
+			_, _ = h.db.Pool.Exec(context.Background(), `
+UPDATE projects
+SET verification_error = $2, updated_at = now()
+WHERE id = $1
+  AND webhook_verified_at IS NULL
+`, projectID, "webhook_ping_not_received (check PUBLIC_BASE_URL and GITHUB_WEBHOOK_SECRET)")
+			return
+		case <-ticker.C:
+		}
+	}
 }
 
 func (h *ProjectsHandler) recordProjectError(ctx context.Context, projectID uuid.UUID, msg string) {
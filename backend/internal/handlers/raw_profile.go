@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/jagadeesh/grainlify/backend/internal/cache"
+	"github.com/jagadeesh/grainlify/backend/internal/config"
+	"github.com/jagadeesh/grainlify/backend/internal/github"
+)
+
+// Raw /user responses are cached briefly so a client polling Me/Status with
+// ?include=raw_profile doesn't spend one GitHub request per call.
+var (
+	rawProfileCacheOnce sync.Once
+	rawProfileCache     *cache.LRU[uuid.UUID, json.RawMessage]
+)
+
+// rawProfiles returns the raw profile cache, built from the CACHE_* settings on first use.
+func rawProfiles(cfg config.Config) *cache.LRU[uuid.UUID, json.RawMessage] {
+	rawProfileCacheOnce.Do(func() {
+		rawProfileCache = cache.New[uuid.UUID, json.RawMessage](cfg.CacheMaxEntries, cappedCacheTTL(cfg, time.Minute))
+		cache.Register("github_raw_profile", rawProfileCache)
+	})
+	return rawProfileCache
+}
+
+// cappedCacheTTL is CACHE_DEFAULT_TTL for a cache of GitHub data that goes stale after max:
+// a longer or unset (never expiring) default gives max.
+func cappedCacheTTL(cfg config.Config, max time.Duration) time.Duration {
+	if cfg.CacheDefaultTTL <= 0 || cfg.CacheDefaultTTL > max {
+		return max
+	}
+	return cfg.CacheDefaultTTL
+}
+
+// wantsInclude reports whether the comma-separated ?include= query names item.
+func wantsInclude(c *fiber.Ctx, item string) bool {
+	for _, v := range strings.Split(c.Query("include"), ",") {
+		if strings.TrimSpace(v) == item {
+			return true
+		}
+	}
+	return false
+}
+
+// rawGitHubProfile returns the user's raw GitHub /user JSON, from cache when fresh.
+func rawGitHubProfile(ctx context.Context, cfg config.Config, gh *github.Client, userID uuid.UUID, accessToken string) (json.RawMessage, error) {
+	if raw, ok := rawProfiles(cfg).Get(userID); ok {
+		return raw, nil
+	}
+	raw, err := gh.GetUserRaw(ctx, accessToken)
+	if err != nil {
+		return nil, err
+	}
+	rawProfiles(cfg).Set(userID, raw)
+	return raw, nil
+}
@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jagadeesh/grainlify/backend/internal/config"
+)
+
+func TestCappedCacheTTL(t *testing.T) {
+	cases := []struct {
+		defaultTTL time.Duration
+		want       time.Duration
+	}{
+		{30 * time.Second, 30 * time.Second},
+		{5 * time.Minute, time.Minute},
+		{0, time.Minute},
+	}
+	for _, tc := range cases {
+		if got := cappedCacheTTL(config.Config{CacheDefaultTTL: tc.defaultTTL}, time.Minute); got != tc.want {
+			t.Errorf("CACHE_DEFAULT_TTL=%s: ttl = %s, want %s", tc.defaultTTL, got, tc.want)
+		}
+	}
+}
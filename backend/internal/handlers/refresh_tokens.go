@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/jagadeesh/grainlify/backend/internal/audit"
+	"github.com/jagadeesh/grainlify/backend/internal/auth"
+	"github.com/jagadeesh/grainlify/backend/internal/clientip"
+)
+
+// IssueRefreshToken starts a refresh token family for the signed-in user.
+func (h *AuthHandler) IssueRefreshToken() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if h.db == nil || h.db.Pool == nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "db_not_configured"})
+		}
+		if h.cfg.JWTSecret == "" {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "jwt_not_configured"})
+		}
+
+		userIDStr, _ := c.Locals(auth.LocalUserID).(string)
+		userID, err := uuid.Parse(userIDStr)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid_user"})
+		}
+
+		token, err := auth.IssueRefreshToken(h.cfg.JWTSecret, userID, h.cfg.RefreshTokenTTL)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "refresh_token_issue_failed"})
+		}
+		rt, err := auth.SaveRefreshToken(c.Context(), h.db.Pool, h.cfg.JWTSecret, token)
+		if err != nil {
+			slog.Error("failed to issue refresh token", "error", err, "user_id", userID)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "refresh_token_issue_failed"})
+		}
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"refresh_token": rt.Token,
+			"expires_at":    rt.ExpiresAt,
+		})
+	}
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Refresh rotates a refresh token and returns a new access token with the replacement.
+func (h *AuthHandler) Refresh() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if h.db == nil || h.db.Pool == nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "db_not_configured"})
+		}
+		if h.cfg.JWTSecret == "" {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "jwt_not_configured"})
+		}
+
+		var req refreshRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_json"})
+		}
+		if req.RefreshToken == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "missing_refresh_token"})
+		}
+
+		rt, err := auth.RotateRefreshToken(c.Context(), h.db.Pool, h.cfg.JWTSecret, req.RefreshToken, h.cfg.RefreshTokenTTL)
+		if errors.Is(err, auth.ErrRefreshTokenReused) {
+			slog.Warn("refresh token reuse detected, family revoked", "user_id", rt.UserID, "family_id", rt.FamilyID)
+			if err := audit.Record(c.Context(), h.db, audit.Event{
+				UserID:    &rt.UserID,
+				Action:    audit.ActionRefreshTokenReuse,
+				Outcome:   "family_revoked",
+				IP:        clientip.FromRequest(c, h.cfg),
+				UserAgent: c.Get("User-Agent"),
+				Metadata:  map[string]any{"family_id": rt.FamilyID.String(), "token_id": rt.ID.String()},
+			}); err != nil {
+				slog.Error("failed to record audit event", "error", err, "action", audit.ActionRefreshTokenReuse, "user_id", rt.UserID)
+			}
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid_refresh_token"})
+		}
+		if errors.Is(err, auth.ErrRefreshTokenInvalid) {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid_refresh_token"})
+		}
+		if err != nil {
+			slog.Error("failed to rotate refresh token", "error", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "refresh_failed"})
+		}
+
+		var role string
+		// The refresh token is already rotated; failing here would cost the user their session.
+		err = h.db.WithRetry(c.Context(), func(ctx context.Context) error {
+			return h.db.Pool.QueryRow(ctx, `SELECT role FROM users WHERE id = $1`, rt.UserID).Scan(&role)
+		})
+		if err != nil {
+			slog.Error("failed to load user for refresh", "error", err, "user_id", rt.UserID)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "refresh_failed"})
+		}
+		token, err := issueLoginToken(c.Context(), h.cfg, rt.UserID, role)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "token_issue_failed"})
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"token":                    token,
+			"refresh_token":            rt.Token,
+			"refresh_token_expires_at": rt.ExpiresAt,
+		})
+	}
+}
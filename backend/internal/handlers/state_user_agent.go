@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/jagadeesh/grainlify/backend/internal/config"
+)
+
+// uaBrowserMarkers are checked in order: browsers put their own token after the ones they
+// claim compatibility with (Edge and Opera also send Chrome/ and Safari/).
+var uaBrowserMarkers = []struct{ token, family string }{
+	{"Edg/", "edge"},
+	{"EdgA/", "edge"},
+	{"EdgiOS/", "edge"},
+	{"OPR/", "opera"},
+	{"SamsungBrowser/", "samsung"},
+	{"Firefox/", "firefox"},
+	{"FxiOS/", "firefox"},
+	{"CriOS/", "chrome"},
+	{"Chrome/", "chrome"},
+	{"Version/", "safari"},
+}
+
+// userAgentFamily reduces a User-Agent to "family/major" (e.g. "chrome/120"), so that the
+// minor updates browsers apply on their own don't count as a different browser. Unrecognized
+// agents fall back to their first product token without its version.
+func userAgentFamily(ua string) string {
+	ua = strings.TrimSpace(ua)
+	if ua == "" {
+		return ""
+	}
+	for _, m := range uaBrowserMarkers {
+		if i := strings.Index(ua, m.token); i >= 0 {
+			return m.family + "/" + majorVersion(ua[i+len(m.token):])
+		}
+	}
+	product, _, _ := strings.Cut(ua, " ")
+	name, _, _ := strings.Cut(product, "/")
+	return strings.ToLower(name)
+}
+
+// majorVersion returns the leading digits of a version string ("120.0.1" -> "120").
+func majorVersion(v string) string {
+	end := 0
+	for end < len(v) && v[end] >= '0' && v[end] <= '9' {
+		end++
+	}
+	return v[:end]
+}
+
+// userAgentHash is what STATE_BIND_USER_AGENT stores with a login state: a hash of the
+// browser family and major version, never the raw User-Agent.
+func userAgentHash(ua string) string {
+	sum := sha256.Sum256([]byte(userAgentFamily(ua)))
+	return hex.EncodeToString(sum[:])
+}
+
+// stateUserAgentHash is the user_agent_hash to store with a new login state: the request's
+// hash with STATE_BIND_USER_AGENT, nil otherwise.
+func stateUserAgentHash(c *fiber.Ctx, cfg config.Config) *string {
+	if !cfg.StateBindUserAgent {
+		return nil
+	}
+	hash := userAgentHash(c.Get(fiber.HeaderUserAgent))
+	return &hash
+}
+
+// stateUserAgentMatches reports whether the callback's User-Agent is the browser that started
+// the flow. States stored without a hash (option off at the time) always match.
+func stateUserAgentMatches(stored *string, ua string) bool {
+	return stored == nil || *stored == userAgentHash(ua)
+}
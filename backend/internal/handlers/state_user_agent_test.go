@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/jagadeesh/grainlify/backend/internal/config"
+)
+
+func TestUserAgentFamily(t *testing.T) {
+	cases := map[string]string{
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.6099.130 Safari/537.36":               "chrome/120",
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Edg/120.0.2210.91":  "edge/120",
+		"Mozilla/5.0 (X11; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0":                                                             "firefox/121",
+		"Mozilla/5.0 (Macintosh; Intel Mac OS X 14_2) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.2 Safari/605.1.15":                 "safari/17",
+		"Mozilla/5.0 (iPhone; CPU iPhone OS 17_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) CriOS/120.0.6099.119 Mobile/15E148": "chrome/120",
+		"curl/8.4.0": "curl",
+		"":           "",
+	}
+	for ua, want := range cases {
+		if got := userAgentFamily(ua); got != want {
+			t.Errorf("userAgentFamily(%q) = %q, want %q", ua, got, want)
+		}
+	}
+}
+
+func TestStateUserAgentMatches(t *testing.T) {
+	start := "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.6099.130 Safari/537.36"
+	stored := userAgentHash(start)
+
+	if !stateUserAgentMatches(&stored, "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.6099.199 Safari/537.36") {
+		t.Error("a patch update of the same browser should match")
+	}
+	if stateUserAgentMatches(&stored, "Mozilla/5.0 (X11; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0") {
+		t.Error("a different browser must not match")
+	}
+	if stateUserAgentMatches(&stored, "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/99.0.0.0 Safari/537.36") {
+		t.Error("a different major version must not match")
+	}
+	if !stateUserAgentMatches(nil, "anything") {
+		t.Error("a state stored without a hash should match")
+	}
+}
+
+func TestStateUserAgentHash(t *testing.T) {
+	const ua = "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.6099.71 Safari/537.36"
+	var got []*string
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		got = append(got, stateUserAgentHash(c, config.Config{}), stateUserAgentHash(c, config.Config{StateBindUserAgent: true}))
+		return nil
+	})
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("User-Agent", ua)
+	if _, err := app.Test(req); err != nil {
+		t.Fatal(err)
+	}
+	if got[0] != nil {
+		t.Errorf("option off: hash = %v, want nil", *got[0])
+	}
+	if got[1] == nil || !stateUserAgentMatches(got[1], ua) {
+		t.Errorf("option on: hash = %v, want one matching the request's browser", got[1])
+	}
+}
@@ -706,7 +706,7 @@ LIMIT 10
 			// It's the authenticated user, try to get access token
 			sub, _ := c.Locals(auth.LocalUserID).(string)
 			if userID, parseErr := uuid.Parse(sub); parseErr == nil {
-				linkedAccount, err := github.GetLinkedAccount(c.Context(), h.db.Pool, userID, h.cfg.TokenEncKeyB64)
+				linkedAccount, err := github.GetLinkedAccount(c.Context(), h.db.Pool, userID, h.cfg.TokenEncKeys())
 				if err == nil {
 					accessToken = linkedAccount.AccessToken
 				}
@@ -714,7 +714,7 @@ LIMIT 10
 		} else if userIDParam != "" {
 			// Try to get access token for the specified user
 			if parsedUserID, parseErr := uuid.Parse(userIDParam); parseErr == nil {
-				linkedAccount, err := github.GetLinkedAccount(c.Context(), h.db.Pool, parsedUserID, h.cfg.TokenEncKeyB64)
+				linkedAccount, err := github.GetLinkedAccount(c.Context(), h.db.Pool, parsedUserID, h.cfg.TokenEncKeys())
 				if err == nil {
 					accessToken = linkedAccount.AccessToken
 				}
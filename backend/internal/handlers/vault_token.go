@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/google/uuid"
+
+	"github.com/jagadeesh/grainlify/backend/internal/tokenvault"
+)
+
+// errTokenVaultWrite means the token vault refused the token; nothing was stored.
+var errTokenVaultWrite = errors.New("token vault write failed")
+
+// putVaultToken hands an encrypted token to the token vault and returns what the token column
+// should hold. Callers discard the result with discardVaultToken if their row doesn't commit.
+// A nil token (none issued) is kept as is.
+func putVaultToken(ctx context.Context, userID uuid.UUID, encToken []byte) ([]byte, error) {
+	if encToken == nil {
+		return nil, nil
+	}
+	stored, err := tokenvault.Default().Put(ctx, userID, encToken)
+	if err != nil {
+		slog.Error("token vault write failed", "error", err, "user_id", userID)
+		return nil, errTokenVaultWrite
+	}
+	return stored, nil
+}
+
+// discardVaultToken removes a vault entry written for a row that never committed.
+func discardVaultToken(ctx context.Context, userID uuid.UUID, stored []byte) {
+	if stored == nil {
+		return
+	}
+	if err := tokenvault.Default().Discard(ctx, userID, stored); err != nil {
+		slog.Error("failed to discard unused vault token", "error", err, "user_id", userID)
+	}
+}
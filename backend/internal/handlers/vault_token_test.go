@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/jagadeesh/grainlify/backend/internal/github"
+	"github.com/jagadeesh/grainlify/backend/internal/tokenvault"
+)
+
+// recordingVault hands out a fresh reference per Put and remembers which ones were discarded.
+type recordingVault struct {
+	mu        sync.Mutex
+	puts      [][]byte
+	discarded [][]byte
+	putErr    error
+}
+
+func (v *recordingVault) Put(_ context.Context, _ uuid.UUID, _ []byte) ([]byte, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.putErr != nil {
+		return nil, v.putErr
+	}
+	ref := []byte("vault:secret/" + uuid.NewString())
+	v.puts = append(v.puts, ref)
+	return ref, nil
+}
+
+func (v *recordingVault) Get(_ context.Context, _ uuid.UUID, stored []byte) ([]byte, error) {
+	return stored, nil
+}
+
+func (v *recordingVault) Discard(_ context.Context, _ uuid.UUID, stored []byte) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.discarded = append(v.discarded, stored)
+	return nil
+}
+
+func (v *recordingVault) Delete(context.Context, uuid.UUID) error { return nil }
+
+func useRecordingVault(t *testing.T) *recordingVault {
+	t.Helper()
+	prev := tokenvault.Default()
+	v := &recordingVault{}
+	tokenvault.SetDefault(v)
+	t.Cleanup(func() { tokenvault.SetDefault(prev) })
+	return v
+}
+
+func TestStoreGitHubAccountDiscardsTokenWhenUpsertFails(t *testing.T) {
+	vault := useRecordingVault(t)
+	store := newMemLoginUserStore()
+	h := &GitHubOAuthHandler{users: store}
+	ctx := context.Background()
+	owner, other := uuid.New(), uuid.New()
+
+	if err := h.storeGitHubAccount(ctx, owner, github.User{ID: 7}, []byte("enc"), github.TokenResponse{}, false); err != nil {
+		t.Fatal(err)
+	}
+	err := h.storeGitHubAccount(ctx, other, github.User{ID: 7}, []byte("enc"), github.TokenResponse{}, false)
+	if !isUniqueViolation(err) {
+		t.Fatalf("err = %v, want the upsert's unique violation", err)
+	}
+
+	if len(vault.puts) != 2 {
+		t.Fatalf("got %d vault writes, want 2", len(vault.puts))
+	}
+	if len(vault.discarded) != 1 || string(vault.discarded[0]) != string(vault.puts[1]) {
+		t.Errorf("discarded %q, want only the failed write %q", vault.discarded, vault.puts[1])
+	}
+}
+
+func TestStoreGitHubAccountVaultFailure(t *testing.T) {
+	vault := useRecordingVault(t)
+	vault.putErr = errors.New("sealed")
+	store := newMemLoginUserStore()
+	h := &GitHubOAuthHandler{users: store}
+
+	err := h.storeGitHubAccount(context.Background(), uuid.New(), github.User{ID: 7}, []byte("enc"), github.TokenResponse{}, false)
+	if !errors.Is(err, errTokenVaultWrite) {
+		t.Errorf("err = %v, want errTokenVaultWrite", err)
+	}
+	if len(store.accounts) != 0 {
+		t.Errorf("account written despite the vault failure")
+	}
+}
@@ -0,0 +1,54 @@
+package mail
+
+import (
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client sends plain-text notification email through an SMTP relay.
+type Client struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// NewClient returns nil when host or from is empty, i.e. email is not configured.
+func NewClient(host string, port int, username, password, from string) *Client {
+	if strings.TrimSpace(host) == "" || strings.TrimSpace(from) == "" {
+		return nil
+	}
+	return &Client{Host: host, Port: port, Username: username, Password: password, From: from}
+}
+
+func (c *Client) Send(to, subject, body string) error {
+	if c == nil {
+		return fmt.Errorf("mail not configured")
+	}
+	if strings.ContainsAny(to, "\r\n") || strings.ContainsAny(subject, "\r\n") {
+		return fmt.Errorf("invalid mail header")
+	}
+
+	msg := strings.Join([]string{
+		"From: " + c.From,
+		"To: " + to,
+		"Subject: " + subject,
+		"Date: " + time.Now().UTC().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"",
+		body,
+	}, "\r\n")
+
+	var a smtp.Auth
+	if c.Username != "" {
+		a = smtp.PlainAuth("", c.Username, c.Password, c.Host)
+	}
+	addr := net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+	return smtp.SendMail(addr, a, c.From, []string{to}, []byte(msg))
+}
@@ -0,0 +1,41 @@
+package metrics
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWritePrometheus(t *testing.T) {
+	r := &Registry{}
+	c := r.NewCounterVec("logins_total", "Logins by result.", "result")
+	h := r.NewHistogram("callback_seconds", "Callback latency.", []float64{0.1, 1})
+
+	c.Inc("success")
+	c.Inc("success")
+	c.Inc(`bad "code"`)
+	h.Observe(0.05)
+	h.Observe(0.5)
+	h.Observe(3)
+
+	if got := c.Value("success"); got != 2 {
+		t.Errorf("Value(success) = %v, want 2", got)
+	}
+
+	var buf bytes.Buffer
+	r.WritePrometheus(&buf)
+	want := `# HELP logins_total Logins by result.
+# TYPE logins_total counter
+logins_total{result="bad \"code\""} 1
+logins_total{result="success"} 2
+# HELP callback_seconds Callback latency.
+# TYPE callback_seconds histogram
+callback_seconds_bucket{le="0.1"} 1
+callback_seconds_bucket{le="1"} 2
+callback_seconds_bucket{le="+Inf"} 3
+callback_seconds_sum 3.55
+callback_seconds_count 3
+`
+	if buf.String() != want {
+		t.Errorf("exposition:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
@@ -0,0 +1,39 @@
+package oauthprovider
+
+import (
+	"context"
+	"testing"
+)
+
+type namedProvider string
+
+func (p namedProvider) Name() string                                { return string(p) }
+func (p namedProvider) AuthorizeURL(string, string) (string, error) { return "", nil }
+func (p namedProvider) ExchangeCode(context.Context, string, string) (Token, error) {
+	return Token{}, nil
+}
+func (p namedProvider) GetUser(context.Context, string) (User, error) { return User{}, nil }
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry(namedProvider("github"))
+	if err := r.Register(namedProvider("gitlab")); err != nil {
+		t.Fatalf("Register(gitlab): %v", err)
+	}
+	if err := r.Register(namedProvider("gitlab")); err == nil {
+		t.Error("expected duplicate registration to fail")
+	}
+	for _, bad := range []string{"", "GitLab", "git_lab", "1lab"} {
+		if err := r.Register(namedProvider(bad)); err == nil {
+			t.Errorf("expected name %q to be rejected", bad)
+		}
+	}
+	if _, ok := r.Get("gitlab"); !ok {
+		t.Error("expected gitlab to be registered")
+	}
+	if got := r.Names(); len(got) != 2 || got[0] != "github" || got[1] != "gitlab" {
+		t.Errorf("Names() = %v", got)
+	}
+	if LoginKind("gitlab") != "gitlab_login" {
+		t.Errorf("LoginKind = %q", LoginKind("gitlab"))
+	}
+}
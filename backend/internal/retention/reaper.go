@@ -0,0 +1,130 @@
+package retention
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/jagadeesh/grainlify/backend/internal/audit"
+	"github.com/jagadeesh/grainlify/backend/internal/config"
+)
+
+const (
+	reapInterval = time.Hour
+	// Small batches keep each DELETE's row locks short on busy tables.
+	reapBatchSize = 1000
+)
+
+// Policy deletes rows of Table whose TimeColumn is older than MaxAge. Filter, when set, is an
+// extra SQL condition narrowing which rows the policy owns.
+type Policy struct {
+	Name       string
+	Table      string
+	TimeColumn string
+	Filter     string
+	MaxAge     time.Duration
+}
+
+// Reaper periodically enforces the retention windows from config.
+type Reaper struct {
+	pool     *pgxpool.Pool
+	policies []Policy
+}
+
+func New(cfg config.Config, pool *pgxpool.Pool) *Reaper {
+	return &Reaper{pool: pool, policies: append(Policies(cfg), ExpiryPolicies()...)}
+}
+
+// ExpiryPolicies remove rows that are useless once their own expires_at has passed. They are
+// not configurable.
+func ExpiryPolicies() []Policy {
+	return []Policy{
+		// A revoked access token past its expiry is rejected by the signature check anyway.
+		{Name: "revoked_tokens", Table: "revoked_tokens", TimeColumn: "expires_at"},
+	}
+}
+
+// Policies turns the *_RETENTION_DAYS settings into policies; a zero window keeps rows forever.
+// Logins live in audit_events too but have their own (usually shorter) window.
+func Policies(cfg config.Config) []Policy {
+	days := func(n int) time.Duration { return time.Duration(n) * 24 * time.Hour }
+	loginFilter := fmt.Sprintf("action = '%s'", audit.ActionLogin)
+
+	var out []Policy
+	if cfg.AuditRetentionDays > 0 {
+		out = append(out, Policy{Name: "audit_events", Table: "audit_events", TimeColumn: "created_at", Filter: "NOT (" + loginFilter + ")", MaxAge: days(cfg.AuditRetentionDays)})
+	}
+	if cfg.LoginHistoryRetentionDays > 0 {
+		out = append(out, Policy{Name: "login_history", Table: "audit_events", TimeColumn: "created_at", Filter: loginFilter, MaxAge: days(cfg.LoginHistoryRetentionDays)})
+	}
+	if cfg.WebhookDeliveryRetentionDays > 0 {
+		out = append(out, Policy{Name: "webhook_deliveries", Table: "github_webhook_deliveries", TimeColumn: "received_at", MaxAge: days(cfg.WebhookDeliveryRetentionDays)})
+	}
+	return out
+}
+
+// Run reaps once at startup and then every hour until ctx is done.
+func (r *Reaper) Run(ctx context.Context) {
+	if r.pool == nil || len(r.policies) == 0 {
+		slog.Info("retention reaper disabled")
+		return
+	}
+	slog.Info("retention reaper started", "policies", len(r.policies))
+
+	ticker := time.NewTicker(reapInterval)
+	defer ticker.Stop()
+	for {
+		r.RunOnce(ctx)
+		select {
+		case <-ctx.Done():
+			slog.Info("retention reaper stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce applies every policy and logs how many rows each removed.
+func (r *Reaper) RunOnce(ctx context.Context) {
+	for _, p := range r.policies {
+		removed, err := r.purge(ctx, p, time.Now().Add(-p.MaxAge))
+		if err != nil {
+			slog.Error("retention purge failed", "policy", p.Name, "error", err, "removed", removed)
+			continue
+		}
+		if removed > 0 {
+			slog.Info("retention purge", "policy", p.Name, "removed", removed, "older_than", p.MaxAge.String())
+		}
+	}
+}
+
+func (r *Reaper) purge(ctx context.Context, p Policy, cutoff time.Time) (int64, error) {
+	query := deleteBatchSQL(p)
+	var total int64
+	for {
+		ct, err := r.pool.Exec(ctx, query, cutoff, reapBatchSize)
+		if err != nil {
+			return total, err
+		}
+		total += ct.RowsAffected()
+		if ct.RowsAffected() < reapBatchSize {
+			return total, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+	}
+}
+
+// deleteBatchSQL builds a DELETE of at most $2 rows older than $1. Table, column and filter
+// come from Policies, never from user input.
+func deleteBatchSQL(p Policy) string {
+	where := p.TimeColumn + " < $1"
+	if p.Filter != "" {
+		where += " AND " + p.Filter
+	}
+	return fmt.Sprintf(`DELETE FROM %s WHERE ctid IN (SELECT ctid FROM %s WHERE %s LIMIT $2)`, p.Table, p.Table, where)
+}
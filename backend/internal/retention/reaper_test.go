@@ -0,0 +1,42 @@
+package retention
+
+import (
+	"testing"
+
+	"github.com/jagadeesh/grainlify/backend/internal/config"
+)
+
+func TestPolicies(t *testing.T) {
+	if got := Policies(config.Config{}); len(got) != 0 {
+		t.Fatalf("zero retention must keep everything, got %d policies", len(got))
+	}
+
+	got := Policies(config.Config{AuditRetentionDays: 365, LoginHistoryRetentionDays: 90, WebhookDeliveryRetentionDays: 30})
+	if len(got) != 3 {
+		t.Fatalf("got %d policies, want 3", len(got))
+	}
+	want := map[string]string{
+		"audit_events":       `DELETE FROM audit_events WHERE ctid IN (SELECT ctid FROM audit_events WHERE created_at < $1 AND NOT (action = 'github_login') LIMIT $2)`,
+		"login_history":      `DELETE FROM audit_events WHERE ctid IN (SELECT ctid FROM audit_events WHERE created_at < $1 AND action = 'github_login' LIMIT $2)`,
+		"webhook_deliveries": `DELETE FROM github_webhook_deliveries WHERE ctid IN (SELECT ctid FROM github_webhook_deliveries WHERE received_at < $1 LIMIT $2)`,
+	}
+	for _, p := range got {
+		if sql := deleteBatchSQL(p); sql != want[p.Name] {
+			t.Errorf("%s:\n got %s\nwant %s", p.Name, sql, want[p.Name])
+		}
+	}
+}
+
+func TestExpiryPolicies(t *testing.T) {
+	got := ExpiryPolicies()
+	if len(got) != 1 || got[0].MaxAge != 0 {
+		t.Fatalf("unexpected expiry policies: %+v", got)
+	}
+	want := `DELETE FROM revoked_tokens WHERE ctid IN (SELECT ctid FROM revoked_tokens WHERE expires_at < $1 LIMIT $2)`
+	if sql := deleteBatchSQL(got[0]); sql != want {
+		t.Errorf("revoked_tokens:\n got %s\nwant %s", sql, want)
+	}
+	if n := len(New(config.Config{}, nil).policies); n != 1 {
+		t.Errorf("reaper must prune expired revocations even without retention settings, got %d policies", n)
+	}
+}
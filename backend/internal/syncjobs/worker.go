@@ -127,7 +127,7 @@ WHERE id = $1
 		return err
 	}
 
-	linked, err := github.GetLinkedAccount(ctx, w.pool, ownerUserID, w.cfg.TokenEncKeyB64)
+	linked, err := github.GetLinkedAccount(ctx, w.pool, ownerUserID, w.cfg.TokenEncKeys())
 	if err != nil {
 		slog.Error("sync job failed: GitHub account not linked",
 			"job_id", jobID,
@@ -0,0 +1,189 @@
+package tokenvault
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// HashiCorpVault stores ciphertext in a HashiCorp Vault KV v2 engine. Every Put writes a new
+// secret under the user's path, so a write whose row never commits can be discarded without
+// touching the token the row still references. Rows keep an ExternalRefPrefix reference to
+// their secret. Rows that still hold ciphertext (written before switching backends), or a
+// reference to the older one-secret-per-user path, keep working.
+type HashiCorpVault struct {
+	HTTP  *http.Client
+	Addr  string // e.g. https://vault.internal:8200
+	Token string
+	Mount string // KV v2 mount, e.g. "secret"
+	Path  string // path prefix under the mount, e.g. "grainlify/github-tokens"
+}
+
+func NewHashiCorpVault(addr, token, mount, path string) *HashiCorpVault {
+	return &HashiCorpVault{
+		HTTP:  &http.Client{Timeout: 10 * time.Second},
+		Addr:  strings.TrimRight(addr, "/"),
+		Token: token,
+		Mount: strings.Trim(mount, "/"),
+		Path:  strings.Trim(path, "/"),
+	}
+}
+
+// userPath is where the user's secrets live; it was also the single secret of older writes.
+func (v *HashiCorpVault) userPath(userID uuid.UUID) string {
+	return v.Path + "/" + userID.String()
+}
+
+func (v *HashiCorpVault) url(kind, secretPath string) string {
+	return v.Addr + "/v1/" + url.PathEscape(v.Mount) + "/" + kind + "/" + secretPath
+}
+
+// refPath returns the secret path in a stored reference, which must belong to the user.
+func (v *HashiCorpVault) refPath(userID uuid.UUID, stored []byte) (string, error) {
+	p := string(bytes.TrimPrefix(stored, ExternalRefPrefix))
+	if up := v.userPath(userID); p != up && !strings.HasPrefix(p, up+"/") {
+		return "", fmt.Errorf("vault reference does not belong to the user")
+	}
+	return p, nil
+}
+
+func (v *HashiCorpVault) do(ctx context.Context, method, u string, body any) (*http.Response, error) {
+	var r *bytes.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		r = bytes.NewReader(b)
+	} else {
+		r = bytes.NewReader(nil)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, r)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", v.Token)
+	req.Header.Set("Content-Type", "application/json")
+	return v.HTTP.Do(req)
+}
+
+func (v *HashiCorpVault) Put(ctx context.Context, userID uuid.UUID, ciphertext []byte) ([]byte, error) {
+	secretPath := v.userPath(userID) + "/" + uuid.NewString()
+	resp, err := v.do(ctx, http.MethodPost, v.url("data", secretPath), map[string]any{
+		"data": map[string]string{"ciphertext": base64.StdEncoding.EncodeToString(ciphertext)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("vault write failed: status %d", resp.StatusCode)
+	}
+	return append(append([]byte{}, ExternalRefPrefix...), secretPath...), nil
+}
+
+func (v *HashiCorpVault) Get(ctx context.Context, userID uuid.UUID, stored []byte) ([]byte, error) {
+	if !IsExternalRef(stored) {
+		return stored, nil
+	}
+	secretPath, err := v.refPath(userID, stored)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := v.do(ctx, http.MethodGet, v.url("data", secretPath), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("vault read failed: status %d", resp.StatusCode)
+	}
+
+	var out struct {
+		Data struct {
+			Data struct {
+				Ciphertext string `json:"ciphertext"`
+			} `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, err
+	}
+	if out.Data.Data.Ciphertext == "" {
+		return nil, fmt.Errorf("vault secret has no ciphertext")
+	}
+	return base64.StdEncoding.DecodeString(out.Data.Data.Ciphertext)
+}
+
+// Discard removes the secret a stored reference points to, e.g. one written for a row that
+// never committed. Values that aren't references have nothing in Vault.
+func (v *HashiCorpVault) Discard(ctx context.Context, userID uuid.UUID, stored []byte) error {
+	if !IsExternalRef(stored) {
+		return nil
+	}
+	secretPath, err := v.refPath(userID, stored)
+	if err != nil {
+		return err
+	}
+	return v.deleteSecret(ctx, secretPath)
+}
+
+// Delete removes all of the user's secrets, with every version of each.
+func (v *HashiCorpVault) Delete(ctx context.Context, userID uuid.UUID) error {
+	keys, err := v.listUserSecrets(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := v.deleteSecret(ctx, v.userPath(userID)+"/"+key); err != nil {
+			return err
+		}
+	}
+	return v.deleteSecret(ctx, v.userPath(userID))
+}
+
+func (v *HashiCorpVault) listUserSecrets(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	resp, err := v.do(ctx, "LIST", v.url("metadata", v.userPath(userID)), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("vault list failed: status %d", resp.StatusCode)
+	}
+	var out struct {
+		Data struct {
+			Keys []string `json:"keys"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, err
+	}
+	return out.Data.Keys, nil
+}
+
+func (v *HashiCorpVault) deleteSecret(ctx context.Context, secretPath string) error {
+	resp, err := v.do(ctx, http.MethodDelete, v.url("metadata", secretPath), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("vault delete failed: status %d", resp.StatusCode)
+	}
+	return nil
+}
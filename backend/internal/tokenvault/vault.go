@@ -0,0 +1,87 @@
+// Package tokenvault decides where encrypted OAuth tokens live.
+//
+// Callers always encrypt first (cryptox) and hand the ciphertext to the vault, so the crypto
+// layer applies whatever the backend. Put returns the value to keep in the token column:
+// the ciphertext itself for the DB vault, or a reference for an external store.
+package tokenvault
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// ExternalRefPrefix marks a token column value as a reference into an external vault
+// rather than ciphertext.
+var ExternalRefPrefix = []byte("vault:")
+
+type TokenVault interface {
+	// Put stores the user's ciphertext and returns what to persist in the token column.
+	Put(ctx context.Context, userID uuid.UUID, ciphertext []byte) ([]byte, error)
+	// Get resolves the persisted column value back to ciphertext.
+	Get(ctx context.Context, userID uuid.UUID, stored []byte) ([]byte, error)
+	// Discard removes what Put stored for a value that never made it into the DB, leaving the
+	// user's other tokens alone.
+	Discard(ctx context.Context, userID uuid.UUID, stored []byte) error
+	// Delete removes anything held outside the DB for the user.
+	Delete(ctx context.Context, userID uuid.UUID) error
+}
+
+// DBVault keeps the ciphertext in the row itself (the default).
+type DBVault struct{}
+
+func (DBVault) Put(_ context.Context, _ uuid.UUID, ciphertext []byte) ([]byte, error) {
+	return ciphertext, nil
+}
+
+func (DBVault) Get(_ context.Context, _ uuid.UUID, stored []byte) ([]byte, error) {
+	if IsExternalRef(stored) {
+		return nil, fmt.Errorf("token stored in external vault but TOKEN_VAULT=db")
+	}
+	return stored, nil
+}
+
+func (DBVault) Discard(context.Context, uuid.UUID, []byte) error { return nil }
+
+func (DBVault) Delete(context.Context, uuid.UUID) error { return nil }
+
+func IsExternalRef(stored []byte) bool {
+	return bytes.HasPrefix(stored, ExternalRefPrefix)
+}
+
+var (
+	mu  sync.RWMutex
+	def TokenVault = DBVault{}
+)
+
+// SetDefault installs the process-wide vault; call once at startup.
+func SetDefault(v TokenVault) {
+	mu.Lock()
+	defer mu.Unlock()
+	def = v
+}
+
+// Default returns the process-wide vault (DBVault unless configured otherwise).
+func Default() TokenVault {
+	mu.RLock()
+	defer mu.RUnlock()
+	return def
+}
+
+// New builds the vault selected by TOKEN_VAULT ("db" or "hashicorp").
+func New(kind, addr, token, mount, path string) (TokenVault, error) {
+	switch kind {
+	case "", "db":
+		return DBVault{}, nil
+	case "hashicorp":
+		if addr == "" || token == "" {
+			return nil, fmt.Errorf("TOKEN_VAULT=hashicorp requires VAULT_ADDR and VAULT_TOKEN")
+		}
+		return NewHashiCorpVault(addr, token, mount, path), nil
+	default:
+		return nil, fmt.Errorf("unknown TOKEN_VAULT %q", kind)
+	}
+}
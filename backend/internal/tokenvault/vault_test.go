@@ -0,0 +1,145 @@
+package tokenvault
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestDBVaultRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	stored, err := DBVault{}.Put(ctx, userID, []byte("ciphertext"))
+	if err != nil || string(stored) != "ciphertext" {
+		t.Fatalf("Put = (%q, %v)", stored, err)
+	}
+	got, err := DBVault{}.Get(ctx, userID, stored)
+	if err != nil || string(got) != "ciphertext" {
+		t.Fatalf("Get = (%q, %v)", got, err)
+	}
+	if _, err := (DBVault{}).Get(ctx, userID, []byte("vault:x")); err == nil {
+		t.Error("expected error resolving an external reference without an external vault")
+	}
+}
+
+func TestHashiCorpVaultRoundTrip(t *testing.T) {
+	secrets := map[string][]byte{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		key := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/v1/secret/data/"), "/v1/secret/metadata/")
+		switch r.Method {
+		case http.MethodPost:
+			var body struct {
+				Data json.RawMessage `json:"data"`
+			}
+			b, _ := io.ReadAll(r.Body)
+			_ = json.Unmarshal(b, &body)
+			secrets[key] = body.Data
+			w.WriteHeader(http.StatusOK)
+		case http.MethodGet:
+			data, ok := secrets[key]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write([]byte(`{"data":{"data":` + string(data) + `}}`))
+		case http.MethodDelete:
+			delete(secrets, key)
+			w.WriteHeader(http.StatusNoContent)
+		case "LIST":
+			var keys []string
+			for k := range secrets {
+				if rest, ok := strings.CutPrefix(k, key+"/"); ok {
+					keys = append(keys, rest)
+				}
+			}
+			if len(keys) == 0 {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"keys": keys}})
+		}
+	}))
+	defer srv.Close()
+
+	v, err := New("hashicorp", srv.URL, "root", "secret", "grainlify/github-tokens")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	userID := uuid.New()
+
+	stored, err := v.Put(ctx, userID, []byte{0x00, 0xff, 0x10})
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if !IsExternalRef(stored) || bytes.Contains(stored, []byte{0xff}) {
+		t.Fatalf("expected a reference, got %q", stored)
+	}
+
+	got, err := v.Get(ctx, userID, stored)
+	if err != nil || !bytes.Equal(got, []byte{0x00, 0xff, 0x10}) {
+		t.Fatalf("Get = (%v, %v)", got, err)
+	}
+
+	// Every Put is a secret of its own: discarding an uncommitted one keeps the current token.
+	next, err := v.Put(ctx, userID, []byte("next"))
+	if err != nil || bytes.Equal(next, stored) {
+		t.Fatalf("second Put = (%q, %v), want a new reference", next, err)
+	}
+	if err := v.Discard(ctx, userID, next); err != nil {
+		t.Fatalf("Discard: %v", err)
+	}
+	if _, err := v.Get(ctx, userID, next); err == nil {
+		t.Error("expected error reading a discarded secret")
+	}
+	if got, err := v.Get(ctx, userID, stored); err != nil || !bytes.Equal(got, []byte{0x00, 0xff, 0x10}) {
+		t.Fatalf("Get after Discard = (%v, %v), want the first token", got, err)
+	}
+
+	// A reference is only resolved for the user it belongs to.
+	if _, err := v.Get(ctx, uuid.New(), stored); err == nil {
+		t.Error("expected error resolving another user's reference")
+	}
+
+	// Rows written before switching backends still hold ciphertext.
+	if got, err := v.Get(ctx, userID, []byte("legacy")); err != nil || string(got) != "legacy" {
+		t.Errorf("legacy Get = (%q, %v)", got, err)
+	}
+
+	// References to the older single secret per user still resolve.
+	legacyRef := []byte("vault:grainlify/github-tokens/" + userID.String())
+	secrets["grainlify/github-tokens/"+userID.String()] = json.RawMessage(`{"ciphertext":"bGVnYWN5"}`)
+	if got, err := v.Get(ctx, userID, legacyRef); err != nil || string(got) != "legacy" {
+		t.Errorf("legacy reference Get = (%q, %v)", got, err)
+	}
+
+	if err := v.Delete(ctx, userID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if len(secrets) != 0 {
+		t.Errorf("Delete left %d secrets", len(secrets))
+	}
+	if _, err := v.Get(ctx, userID, stored); err == nil {
+		t.Error("expected error after Delete")
+	}
+}
+
+func TestNewUnknownKind(t *testing.T) {
+	if _, err := New("s3", "", "", "", ""); err == nil {
+		t.Error("expected error for unknown vault kind")
+	}
+	if _, err := New("hashicorp", "", "", "", ""); err == nil {
+		t.Error("expected error when VAULT_ADDR/VAULT_TOKEN missing")
+	}
+}
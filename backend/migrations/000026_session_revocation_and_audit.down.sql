@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_audit_events_created;
+DROP INDEX IF EXISTS idx_audit_events_user_created;
+DROP TABLE IF EXISTS audit_events;
+
+ALTER TABLE users
+  DROP COLUMN IF EXISTS sessions_revoked_at;
@@ -0,0 +1,19 @@
+-- Revoke-all-sessions support: any JWT issued at or before this instant is rejected.
+ALTER TABLE users
+  ADD COLUMN IF NOT EXISTS sessions_revoked_at TIMESTAMPTZ;
+
+-- Security-relevant account actions (session revocation, links, logins, admin actions).
+CREATE TABLE IF NOT EXISTS audit_events (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  actor_user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+  user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+  action TEXT NOT NULL,
+  outcome TEXT NOT NULL DEFAULT 'success',
+  ip TEXT,
+  user_agent TEXT,
+  metadata JSONB NOT NULL DEFAULT '{}'::jsonb,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_events_user_created ON audit_events(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_events_created ON audit_events(created_at DESC);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_pending_links_expires;
+DROP TABLE IF EXISTS pending_links;
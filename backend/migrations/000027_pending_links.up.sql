@@ -0,0 +1,15 @@
+-- GitHub accounts awaiting explicit confirmation before being linked (REQUIRE_LINK_CONFIRMATION).
+-- At most one pending link per user; a newer authorization replaces the older one.
+CREATE TABLE IF NOT EXISTS pending_links (
+  user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+  github_user_id BIGINT NOT NULL,
+  login TEXT NOT NULL,
+  avatar_url TEXT,
+  access_token BYTEA NOT NULL,
+  token_type TEXT,
+  scope TEXT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_pending_links_expires ON pending_links(expires_at);
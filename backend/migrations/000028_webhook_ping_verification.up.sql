@@ -0,0 +1,4 @@
+-- Set when GitHub's ping for the project's webhook arrives with a valid signature,
+-- confirming the hook URL is reachable and the secret matches.
+ALTER TABLE projects
+  ADD COLUMN IF NOT EXISTS webhook_verified_at TIMESTAMPTZ;
@@ -0,0 +1,2 @@
+ALTER TABLE github_accounts
+  DROP COLUMN IF EXISTS reauth_required;
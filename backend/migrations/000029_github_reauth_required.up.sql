@@ -0,0 +1,4 @@
+-- Set when GitHub rejects the stored token (revoked, expired, app access removed).
+-- Cleared by the next successful link.
+ALTER TABLE github_accounts
+  ADD COLUMN IF NOT EXISTS reauth_required BOOLEAN NOT NULL DEFAULT false;
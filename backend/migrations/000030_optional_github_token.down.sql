@@ -0,0 +1,8 @@
+-- Accounts linked without a stored token cannot satisfy NOT NULL; they must re-link.
+DELETE FROM pending_links WHERE access_token IS NULL;
+ALTER TABLE pending_links
+  ALTER COLUMN access_token SET NOT NULL;
+
+DELETE FROM github_accounts WHERE access_token IS NULL;
+ALTER TABLE github_accounts
+  ALTER COLUMN access_token SET NOT NULL;
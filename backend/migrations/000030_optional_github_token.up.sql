@@ -0,0 +1,6 @@
+-- STORE_GITHUB_TOKEN=false links accounts without persisting the access token.
+ALTER TABLE github_accounts
+  ALTER COLUMN access_token DROP NOT NULL;
+
+ALTER TABLE pending_links
+  ALTER COLUMN access_token DROP NOT NULL;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_link_revocations_user;
+DROP TABLE IF EXISTS link_revocations;
+
+ALTER TABLE users
+  DROP COLUMN IF EXISTS notification_email;
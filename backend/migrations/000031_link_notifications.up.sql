@@ -0,0 +1,17 @@
+-- Address for account-security notices (NOTIFY_ON_LINK). Captured from the verified primary
+-- email at GitHub login and never overwritten by a later link, so a notice about a new link
+-- reaches the original owner.
+ALTER TABLE users
+  ADD COLUMN IF NOT EXISTS notification_email TEXT;
+
+-- One-time "this wasn't me" tokens mailed when a GitHub account is linked.
+CREATE TABLE IF NOT EXISTS link_revocations (
+  token_hash TEXT PRIMARY KEY,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  github_user_id BIGINT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  expires_at TIMESTAMPTZ NOT NULL,
+  used_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_link_revocations_user ON link_revocations(user_id);
@@ -0,0 +1,2 @@
+ALTER TABLE oauth_states
+  DROP CONSTRAINT IF EXISTS oauth_states_expires_at_bound;
@@ -0,0 +1,9 @@
+-- A state must never outlive its flow: reject expires_at more than an hour after insert,
+-- so a bug or clock skew can't create an effectively permanent, replayable state.
+DELETE FROM oauth_states WHERE expires_at > created_at + interval '1 hour';
+
+ALTER TABLE oauth_states
+  DROP CONSTRAINT IF EXISTS oauth_states_expires_at_bound;
+
+ALTER TABLE oauth_states
+  ADD CONSTRAINT oauth_states_expires_at_bound CHECK (expires_at <= created_at + interval '1 hour');
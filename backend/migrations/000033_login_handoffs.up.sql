@@ -0,0 +1,13 @@
+-- One-time codes for LOGIN_COOKIE_HANDOFF: the callback sets the code in an HttpOnly cookie
+-- and POST /auth/finalize trades it for the access token, so no token ever appears in a URL.
+CREATE TABLE IF NOT EXISTS login_handoffs (
+  code_hash TEXT PRIMARY KEY,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  github_login TEXT NOT NULL,
+  id_token TEXT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  expires_at TIMESTAMPTZ NOT NULL,
+  used_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_handoffs_expires ON login_handoffs(expires_at);
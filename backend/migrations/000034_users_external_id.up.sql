@@ -0,0 +1,4 @@
+-- Id of the user in an external identity system (EXTERNAL_ID_HEADER), for SSO chaining.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS external_id TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_external_id ON users(external_id) WHERE external_id IS NOT NULL;
@@ -0,0 +1,2 @@
+ALTER TABLE oauth_states DROP COLUMN IF EXISTS force_consent;
+ALTER TABLE github_accounts DROP COLUMN IF EXISTS token_granted_at;
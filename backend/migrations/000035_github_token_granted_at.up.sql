@@ -0,0 +1,7 @@
+-- When the user last went through GitHub's consent screen for this app (FORCE_REAUTH_INTERVAL).
+-- Existing rows start from their last update, the closest record of the last authorization.
+ALTER TABLE github_accounts ADD COLUMN IF NOT EXISTS token_granted_at TIMESTAMPTZ NOT NULL DEFAULT now();
+UPDATE github_accounts SET token_granted_at = updated_at;
+
+-- Set on the state of a login restarted to force re-consent.
+ALTER TABLE oauth_states ADD COLUMN IF NOT EXISTS force_consent BOOLEAN NOT NULL DEFAULT false;
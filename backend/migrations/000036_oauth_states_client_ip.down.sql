@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_oauth_states_client_ip;
+ALTER TABLE oauth_states DROP COLUMN IF EXISTS client_ip;
@@ -0,0 +1,4 @@
+-- Client IP of unauthenticated login flows, to cap pending states per IP (MAX_PENDING_STATES_PER_IP).
+ALTER TABLE oauth_states ADD COLUMN IF NOT EXISTS client_ip TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_oauth_states_client_ip ON oauth_states(client_ip, expires_at) WHERE client_ip IS NOT NULL;
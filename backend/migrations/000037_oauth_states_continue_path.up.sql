@@ -0,0 +1,2 @@
+-- Frontend path to resume after login (LoginStart ?continue=), kept server-side with the state.
+ALTER TABLE oauth_states ADD COLUMN IF NOT EXISTS continue_path TEXT;
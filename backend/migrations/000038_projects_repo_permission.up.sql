@@ -0,0 +1,4 @@
+-- The owner's role on the GitHub repo as of the last verification, for gating by exact level.
+ALTER TABLE projects
+  ADD COLUMN IF NOT EXISTS repo_permission TEXT
+  CHECK (repo_permission IN ('admin', 'maintain', 'write', 'triage', 'read'));
@@ -0,0 +1 @@
+ALTER TABLE github_accounts DROP COLUMN IF EXISTS reauth_required_at;
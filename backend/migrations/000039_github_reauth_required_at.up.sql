@@ -0,0 +1,3 @@
+-- When the stored token was first found dead, for AUTO_UNLINK_DEAD_TOKEN_AFTER.
+ALTER TABLE github_accounts ADD COLUMN IF NOT EXISTS reauth_required_at TIMESTAMPTZ;
+UPDATE github_accounts SET reauth_required_at = updated_at WHERE reauth_required AND reauth_required_at IS NULL;
@@ -0,0 +1,3 @@
+ALTER TABLE oauth_states
+  DROP COLUMN IF EXISTS code_challenge,
+  DROP COLUMN IF EXISTS code_verifier;
@@ -0,0 +1,5 @@
+-- PKCE (RFC 7636) pair for flows started with GITHUB_OAUTH_USE_PKCE. The verifier never leaves
+-- the server until the token exchange; the challenge is what went to GitHub.
+ALTER TABLE oauth_states
+  ADD COLUMN IF NOT EXISTS code_verifier TEXT,
+  ADD COLUMN IF NOT EXISTS code_challenge TEXT;
@@ -0,0 +1,18 @@
+-- Refresh tokens are never stored in the clear: each row keeps a per-token salt and
+-- SHA-256(salt || secret). Rotation links rows of one login into a family so that replaying
+-- an already-rotated token can revoke the whole family.
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+  id UUID PRIMARY KEY,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  family_id UUID NOT NULL,
+  token_salt BYTEA NOT NULL,
+  token_hash BYTEA NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  expires_at TIMESTAMPTZ NOT NULL,
+  rotated_at TIMESTAMPTZ,
+  replaced_by UUID,
+  revoked_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family ON refresh_tokens(family_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user ON refresh_tokens(user_id);
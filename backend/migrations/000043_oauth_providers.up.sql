@@ -0,0 +1,22 @@
+-- Logins through providers other than GitHub (internal/oauthprovider). Their oauth_states
+-- kind is "<provider>_login", and the account they sign in to is found via oauth_identities.
+ALTER TABLE oauth_states
+  DROP CONSTRAINT IF EXISTS oauth_states_kind_check;
+
+ALTER TABLE oauth_states
+  ADD CONSTRAINT oauth_states_kind_check CHECK (
+    kind IN ('github_link', 'github_login', 'github_app_install')
+    OR kind ~ '^[a-z][a-z0-9]*_login$'
+  );
+
+CREATE TABLE IF NOT EXISTS oauth_identities (
+  provider TEXT NOT NULL,
+  provider_user_id TEXT NOT NULL,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  login TEXT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (provider, provider_user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_oauth_identities_user_id ON oauth_identities(user_id);
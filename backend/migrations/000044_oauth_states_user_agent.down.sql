@@ -0,0 +1 @@
+ALTER TABLE oauth_states DROP COLUMN IF EXISTS user_agent_hash;
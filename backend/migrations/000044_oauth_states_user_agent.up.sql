@@ -0,0 +1,2 @@
+-- Hash of the browser family and major version that started a login (STATE_BIND_USER_AGENT).
+ALTER TABLE oauth_states ADD COLUMN IF NOT EXISTS user_agent_hash TEXT;
@@ -0,0 +1,3 @@
+-- Primary verified GitHub address, refreshed at every GitHub login. Unlike notification_email
+-- it follows changes on GitHub; NULL when the account has no verified address.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email TEXT;
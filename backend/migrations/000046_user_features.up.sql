@@ -0,0 +1,9 @@
+-- Per-user feature flags for gradual rollouts. A row means the feature is on for that user;
+-- the enabled names are embedded in access tokens (features claim) at issuance.
+CREATE TABLE IF NOT EXISTS user_features (
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  feature TEXT NOT NULL,
+  enabled_by UUID REFERENCES users(id) ON DELETE SET NULL,
+  enabled_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (user_id, feature)
+);
@@ -0,0 +1,3 @@
+-- Per-repository webhook signing secret, encrypted like GitHub tokens (TOKEN_ENC_KEYS_B64).
+-- NULL for hooks created with the shared GITHUB_WEBHOOK_SECRET.
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS webhook_secret BYTEA;
@@ -0,0 +1,3 @@
+-- A first GitHub login looks up the account to merge into by verified email among users
+-- without GitHub (emailMergeCandidate); index exactly that lookup.
+CREATE INDEX IF NOT EXISTS idx_users_email_unlinked ON users (lower(email)) WHERE github_user_id IS NULL;